@@ -0,0 +1,27 @@
+package gokv
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// It returns true if the value was stored.
+// If the store doesn't implement AtomicStore, ErrUnsupported is returned,
+// because the operation can't be emulated without a race condition.
+func SetIfAbsent(store Store, k string, v interface{}) (stored bool, err error) {
+	atomicStore, ok := store.(AtomicStore)
+	if !ok {
+		return false, ErrUnsupported
+	}
+	return atomicStore.SetIfAbsent(k, v)
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// It returns true if the new value was stored.
+// If the store doesn't implement AtomicStore, ErrUnsupported is returned,
+// because the operation can't be emulated without a race condition.
+func CompareAndSwap(store Store, k string, old, new interface{}) (swapped bool, err error) {
+	atomicStore, ok := store.(AtomicStore)
+	if !ok {
+		return false, ErrUnsupported
+	}
+	return atomicStore.CompareAndSwap(k, old, new)
+}
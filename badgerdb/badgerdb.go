@@ -1,12 +1,32 @@
 package badgerdb
 
 import (
+	"bytes"
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	"github.com/dgraph-io/badger"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
+// valueMeta is the user meta byte of the entries that store values,
+// because BadgerDB doesn't tell subscribers whether an entry is a deletion.
+const valueMeta byte = 1
+
+// watchMarkerPrefix is the prefix of the marker keys that Watch deletes to detect when its subscription is active.
+// Their events aren't sent to any watcher.
+const watchMarkerPrefix = "\x00gokv-watch-"
+
+// watchMarkerCount is used for making the marker keys of the watchers unique.
+var watchMarkerCount uint64
+
 // Store is a gokv.Store implementation for BadgerDB.
 type Store struct {
 	db    *badger.DB
@@ -17,9 +37,20 @@ type Store struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (s Store) Set(k string, v interface{}) error {
+	return s.SetWithTTL(k, v, 0)
+}
+
+// SetWithTTL stores the given value for the given key, with BadgerDB's native expiration.
+// BadgerDB stores the expiry time with a precision of seconds.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
 
 	// First turn the passed object into something that BadgerDB can handle
 	data, err := s.codec.Marshal(v)
@@ -27,13 +58,7 @@ func (s Store) Set(k string, v interface{}) error {
 		return err
 	}
 
-	err = s.db.Update(func(txn *badger.Txn) error {
-		return txn.Set([]byte(k), data)
-	})
-	if err != nil {
-		return err
-	}
-	return nil
+	return s.set(k, data, ttl)
 }
 
 // Get retrieves the stored value for the given key.
@@ -47,7 +72,473 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 		return false, err
 	}
 
-	var data []byte
+	data, found, err := s.get(k)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(k))
+	})
+	return wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return s.set(k, v, 0)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return s.get(k)
+}
+
+// SetBytesWithTTL stores the given bytes for the given key without marshalling them, with BadgerDB's native expiration.
+// It behaves like SetBytes, and like SetWithTTL regarding the TTL.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	return s.set(k, v, ttl)
+}
+
+// TTL returns the remaining time to live of the key-value pair for the given key,
+// or 0 if it was stored without a TTL.
+// BadgerDB stores the expiry time with a precision of seconds.
+// If no value is found it returns (0, false, nil).
+// The key must not be "".
+func (s Store) TTL(k string) (ttl time.Duration, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, false, err
+	}
+
+	var expiresAt uint64
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(k))
+		if err != nil {
+			return err
+		}
+		expiresAt = item.ExpiresAt()
+		return nil
+	})
+	if err == badger.ErrKeyNotFound {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, wrapError(err)
+	}
+	if expiresAt == 0 {
+		return 0, true, nil
+	}
+	ttl = time.Until(time.Unix(int64(expiresAt), 0))
+	if ttl <= 0 {
+		return 0, false, nil
+	}
+	return ttl, true, nil
+}
+
+// SetContext stores the given value for the given key.
+// BadgerDB doesn't support contexts, so the context is only checked before the transaction is started.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in byte-sorted order.
+// Values aren't fetched while iterating.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		p := []byte(prefix)
+		for it.Seek(p); it.ValidForPrefix(p); it.Next() {
+			// Converting to a string copies the key, which is only valid until Next() is called.
+			if err := fn(string(it.Item().Key())); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return wrapError(err)
+}
+
+// SetMulti stores the given values for the given keys in a single transaction.
+// If the transaction gets too big for BadgerDB, badger.ErrTxnTooBig is returned
+// and none of the values are stored.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	data := make([][]byte, len(vs))
+	for i, v := range vs {
+		var err error
+		if data[i], err = s.codec.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		for i, k := range keys {
+			if err := txn.SetEntry(newEntry(k, data[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return wrapError(err)
+}
+
+// GetMulti retrieves the values for the given keys in a single read-only transaction.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(keys))
+	found = make([]bool, len(keys))
+	err = s.db.View(func(txn *badger.Txn) error {
+		for i, k := range keys {
+			item, err := txn.Get([]byte(k))
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			// item.Value() is only valid within the transaction.
+			if data[i], err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+			found[i] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	for i, v := range vs {
+		if !found[i] {
+			continue
+		}
+		if err := s.codec.Unmarshal(data[i], v); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys in a single transaction.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		for _, k := range keys {
+			if err := txn.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return wrapError(err)
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// The check and the write happen in the same transaction.
+// BadgerDB detects conflicts with concurrent transactions when committing,
+// in which case the transaction is retried.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.updateWithRetry(func(txn *badger.Txn) error {
+		stored = false
+		_, err := txn.Get([]byte(k))
+		if err == nil {
+			return nil
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+		stored = true
+		return txn.SetEntry(newEntry(k, data))
+	})
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return stored, nil
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The values are compared in their marshalled form,
+// in the same transaction in which the new value is stored.
+// BadgerDB detects conflicts with concurrent transactions when committing,
+// in which case the transaction is retried.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := s.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := s.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.updateWithRetry(func(txn *badger.Txn) error {
+		swapped = false
+		item, err := txn.Get([]byte(k))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		equal := false
+		err = item.Value(func(data []byte) error {
+			equal = bytes.Equal(data, oldData)
+			return nil
+		})
+		if err != nil || !equal {
+			return err
+		}
+		swapped = true
+		return txn.SetEntry(newEntry(k, newData))
+	})
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return swapped, nil
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// The value is read, incremented and written in the same transaction.
+// BadgerDB detects conflicts with concurrent transactions when committing,
+// in which case the transaction is retried.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
+// The key must not be "".
+func (s Store) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+
+	var value int64
+	err := s.updateWithRetry(func(txn *badger.Txn) error {
+		var data []byte
+		var expiresAt uint64
+		item, err := txn.Get([]byte(k))
+		if err == nil {
+			expiresAt = item.ExpiresAt()
+			if data, err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+		var newData []byte
+		value, newData, err = util.IncrData(s.codec, data, delta)
+		if err != nil {
+			return err
+		}
+		entry := newEntry(k, newData)
+		entry.ExpiresAt = expiresAt
+		return txn.SetEntry(entry)
+	})
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	return value, nil
+}
+
+// Update calls fn with a new transaction, which is based on a read-write transaction of BadgerDB.
+// The transaction is committed if fn returns nil, and discarded otherwise.
+// When committing fails due to a conflict with a concurrent transaction,
+// fn is called again with a new transaction.
+// If the transaction gets too big for BadgerDB, badger.ErrTxnTooBig is returned.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	err := s.updateWithRetry(func(txn *badger.Txn) error {
+		return fn(transaction{
+			s:   s,
+			txn: txn,
+		})
+	})
+	return wrapError(err)
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix, using BadgerDB's Subscribe.
+// BadgerDB registers the subscription in the background, so Watch deletes a marker key
+// until the subscription reports the deletion, to make sure that no changes after returning are missed.
+// BadgerDB doesn't write anything when a key-value pair expires, so expiry doesn't lead to an event.
+// Events that aren't received block BadgerDB's delivery of changes to all subscribers.
+// The channel is closed when the context is canceled or the store is closed.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	marker := watchMarkerPrefix + strconv.FormatUint(atomic.AddUint64(&watchMarkerCount, 1), 10)
+	ctx, cancel := context.WithCancel(ctx)
+	subscribed := make(chan struct{})
+	stopped := make(chan struct{})
+	events := make(chan gokv.Event)
+	go func() {
+		defer cancel()
+		defer close(events)
+		defer close(stopped)
+		active := false
+		_ = s.db.Subscribe(ctx, func(kvs *badger.KVList) error {
+			for _, kv := range kvs.Kv {
+				k := string(kv.Key)
+				if strings.HasPrefix(k, watchMarkerPrefix) {
+					if k == marker && !active {
+						active = true
+						close(subscribed)
+					}
+					continue
+				}
+				// Changes before the subscription was known to be active happened before Watch returned
+				if !active {
+					continue
+				}
+				e := gokv.Event{
+					Key: k,
+				}
+				// Values are written with valueMeta, while deletions aren't
+				if len(kv.Meta) > 0 && kv.Meta[0]&valueMeta != 0 {
+					e.Type = gokv.EventPut
+					e.Value = kv.Value
+				} else {
+					e.Type = gokv.EventDelete
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		}, []byte(prefix), []byte(marker))
+	}()
+
+	// Delete the marker key until the subscription reports it, because deletions before the registration aren't reported
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		err := s.db.Update(func(txn *badger.Txn) error {
+			return txn.Delete([]byte(marker))
+		})
+		if err != nil {
+			cancel()
+			return nil, wrapError(err)
+		}
+		select {
+		case <-subscribed:
+			return events, nil
+		case <-stopped:
+			// The store was closed
+			return events, nil
+		case <-ctx.Done():
+			cancel()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// newEntry creates an entry for storing the given data for the given key,
+// which is marked with valueMeta so that Watch can tell it apart from a deletion.
+func newEntry(k string, data []byte) *badger.Entry {
+	return badger.NewEntry([]byte(k), data).WithMeta(valueMeta)
+}
+
+// set stores the given data for the given key, with the given TTL if it's not 0.
+func (s Store) set(k string, data []byte, ttl time.Duration) error {
+	entry := newEntry(k, data)
+	if ttl > 0 {
+		entry = entry.WithTTL(ttl)
+	}
+	err := s.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(entry)
+	})
+	return wrapError(err)
+}
+
+// get retrieves a copy of the stored data for the given key.
+func (s Store) get(k string) (data []byte, found bool, err error) {
 	err = s.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get([]byte(k))
 		if err != nil {
@@ -65,25 +556,56 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 	})
 	// If no value was found return false
 	if err == badger.ErrKeyNotFound {
-		return false, nil
+		return nil, false, nil
 	} else if err != nil {
-		return false, err
+		return nil, false, wrapError(err)
 	}
 
-	return true, s.codec.Unmarshal(data, v)
+	return data, true, nil
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
-// The key must not be "".
-func (s Store) Delete(k string) error {
-	if err := util.CheckKey(k); err != nil {
-		return err
+// updateWithRetry runs fn in a read-write transaction
+// and repeats it as long as committing fails due to a conflict with another transaction.
+func (s Store) updateWithRetry(fn func(txn *badger.Txn) error) error {
+	for {
+		err := s.db.Update(fn)
+		if err != badger.ErrConflict {
+			return err
+		}
 	}
+}
 
-	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete([]byte(k))
-	})
+// wrapError wraps BadgerDB errors into gokv's errors where possible.
+// BadgerDB doesn't export the errors for keys and values that exceed its size limits,
+// so their messages are compared.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	} else if err == badger.ErrBlockedWrites {
+		return util.WrapError(gokv.ErrClosed, err)
+	} else if strings.HasPrefix(err.Error(), "Key with size") {
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	} else if strings.HasPrefix(err.Error(), "Value with size") {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
+}
+
+// Clear deletes all key-value pairs, using BadgerDB's DropAll method.
+// Writes are blocked while the data is dropped, so concurrent writes fail with an error.
+func (s Store) Clear() error {
+	return wrapError(s.db.DropAll())
+}
+
+// Stats returns the size of the LSM tree and the value log as reported by BadgerDB,
+// which updates it periodically (every minute by default), so it doesn't reflect recent changes.
+// The number of keys is unknown (-1), because BadgerDB would have to iterate over all keys to count them,
+// and so are the statistics for caches.
+func (s Store) Stats() (gokv.StoreStats, error) {
+	lsm, vlog := s.db.Size()
+	result := gokv.UnknownStats
+	result.Bytes = lsm + vlog
+	return result, nil
 }
 
 // Close closes the store.
@@ -138,3 +660,78 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("badgerdb", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Dir: util.URLPath(u),
+	}
+	q := util.NewURLQuery(u)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// transaction is a gokv.Tx for BadgerDB.
+type transaction struct {
+	s   Store
+	txn *badger.Txn
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return wrapError(t.txn.SetEntry(newEntry(k, data)))
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	item, err := t.txn.Get([]byte(k))
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, wrapError(err)
+	}
+	err = item.Value(func(data []byte) error {
+		return t.s.codec.Unmarshal(data, v)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	return wrapError(t.txn.Delete([]byte(k)))
+}
@@ -169,6 +169,102 @@ func TestNonExistingDir(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestContextStore(store, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+func TestKeys(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestLister(store, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+func TestTTL(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestExpiringStore(store, t)
+}
+
+// TestBytesWithTTL tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+func TestBytesWithTTL(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestTTLStore(store, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+func TestBatch(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestBatchStore(store, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+func TestAtomic(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestAtomicStore(store, t)
+}
+
+// TestTransaction tests if transactions work properly.
+func TestTransaction(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestTransactional(store, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+func TestWatch(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestWatcher(store, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+func TestRawStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestRawStore(store, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+func TestCounter(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestCounter(store, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+func TestClear(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestClearer(store, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+func TestStats(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestStatsStore(store, t)
+}
+
 func createStore(t *testing.T, codec encoding.Codec) (badgerdb.Store, string) {
 	randPath := generateRandomTempDBpath(t)
 	options := badgerdb.Options{
@@ -1,4 +1,8 @@
 /*
 Package badgerdb contains an implementation of the `gokv.Store` interface for BadgerDB.
+
+The package registers itself for the URL scheme "badgerdb", so a store can also be created with gokv.Open,
+for example with "badgerdb:///tmp/gokv?codec=gob" (absolute path) or "badgerdb://BadgerDB" (relative path).
+The path is used as Dir and the query parameter "codec" ("json" or "gob") corresponds to the field of Options.
 */
 package badgerdb
@@ -4,7 +4,7 @@ go 1.13
 
 require (
 	github.com/AndreasBriese/bbloom v0.0.0-20190825152654-46b345b51c96 // indirect
-	github.com/dgraph-io/badger v1.6.0
+	github.com/dgraph-io/badger v1.6.1
 	github.com/golang/protobuf v1.3.2 // indirect
 	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
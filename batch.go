@@ -0,0 +1,65 @@
+package gokv
+
+import (
+	"errors"
+)
+
+// SetMulti stores the given values for the given keys.
+// If the store implements BatchStore, its SetMulti method is used.
+// Otherwise Set is called for each key-value pair,
+// stopping at the first error.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+func SetMulti(store Store, keys []string, vs []interface{}) error {
+	if batchStore, ok := store.(BatchStore); ok {
+		return batchStore.SetMulti(keys, vs)
+	}
+	if len(keys) != len(vs) {
+		return errLengthMismatch
+	}
+	for i, k := range keys {
+		if err := store.Set(k, vs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys.
+// If the store implements BatchStore, its GetMulti method is used.
+// Otherwise Get is called for each key,
+// stopping at the first error.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+func GetMulti(store Store, keys []string, vs []interface{}) (found []bool, err error) {
+	if batchStore, ok := store.(BatchStore); ok {
+		return batchStore.GetMulti(keys, vs)
+	}
+	if len(keys) != len(vs) {
+		return nil, errLengthMismatch
+	}
+	found = make([]bool, len(keys))
+	for i, k := range keys {
+		if found[i], err = store.Get(k, vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// If the store implements BatchStore, its DeleteMulti method is used.
+// Otherwise Delete is called for each key,
+// stopping at the first error.
+func DeleteMulti(store Store, keys []string) error {
+	if batchStore, ok := store.(BatchStore); ok {
+		return batchStore.DeleteMulti(keys)
+	}
+	for _, k := range keys {
+		if err := store.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errLengthMismatch = errors.New("The number of passed keys and values differs")
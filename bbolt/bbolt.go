@@ -1,17 +1,34 @@
 package bbolt
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"time"
+
 	bolt "go.etcd.io/bbolt"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
+// expiryBucketSuffix is appended to the bucket name to get the name of the bucket
+// that contains the expiry times of the key-value pairs that were stored with a TTL.
+const expiryBucketSuffix = "%expiry"
+
 // Store is a gokv.Store implementation for bbolt (formerly known as Bolt / Bolt DB).
 type Store struct {
-	db         *bolt.DB
-	bucketName string
-	codec      encoding.Codec
+	db               *bolt.DB
+	parentBuckets    []string
+	bucketName       string
+	expiryBucketName string
+	sweeper          *util.Sweeper
+	codec            encoding.Codec
 }
 
 // Set stores the given value for the given key.
@@ -28,14 +45,31 @@ func (s Store) Set(k string, v interface{}) error {
 		return err
 	}
 
-	err = s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(s.bucketName))
-		return b.Put([]byte(k), data)
-	})
+	return s.set(k, data)
+}
+
+// SetWithTTL stores the given value for the given key.
+// The expiry time is stored in a separate bucket, in the same transaction as the value.
+// After the TTL has passed, the key-value pair is deleted when it's accessed the next time
+// or by the sweeper (if configured), whichever comes first.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return s.Set(k, v)
+	}
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	return s.setWithTTL(k, data, ttl)
 }
 
 // Get retrieves the stored value for the given key.
@@ -49,9 +83,450 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 		return false, err
 	}
 
-	var data []byte
-	err = s.db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(s.bucketName))
+	data, found, err := s.get(k)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		if err := b.Delete([]byte(k)); err != nil {
+			return err
+		}
+		return s.expiryBucket(tx).Delete([]byte(k))
+	})
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return s.set(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return s.get(k)
+}
+
+// SetBytesWithTTL stores the given bytes for the given key without marshalling them, with the given time to live.
+// It behaves like SetBytes, and like SetWithTTL regarding the TTL.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return s.SetBytes(k, v)
+	}
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return s.setWithTTL(k, v, ttl)
+}
+
+// TTL returns the remaining time to live of the key-value pair for the given key,
+// or 0 if it was stored without a TTL.
+// If no value is found it returns (0, false, nil).
+// The key must not be "".
+func (s Store) TTL(k string) (ttl time.Duration, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, false, err
+	}
+
+	err = s.view(func(tx *bolt.Tx) error {
+		if s.bucket(tx).Get([]byte(k)) == nil {
+			return nil
+		}
+		expiry := s.expiryBucket(tx).Get([]byte(k))
+		if expiry == nil {
+			found = true
+			return nil
+		}
+		ttl = time.Until(time.Unix(0, int64(binary.BigEndian.Uint64(expiry))))
+		found = ttl > 0
+		return nil
+	})
+	if err != nil || !found {
+		return 0, false, err
+	}
+	return ttl, true, nil
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// bbolt requires the whole value for storing it, so it's read into memory first.
+// The key must not be "" and the reader must not be nil.
+func (s Store) SetReader(k string, r io.Reader) error {
+	if err := util.CheckKeyAndReader(k, r); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return s.set(k, data)
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// bbolt's values are only valid during a transaction, so the value is copied into memory
+// instead of keeping the transaction open until the reader is closed, which could block writes.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, found, err := s.get(k)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), true, nil
+}
+
+// SetContext stores the given value for the given key.
+// bbolt doesn't support contexts, so the context is only checked before the transaction is started.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// The check and the write happen in the same read-write transaction,
+// which bbolt never runs concurrently with another one.
+// An expired key-value pair counts as absent.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		if b.Get([]byte(k)) != nil && !s.expired(tx, []byte(k), time.Now()) {
+			return nil
+		}
+		if err := b.Put([]byte(k), data); err != nil {
+			return err
+		}
+		stored = true
+		return s.expiryBucket(tx).Delete([]byte(k))
+	})
+	if err != nil {
+		return false, err
+	}
+	return stored, nil
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The values are compared in their marshalled form,
+// in the same read-write transaction in which the new value is stored.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := s.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := s.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		data := b.Get([]byte(k))
+		if data == nil || !bytes.Equal(data, oldData) || s.expired(tx, []byte(k), time.Now()) {
+			return nil
+		}
+		if err := b.Put([]byte(k), newData); err != nil {
+			return err
+		}
+		swapped = true
+		return s.expiryBucket(tx).Delete([]byte(k))
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// The value is read, incremented and written in the same read-write transaction,
+// which bbolt never runs concurrently with another one.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
+// The key must not be "".
+func (s Store) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+
+	var value int64
+	err := s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		data := b.Get([]byte(k))
+		if data != nil && s.expired(tx, []byte(k), time.Now()) {
+			data = nil
+			if err := s.expiryBucket(tx).Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		var newData []byte
+		var err error
+		value, newData, err = util.IncrData(s.codec, data, delta)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(k), newData)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// Update calls fn with a new transaction, which is based on a read-write transaction of bbolt.
+// The transaction is committed if fn returns nil, and rolled back otherwise.
+// bbolt only allows one read-write transaction at a time, so transactions never conflict,
+// but fn must not call the store's methods, as that would lead to a deadlock.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	return s.update(func(tx *bolt.Tx) error {
+		return fn(transaction{
+			s:  s,
+			tx: tx,
+		})
+	})
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in byte-sorted order.
+// The keys are collected in a read-only transaction before fn is called the first time,
+// because bbolt doesn't allow writes while a transaction is open in the same goroutine.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	var keys []string
+	err := s.view(func(tx *bolt.Tx) error {
+		c := s.bucket(tx).Cursor()
+		p := []byte(prefix)
+		now := time.Now()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			// Nested buckets (see Bucket) have a nil value and aren't key-value pairs of the store.
+			if v == nil || s.expired(tx, k, now) {
+				continue
+			}
+			// Converting to a string copies the data, which is only valid during the transaction.
+			keys = append(keys, string(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetMulti stores the given values for the given keys in a single transaction.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	data := make([][]byte, len(vs))
+	for i, v := range vs {
+		var err error
+		if data[i], err = s.codec.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		expiryBucket := s.expiryBucket(tx)
+		for i, k := range keys {
+			if err := b.Put([]byte(k), data[i]); err != nil {
+				return err
+			}
+			if err := expiryBucket.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMulti retrieves the values for the given keys in a single read-only transaction.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(keys))
+	var expiredKeys [][]byte
+	err = s.view(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		now := time.Now()
+		for i, k := range keys {
+			if s.expired(tx, []byte(k), now) {
+				expiredKeys = append(expiredKeys, []byte(k))
+				continue
+			}
+			// The data is only valid during the transaction, so it must be copied.
+			if txData := b.Get([]byte(k)); txData != nil {
+				data[i] = append([]byte{}, txData...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range expiredKeys {
+		if err := s.deleteExpired(k); err != nil {
+			return nil, err
+		}
+	}
+	found = make([]bool, len(keys))
+	for i, v := range vs {
+		if data[i] == nil {
+			continue
+		}
+		found[i] = true
+		if err := s.codec.Unmarshal(data[i], v); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys in a single transaction.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		expiryBucket := s.expiryBucket(tx)
+		for _, k := range keys {
+			if err := b.Delete([]byte(k)); err != nil {
+				return err
+			}
+			if err := expiryBucket.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// set stores the given data for the given key and removes a TTL from a previous SetWithTTL().
+func (s Store) set(k string, data []byte) error {
+	return s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		if err := b.Put([]byte(k), data); err != nil {
+			return err
+		}
+		// Remove a TTL from a previous SetWithTTL()
+		return s.expiryBucket(tx).Delete([]byte(k))
+	})
+}
+
+// setWithTTL stores the given data for the given key, with its expiry time in the same transaction.
+func (s Store) setWithTTL(k string, data []byte, ttl time.Duration) error {
+	expiry := make([]byte, 8)
+	binary.BigEndian.PutUint64(expiry, uint64(time.Now().Add(ttl).UnixNano()))
+
+	return s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		if err := b.Put([]byte(k), data); err != nil {
+			return err
+		}
+		return s.expiryBucket(tx).Put([]byte(k), expiry)
+	})
+}
+
+// get retrieves a copy of the stored data for the given key.
+// Expired key-value pairs are deleted and reported as not found.
+func (s Store) get(k string) (data []byte, found bool, err error) {
+	expired := false
+	err = s.view(func(tx *bolt.Tx) error {
+		if s.expired(tx, []byte(k), time.Now()) {
+			expired = true
+			return nil
+		}
+		b := s.bucket(tx)
 		txData := b.Get([]byte(k))
 		// txData is only valid during the transaction.
 		// Its value must be copied to make it valid outside of the tx.
@@ -65,34 +540,222 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 		return nil
 	})
 	if err != nil {
-		return false, nil
+		return nil, false, err
+	}
+
+	if expired {
+		return nil, false, s.deleteExpired([]byte(k))
 	}
 
 	// If no value was found return false
 	if data == nil {
-		return false, nil
+		return nil, false, nil
 	}
 
-	return true, s.codec.Unmarshal(data, v)
+	return data, true, nil
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
-// The key must not be "".
-func (s Store) Delete(k string) error {
-	if err := util.CheckKey(k); err != nil {
+// expired returns true if the key-value pair for the given key was stored with a TTL
+// that has passed at the given time.
+func (s Store) expired(tx *bolt.Tx, k []byte, now time.Time) bool {
+	expiry := s.expiryBucket(tx).Get(k)
+	return expiry != nil && now.UnixNano() >= int64(binary.BigEndian.Uint64(expiry))
+}
+
+// deleteExpired deletes the key-value pair for the given key if it's expired.
+// The expiry is checked again in the write transaction,
+// because the key-value pair could have been overwritten in the meantime.
+func (s Store) deleteExpired(k []byte) error {
+	return s.update(func(tx *bolt.Tx) error {
+		if !s.expired(tx, k, time.Now()) {
+			return nil
+		}
+		if err := s.bucket(tx).Delete(k); err != nil {
+			return err
+		}
+		return s.expiryBucket(tx).Delete(k)
+	})
+}
+
+// sweep deletes all expired key-value pairs.
+func (s Store) sweep() error {
+	return s.update(func(tx *bolt.Tx) error {
+		now := time.Now()
+		// Keys must not be deleted while iterating with a cursor, so they're collected first.
+		var expiredKeys [][]byte
+		err := s.expiryBucket(tx).ForEach(func(k, expiry []byte) error {
+			if now.UnixNano() >= int64(binary.BigEndian.Uint64(expiry)) {
+				expiredKeys = append(expiredKeys, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expiredKeys {
+			if err := s.bucket(tx).Delete(k); err != nil {
+				return err
+			}
+			if err := s.expiryBucket(tx).Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Clear deletes all key-value pairs in a single transaction.
+// The nested buckets (see Bucket) are kept, so the stores for them can still be used.
+func (s Store) Clear() error {
+	return s.update(func(tx *bolt.Tx) error {
+		if err := deleteValues(s.bucket(tx)); err != nil {
+			return err
+		}
+		return deleteValues(s.expiryBucket(tx))
+	})
+}
+
+// Stats returns the number of key-value pairs, including expired ones that weren't deleted yet,
+// and the size of the pages that bbolt uses for the bucket, including the nested buckets (see Bucket).
+// The statistics for caches are unknown (-1).
+func (s Store) Stats() (gokv.StoreStats, error) {
+	result := gokv.UnknownStats
+	err := s.view(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		var keys int64
+		err := b.ForEach(func(k, v []byte) error {
+			// Nested buckets have a nil value
+			if v != nil {
+				keys++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		bucketStats := b.Stats()
+		result.Keys = keys
+		result.Bytes = int64(bucketStats.BranchInuse + bucketStats.LeafInuse)
+		// Small buckets are stored inline in their parent and don't have pages of their own
+		if b.Root() == 0 {
+			result.Bytes = int64(bucketStats.InlineBucketInuse)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// deleteValues deletes all key-value pairs of the given bucket, but not its nested buckets.
+func deleteValues(b *bolt.Bucket) error {
+	// Keys must not be deleted while iterating with a cursor, so they're collected first.
+	var keys [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		// Nested buckets have a nil value
+		if v != nil {
+			keys = append(keys, append([]byte{}, k...))
+		}
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bucket returns the bucket that contains the key-value pairs.
+func (s Store) bucket(tx *bolt.Tx) *bolt.Bucket {
+	return nestedBucket(tx, s.parentBuckets, s.bucketName)
+}
+
+// expiryBucket returns the bucket that contains the expiry times of the key-value pairs that were stored with a TTL.
+func (s Store) expiryBucket(tx *bolt.Tx) *bolt.Bucket {
+	return nestedBucket(tx, s.parentBuckets, s.expiryBucketName)
+}
 
-	return s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(s.bucketName))
-		return b.Delete([]byte(k))
+// nestedBucket returns the bucket with the given name that's nested in the given parent buckets.
+func nestedBucket(tx *bolt.Tx, parents []string, name string) *bolt.Bucket {
+	if len(parents) == 0 {
+		return tx.Bucket([]byte(name))
+	}
+	b := tx.Bucket([]byte(parents[0]))
+	for _, parent := range parents[1:] {
+		b = b.Bucket([]byte(parent))
+	}
+	return b.Bucket([]byte(name))
+}
+
+// Bucket returns a store for the bucket with the given name, which is nested in the store's bucket.
+// The bucket is created if it doesn't exist yet.
+// This allows to use separate namespaces in a single DB file, for example one for each tenant.
+// The key-value pairs of the nested bucket aren't listed by the Keys method of the parent store,
+// and the parent store can't store a key-value pair with the bucket's name as key.
+//
+// The returned store shares the DB with the parent store, so closing either of them closes the DB.
+// The background sweeping (if configured) only applies to the parent store,
+// so expired key-value pairs of the nested bucket are only deleted when they're accessed.
+func (s Store) Bucket(name string) (Store, error) {
+	result := Store{}
+	if name == "" {
+		return result, errors.New("The bucket name must not be empty")
+	}
+
+	err := s.update(func(tx *bolt.Tx) error {
+		b := s.bucket(tx)
+		if _, err := b.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return err
+		}
+		_, err := b.CreateBucketIfNotExists([]byte(name + expiryBucketSuffix))
+		return err
 	})
+	if err != nil {
+		return result, err
+	}
+
+	result.db = s.db
+	result.parentBuckets = append(append([]string{}, s.parentBuckets...), s.bucketName)
+	result.bucketName = name
+	result.expiryBucketName = name + expiryBucketSuffix
+	result.codec = s.codec
+
+	return result, nil
+}
+
+// update runs fn in a read-write transaction of bbolt.
+// Errors of bbolt are wrapped into gokv's errors where possible.
+func (s Store) update(fn func(tx *bolt.Tx) error) error {
+	return wrapError(s.db.Update(fn))
+}
+
+// view runs fn in a read-only transaction of bbolt.
+// Errors of bbolt are wrapped into gokv's errors where possible.
+func (s Store) view(fn func(tx *bolt.Tx) error) error {
+	return wrapError(s.db.View(fn))
+}
+
+// wrapError wraps bbolt errors into gokv's errors where possible.
+func wrapError(err error) error {
+	switch err {
+	case bolt.ErrDatabaseNotOpen:
+		return util.WrapError(gokv.ErrClosed, err)
+	case bolt.ErrKeyTooLarge:
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	case bolt.ErrValueTooLarge:
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
 }
 
 // Close closes the store.
 // It must be called to make sure that all open transactions finish and to release all DB resources.
 func (s Store) Close() error {
+	if s.sweeper != nil {
+		s.sweeper.Stop()
+	}
 	return s.db.Close()
 }
 
@@ -104,6 +767,10 @@ type Options struct {
 	// Path of the DB file.
 	// Optional ("bbolt.db" by default).
 	Path string
+	// Interval in which a background goroutine deletes expired key-value pairs.
+	// Without it, expired key-value pairs are only deleted when they're accessed.
+	// Optional (0 by default, which disables the background sweeping).
+	SweepInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
@@ -144,11 +811,16 @@ func NewStore(options Options) (Store, error) {
 
 	// Create a bucket if it doesn't exist yet.
 	// In bbolt key/value pairs are stored to and read from buckets.
+	// The expiry times for key-value pairs with a TTL are stored in a second bucket.
 	err = db.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte(options.BucketName))
 		if err != nil {
 			return err
 		}
+		_, err = tx.CreateBucketIfNotExists([]byte(options.BucketName + expiryBucketSuffix))
+		if err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
@@ -157,7 +829,89 @@ func NewStore(options Options) (Store, error) {
 
 	result.db = db
 	result.bucketName = options.BucketName
+	result.expiryBucketName = options.BucketName + expiryBucketSuffix
 	result.codec = options.Codec
+	if options.SweepInterval > 0 {
+		sweeper := util.StartSweeper(options.SweepInterval, result.sweep)
+		result.sweeper = &sweeper
+	}
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("bbolt", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Path: util.URLPath(u),
+	}
+	q := util.NewURLQuery(u)
+	q.String("bucketName", &options.BucketName)
+	q.Duration("sweepInterval", &options.SweepInterval)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// transaction is a gokv.Tx for bbolt.
+type transaction struct {
+	s  Store
+	tx *bolt.Tx
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := t.s.bucket(t.tx).Put([]byte(k), data); err != nil {
+		return err
+	}
+	return t.s.expiryBucket(t.tx).Delete([]byte(k))
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	// The data is only valid during the transaction, which is fine for unmarshalling it.
+	data := t.s.bucket(t.tx).Get([]byte(k))
+	if data == nil || t.s.expired(t.tx, []byte(k), time.Now()) {
+		return false, nil
+	}
+	return true, t.s.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	if err := t.s.bucket(t.tx).Delete([]byte(k)); err != nil {
+		return err
+	}
+	return t.s.expiryBucket(t.tx).Delete([]byte(k))
+}
@@ -168,6 +168,175 @@ func TestNonExistingDir(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestContextStore(store, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+func TestKeys(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestLister(store, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+func TestTTL(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestExpiringStore(store, t)
+}
+
+// TestBytesWithTTL tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+func TestBytesWithTTL(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestTTLStore(store, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+func TestBatch(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestBatchStore(store, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+func TestAtomic(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestAtomicStore(store, t)
+}
+
+// TestTransaction tests if transactions work properly.
+func TestTransaction(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestTransactional(store, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+func TestRawStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestRawStore(store, t)
+}
+
+// TestOpen tests if a store can be created with gokv.Open.
+func TestOpen(t *testing.T) {
+	path := generateRandomTempDbPath(t)
+	store, err := gokv.Open("bbolt://" + path + "?bucketName=foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp(store, path)
+
+	test.TestStore(store, t)
+
+	// The file must have been created at the given path
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestStreamStore tests if storing and retrieving values as streams works properly.
+func TestStreamStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestStreamStore(store, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+func TestCounter(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestCounter(store, t)
+}
+
+// TestBucket tests if nested buckets work properly as namespaces.
+func TestBucket(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	tenantA, err := store.Bucket("tenantA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenantB, err := store.Bucket("tenantB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.TestStore(tenantA, t)
+	test.TestLister(tenantA, t)
+	test.TestExpiringStore(tenantA, t)
+	test.TestTransactional(tenantA, t)
+	test.TestNamespaces(tenantA, tenantB, t)
+	test.TestNamespaces(store, tenantA, t)
+
+	// Buckets can be nested multiple times
+	nested, err := tenantA.Bucket("nested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.TestNamespaces(tenantA, nested, t)
+
+	// The nested buckets must not be listed as keys of the parent store
+	err = store.Keys("", func(k string) error {
+		t.Errorf("Key %v was listed, but the store only contains nested buckets", k)
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Clearing the parent store must keep the nested buckets
+	err = tenantA.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.TestClearer(store, t)
+	found, err := tenantA.Get("foo", new(test.Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+
+	_, err = store.Bucket("")
+	if err == nil {
+		t.Error("An error was expected for an empty bucket name")
+	}
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+func TestClear(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestClearer(store, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+func TestStats(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestStatsStore(store, t)
+}
+
 func createStore(t *testing.T, codec encoding.Codec) (bbolt.Store, string) {
 	path := generateRandomTempDbPath(t)
 	options := bbolt.Options{
@@ -1,4 +1,11 @@
 /*
 Package bbolt contains an implementation of the `gokv.Store` interface for bbolt (formerly known as Bolt / Bolt DB).
+
+The package registers itself for the URL scheme "bbolt", so a store can also be created with gokv.Open,
+for example with "bbolt:///tmp/gokv.db?bucketName=foo&sweepInterval=1m&codec=gob" (absolute path) or "bbolt://bbolt.db" (relative path).
+The path is used as Path and the query parameters "bucketName", "sweepInterval" and "codec" ("json" or "gob")
+correspond to the fields of Options.
+
+Store.Bucket returns a store for a nested bucket, which can be used as separate namespace in the same DB file.
 */
 package bbolt
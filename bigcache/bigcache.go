@@ -1,10 +1,14 @@
 package bigcache
 
 import (
+	"context"
+	"net/url"
+	"strings"
 	"time"
 
-	"github.com/allegro/bigcache"
+	"github.com/allegro/bigcache/v3"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -28,7 +32,7 @@ func (s Store) Set(k string, v interface{}) error {
 		return err
 	}
 
-	return s.s.Set(k, data)
+	return s.set(k, data)
 }
 
 // Get retrieves the stored value for the given key.
@@ -71,6 +75,116 @@ func (s Store) Delete(k string) error {
 	return nil
 }
 
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return s.set(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err := s.s.Get(k)
+	if err != nil {
+		if err == bigcache.ErrEntryNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// set stores the given data for the given key.
+func (s Store) set(k string, data []byte) error {
+	err := s.s.Set(k, data)
+	// BigCache doesn't export the error for entries that exceed the maximum size of a shard,
+	// which is limited by HardMaxCacheSize, so its message is compared.
+	if err != nil && err.Error() == "entry is bigger than max shard size" {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
+}
+
+// SetContext stores the given value for the given key.
+// BigCache doesn't support contexts, so the context is only checked before accessing the cache.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in no particular order.
+// Keys that are evicted during the iteration are skipped.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	it := s.s.Iterator()
+	for it.SetNext() {
+		entry, err := it.Value()
+		if err == bigcache.ErrCannotRetrieveEntry {
+			continue
+		} else if err != nil {
+			return err
+		}
+		k := entry.Key()
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear deletes all key-value pairs of the cache, using BigCache's Reset method.
+func (s Store) Clear() error {
+	return s.s.Reset()
+}
+
+// Stats returns the number of entries of the cache, the capacity of its byte queues as size
+// and BigCache's counters for hits and misses.
+// The counters include the lookups of all methods of the store, for example of GetMulti.
+// The number of evictions is unknown (-1), because BigCache doesn't count them.
+func (s Store) Stats() (gokv.StoreStats, error) {
+	stats := s.s.Stats()
+	result := gokv.UnknownStats
+	result.Keys = int64(s.s.Len())
+	result.Bytes = int64(s.s.Capacity())
+	result.Hits = stats.Hits
+	result.Misses = stats.Misses
+	return result, nil
+}
+
 // Close closes the store.
 // When called, the cache is left for removal by the garbage collector.
 func (s Store) Close() error {
@@ -115,7 +229,7 @@ func NewStore(options Options) (Store, error) {
 
 	config := bigcache.DefaultConfig(options.Eviction)
 	config.HardMaxCacheSize = options.HardMaxCacheSize
-	cache, err := bigcache.NewBigCache(config)
+	cache, err := bigcache.New(context.Background(), config)
 	if err != nil {
 		return result, err
 	}
@@ -125,3 +239,25 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("bigcache", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.Int("hardMaxCacheSize", &options.HardMaxCacheSize)
+	q.Duration("eviction", &options.Eviction)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
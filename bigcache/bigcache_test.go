@@ -174,6 +174,46 @@ func TestEvictionOnMaxSize(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	test.TestContextStore(store, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+func TestKeys(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	test.TestLister(store, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+func TestRawStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	test.TestRawStore(store, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+func TestClear(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	test.TestClearer(store, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+func TestStats(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	test.TestStatsStore(store, t)
+}
+
 func createStore(t *testing.T, codec encoding.Codec) bigcache.Store {
 	options := bigcache.Options{
 		Codec: codec,
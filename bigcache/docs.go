@@ -1,4 +1,8 @@
 /*
 Package bigcache contains an implementation of the `gokv.Store` interface for BigCache.
+
+The package registers itself for the URL scheme "bigcache", so a store can also be created with gokv.Open,
+for example with "bigcache://?hardMaxCacheSize=512&eviction=10m&codec=gob".
+The query parameters "hardMaxCacheSize" (in MiB), "eviction" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package bigcache
@@ -1,11 +1,11 @@
 module github.com/philippgille/gokv/bigcache
 
-go 1.13
+go 1.16
 
 require (
-	github.com/allegro/bigcache v1.2.1
+	github.com/allegro/bigcache/v3 v3.1.0
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/util v0.0.0-20191011213304-eb77f15b9c61
-	github.com/stretchr/testify v1.4.0 // indirect
 )
@@ -4,7 +4,7 @@ $workingDir = $(pwd).Path
 cd "$PSScriptRoot/.."; go build -v; cd $workingDir
 
 # Helper packages
-$array = @("encoding","sql","test", "util")
+$array = @("compress", "dump", "encoding", "instrument", "mirror", "resilience", "shard","sql","test", "tiered", "typed", "util")
 foreach ($moduleName in $array){
     echo "building $moduleName"
     cd "$PSScriptRoot/../$moduleName"; go build -v; cd $workingDir
@@ -19,3 +19,7 @@ cat "$PSScriptRoot/implementations" | foreach {
 # Examples
 echo "building examples"
 cd "$PSScriptRoot/../examples"; go build -v; cd $workingDir
+
+# Command-line tool
+echo "building cmd/gokv"
+cd "$PSScriptRoot/../cmd/gokv"; go build -v; cd $workingDir
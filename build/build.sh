@@ -10,7 +10,7 @@ echo "building gokv"
 (cd "$SCRIPT_DIR"/.. && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
 
 # Helper packages
-array=( encoding sql test util )
+array=( compress dump encoding instrument mirror resilience shard sql test tiered typed util )
 for MODULE_NAME in "${array[@]}"; do
     echo "building $MODULE_NAME"
     (cd "$SCRIPT_DIR"/../"$MODULE_NAME" && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
@@ -26,4 +26,8 @@ done
 echo "building examples"
 (cd "$SCRIPT_DIR"/../examples && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
 
+# Command-line tool
+echo "building cmd/gokv"
+(cd "$SCRIPT_DIR"/../cmd/gokv && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
+
 cd "$WORKING_DIR"
@@ -6,12 +6,16 @@ WORKING_DIR="$(pwd)"
 SCRIPT_DIR="$( cd "$( dirname "${BASH_SOURCE[0]}" )" >/dev/null 2>&1 && pwd )"
 
 # Helper packages
-# TODO: Currently no tests
+array=( encoding sql typed )
+for MODULE_NAME in "${array[@]}"; do
+    echo "testing $MODULE_NAME"
+    (cd "$SCRIPT_DIR"/../"$MODULE_NAME" && go test -v -race -coverprofile=coverage.txt -covermode=atomic) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
+done
 
 # Implementations
 
 # Modules that don't require a service
-array=( badgerdb bbolt bigcache file freecache gomap leveldb syncmap )
+array=( badgerdb bbolt bigcache cmd/gokv compress dump file freecache gomap instrument leveldb mirror resilience shard syncmap tiered )
 for MODULE_NAME in "${array[@]}"; do
     echo "testing $MODULE_NAME"
     (cd "$SCRIPT_DIR"/../"$MODULE_NAME" && go test -v -race -coverprofile=coverage.txt -covermode=atomic) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
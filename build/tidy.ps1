@@ -4,7 +4,7 @@ $workingDir = $(pwd).Path
 cd "$PSScriptRoot/.."; go mod tidy; cd $workingDir
 
 # Helper packages
-$array = @("encoding","sql","test", "util")
+$array = @("compress", "dump", "encoding", "instrument", "mirror", "resilience", "shard","sql","test", "tiered", "typed", "util")
 foreach ($moduleName in $array){
     echo "tidying $moduleName"
     cd "$PSScriptRoot/../$moduleName"; go mod tidy; cd $workingDir
@@ -19,3 +19,7 @@ cat "$PSScriptRoot/implementations" | foreach {
 # Examples
 echo "tidying examples"
 cd "$PSScriptRoot/../examples"; go mod tidy; cd $workingDir
+
+# Command-line tool
+echo "tidying cmd/gokv"
+cd "$PSScriptRoot/../cmd/gokv"; go mod tidy; cd $workingDir
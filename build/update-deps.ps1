@@ -4,7 +4,7 @@ $workingDir = $(pwd).Path
 # go get $(go list -f '{{if not (or .Main .Indirect)}}{{.Path}}{{end}}' -m all)
 
 # Helper packages
-$array = @("encoding","sql","test", "util")
+$array = @("compress", "dump", "encoding", "instrument", "mirror", "resilience", "shard","sql","test", "tiered", "typed", "util")
 foreach ($moduleName in $array){
     echo "updating $moduleName"
     cd "$PSScriptRoot/../$moduleName"; go get -u -t; go mod tidy; cd $workingDir
@@ -19,3 +19,7 @@ cat "$PSScriptRoot/implementations" | foreach {
 # Examples
 echo "updating examples"
 cd "$PSScriptRoot/../examples"; go get -u -t; go mod tidy; cd $workingDir
+
+# Command-line tool
+echo "updating cmd/gokv"
+cd "$PSScriptRoot/../cmd/gokv"; go get -u -t; go mod tidy; cd $workingDir
@@ -15,7 +15,7 @@ export GO111MODULE=on
 # go get $(go list -f '{{if not (or .Main .Indirect)}}{{.Path}}{{end}}' -m all)
 
 # Helper packages
-array=( encoding sql test util )
+array=( compress dump encoding instrument mirror resilience shard sql test tiered typed util )
 for MODULE_NAME in "${array[@]}"; do
     echo "updating $MODULE_NAME"
     (cd "$SCRIPT_DIR"/../"$MODULE_NAME" && go get -u -t && go mod tidy) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
@@ -30,4 +30,7 @@ done
 # Examples
 (cd "$SCRIPT_DIR"/../examples && go get -u -t && go mod tidy) || (cd "$WORKING_DIR" && echo "update failed" && exit 1)
 
+# Command-line tool
+(cd "$SCRIPT_DIR"/../cmd/gokv && go get -u -t && go mod tidy) || (cd "$WORKING_DIR" && echo "update failed" && exit 1)
+
 cd "$WORKING_DIR"
@@ -0,0 +1,22 @@
+package gokv
+
+// Clear deletes all key-value pairs of the store.
+// If the store implements Clearer, its Clear method is used.
+// Otherwise, if the store implements Lister, Delete is called for each key,
+// stopping at the first error.
+// If the store implements neither, ErrUnsupported is returned.
+func Clear(store Store) error {
+	if clearer, ok := store.(Clearer); ok {
+		return clearer.Clear()
+	}
+	lister, ok := store.(Lister)
+	if !ok {
+		return ErrUnsupported
+	}
+	return deleteKeys(lister)
+}
+
+// deleteKeys deletes all key-value pairs whose keys are listed by the given Lister.
+func deleteKeys(lister Lister) error {
+	return lister.Keys("", lister.Delete)
+}
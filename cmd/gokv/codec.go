@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/util"
+)
+
+func init() {
+	// The generic values of JSON objects and arrays, which are encoded with gob when a value is copied or imported
+	// into a store with the gob codec
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// urlCodec returns the codec that's set by the "codec" query parameter of the given store URL,
+// which is encoding.JSON if the parameter is missing, like for all implementations.
+func urlCodec(rawURL string) (encoding.Codec, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	codec := encoding.Codec(encoding.JSON)
+	q := util.NewURLQuery(u)
+	q.Codec(&codec)
+	// The other parameters are checked by the implementation
+	q.Rest()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return codec, nil
+}
+
+// decodeValue decodes the given data with the given codec into a generic value.
+// JSON is kept as json.RawMessage, so it isn't changed when it's encoded as JSON again.
+// gob is decoded into maps, slices and basic values as described for decodeGob.
+func decodeValue(codec encoding.Codec, data []byte) (interface{}, error) {
+	switch codec.(type) {
+	case encoding.JSONcodec:
+		if !json.Valid(data) {
+			return nil, errors.New("The value isn't valid JSON")
+		}
+		return json.RawMessage(data), nil
+	case encoding.GobCodec:
+		return decodeGob(data)
+	default:
+		return nil, fmt.Errorf("The codec %T isn't supported", codec)
+	}
+}
+
+// encodeValue encodes a generic value that was returned by decodeValue with the given codec.
+func encodeValue(codec encoding.Codec, v interface{}) ([]byte, error) {
+	switch codec.(type) {
+	case encoding.JSONcodec:
+		return json.Marshal(v)
+	case encoding.GobCodec:
+		if raw, ok := v.(json.RawMessage); ok {
+			var err error
+			if v, err = jsonValue(raw); err != nil {
+				return nil, err
+			}
+		}
+		if v == nil {
+			return nil, errors.New("The value null can't be encoded with gob")
+		}
+		return codec.Marshal(v)
+	default:
+		return nil, fmt.Errorf("The codec %T isn't supported", codec)
+	}
+}
+
+// jsonValue decodes the given JSON into a generic value.
+// Unlike with json.Unmarshal, numbers without a fraction or exponent are decoded into int64,
+// so they can be decoded into integers after they're encoded with gob.
+func jsonValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertNumbers(v), nil
+}
+
+// convertNumbers replaces the json.Number values in v by int64 or float64 values.
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, elem := range v {
+			v[k] = convertNumbers(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = convertNumbers(elem)
+		}
+	}
+	return v
+}
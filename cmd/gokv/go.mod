@@ -0,0 +1,31 @@
+module github.com/philippgille/gokv/cmd/gokv
+
+go 1.13
+
+require (
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/badgerdb v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/bbolt v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/cockroachdb v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/consul v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/datastore v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/dump v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/dynamodb v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/etcd v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/file v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/hazelcast v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/ignite v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/leveldb v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/memcached v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/mongodb v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/mysql v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/postgresql v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/redis v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/s3 v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/tablestorage v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/tablestore v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/util v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/zookeeper v0.0.0-20191011213304-eb77f15b9c61
+)
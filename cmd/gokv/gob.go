@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Type IDs of the types that are predefined by the gob format.
+const (
+	gobBool      = 1
+	gobInt       = 2
+	gobUint      = 3
+	gobFloat     = 4
+	gobBytes     = 5
+	gobString    = 6
+	gobComplex   = 7
+	gobInterface = 8
+)
+
+// gobType is a type that's defined in a gob stream (the "wireType" of the gob format).
+type gobType struct {
+	name string
+	kind gobKind
+	// elem is the type ID of the elements of arrays, slices and maps.
+	elem int
+	// key is the type ID of the keys of maps.
+	key int
+	// fields are the fields of structs, in the order of their field numbers.
+	fields []gobField
+}
+
+type gobKind int
+
+const (
+	gobArray gobKind = iota
+	gobSlice
+	gobStruct
+	gobMap
+	gobGobEncoder
+	gobBinaryMarshaler
+	gobTextMarshaler
+)
+
+type gobField struct {
+	name string
+	id   int
+}
+
+// gobDecoder decodes gob data into generic Go values (like map[string]interface{} for structs) without knowing the Go types
+// that were encoded, which encoding/gob can't do.
+// It follows the structure of encoding/gob's decoder. Like there, errors are passed up with panics within the decoder.
+type gobDecoder struct {
+	// stream is the rest of the data after the current message.
+	stream gobBuffer
+	// message is the rest of the current message.
+	message gobBuffer
+	types   map[int]gobType
+}
+
+// decodeGob decodes the given gob data into a generic Go value.
+// Structs are decoded into map[string]interface{} (without the fields that had their zero value, as gob doesn't send them),
+// maps into map[string]interface{}, arrays and slices into []interface{} and numbers into int64, uint64 and float64.
+// The JSON encoding of the result can be unmarshalled into the original Go type, with the same result as decoding the gob data.
+// That's not possible for values that are encoded by their own GobEncode or MarshalBinary method (except time.Time)
+// and for maps whose keys aren't strings or integers, so they lead to an error.
+// Values of interface types are decoded into generic values as well, so they can't be unmarshalled into their original type.
+func decodeGob(data []byte) (v interface{}, err error) {
+	d := gobDecoder{
+		stream: data,
+		types:  make(map[int]gobType),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			gobErr, ok := r.(gobError)
+			if !ok {
+				panic(r)
+			}
+			err = gobErr.err
+		}
+	}()
+	id := d.typeSequence(false)
+	return d.topLevelValue(id), nil
+}
+
+// typeSequence reads type definitions until a type ID of a value follows, which it returns.
+func (d *gobDecoder) typeSequence(isInterface bool) int {
+	for {
+		if len(d.message) == 0 {
+			if len(d.stream) == 0 {
+				gobErrorf("Unexpected end of the gob data")
+			}
+			n := d.stream.uint()
+			d.message = d.stream.next(n)
+		}
+		id := d.message.int()
+		if id >= 0 {
+			return int(id)
+		}
+		d.typeDefinition(int(-id))
+		// When decoding an interface, a type definition can be followed by the byte count of a value
+		if len(d.message) > 0 {
+			if !isInterface {
+				gobErrorf("Extra data after the type definition in the gob data")
+			}
+			d.message.uint()
+		}
+	}
+}
+
+// typeDefinition reads the definition of the type with the given ID.
+func (d *gobDecoder) typeDefinition(id int) {
+	t := gobType{}
+	b := &d.message
+	// The wireType struct has exactly one of the following fields
+	b.fields(func(field int) {
+		t.kind = gobKind(field)
+		b.fields(func(typeField int) {
+			switch {
+			case typeField == 0:
+				// CommonType
+				b.fields(func(commonField int) {
+					switch commonField {
+					case 0:
+						t.name = string(b.next(b.uint()))
+					case 1:
+						b.int()
+					default:
+						gobErrorf("Invalid type definition in the gob data")
+					}
+				})
+			case t.kind == gobArray && typeField == 1, t.kind == gobSlice && typeField == 1, t.kind == gobMap && typeField == 2:
+				t.elem = int(b.int())
+			case t.kind == gobArray && typeField == 2:
+				// The length is sent with each value
+				b.int()
+			case t.kind == gobMap && typeField == 1:
+				t.key = int(b.int())
+			case t.kind == gobStruct && typeField == 1:
+				n := b.uint()
+				for i := 0; i < n; i++ {
+					f := gobField{}
+					b.fields(func(fieldField int) {
+						switch fieldField {
+						case 0:
+							f.name = string(b.next(b.uint()))
+						case 1:
+							f.id = int(b.int())
+						default:
+							gobErrorf("Invalid type definition in the gob data")
+						}
+					})
+					t.fields = append(t.fields, f)
+				}
+			default:
+				gobErrorf("Invalid type definition in the gob data")
+			}
+		})
+	})
+	d.types[id] = t
+}
+
+// topLevelValue reads a value that's not part of another value, which is framed like a struct field if it's not a struct.
+func (d *gobDecoder) topLevelValue(id int) interface{} {
+	if t, ok := d.types[id]; !ok || t.kind != gobStruct {
+		if d.message.uint() != 0 {
+			gobErrorf("Invalid value in the gob data")
+		}
+	}
+	return d.value(id)
+}
+
+// value reads a value of the type with the given ID.
+func (d *gobDecoder) value(id int) interface{} {
+	b := &d.message
+	switch id {
+	case gobBool:
+		return b.uint() != 0
+	case gobInt:
+		return b.int()
+	case gobUint:
+		return b.uint64()
+	case gobFloat:
+		return b.float()
+	case gobBytes:
+		return append([]byte(nil), b.next(b.uint())...)
+	case gobString:
+		return string(b.next(b.uint()))
+	case gobComplex:
+		return complex(b.float(), b.float())
+	case gobInterface:
+		return d.interfaceValue()
+	}
+
+	t, ok := d.types[id]
+	if !ok {
+		gobErrorf("Unknown type ID %v in the gob data", id)
+	}
+	switch t.kind {
+	case gobArray, gobSlice:
+		n := b.count()
+		// encoding/gob decodes empty slices into nil slices, which JSON represents as null
+		if n == 0 && t.kind == gobSlice {
+			return nil
+		}
+		result := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			result = append(result, d.value(t.elem))
+		}
+		return result
+	case gobMap:
+		n := b.count()
+		result := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k := d.value(t.key)
+			// Only the keys that can be unmarshalled from JSON object keys are supported
+			switch k.(type) {
+			case string, int64, uint64:
+				result[fmt.Sprint(k)] = d.value(t.elem)
+			default:
+				gobErrorf("The map keys of type %v in the gob data aren't supported", t.name)
+			}
+		}
+		return result
+	case gobStruct:
+		result := make(map[string]interface{}, len(t.fields))
+		b.fields(func(field int) {
+			if field >= len(t.fields) {
+				gobErrorf("Invalid field number %v of type %v in the gob data", field, t.name)
+			}
+			result[t.fields[field].name] = d.value(t.fields[field].id)
+		})
+		return result
+	case gobTextMarshaler:
+		return string(b.next(b.uint()))
+	default:
+		data := b.next(b.uint())
+		// time.Time is the only type of the standard library that's commonly stored in gob
+		if t.kind == gobGobEncoder && t.name == "Time" {
+			if result, ok := decodeTime(data); ok {
+				return result
+			}
+		}
+		gobErrorf("The values of type %v in the gob data are encoded by their own methods, so they can't be decoded without the Go type", t.name)
+		return nil
+	}
+}
+
+// decodeTime decodes the data of a type named "Time" that implements gob.GobEncoder.
+// Only the name of the type is sent, so the data is only accepted if it's exactly how time.Time encodes itself.
+func decodeTime(data []byte) (time.Time, bool) {
+	var result time.Time
+	if err := result.UnmarshalBinary(data); err != nil {
+		return time.Time{}, false
+	}
+	encoded, err := result.MarshalBinary()
+	if err != nil || !bytes.Equal(encoded, data) {
+		return time.Time{}, false
+	}
+	return result, true
+}
+
+// interfaceValue reads a value of an interface type, which consists of the name and type ID of the concrete type
+// (with the type's definition if it wasn't sent before) and the value.
+func (d *gobDecoder) interfaceValue() interface{} {
+	name := d.message.next(d.message.uint())
+	if len(name) == 0 {
+		return nil
+	}
+	id := d.typeSequence(true)
+	// Byte count of the value
+	d.message.uint()
+	return d.topLevelValue(id)
+}
+
+// gobBuffer reads the primitive values of the gob format.
+type gobBuffer []byte
+
+// fields calls fn with the number of each field of a struct, which fn must read.
+func (b *gobBuffer) fields(fn func(field int)) {
+	field := -1
+	for {
+		delta := b.uint()
+		if delta == 0 {
+			return
+		}
+		field += delta
+		fn(field)
+	}
+}
+
+func (b *gobBuffer) next(n int) []byte {
+	if n > len(*b) {
+		gobErrorf("Unexpected end of the gob data")
+	}
+	result := (*b)[:n]
+	*b = (*b)[n:]
+	return result
+}
+
+// uint reads an unsigned integer that's used as a length or count.
+func (b *gobBuffer) uint() int {
+	u := b.uint64()
+	if u > math.MaxInt32 {
+		gobErrorf("Invalid length %v in the gob data", u)
+	}
+	return int(u)
+}
+
+// count reads the number of elements of an array, slice or map.
+// Each element takes at least one byte, so a larger count than the remaining bytes is invalid,
+// which prevents allocating memory for a count that's only claimed by malformed data.
+func (b *gobBuffer) count() int {
+	n := b.uint()
+	if n > len(*b) {
+		gobErrorf("Invalid element count %v in the gob data", n)
+	}
+	return n
+}
+
+// uint64 reads an unsigned integer, which is either a single byte smaller than 128,
+// or the negated number of bytes followed by the big-endian bytes.
+func (b *gobBuffer) uint64() uint64 {
+	first := b.next(1)[0]
+	if first < 0x80 {
+		return uint64(first)
+	}
+	n := -int(int8(first))
+	if n > 8 {
+		gobErrorf("Invalid unsigned integer in the gob data")
+	}
+	var result uint64
+	for _, c := range b.next(n) {
+		result = result<<8 | uint64(c)
+	}
+	return result
+}
+
+// int reads a signed integer, whose sign is in the lowest bit of an unsigned integer.
+func (b *gobBuffer) int() int64 {
+	u := b.uint64()
+	if u&1 != 0 {
+		return ^int64(u >> 1)
+	}
+	return int64(u >> 1)
+}
+
+// float reads a floating point number, whose bytes are reversed in an unsigned integer.
+func (b *gobBuffer) float() float64 {
+	return math.Float64frombits(bits.ReverseBytes64(b.uint64()))
+}
+
+// gobError wraps an error that's passed up with a panic within the gobDecoder.
+type gobError struct {
+	err error
+}
+
+func gobErrorf(format string, args ...interface{}) {
+	panic(gobError{fmt.Errorf(format, args...)})
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type gobInner struct {
+	Name  string
+	Tags  []string
+	Sizes map[int]uint
+}
+
+type gobOuter struct {
+	ID      int
+	Ratio   float64
+	Enabled bool
+	Data    []byte
+	Inner   gobInner
+	Inners  []gobInner
+	Pointer *gobInner
+	Any     interface{}
+	Matrix  [2][2]int8
+	Created time.Time
+	Custom  gobCustom
+	Level   gobLevel
+}
+
+// gobCustom implements gob.GobEncoder, so its values are sent as bytes that can't be decoded without the Go type.
+type gobCustom struct {
+	secret string
+}
+
+func (c gobCustom) GobEncode() ([]byte, error) {
+	return []byte(c.secret), nil
+}
+
+func (c *gobCustom) GobDecode(data []byte) error {
+	c.secret = string(data)
+	return nil
+}
+
+// gobLevel implements encoding.BinaryMarshaler, so its values are sent as bytes that can't be decoded without the Go type.
+type gobLevel int
+
+func (l gobLevel) MarshalBinary() ([]byte, error) {
+	return []byte{byte(l)}, nil
+}
+
+func (l *gobLevel) UnmarshalBinary(data []byte) error {
+	if len(data) != 1 {
+		return errors.New("invalid level")
+	}
+	*l = gobLevel(data[0])
+	return nil
+}
+
+// Time implements gob.GobEncoder and has the same name as time.Time, but encodes itself differently.
+type Time struct {
+	Unix int64
+}
+
+func (t Time) GobEncode() ([]byte, error) {
+	return []byte(fmt.Sprint(t.Unix)), nil
+}
+
+func (t *Time) GobDecode(data []byte) error {
+	_, err := fmt.Sscan(string(data), &t.Unix)
+	return err
+}
+
+func init() {
+	gob.Register(gobInner{})
+}
+
+// TestDecodeGob tests if values of various types are decoded into generic values,
+// by comparing their JSON encoding.
+func TestDecodeGob(t *testing.T) {
+	inner := gobInner{
+		Name:  "a",
+		Tags:  []string{"x", "y"},
+		Sizes: map[int]uint{1: 10, -2: 20},
+	}
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"string", "foo", `"foo"`},
+		{"int", -123, `-123`},
+		{"uint", uint64(1) << 63, `9223372036854775808`},
+		{"float", 1.5, `1.5`},
+		{"bool", true, `true`},
+		{"bytes", []byte("foo"), `"Zm9v"`},
+		{"slice", []int{1, 2, 3}, `[1,2,3]`},
+		{"map", map[string]bool{"a": true, "b": false}, `{"a":true,"b":false}`},
+		{"nested map", map[string]map[string]int{"a": {"b": 1}}, `{"a":{"b":1}}`},
+		{"interface values", []interface{}{"a", 1, nil, inner}, `["a",1,null,{"Name":"a","Sizes":{"-2":20,"1":10},"Tags":["x","y"]}]`},
+		{"time", time.Date(2019, 10, 11, 21, 33, 4, 0, time.UTC), `"2019-10-11T21:33:04Z"`},
+		{"nested struct", gobOuter{
+			ID:      1,
+			Ratio:   0.25,
+			Enabled: true,
+			Data:    []byte{1, 2},
+			Inner:   inner,
+			Inners:  []gobInner{{Name: "b"}, inner},
+			Pointer: &gobInner{Name: "c"},
+			Any:     inner,
+			Matrix:  [2][2]int8{{1, 2}, {3, 4}},
+			Created: time.Date(2019, 10, 11, 21, 33, 4, 0, time.UTC),
+		}, `{"Any":{"Name":"a","Sizes":{"-2":20,"1":10},"Tags":["x","y"]},` +
+			`"Created":"2019-10-11T21:33:04Z",` +
+			`"Data":"AQI=",` +
+			`"Enabled":true,` +
+			`"ID":1,` +
+			`"Inner":{"Name":"a","Sizes":{"-2":20,"1":10},"Tags":["x","y"]},` +
+			`"Inners":[{"Name":"b"},{"Name":"a","Sizes":{"-2":20,"1":10},"Tags":["x","y"]}],` +
+			`"Matrix":[[1,2],[3,4]],` +
+			`"Pointer":{"Name":"c"},` +
+			`"Ratio":0.25}`},
+		// Zero values aren't sent, except for structs and arrays
+		{"empty struct", gobOuter{}, `{"Inner":{},"Matrix":[[0,0],[0,0]]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := decodeGob(encodeGob(t, test.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != test.expected {
+				t.Errorf("Expected %s, but was: %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+// TestDecodeGobRoundTrip tests if the JSON encoding of the decoded values can be unmarshalled into the original Go types,
+// with the same result as decoding the gob data with encoding/gob.
+func TestDecodeGobRoundTrip(t *testing.T) {
+	inner := gobInner{
+		Name:  "a",
+		Tags:  []string{"x", "y"},
+		Sizes: map[int]uint{1: 10, -2: 20},
+	}
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"struct", gobOuter{
+			ID:      -1,
+			Ratio:   0.25,
+			Enabled: true,
+			Data:    []byte{1, 2},
+			Inner:   inner,
+			Inners:  []gobInner{{Name: "b"}, {}, inner},
+			Pointer: &gobInner{Tags: []string{""}},
+			// The generic values of JSON, which are decoded into the same types again
+			Any:     map[string]interface{}{"a": []interface{}{"b", 1.5, true, nil}},
+			Matrix:  [2][2]int8{{1, -2}, {0, 4}},
+			Created: time.Date(2019, 10, 11, 21, 33, 4, 5, time.UTC),
+		}},
+		// Zero values aren't sent, but they're the values of missing JSON fields as well
+		{"empty struct", gobOuter{}},
+		{"struct pointer", &inner},
+		{"nested maps", map[string]map[uint8][]int{"a": {1: {1, 2}, 255: nil}, "b": {}}},
+		{"nested slices", [][]gobInner{{inner}, {}, {{Name: "b"}, inner}}},
+		{"interfaces", []interface{}{"a", 1.5, false, nil, []interface{}{map[string]interface{}{"b": "c"}}}},
+		{"time", time.Date(2019, 10, 11, 21, 33, 4, 123456789, time.UTC)},
+		{"time with offset", time.Date(2019, 10, 11, 21, 33, 4, 0, time.FixedZone("", -90*60))},
+		{"times", map[string]time.Time{"a": time.Date(2019, 10, 11, 21, 33, 4, 0, time.UTC), "zero": {}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := encodeGob(t, test.value)
+			expected := reflect.New(reflect.TypeOf(test.value))
+			if err := gob.NewDecoder(bytes.NewReader(data)).DecodeValue(expected); err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := decodeGob(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := reflect.New(reflect.TypeOf(test.value))
+			if err := json.Unmarshal(encoded, actual.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual.Elem().Interface(), expected.Elem().Interface()) {
+				t.Errorf("Expected %#v, but was: %#v (JSON: %s)", expected.Elem(), actual.Elem(), encoded)
+			}
+		})
+	}
+}
+
+// TestDecodeGobUnsupported tests if values that can't be decoded without their Go types lead to errors.
+func TestDecodeGobUnsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"GobEncoder", gobCustom{secret: "foo"}},
+		{"BinaryMarshaler", gobLevel(3)},
+		{"GobEncoder field", gobOuter{Custom: gobCustom{secret: "foo"}}},
+		{"BinaryMarshaler field", gobOuter{Level: 3}},
+		{"other type named Time", Time{Unix: 1570829584}},
+		{"bool keys", map[bool]string{true: "a"}},
+		{"float keys", map[float64]string{1.5: "a"}},
+		{"struct keys", map[struct{ A int }]string{{A: 1}: "a"}},
+		{"array keys", map[[2]int]string{{1, 2}: "a"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := decodeGob(encodeGob(t, test.value))
+			if err == nil {
+				t.Errorf("Expected an error, but the value was decoded into: %#v", v)
+			}
+		})
+	}
+}
+
+// TestDecodeGobErrors tests if truncated and malformed gob data leads to errors instead of panics.
+func TestDecodeGobErrors(t *testing.T) {
+	data := encodeGob(t, gobOuter{
+		ID:      1,
+		Inner:   gobInner{Name: "a", Tags: []string{"x"}, Sizes: map[int]uint{1: 10}},
+		Inners:  []gobInner{{Name: "b"}},
+		Any:     []interface{}{"a", 1},
+		Created: time.Date(2019, 10, 11, 21, 33, 4, 0, time.UTC),
+	})
+
+	_, err := decodeGob(nil)
+	if err == nil {
+		t.Error("Expected an error for empty data")
+	}
+
+	// Truncated data
+	for i := 1; i < len(data); i++ {
+		if _, err := decodeGobSafely(t, data[:i]); err == nil {
+			t.Errorf("Expected an error for the data truncated to %v bytes", i)
+		}
+	}
+
+	// Malformed data, which might happen to be decodable
+	for i := range data {
+		for _, b := range []byte{0x00, 0x01, 0x7f, 0x80, 0xf8, 0xff, data[i] ^ 0x40} {
+			malformed := append([]byte(nil), data...)
+			malformed[i] = b
+			decodeGobSafely(t, malformed)
+		}
+	}
+
+	// A count of elements that's larger than the data, after the type definition of []int
+	slice := encodeGob(t, []int{1, 2, 3})
+	invalidCount := append([]byte(nil), slice[:1+slice[0]]...)
+	invalidCount = append(invalidCount, 0x09, 0xff, 0x80, 0x00, 0xfc, 0x7f, 0xff, 0xff, 0xff, 0x02)
+	_, err = decodeGob(invalidCount)
+	if err == nil || !strings.Contains(err.Error(), "Invalid element count") {
+		t.Errorf("Expected an error for the invalid count, but was: %v", err)
+	}
+}
+
+// decodeGobSafely calls decodeGob and reports a panic as test error.
+func decodeGobSafely(t *testing.T, data []byte) (v interface{}, err error) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("decodeGob panicked for the data %x: %v", data, r)
+		}
+	}()
+	return decodeGob(data)
+}
+
+func encodeGob(t *testing.T, v interface{}) []byte {
+	buffer := new(bytes.Buffer)
+	if err := gob.NewEncoder(buffer).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,26 @@
+package main
+
+// The implementations register themselves for their URL schemes when they're imported.
+// The in-memory implementations (bigcache, freecache, gomap and syncmap) are left out on purpose.
+import (
+	_ "github.com/philippgille/gokv/badgerdb"
+	_ "github.com/philippgille/gokv/bbolt"
+	_ "github.com/philippgille/gokv/cockroachdb"
+	_ "github.com/philippgille/gokv/consul"
+	_ "github.com/philippgille/gokv/datastore"
+	_ "github.com/philippgille/gokv/dynamodb"
+	_ "github.com/philippgille/gokv/etcd"
+	_ "github.com/philippgille/gokv/file"
+	_ "github.com/philippgille/gokv/hazelcast"
+	_ "github.com/philippgille/gokv/ignite"
+	_ "github.com/philippgille/gokv/leveldb"
+	_ "github.com/philippgille/gokv/memcached"
+	_ "github.com/philippgille/gokv/mongodb"
+	_ "github.com/philippgille/gokv/mysql"
+	_ "github.com/philippgille/gokv/postgresql"
+	_ "github.com/philippgille/gokv/redis"
+	_ "github.com/philippgille/gokv/s3"
+	_ "github.com/philippgille/gokv/tablestorage"
+	_ "github.com/philippgille/gokv/tablestore"
+	_ "github.com/philippgille/gokv/zookeeper"
+)
@@ -0,0 +1,431 @@
+/*
+The gokv command inspects the key-value pairs of a gokv store and copies them between stores,
+for example when migrating from one implementation to another.
+
+Usage:
+
+	gokv get [--raw] URL KEY
+	gokv set URL KEY VALUE
+	gokv del URL KEY...
+	gokv list [--prefix PREFIX] URL
+	gokv copy [--prefix PREFIX] [--ignore-ttls] --from URL --to URL
+	gokv export [--prefix PREFIX] [--ignore-ttls] URL > FILE
+	gokv import URL < FILE
+
+The stores are opened with gokv.Open, so the URLs are mapped to the Options of the implementations
+as described in their package documentation, for example "redis://localhost:6379/15" or "bbolt:///var/lib/app/gokv.db?codec=gob".
+The in-memory implementations (like gomap) aren't included, as their key-value pairs are lost when the command exits.
+
+Values are printed as JSON, regardless of the codec of the store, and VALUE is parsed as JSON,
+or stored as string if it isn't valid JSON.
+"copy" copies the raw bytes if both stores use the same codec, and otherwise decodes each value and encodes it with the codec of the target store,
+for example from gob in bbolt to JSON in PostgreSQL.
+"export" and "import" use the dump package, so they write and read one dump.Record per line, with the value as it's stored (encoded as base64)
+and its expiry time, and "import" requires the store to use the same codec as the exported one.
+"copy", "export" and "import" keep the TTLs of the key-value pairs, which requires the stores to support dump.TTLStore
+if a key-value pair has a TTL, and otherwise fails. "copy" and "export" also fail for a source store that supports TTLs,
+but not dump.TTLStore (like the file store), because the TTLs would be lost, unless --ignore-ttls is passed.
+Source stores that don't support TTLs at all are treated as having no TTLs.
+
+Values that are encoded with gob are decoded without their Go type, so structs are converted into JSON objects,
+[]byte into base64 strings and time.Time into RFC 3339 strings, which applications can unmarshal into the original types.
+Values of types that encode themselves with GobEncode or MarshalBinary (except time.Time) and maps whose keys
+aren't strings or integers can't be decoded without their Go type, so they lead to an error.
+When JSON values are encoded with gob, objects are encoded as map[string]interface{} and arrays as []interface{},
+so they can't be decoded into structs and slices of other types by applications that read them with gob.
+*/
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/dump"
+	"github.com/philippgille/gokv/encoding"
+)
+
+const usage = `Usage:
+	gokv get [--raw] URL KEY
+	gokv set URL KEY VALUE
+	gokv del URL KEY...
+	gokv list [--prefix PREFIX] URL
+	gokv copy [--prefix PREFIX] [--ignore-ttls] --from URL --to URL
+	gokv export [--prefix PREFIX] [--ignore-ttls] URL > FILE
+	gokv import URL < FILE
+
+Registered URL schemes: %v
+`
+
+// errUsage is returned by commands that were called with invalid arguments, after the usage was printed.
+var errUsage = errors.New("Invalid arguments")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command that's given by args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, usage, strings.Join(gokv.Schemes(), ", "))
+		return 2
+	}
+	c := cli{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	commands := map[string]func(args []string) error{
+		"get":    c.get,
+		"set":    c.set,
+		"del":    c.del,
+		"list":   c.list,
+		"copy":   c.copy,
+		"export": c.export,
+		"import": c.importRecords,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", args[0])
+		fmt.Fprintf(stderr, usage, strings.Join(gokv.Schemes(), ", "))
+		return 2
+	}
+	err := command(args[1:])
+	switch {
+	case err == flag.ErrHelp:
+		return 0
+	case err == errUsage:
+		return 2
+	case err != nil:
+		fmt.Fprintln(stderr, "Error:", err)
+		return 1
+	}
+	return 0
+}
+
+// cli contains the commands, which read from stdin and write to stdout and stderr.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (c cli) get(args []string) error {
+	flags := c.flagSet("get", "[--raw] URL KEY")
+	raw := flags.Bool("raw", false, "Print the stored bytes instead of JSON")
+	if err := parse(flags, args, 2); err != nil {
+		return err
+	}
+
+	store, codec, err := open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	rawStore, err := asRawStore(store)
+	if err != nil {
+		return err
+	}
+
+	data, found, err := rawStore.GetBytes(flags.Arg(1))
+	if err != nil {
+		return err
+	} else if !found {
+		return fmt.Errorf("No value was found for the key %q", flags.Arg(1))
+	}
+	if *raw {
+		_, err = c.stdout.Write(data)
+		return err
+	}
+	v, err := decodeValue(codec, data)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "%s\n", data)
+	return err
+}
+
+func (c cli) set(args []string) error {
+	flags := c.flagSet("set", "URL KEY VALUE")
+	if err := parse(flags, args, 3); err != nil {
+		return err
+	}
+
+	store, codec, err := open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	rawStore, err := asRawStore(store)
+	if err != nil {
+		return err
+	}
+
+	v := json.RawMessage(flags.Arg(2))
+	if !json.Valid(v) {
+		v, _ = json.Marshal(flags.Arg(2))
+	}
+	data, err := encodeValue(codec, v)
+	if err != nil {
+		return err
+	}
+	return rawStore.SetBytes(flags.Arg(1), data)
+}
+
+func (c cli) del(args []string) error {
+	flags := c.flagSet("del", "URL KEY...")
+	if err := parse(flags, args, -2); err != nil {
+		return err
+	}
+
+	store, _, err := open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	for _, k := range flags.Args()[1:] {
+		if err := store.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c cli) list(args []string) error {
+	flags := c.flagSet("list", "[--prefix PREFIX] URL")
+	prefix := flags.String("prefix", "", "Only list the keys that start with the prefix")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	store, _, err := open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	lister, err := asLister(store)
+	if err != nil {
+		return err
+	}
+
+	return lister.Keys(*prefix, func(k string) error {
+		_, err := fmt.Fprintln(c.stdout, k)
+		return err
+	})
+}
+
+func (c cli) copy(args []string) error {
+	flags := c.flagSet("copy", "[--prefix PREFIX] [--ignore-ttls] --from URL --to URL")
+	prefix := flags.String("prefix", "", "Only copy the key-value pairs whose keys start with the prefix")
+	ignoreTTLs := flags.Bool("ignore-ttls", false, "Copy the key-value pairs without their TTLs if the source store can't report them")
+	from := flags.String("from", "", "The URL of the store to copy from")
+	to := flags.String("to", "", "The URL of the store to copy to")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	source, sourceCodec, err := open(*from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	lister, err := asLister(source)
+	if err != nil {
+		return err
+	}
+	sourceRawStore, err := asRawStore(source)
+	if err != nil {
+		return err
+	}
+	sourceHasTTLs, err := hasTTLs(source, *ignoreTTLs)
+	if err != nil {
+		return err
+	}
+	target, targetCodec, err := open(*to)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	targetRawStore, err := asRawStore(target)
+	if err != nil {
+		return err
+	}
+	sourceTTLStore, _ := source.(dump.TTLStore)
+	targetHasTTLs := gokv.Supports(target, (*dump.TTLStore)(nil))
+	targetTTLStore, _ := target.(dump.TTLStore)
+
+	copied := 0
+	err = lister.Keys(*prefix, func(k string) error {
+		var ttl time.Duration
+		if sourceHasTTLs {
+			var found bool
+			var err error
+			ttl, found, err = sourceTTLStore.TTL(k)
+			if err != nil {
+				return err
+			} else if !found {
+				// Deleted or expired in the meantime
+				return nil
+			}
+		}
+		data, found, err := sourceRawStore.GetBytes(k)
+		if err != nil {
+			return err
+		} else if !found {
+			// Deleted or expired in the meantime
+			return nil
+		}
+		if sourceCodec != targetCodec {
+			v, err := decodeValue(sourceCodec, data)
+			if err != nil {
+				return fmt.Errorf("The value for the key %q can't be decoded: %w", k, err)
+			}
+			if data, err = encodeValue(targetCodec, v); err != nil {
+				return fmt.Errorf("The value for the key %q can't be encoded: %w", k, err)
+			}
+		}
+		if ttl == 0 {
+			err = targetRawStore.SetBytes(k, data)
+		} else if !targetHasTTLs {
+			err = fmt.Errorf("The key-value pair for the key %q has a TTL, but the store %T doesn't support dump.TTLStore: %w", k, target, gokv.ErrUnsupported)
+		} else {
+			err = targetTTLStore.SetBytesWithTTL(k, data, ttl)
+		}
+		if err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	fmt.Fprintf(c.stderr, "Copied %v key-value pairs\n", copied)
+	return err
+}
+
+func (c cli) export(args []string) error {
+	flags := c.flagSet("export", "[--prefix PREFIX] [--ignore-ttls] URL > FILE")
+	prefix := flags.String("prefix", "", "Only export the key-value pairs whose keys start with the prefix")
+	ignoreTTLs := flags.Bool("ignore-ttls", false, "Export the key-value pairs without their expiry times if the store can't report them")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	store, codec, err := open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	w := bufio.NewWriter(c.stdout)
+	exported, err := dump.Export(context.Background(), store, w, dump.Options{
+		Codec:      codec,
+		Prefix:     *prefix,
+		IgnoreTTLs: *ignoreTTLs,
+	})
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	fmt.Fprintf(c.stderr, "Exported %v key-value pairs\n", exported)
+	return err
+}
+
+func (c cli) importRecords(args []string) error {
+	flags := c.flagSet("import", "URL < FILE")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+
+	store, codec, err := open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	imported, err := dump.Import(context.Background(), store, c.stdin, dump.Options{
+		Codec: codec,
+	})
+	fmt.Fprintf(c.stderr, "Imported %v key-value pairs\n", imported)
+	return err
+}
+
+// flagSet creates a flag set for the given command, whose usage is printed to stderr.
+func (c cli) flagSet(command, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: gokv %v %v\n", command, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parse parses the given arguments and checks the number of the remaining arguments,
+// which must be n, or at least -n if n is negative.
+func parse(flags *flag.FlagSet, args []string, n int) error {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if (n >= 0 && flags.NArg() != n) || (n < 0 && flags.NArg() < -n) {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// open opens the store with the given URL and returns it together with its codec.
+func open(rawURL string) (gokv.Store, encoding.Codec, error) {
+	codec, err := urlCodec(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := gokv.Open(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, codec, nil
+}
+
+func asRawStore(store gokv.Store) (gokv.RawStore, error) {
+	if !gokv.Supports(store, (*gokv.RawStore)(nil)) {
+		return nil, fmt.Errorf("The store %T doesn't support gokv.RawStore: %w", store, gokv.ErrUnsupported)
+	}
+	return store.(gokv.RawStore), nil
+}
+
+func asLister(store gokv.Store) (gokv.Lister, error) {
+	if !gokv.Supports(store, (*gokv.Lister)(nil)) {
+		return nil, fmt.Errorf("The store %T doesn't support gokv.Lister: %w", store, gokv.ErrUnsupported)
+	}
+	return store.(gokv.Lister), nil
+}
+
+// hasTTLs reports whether the TTLs of the given source store can be read.
+// It returns an error for a store that supports TTLs, but not dump.TTLStore, unless the TTLs are ignored.
+func hasTTLs(store gokv.Store, ignoreTTLs bool) (bool, error) {
+	if gokv.Supports(store, (*dump.TTLStore)(nil)) {
+		return true, nil
+	}
+	if !ignoreTTLs && gokv.Supports(store, (*gokv.ExpiringStore)(nil)) {
+		return false, fmt.Errorf("The store %T supports TTLs, but doesn't implement dump.TTLStore, so they can't be read (pass --ignore-ttls to copy without them): %w", store, gokv.ErrUnsupported)
+	}
+	return false, nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv/bbolt"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/file"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestGetSetDel tests if values can be set, printed and deleted.
+func TestGetSetDel(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, codec := range []string{"json", "gob"} {
+		u := "bbolt://" + filepath.Join(dir, codec+".db") + "?codec=" + codec
+
+		expectRun(t, "", 0, "set", u, "foo", `{"Bar":"baz"}`)
+		expectRun(t, "", 0, "set", u, "qux", "some text")
+		expectRun(t, "{\"Bar\":\"baz\"}\n", 0, "get", u, "foo")
+		expectRun(t, "\"some text\"\n", 0, "get", u, "qux")
+		expectRun(t, "", 0, "del", u, "foo", "qux")
+		expectRun(t, "", 1, "get", u, "foo")
+	}
+}
+
+// TestList tests if the keys are listed.
+func TestList(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	u := "file://" + dir
+
+	for _, k := range []string{"a1", "a2", "b1"} {
+		expectRun(t, "", 0, "set", u, k, "1")
+	}
+	expectRun(t, "a1\na2\nb1\n", 0, "list", u)
+	expectRun(t, "a1\na2\n", 0, "list", "--prefix", "a", u)
+}
+
+// TestCopy tests if values are copied between stores with different codecs.
+func TestCopy(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	// gob in bbolt
+	path := filepath.Join(dir, "gokv.db")
+	source, err := bbolt.NewStore(bbolt.Options{
+		Path:  path,
+		Codec: encoding.Gob,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = source.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = source.Set("counter", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.Close()
+
+	// To JSON in files
+	fromURL := "bbolt://" + path + "?codec=gob"
+	toURL := "file://" + filepath.Join(dir, "files")
+	expectRun(t, "", 0, "copy", "--from", fromURL, "--to", toURL)
+	target, err := file.NewStore(file.Options{
+		Directory: filepath.Join(dir, "files"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foo := test.Foo{}
+	found, err := target.Get("foo", &foo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || foo.Bar != "baz" {
+		t.Errorf("Expected %v, but was: %v (found: %v)", test.Foo{Bar: "baz"}, foo, found)
+	}
+	target.Close()
+
+	// And back to gob, for a basic value.
+	// The file store supports TTLs, but can't report them, so they must be ignored explicitly.
+	backURL := "bbolt://" + filepath.Join(dir, "back.db") + "?codec=gob"
+	expectRun(t, "", 1, "copy", "--from", toURL, "--to", backURL)
+	expectRun(t, "", 0, "copy", "--ignore-ttls", "--from", toURL, "--to", backURL)
+	back, err := bbolt.NewStore(bbolt.Options{
+		Path:  filepath.Join(dir, "back.db"),
+		Codec: encoding.Gob,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer back.Close()
+	counter := 0
+	found, err = back.Get("counter", &counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || counter != 42 {
+		t.Errorf("Expected 42, but was: %v (found: %v)", counter, found)
+	}
+
+	// TTLs are kept, and can't be copied to a store that doesn't support them
+	ttlPath := filepath.Join(dir, "ttl.db")
+	createTTLStore(t, ttlPath)
+	ttlURL := "bbolt://" + ttlPath + "?codec=gob"
+	ttlCopyPath := filepath.Join(dir, "ttl-copy.db")
+	expectRun(t, "", 0, "copy", "--from", ttlURL, "--to", "bbolt://"+ttlCopyPath)
+	checkTTLStore(t, ttlCopyPath, encoding.JSON)
+	expectRun(t, "", 1, "copy", "--from", ttlURL, "--to", "file://"+filepath.Join(dir, "ttl-files"))
+}
+
+// TestExportImport tests if exported key-value pairs can be imported, along with their TTLs.
+func TestExportImport(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	fromPath := filepath.Join(dir, "from.db")
+	createTTLStore(t, fromPath)
+	fromURL := "bbolt://" + fromPath + "?codec=gob"
+	toPath := filepath.Join(dir, "to.db")
+	toURL := "bbolt://" + toPath + "?codec=gob"
+
+	exported := runWithInput(t, "", 0, "export", fromURL)
+	records := strings.Split(strings.TrimSpace(exported), "\n")
+	if len(records) != 2 || !strings.Contains(records[0], `"k":"foo"`) || !strings.Contains(records[1], `"expiry":`) {
+		t.Errorf("Expected a record for foo and one for session with an expiry time, but was: %q", exported)
+	}
+
+	runWithInput(t, exported, 0, "import", toURL)
+	checkTTLStore(t, toPath, encoding.Gob)
+
+	// Values can only be imported into stores with the same codec
+	runWithInput(t, exported, 1, "import", "bbolt://"+filepath.Join(dir, "json.db"))
+
+	// Stores that support TTLs, but can't report them, are only exported with --ignore-ttls
+	filesURL := "file://" + filepath.Join(dir, "files")
+	runWithInput(t, "", 0, "set", filesURL, "foo", `"bar"`)
+	runWithInput(t, "", 1, "export", filesURL)
+	exported = runWithInput(t, "", 0, "export", "--ignore-ttls", filesURL)
+	if !strings.Contains(exported, `"k":"foo"`) || strings.Contains(exported, `"expiry":`) {
+		t.Errorf("Expected a record for foo without expiry time, but was: %q", exported)
+	}
+}
+
+// createTTLStore creates a bbolt store with gob values, with a value for "foo" and one for "session" that has a TTL.
+func createTTLStore(t *testing.T, path string) {
+	store, err := bbolt.NewStore(bbolt.Options{
+		Path:  path,
+		Codec: encoding.Gob,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	err = store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SetWithTTL("session", test.Foo{Bar: "qux"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkTTLStore checks if the bbolt store contains the key-value pairs of createTTLStore.
+func checkTTLStore(t *testing.T, path string, codec encoding.Codec) {
+	t.Helper()
+	store, err := bbolt.NewStore(bbolt.Options{
+		Path:  path,
+		Codec: codec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for k, expected := range map[string]string{"foo": "baz", "session": "qux"} {
+		actual := test.Foo{}
+		found, err := store.Get(k, &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || actual.Bar != expected {
+			t.Errorf("Expected %v, but was: %v (found: %v)", test.Foo{Bar: expected}, actual, found)
+		}
+	}
+	ttl, _, err := store.TTL("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 0 {
+		t.Errorf("Expected no TTL, but was: %v", ttl)
+	}
+	ttl, _, err = store.TTL("session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected a TTL of up to an hour, but was: %v", ttl)
+	}
+}
+
+// TestUsage tests if invalid arguments lead to the usage being printed.
+func TestUsage(t *testing.T) {
+	expectRun(t, "", 2)
+	expectRun(t, "", 2, "foo")
+	expectRun(t, "", 2, "get", "file://foo")
+	expectRun(t, "", 2, "copy", "--from", "file://foo")
+	expectRun(t, "", 1, "get", "unknown://foo", "bar")
+}
+
+// runWithInput runs the command with the given arguments and input, checks its exit code and returns its output.
+func runWithInput(t *testing.T, input string, expectedExitCode int, args ...string) string {
+	t.Helper()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	exitCode := run(args, strings.NewReader(input), stdout, stderr)
+	if exitCode != expectedExitCode {
+		t.Errorf("Expected exit code %v for %v, but was: %v (%v)", expectedExitCode, args, exitCode, stderr)
+	}
+	return stdout.String()
+}
+
+// expectRun runs the command with the given arguments and checks its exit code and, for successful runs, its output,
+// which it returns.
+func expectRun(t *testing.T, expectedOutput string, expectedExitCode int, args ...string) string {
+	t.Helper()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	exitCode := run(args, strings.NewReader(""), stdout, stderr)
+	if exitCode != expectedExitCode {
+		t.Errorf("Expected exit code %v for %v, but was: %v (%v)", expectedExitCode, args, exitCode, stderr)
+	}
+	if exitCode == 0 && stdout.String() != expectedOutput {
+		t.Errorf("Expected output %q for %v, but was: %q", expectedOutput, args, stdout)
+	}
+	return stdout.String()
+}
+
+func createTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir(os.TempDir(), "gokv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
@@ -2,19 +2,27 @@ package cockroachdb
 
 import (
 	gosql "database/sql"
+	"net/url"
+	"time"
 
-	// pq must just be registered as driver.
-	// We don't need to use the package directly.
-	// This comment is required by golint
-	// ("warning: a blank import should be only in a main or test package, or have a comment justifying it").
-	_ "github.com/lib/pq"
+	// pq is registered as driver and its Error type is used for wrapping errors.
+	"github.com/lib/pq"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/sql"
+	"github.com/philippgille/gokv/util"
 )
 
 const defaultDBname = "gokv"
 
+// nowMillis is the SQL expression for the database's current time in Unix epoch milliseconds.
+// The database's clock is used so that multiple clients agree on whether a key-value pair is expired.
+const nowMillis = "(EXTRACT(EPOCH FROM CLOCK_TIMESTAMP()) * 1000)::INT8"
+
+// notExpired is the SQL condition for rows that don't have an expiry time or whose expiry time hasn't passed yet.
+const notExpired = "(e IS NULL OR e > " + nowMillis + ")"
+
 // Client is a gokv.Store implementation for CockroachDB.
 type Client struct {
 	*sql.Client
@@ -39,6 +47,10 @@ type Options struct {
 	// -1 for no limit. 0 will lead to the default value (100) being set.
 	// Optional (100 by default).
 	MaxOpenConnections int
+	// Interval in which a background goroutine deletes expired key-value pairs.
+	// Without it, expired key-value pairs are only filtered out, but stay in the table until they're overwritten.
+	// Optional (0 by default, which disables the background sweeping).
+	SweepInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
@@ -97,7 +109,13 @@ func NewClient(options Options) (Client, error) {
 	// Use a "column family" so that a the row is a single entry in CockroachDB's underlying key-value store.
 	// See: https://forum.cockroachlabs.com/t/can-i-use-cockroachdb-as-a-kv-store/56.
 	// And: https://github.com/cockroachdb/docs/blob/b68c9ad8097d1efec4d2b6d849f6788a0e857215/v2.1/column-families.md
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k STRING PRIMARY KEY, v BYTES NOT NULL, FAMILY kv (k, v))")
+	// The column "e" contains the expiry time (in Unix epoch milliseconds) of key-value pairs with a TTL.
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k STRING PRIMARY KEY, v BYTES NOT NULL, e INT8, FAMILY kv (k, v, e))")
+	if err != nil {
+		return result, err
+	}
+	// Tables that were created by previous versions of gokv don't have the column yet.
+	_, err = db.Exec("ALTER TABLE " + options.TableName + " ADD COLUMN IF NOT EXISTS e INT8 FAMILY kv")
 	if err != nil {
 		return result, err
 	}
@@ -108,11 +126,20 @@ func NewClient(options Options) (Client, error) {
 
 	// Use "UPSERT" instead of "INSERT ON CONFLICT" because it doesn't do a read operation to determine the write operation,
 	// which makes it faster.
-	upsertStmt, err := db.Prepare("UPSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2)")
+	// Columns that aren't listed keep their values with "UPSERT", so "e" must be reset explicitly.
+	upsertStmt, err := db.Prepare("UPSERT INTO " + options.TableName + " (k, v, e) VALUES ($1, $2, NULL)")
 	if err != nil {
 		return result, err
 	}
-	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1")
+	setWithTTLStmt, err := db.Prepare("UPSERT INTO " + options.TableName + " (k, v, e) VALUES ($1, $2, " + nowMillis + " + $3)")
+	if err != nil {
+		return result, err
+	}
+	ttlStmt, err := db.Prepare("SELECT e - " + nowMillis + " FROM " + options.TableName + " WHERE k = $1 AND " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1 AND " + notExpired)
 	if err != nil {
 		return result, err
 	}
@@ -120,16 +147,129 @@ func NewClient(options Options) (Client, error) {
 	if err != nil {
 		return result, err
 	}
+	keysStmt, err := db.Prepare("SELECT k FROM " + options.TableName + " WHERE k LIKE $1 ESCAPE '!' AND " + notExpired + " ORDER BY k")
+	if err != nil {
+		return result, err
+	}
+	deleteExpiredStmt, err := db.Prepare("DELETE FROM " + options.TableName + " WHERE e <= " + nowMillis)
+	if err != nil {
+		return result, err
+	}
+	// Expired rows count as absent, so they're overwritten
+	setIfAbsentStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = $2, e = NULL WHERE " + options.TableName + ".e <= " + nowMillis)
+	if err != nil {
+		return result, err
+	}
+	compareAndSwapStmt, err := db.Prepare("UPDATE " + options.TableName + " SET v = $1, e = NULL WHERE k = $2 AND v = $3 AND " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	// The expiry time is checked twice, so the statement's start time is used instead of the current time,
+	// which could differ between the checks.
+	// The value is converted from BYTES to a number and back, with the delta being the value for new and expired rows.
+	stmtNowMillis := "(EXTRACT(EPOCH FROM STATEMENT_TIMESTAMP()) * 1000)::INT8"
+	incrStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, CONVERT_TO($2::INT8::STRING, 'UTF8')) ON CONFLICT (k) DO UPDATE SET " +
+		"v = CASE WHEN " + options.TableName + ".e <= " + stmtNowMillis + " THEN excluded.v ELSE CONVERT_TO((CONVERT_FROM(" + options.TableName + ".v, 'UTF8')::INT8 + $2::INT8)::STRING, 'UTF8') END, " +
+		"e = CASE WHEN " + options.TableName + ".e <= " + stmtNowMillis + " THEN NULL ELSE " + options.TableName + ".e END")
+	if err != nil {
+		return result, err
+	}
+	incrResultStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1")
+	if err != nil {
+		return result, err
+	}
+	clearStmt, err := db.Prepare("DELETE FROM " + options.TableName)
+	if err != nil {
+		return result, err
+	}
+	// CockroachDB doesn't offer the size of a single table, so only the rows are counted.
+	countStmt, err := db.Prepare("SELECT COUNT(*) FROM " + options.TableName + " WHERE " + notExpired)
+	if err != nil {
+		return result, err
+	}
 
 	c := sql.Client{
-		C:          db,
-		UpsertStmt: upsertStmt,
-		GetStmt:    getStmt,
-		DeleteStmt: deleteStmt,
-		Codec:      options.Codec,
+		C:                  db,
+		UpsertStmt:         upsertStmt,
+		GetStmt:            getStmt,
+		DeleteStmt:         deleteStmt,
+		KeysStmt:           keysStmt,
+		SetWithTTLStmt:     setWithTTLStmt,
+		TTLStmt:            ttlStmt,
+		DeleteExpiredStmt:  deleteExpiredStmt,
+		SetIfAbsentStmt:    setIfAbsentStmt,
+		CompareAndSwapStmt: compareAndSwapStmt,
+		IncrStmt:           incrStmt,
+		IncrResultStmt:     incrResultStmt,
+		ClearStmt:          clearStmt,
+		CountStmt:          countStmt,
+		Codec:              options.Codec,
+		MultiStmts:         multiStmts(options.TableName),
+		WrapError:          wrapError,
+	}
+	if options.SweepInterval > 0 {
+		if err = c.StartSweeper(options.SweepInterval); err != nil {
+			return result, err
+		}
 	}
 
 	result.Client = &c
 
 	return result, nil
 }
+
+// multiStmts returns the statements for operating on multiple rows of the given table at once.
+func multiStmts(tableName string) *sql.MultiStmts {
+	return &sql.MultiStmts{
+		Upsert: func(n int) string {
+			return "INSERT INTO " + tableName + " (k, v) VALUES " + sql.ValuesList(n, 2, sql.Dollar) + " ON CONFLICT (k) DO UPDATE SET v = excluded.v, e = NULL"
+		},
+		Get: func(n int) string {
+			return "SELECT k, v FROM " + tableName + " WHERE k IN (" + sql.InList(n, sql.Dollar) + ") AND " + notExpired
+		},
+		Delete: func(n int) string {
+			return "DELETE FROM " + tableName + " WHERE k IN (" + sql.InList(n, sql.Dollar) + ")"
+		},
+	}
+}
+
+func init() {
+	gokv.Register("cockroachdb", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+// The query parameters that don't correspond to fields of Options are kept in the connection URL.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.String("tableName", &options.TableName)
+	q.Int("maxOpenConnections", &options.MaxOpenConnections)
+	q.Duration("sweepInterval", &options.SweepInterval)
+	q.Codec(&options.Codec)
+	rest := q.Rest()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	// Use the default connection URL if the URL only contains options for gokv
+	if u.User != nil || u.Host != "" || u.Path != "" || len(rest) > 0 {
+		connectionURL := *u
+		connectionURL.Scheme = "postgres"
+		connectionURL.RawQuery = rest.Encode()
+		options.ConnectionURL = connectionURL.String()
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// wrapError wraps CockroachDB errors into gokv's errors where possible.
+func wrapError(err error) error {
+	// CockroachDB uses serialization_failure (40001) for all transaction retry errors
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "40001" {
+		return util.WrapError(gokv.ErrConflict, err)
+	}
+	return err
+}
@@ -176,6 +176,174 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestKeys(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestExpiringStore(client, t)
+}
+
+// TestBytesWithTTL tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestBytesWithTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTTLStore(client, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestBatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestBatchStore(client, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestAtomic(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestAtomicStore(client, t)
+}
+
+// TestTransaction tests if transactions work properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestTransaction(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTransactional(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestStreamStore tests if storing and retrieving values as streams works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestStreamStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestStreamStore(client, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestCounter(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestCounter(client, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestClear(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestClearer(client, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+//
+// Note: This test is only executed if the initial connection to CockroachDB works.
+func TestStats(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to CockroachDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestStatsStore(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	db, err := sql.Open("postgres", "postgres://root@localhost:26257/?sslmode=disable")
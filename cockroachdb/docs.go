@@ -1,4 +1,9 @@
 /*
 Package cockroachdb contains an implementation of the `gokv.Store` interface for CockroachDB.
+
+The package registers itself for the URL scheme "cockroachdb", so a client can also be created with gokv.Open,
+for example with "cockroachdb://root@localhost:26257/gokv?sslmode=disable&tableName=Item&codec=gob".
+The query parameters "tableName", "maxOpenConnections", "sweepInterval" and "codec" ("json" or "gob") correspond to the fields of Options,
+and the rest of the URL is used as ConnectionURL (with the scheme "postgres").
 */
 package cockroachdb
@@ -4,7 +4,9 @@ go 1.13
 
 require (
 	github.com/lib/pq v1.2.0
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/sql v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/util v0.0.0-20191011213304-eb77f15b9c61
 )
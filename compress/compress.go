@@ -0,0 +1,94 @@
+package compress
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/philippgille/gokv/encoding"
+)
+
+// The zstd encoder and decoder can be used concurrently for single values with EncodeAll() and DecodeAll().
+var (
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func init() {
+	var err error
+	// With valid options, creating them only fails if the library is broken
+	if zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+		panic("compress: creating the zstd encoder failed: " + err.Error())
+	}
+	if zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(encoding.MaxDecompressedSize)); err != nil {
+		panic("compress: creating the zstd decoder failed: " + err.Error())
+	}
+
+	encoding.RegisterCompressor(Zstd)
+	encoding.RegisterCompressor(S2)
+}
+
+// ZstdCompressor compresses data with Zstandard, which compresses better than gzip and is much faster.
+// You can use compress.Zstd instead of creating an instance of this struct.
+type ZstdCompressor struct{}
+
+// ID returns 2, the ID of zstd.
+func (c ZstdCompressor) ID() byte {
+	return 2
+}
+
+// Compress compresses the given data with zstd.
+func (c ZstdCompressor) Compress(data []byte) ([]byte, error) {
+	return zstdEncoder.EncodeAll(data, nil), nil
+}
+
+// Decompress decompresses the given zstd data.
+// If it decompresses into more than encoding.MaxDecompressedSize bytes, an error matching encoding.ErrTooLarge is returned.
+func (c ZstdCompressor) Decompress(data []byte) ([]byte, error) {
+	result, err := zstdDecoder.DecodeAll(data, nil)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		return nil, fmt.Errorf("%w: %v", encoding.ErrTooLarge, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// S2Compressor compresses data with S2, an extension of Snappy that's even faster than zstd, but doesn't compress as well.
+// It can also decompress values that were compressed with Snappy's block format.
+// You can use compress.S2 instead of creating an instance of this struct.
+type S2Compressor struct{}
+
+// ID returns 3, the ID of S2.
+func (c S2Compressor) ID() byte {
+	return 3
+}
+
+// Compress compresses the given data with S2.
+func (c S2Compressor) Compress(data []byte) ([]byte, error) {
+	return s2.Encode(nil, data), nil
+}
+
+// Decompress decompresses the given S2 or Snappy data.
+// If it decompresses into more than encoding.MaxDecompressedSize bytes, encoding.ErrTooLarge is returned.
+func (c S2Compressor) Decompress(data []byte) ([]byte, error) {
+	// The decompressed length is stored at the beginning of the data
+	n, err := s2.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if n > encoding.MaxDecompressedSize {
+		return nil, encoding.ErrTooLarge
+	}
+	return s2.Decode(nil, data)
+}
+
+// Convenience variables
+var (
+	// Zstd is a ZstdCompressor that compresses data with Zstandard.
+	Zstd = ZstdCompressor{}
+	// S2 is an S2Compressor that compresses data with S2.
+	S2 = S2Compressor{}
+)
@@ -0,0 +1,192 @@
+package compress_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/philippgille/gokv/compress"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly with compressed values.
+func TestStore(t *testing.T) {
+	// Test with zstd
+	t.Run("zstd", func(t *testing.T) {
+		store := createStore(encoding.NewCompressed(encoding.JSON, compress.Zstd).WithThreshold(0))
+		test.TestStore(store, t)
+	})
+
+	// Test with S2
+	t.Run("S2", func(t *testing.T) {
+		store := createStore(encoding.NewCompressed(encoding.JSON, compress.S2).WithThreshold(0))
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with zstd
+	t.Run("zstd", func(t *testing.T) {
+		store := createStore(encoding.NewCompressed(encoding.Gob, compress.Zstd).WithThreshold(0))
+		test.TestTypes(store, t)
+	})
+
+	// Test with S2
+	t.Run("S2", func(t *testing.T) {
+		store := createStore(encoding.NewCompressed(encoding.Gob, compress.S2).WithThreshold(0))
+		test.TestTypes(store, t)
+	})
+}
+
+// TestThreshold tests if only values that are at least as large as the threshold are compressed.
+func TestThreshold(t *testing.T) {
+	store := createStore(encoding.NewCompressed(encoding.JSON, compress.Zstd).WithThreshold(100))
+	large := test.Foo{Bar: strings.Repeat("a", 1000)}
+	err := store.Set("small", test.Foo{Bar: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("large", large)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _, err := store.GetBytes("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "\x00"+`{"Bar":"a"}` {
+		t.Errorf("Expected the small value to be uncompressed, but was: %q", data)
+	}
+	data, _, err = store.GetBytes("large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != compress.Zstd.ID() || len(data) >= 100 {
+		t.Errorf("Expected the large value to be compressed, but was: %q", data)
+	}
+	checkValue(t, store, "large", large)
+}
+
+// TestConfigChange tests if values that were stored with another algorithm or without compression can still be read.
+func TestConfigChange(t *testing.T) {
+	value := test.Foo{Bar: strings.Repeat("a", 1000)}
+	codecs := map[string]encoding.Codec{
+		"json": encoding.JSON,
+		"gzip": encoding.NewCompressed(encoding.JSON, encoding.Gzip),
+		"zstd": encoding.NewCompressed(encoding.JSON, compress.Zstd),
+		"s2":   encoding.NewCompressed(encoding.JSON, compress.S2),
+	}
+	store := createStore(encoding.JSON)
+	for name, codec := range codecs {
+		data, err := codec.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.SetBytes(name, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name := range codecs {
+		for _, algo := range []encoding.Compressor{encoding.Gzip, compress.Zstd, compress.S2} {
+			data, _, err := store.GetBytes(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := test.Foo{}
+			err = encoding.NewCompressed(encoding.JSON, algo).Unmarshal(data, &actual)
+			if err != nil {
+				t.Errorf("Expected the value stored with %v to be readable with the compressor %v, but got: %v", name, algo.ID(), err)
+			} else if actual != value {
+				t.Errorf("Expected %v, but was: %v", value, actual)
+			}
+		}
+	}
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	codec := encoding.NewCompressed(encoding.JSON, compress.Zstd)
+	err := codec.Unmarshal([]byte{compress.Zstd.ID(), 1, 2, 3}, new(test.Foo))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for registering an ID twice")
+		}
+	}()
+	encoding.RegisterCompressor(compress.Zstd)
+}
+
+// TestTooLarge tests if values that decompress into more than encoding.MaxDecompressedSize bytes lead to an error.
+func TestTooLarge(t *testing.T) {
+	// Zeros compress very well, so the compressed data is small
+	zeros := make([]byte, encoding.MaxDecompressedSize+1)
+	s2Data, err := compress.S2.Compress(zeros)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zstdData, err := compress.Zstd.Compress(zeros)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without the decompressed size in the header, which zstd only knows when compressing all data at once
+	buffer := new(bytes.Buffer)
+	writer, err := zstd.NewWriter(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write(zeros); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		algo encoding.Compressor
+		data []byte
+	}{
+		"S2":                  {compress.S2, s2Data},
+		"zstd":                {compress.Zstd, zstdData},
+		"zstd without header": {compress.Zstd, buffer.Bytes()},
+	} {
+		_, err := tc.algo.Decompress(tc.data)
+		if !errors.Is(err, encoding.ErrTooLarge) {
+			t.Errorf("Expected an error matching encoding.ErrTooLarge for %v, but was: %v", name, err)
+		}
+		err = encoding.NewCompressed(encoding.JSON, tc.algo).Unmarshal(append([]byte{tc.algo.ID()}, tc.data...), new(test.Foo))
+		if !errors.Is(err, encoding.ErrTooLarge) {
+			t.Errorf("Expected an error matching encoding.ErrTooLarge for %v, but was: %v", name, err)
+		}
+	}
+}
+
+func checkValue(t *testing.T, store gomap.Store, k string, expected test.Foo) {
+	t.Helper()
+	actual := test.Foo{}
+	found, err := store.Get(k, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual != expected {
+		t.Errorf("Expected %v, but was: %v (found: %v)", expected, actual, found)
+	}
+}
+
+func createStore(codec encoding.Codec) gomap.Store {
+	options := gomap.Options{
+		Codec: codec,
+	}
+	return gomap.NewStore(options)
+}
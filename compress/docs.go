@@ -0,0 +1,13 @@
+/*
+Package compress contains the zstd and S2 compressors for encoding.NewCompressed().
+They're in their own module so that the encoding package, which all implementations depend on, doesn't require their library.
+
+Importing the package registers the compressors with encoding.RegisterCompressor(),
+so values that were compressed with them can be read by any encoding.CompressedCodec:
+
+	codec := encoding.NewCompressed(encoding.JSON, compress.Zstd)
+	store, err := redis.NewClient(redis.Options{
+		Codec: codec,
+	})
+*/
+package compress
@@ -0,0 +1,10 @@
+module github.com/philippgille/gokv/compress
+
+go 1.22
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/gomap v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
+)
@@ -1,8 +1,14 @@
 package consul
 
 import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
 	"github.com/hashicorp/consul/api"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -18,6 +24,14 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The context is passed to the Consul API client.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -35,9 +49,9 @@ func (c Client) Set(k string, v interface{}) error {
 		Key:   k,
 		Value: data,
 	}
-	_, err = c.c.Put(&kvPair, nil)
+	_, err = c.c.Put(&kvPair, (&api.WriteOptions{}).WithContext(ctx))
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 
 	return nil
@@ -50,6 +64,13 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is passed to the Consul API client.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
@@ -57,7 +78,7 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 	if c.folder != "" {
 		k = c.folder + "/" + k
 	}
-	kvPair, _, err := c.c.Get(k, nil)
+	kvPair, _, err := c.c.Get(k, (&api.QueryOptions{}).WithContext(ctx))
 	if err != nil {
 		return false, err
 	}
@@ -74,6 +95,13 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is passed to the Consul API client.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
@@ -81,10 +109,234 @@ func (c Client) Delete(k string) error {
 	if c.folder != "" {
 		k = c.folder + "/" + k
 	}
-	_, err := c.c.Delete(k, nil)
+	_, err := c.c.Delete(k, (&api.WriteOptions{}).WithContext(ctx))
 	return err
 }
 
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair := api.KVPair{
+		Key:   k,
+		Value: v,
+	}
+	_, err := c.c.Put(&kvPair, nil)
+	return wrapError(err)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair, _, err := c.c.Get(k, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	// If no value was found return false
+	if kvPair == nil {
+		return nil, false, nil
+	}
+	return kvPair.Value, true, nil
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// It uses Consul's check-and-set with a ModifyIndex of 0, which only succeeds if the key doesn't exist.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair := api.KVPair{
+		Key:   k,
+		Value: data,
+	}
+	stored, _, err = c.c.CAS(&kvPair, nil)
+	return stored, wrapError(err)
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The stored value is retrieved and compared with the old value,
+// then the new value is stored with Consul's check-and-set,
+// which only succeeds if the key wasn't modified in the meantime (same ModifyIndex).
+// Otherwise the comparison is repeated.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	for {
+		kvPair, _, err := c.c.Get(k, nil)
+		if err != nil {
+			return false, err
+		}
+		if kvPair == nil || !bytes.Equal(kvPair.Value, oldData) {
+			return false, nil
+		}
+		newKVPair := api.KVPair{
+			Key:         k,
+			Value:       newData,
+			ModifyIndex: kvPair.ModifyIndex,
+		}
+		swapped, _, err := c.c.CAS(&newKVPair, nil)
+		if err != nil || swapped {
+			return swapped, wrapError(err)
+		}
+	}
+}
+
+// wrapError wraps errors of the Consul API client into gokv's errors where possible.
+// The client only returns the status code and body of failed requests as message,
+// so the status code 413 (Request Entity Too Large) is checked in the message.
+// Consul responds with it when a value exceeds its maximum size (512 KiB by default).
+func wrapError(err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), "Unexpected response code: 413") {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// It uses Consul's blocking queries to list the key-value pairs whenever one of them changed,
+// and compares them with the previous list to determine the changes.
+// Multiple changes of a value that happen between two queries only lead to a single event with the latest value.
+// The channel is closed when the context is canceled or a query fails.
+func (c Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	folderPrefix := ""
+	if c.folder != "" {
+		folderPrefix = c.folder + "/"
+	}
+	kvPairs, meta, err := c.c.List(folderPrefix+prefix, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	modifyIndexes := make(map[string]uint64, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		modifyIndexes[kvPair.Key] = kvPair.ModifyIndex
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		send := func(e gokv.Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		waitIndex := meta.LastIndex
+		for {
+			kvPairs, meta, err := c.c.List(folderPrefix+prefix, (&api.QueryOptions{WaitIndex: waitIndex}).WithContext(ctx))
+			if err != nil {
+				return
+			}
+			// Consul recommends resetting the index if it goes backwards
+			if meta.LastIndex < waitIndex {
+				waitIndex = 0
+			} else {
+				waitIndex = meta.LastIndex
+			}
+			newModifyIndexes := make(map[string]uint64, len(kvPairs))
+			for _, kvPair := range kvPairs {
+				newModifyIndexes[kvPair.Key] = kvPair.ModifyIndex
+				// The folder itself can exist as separate key
+				k := strings.TrimPrefix(kvPair.Key, folderPrefix)
+				if k == "" || modifyIndexes[kvPair.Key] == kvPair.ModifyIndex {
+					continue
+				}
+				if !send(gokv.Event{Key: k, Type: gokv.EventPut, Value: kvPair.Value}) {
+					return
+				}
+			}
+			for key := range modifyIndexes {
+				k := strings.TrimPrefix(key, folderPrefix)
+				if _, found := newModifyIndexes[key]; found || k == "" {
+					continue
+				}
+				if !send(gokv.Event{Key: k, Type: gokv.EventDelete}) {
+					return
+				}
+			}
+			modifyIndexes = newModifyIndexes
+		}
+	}()
+	return events, nil
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in the order in which Consul lists them, which is byte-sorted order.
+// When a folder is configured, only the keys in the folder are listed,
+// and they're passed without the folder.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	folderPrefix := ""
+	if c.folder != "" {
+		folderPrefix = c.folder + "/"
+	}
+	keys, _, err := c.c.Keys(folderPrefix+prefix, "", nil)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		k = strings.TrimPrefix(k, folderPrefix)
+		// The folder itself can exist as separate key
+		if k == "" {
+			continue
+		}
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the client.
 // In the Consul implementation this doesn't have any effect.
 func (c Client) Close() error {
@@ -146,3 +398,27 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("consul", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Address: u.Host,
+		Folder:  strings.Trim(u.Path, "/"),
+	}
+	q := util.NewURLQuery(u)
+	q.String("scheme", &options.Scheme)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
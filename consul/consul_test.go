@@ -170,6 +170,76 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to Consul works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Consul could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to Consul works.
+func TestKeys(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Consul could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+//
+// Note: This test is only executed if the initial connection to Consul works.
+func TestAtomic(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Consul could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestAtomicStore(client, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+//
+// Note: This test is only executed if the initial connection to Consul works.
+func TestWatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Consul could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to Consul works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Consul could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	client, err := api.NewClient(api.DefaultConfig())
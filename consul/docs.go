@@ -1,4 +1,9 @@
 /*
 Package consul contains an implementation of the `gokv.Store` interface for Consul.
+
+The package registers itself for the URL scheme "consul", so a client can also be created with gokv.Open,
+for example with "consul://127.0.0.1:8500/folder?scheme=https&codec=gob".
+The host is used as Address, the path as Folder
+and the query parameters "scheme" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package consul
@@ -9,6 +9,7 @@ require (
 	github.com/hashicorp/go-rootcerts v1.0.1 // indirect
 	github.com/hashicorp/golang-lru v0.5.3 // indirect
 	github.com/hashicorp/serf v0.8.5 // indirect
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/util v0.0.0-20191011213304-eb77f15b9c61
@@ -0,0 +1,12 @@
+package gokv
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// If the store doesn't implement Counter, ErrUnsupported is returned,
+// because the operation can't be emulated without a race condition.
+func Incr(store Store, k string, delta int64) (int64, error) {
+	counter, ok := store.(Counter)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+	return counter.Incr(k, delta)
+}
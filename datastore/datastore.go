@@ -3,17 +3,27 @@ package datastore
 import (
 	"context"
 	"errors"
+	"net/url"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/datastore"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 const kind = "gokv"
 
+// Cloud Datastore's limits for the number of entities in a single request.
+const (
+	maxWriteBatchSize = 500
+	maxReadBatchSize  = 1000
+)
+
 // entity is a struct that holds the actual value as a slice of bytes named "V"
 // (translated to lowercase "v" in Cloud Datastore).
 // Cloud Datastore requires a pointer to a struct as value.
@@ -24,14 +34,25 @@ type entity struct {
 
 // Client is a gokv.Store implementation for Cloud Datastore.
 type Client struct {
-	c     *datastore.Client
-	codec encoding.Codec
+	c         *datastore.Client
+	namespace string
+	codec     encoding.Codec
 }
 
 // Set stores the given value for the given key.
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return c.SetContext(tctx, k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The default timeout of 2 seconds isn't applied, the context is used instead.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -42,16 +63,11 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-	key := datastore.Key{
-		Kind: kind,
-		Name: k,
-	}
+	key := c.key(k)
 	src := entity{
 		V: data,
 	}
-	_, err = c.c.Put(tctx, &key, &src)
+	_, err = c.c.Put(ctx, key, &src)
 
 	return err
 }
@@ -63,18 +79,22 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return c.GetContext(tctx, k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The default timeout of 2 seconds isn't applied, the context is used instead.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-	key := datastore.Key{
-		Kind: kind,
-		Name: k,
-	}
+	key := c.key(k)
 	dst := new(entity)
-	err = c.c.Get(tctx, &key, dst)
+	err = c.c.Get(ctx, key, dst)
 	if err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return false, nil
@@ -90,17 +110,252 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return c.DeleteContext(tctx, k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The default timeout of 2 seconds isn't applied, the context is used instead.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	key := c.key(k)
+	return c.c.Delete(ctx, key)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	key := c.key(k)
+	src := entity{
+		V: v,
+	}
+	_, err := c.c.Put(tctx, key, &src)
+
+	return err
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
 	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	key := c.key(k)
+	dst := new(entity)
+	err = c.c.Get(tctx, key, dst)
+	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return dst.V, true, nil
+}
+
+// SetMulti stores the given values for the given keys with PutMulti.
+// Cloud Datastore limits the number of entities per request,
+// so for many key-value pairs multiple requests are sent, each with the default timeout of 2 seconds.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	// A single request must not contain multiple mutations for the same key.
+	indexes := util.UniqueKeyIndexes(keys)
+	dsKeys := make([]*datastore.Key, len(indexes))
+	src := make([]entity, len(indexes))
+	for j, i := range indexes {
+		data, err := c.codec.Marshal(vs[i])
+		if err != nil {
+			return err
+		}
+		dsKeys[j] = c.key(keys[i])
+		src[j] = entity{
+			V: data,
+		}
+	}
+
+	for start := 0; start < len(dsKeys); start += maxWriteBatchSize {
+		end := min(start+maxWriteBatchSize, len(dsKeys))
+		tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_, err := c.c.PutMulti(tctx, dsKeys[start:end], src[start:end])
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys with GetMulti.
+// Cloud Datastore limits the number of entities per request,
+// so for many keys multiple requests are sent, each with the default timeout of 2 seconds.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (c Client) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	indexes := util.UniqueKeyIndexes(keys)
+	dsKeys := make([]*datastore.Key, len(indexes))
+	for j, i := range indexes {
+		dsKeys[j] = c.key(keys[i])
+	}
+	dst := make([]entity, len(dsKeys))
+	data := make(map[string][]byte, len(dsKeys))
+	for start := 0; start < len(dsKeys); start += maxReadBatchSize {
+		end := min(start+maxReadBatchSize, len(dsKeys))
+		tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := c.c.GetMulti(tctx, dsKeys[start:end], dst[start:end])
+		cancel()
+		// Missing entities lead to a MultiError with ErrNoSuchEntity at their index
+		multiErr, isMultiErr := err.(datastore.MultiError)
+		if err != nil && !isMultiErr {
+			return nil, err
+		}
+		for j := start; j < end; j++ {
+			if isMultiErr && multiErr[j-start] != nil {
+				if multiErr[j-start] == datastore.ErrNoSuchEntity {
+					continue
+				}
+				return nil, multiErr[j-start]
+			}
+			data[dsKeys[j].Name] = dst[j].V
+		}
+	}
+
+	found = make([]bool, len(keys))
+	for i, k := range keys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+		found[i] = true
+		if err := c.codec.Unmarshal(d, vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys with DeleteMulti.
+// Cloud Datastore limits the number of entities per request,
+// so for many keys multiple requests are sent, each with the default timeout of 2 seconds.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
 		return err
 	}
 
+	indexes := util.UniqueKeyIndexes(keys)
+	dsKeys := make([]*datastore.Key, len(indexes))
+	for j, i := range indexes {
+		dsKeys[j] = c.key(keys[i])
+	}
+	for start := 0; start < len(dsKeys); start += maxWriteBatchSize {
+		end := min(start+maxWriteBatchSize, len(dsKeys))
+		tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := c.c.DeleteMulti(tctx, dsKeys[start:end])
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// key returns the Cloud Datastore key for the given key, in the client's namespace.
+func (c Client) key(k string) *datastore.Key {
+	key := datastore.NameKey(kind, k, nil)
+	key.Namespace = c.namespace
+	return key
+}
+
+// Namespace returns a client that uses the given Cloud Datastore namespace instead of the client's own namespace
+// (see Options.Namespace). The empty string is the default namespace.
+// This allows to use separate namespaces in the same project, for example one for each tenant.
+//
+// The returned client shares the connection with the original client,
+// so closing either of them closes the connection.
+func (c Client) Namespace(namespace string) Client {
+	c.namespace = namespace
+	return c
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in the order of Cloud Datastore's key ordering.
+// As opposed to the other methods, no timeout is applied, as listing many keys can take long.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	query := datastore.NewQuery(kind).Namespace(c.namespace).KeysOnly().Order("__key__")
+	if prefix != "" {
+		query = query.Filter("__key__ >=", c.key(prefix))
+	}
+	it := c.c.Run(context.Background(), query)
+	for {
+		key, err := it.Next(nil)
+		if err == iterator.Done {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// The keys are ordered, so the first key without the prefix ends the iteration.
+		if !strings.HasPrefix(key.Name, prefix) {
+			return nil
+		}
+		if err := fn(key.Name); err != nil {
+			return err
+		}
+	}
+}
+
+// Update calls fn with a new transaction, which is based on a Cloud Datastore transaction.
+// The transaction is committed if fn returns nil, and rolled back otherwise.
+// When committing fails due to a concurrent transaction, fn is called again with a new transaction
+// (up to three attempts in total). If all attempts fail, the returned error matches gokv.ErrConflict.
+// The default timeout of 2 seconds applies to all attempts together.
+// Cloud Datastore limits the number of entities that a transaction can write (500).
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
 	tctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
-	key := datastore.Key{
-		Kind: kind,
-		Name: k,
+	_, err := c.c.RunInTransaction(tctx, func(tx *datastore.Transaction) error {
+		return fn(transaction{
+			c:      c,
+			tx:     tx,
+			writes: make(map[string][]byte),
+		})
+	})
+	if err == datastore.ErrConcurrentTransaction {
+		return util.WrapError(gokv.ErrConflict, err)
 	}
-	return c.c.Delete(tctx, &key)
+	return err
 }
 
 // Close closes the client.
@@ -119,16 +374,20 @@ type Options struct {
 	// GOOGLE_APPLICATION_CREDENTIALS environment variable.
 	// Optional ("" by default, leading to a lookup via environment variable).
 	CredentialsFile string
+	// Namespace for the entities.
+	// Can be used to separate the data of multiple applications or tenants in the same project.
+	// Optional ("" by default, which is the default namespace).
+	Namespace string
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
 }
 
 // DefaultOptions is an Options object with default values.
-// CredentialsFile: "", Codec: encoding.JSON
+// CredentialsFile: "", Namespace: "", Codec: encoding.JSON
 var DefaultOptions = Options{
 	Codec: encoding.JSON,
-	// No need to set CredentialsFile because its Go zero value is fine.
+	// No need to set CredentialsFile or Namespace because their Go zero values are fine.
 }
 
 // NewClient creates a new Cloud Datastore client.
@@ -162,7 +421,106 @@ func NewClient(options Options) (Client, error) {
 	}
 
 	result.c = dsClient
+	result.namespace = options.Namespace
 	result.codec = options.Codec
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("datastore", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		ProjectID: u.Host,
+	}
+	q := util.NewURLQuery(u)
+	q.String("credentialsFile", &options.CredentialsFile)
+	q.String("namespace", &options.Namespace)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// transaction is a gokv.Tx for Cloud Datastore.
+type transaction struct {
+	c  Client
+	tx *datastore.Transaction
+	// Reads in a Cloud Datastore transaction don't see the transaction's own mutations,
+	// so the marshalled values of the keys that were set in the transaction are kept here,
+	// with nil values for the keys that were deleted.
+	writes map[string][]byte
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	key := t.c.key(k)
+	src := entity{
+		V: data,
+	}
+	if _, err = t.tx.Put(key, &src); err != nil {
+		return err
+	}
+	t.writes[k] = data
+	return nil
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, written := t.writes[k]
+	if !written {
+		key := t.c.key(k)
+		dst := new(entity)
+		err = t.tx.Get(key, dst)
+		if err == datastore.ErrNoSuchEntity {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		data = dst.V
+	} else if data == nil {
+		return false, nil
+	}
+
+	return true, t.c.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	key := t.c.key(k)
+	if err := t.tx.Delete(key); err != nil {
+		return err
+	}
+	t.writes[k] = nil
+	return nil
+}
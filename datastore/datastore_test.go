@@ -183,6 +183,95 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to Cloud Datastore works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Cloud Datastore could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to Cloud Datastore works.
+func TestKeys(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Cloud Datastore could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+//
+// Note: This test is only executed if the initial connection to Cloud Datastore works.
+func TestBatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Cloud Datastore could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestBatchStore(client, t)
+}
+
+// TestTransaction tests if transactions work properly.
+//
+// Note: This test is only executed if the initial connection to Cloud Datastore works.
+func TestTransaction(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Cloud Datastore could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTransactional(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to Cloud Datastore works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Cloud Datastore could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestNamespace tests if Cloud Datastore namespaces work properly.
+//
+// Note: This test is only executed if the initial connection to Cloud Datastore works.
+func TestNamespace(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Cloud Datastore could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	tenantA := client.Namespace("tenantA")
+	test.TestStore(tenantA, t)
+	test.TestLister(tenantA, t)
+	test.TestBatchStore(tenantA, t)
+	test.TestTransactional(tenantA, t)
+	test.TestNamespaces(tenantA, client.Namespace("tenantB"), t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	err := os.Setenv("DATASTORE_EMULATOR_HOST", "localhost:8081")
@@ -1,4 +1,8 @@
 /*
 Package datastore contains an implementation of the `gokv.Store` interface for Google Cloud Datastore.
+
+The package registers itself for the URL scheme "datastore", so a client can also be created with gokv.Open,
+for example with "datastore://my-project?credentialsFile=/home/user/Downloads/key.json&namespace=tenantA".
+The host is used as ProjectID, and the query parameters "credentialsFile", "namespace" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package datastore
@@ -7,6 +7,7 @@ require (
 	cloud.google.com/go/datastore v1.0.0
 	github.com/golang/groupcache v0.0.0-20191002201903-404acd9df4cc // indirect
 	github.com/jstemmer/go-junit-report v0.9.1 // indirect
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/util v0.0.0-20191011213304-eb77f15b9c61
@@ -0,0 +1,23 @@
+/*
+Package dump contains functions for backing up the key-value pairs of a gokv.Store and restoring them,
+possibly into a store of a different implementation, for example from bbolt to badgerdb.
+
+The dump format is independent of the implementation: It consists of one JSON object (Record) per line,
+with the key ("k"), the value as it's stored, encoded as base64 ("v"), the name of the codec that the value was encoded with ("codec")
+and the expiry time, if the key-value pair was stored with a TTL ("expiry"):
+
+	{"k":"foo","v":"eyJCYXIiOiJiYXoifQ==","codec":"json"}
+	{"k":"session-1","v":"eyJVc2VyIjoiYWxpY2UifQ==","codec":"json","expiry":"2019-10-20T08:15:00.123Z"}
+
+Exports and imports report their progress to a callback, and can be resumed from the last reported checkpoint after a failure:
+
+	f, err := os.OpenFile("backup.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	...
+	options := dump.DefaultOptions
+	options.Checkpoint = lastCheckpoint // "" for a new backup
+	options.Progress = func(p dump.Progress) {
+		lastCheckpoint = p.Checkpoint
+	}
+	exported, err := dump.Export(ctx, store, f, options)
+*/
+package dump
@@ -0,0 +1,250 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+)
+
+// Record is a key-value pair in a dump, which is written as one line of JSON.
+type Record struct {
+	// Key is the key of the key-value pair.
+	Key string `json:"k"`
+	// Value is the value as it's stored, so encoded with the store's codec. It's encoded as base64 in JSON.
+	Value []byte `json:"v"`
+	// Codec is the name of the codec that the value was encoded with, for example "json" or "gob".
+	Codec string `json:"codec"`
+	// Expiry is the time when the key-value pair expires, or nil if it doesn't expire.
+	// The expiry time is dumped instead of the remaining TTL, so a restored key-value pair expires
+	// at the same time as the original one, regardless of when the dump is restored.
+	Expiry *time.Time `json:"expiry,omitempty"`
+}
+
+// TTLStore is a gokv.RawStore that can return the remaining TTL of a key-value pair
+// and store raw bytes with a TTL, so its key-value pairs can be exported and imported with their TTLs.
+// It's implemented by the badgerdb, bbolt, cockroachdb, gomap, mysql, postgresql and redis stores.
+type TTLStore interface {
+	gokv.RawStore
+	// SetBytesWithTTL stores the given bytes for the given key without marshalling them, with the given time to live.
+	// A TTL of 0 means that the key-value pair doesn't expire.
+	SetBytesWithTTL(k string, v []byte, ttl time.Duration) error
+	// TTL returns the remaining time to live of the key-value pair for the given key,
+	// or 0 if it was stored without a TTL.
+	// If no value is found it returns (0, false, nil).
+	TTL(k string) (ttl time.Duration, found bool, err error)
+}
+
+// Progress is passed to the Progress function of the options after each exported or imported record.
+type Progress struct {
+	// Records is the number of records that were exported or imported by the current call,
+	// not including the records before the checkpoint that the call was resumed from.
+	Records int
+	// Checkpoint is the key of the last exported or imported record.
+	// When the export or import fails afterwards, it can be resumed from this record with Options.Checkpoint.
+	Checkpoint string
+}
+
+// Export writes the key-value pairs of the store to w, as one Record per line, and returns the number of written records.
+// The store must support gokv.Lister and gokv.RawStore. If it supports TTLStore, the expiry times are exported as well.
+// If it supports gokv.ExpiringStore, but not TTLStore, Export returns an error matching gokv.ErrUnsupported,
+// because the expiry times would be lost, unless Options.IgnoreTTLs is set.
+// The capabilities are detected with gokv.Supports(), so wrapped stores work as well.
+// The keys are listed first and exported in byte-sorted order, so the export can be resumed after a failure
+// by appending to the incomplete dump and passing the last Progress.Checkpoint as Options.Checkpoint.
+// Key-value pairs that are changed during the export are exported with either their old or their new value,
+// and key-value pairs that are deleted during the export aren't exported.
+// When the context is done, Export stops and returns the context's error.
+func Export(ctx context.Context, store gokv.Store, w io.Writer, options Options) (exported int, err error) {
+	options = withDefaults(options)
+	if !gokv.Supports(store, (*gokv.Lister)(nil)) {
+		return 0, fmt.Errorf("The store must support gokv.Lister: %w", gokv.ErrUnsupported)
+	}
+	if !gokv.Supports(store, (*gokv.RawStore)(nil)) {
+		return 0, fmt.Errorf("The store must support gokv.RawStore: %w", gokv.ErrUnsupported)
+	}
+	lister := store.(gokv.Lister)
+	rawStore := store.(gokv.RawStore)
+	hasTTLs := gokv.Supports(store, (*TTLStore)(nil))
+	if !hasTTLs && !options.IgnoreTTLs && gokv.Supports(store, (*gokv.ExpiringStore)(nil)) {
+		return 0, fmt.Errorf("The store supports TTLs, but doesn't implement dump.TTLStore, so the expiry times can't be exported: %w", gokv.ErrUnsupported)
+	}
+	ttlStore, _ := store.(TTLStore)
+	codec := codecName(options.Codec)
+
+	var keys []string
+	err = lister.Keys(options.Prefix, func(k string) error {
+		if k > options.Checkpoint {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(keys)
+
+	encoder := json.NewEncoder(w)
+	for _, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return exported, err
+		}
+		data, found, err := rawStore.GetBytes(k)
+		if err != nil {
+			return exported, err
+		} else if !found {
+			// Deleted or expired in the meantime
+			continue
+		}
+		record := Record{
+			Key:   k,
+			Value: data,
+			Codec: codec,
+		}
+		if hasTTLs {
+			ttl, found, err := ttlStore.TTL(k)
+			if err != nil {
+				return exported, err
+			} else if !found {
+				continue
+			}
+			if ttl > 0 {
+				expiry := time.Now().Add(ttl)
+				record.Expiry = &expiry
+			}
+		}
+		if err := encoder.Encode(record); err != nil {
+			return exported, err
+		}
+		exported++
+		if options.Progress != nil {
+			options.Progress(Progress{
+				Records:    exported,
+				Checkpoint: k,
+			})
+		}
+	}
+	return exported, nil
+}
+
+// Import reads the records that were written by Export from r, stores them in the store and returns the number of imported records.
+// The store must support gokv.RawStore, and the values must have been exported with the same codec as the store uses.
+// Records with an expiry time require the store to support TTLStore, and records that are already expired are skipped.
+// An import can be resumed after a failure by passing the last Progress.Checkpoint as Options.Checkpoint,
+// which skips all records up to and including the one with that key.
+// When the context is done, Import stops and returns the context's error.
+func Import(ctx context.Context, store gokv.Store, r io.Reader, options Options) (imported int, err error) {
+	options = withDefaults(options)
+	if !gokv.Supports(store, (*gokv.RawStore)(nil)) {
+		return 0, fmt.Errorf("The store must support gokv.RawStore: %w", gokv.ErrUnsupported)
+	}
+	rawStore := store.(gokv.RawStore)
+	hasTTLs := gokv.Supports(store, (*TTLStore)(nil))
+	ttlStore, _ := store.(TTLStore)
+	codec := codecName(options.Codec)
+
+	decoder := json.NewDecoder(r)
+	skipping := options.Checkpoint != ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return imported, err
+		}
+		record := Record{}
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, err
+		}
+		if skipping {
+			skipping = record.Key != options.Checkpoint
+			continue
+		}
+
+		if record.Codec != codec {
+			return imported, fmt.Errorf("The value for the key %q was encoded with the codec %q, but the store uses %q", record.Key, record.Codec, codec)
+		}
+		if record.Expiry == nil {
+			err = rawStore.SetBytes(record.Key, record.Value)
+		} else if !hasTTLs {
+			err = fmt.Errorf("The key-value pair for the key %q has an expiry time, but the store doesn't support dump.TTLStore: %w", record.Key, gokv.ErrUnsupported)
+		} else {
+			ttl := time.Until(*record.Expiry)
+			if ttl <= 0 {
+				// Expired since the export
+				continue
+			}
+			err = ttlStore.SetBytesWithTTL(record.Key, record.Value, ttl)
+		}
+		if err != nil {
+			return imported, err
+		}
+		imported++
+		if options.Progress != nil {
+			options.Progress(Progress{
+				Records:    imported,
+				Checkpoint: record.Key,
+			})
+		}
+	}
+	if skipping {
+		return imported, fmt.Errorf("The checkpoint %q wasn't found in the dump", options.Checkpoint)
+	}
+	return imported, nil
+}
+
+// codecName returns the name of the given codec, which is recorded for each value.
+func codecName(codec encoding.Codec) string {
+	switch codec.(type) {
+	case encoding.JSONcodec:
+		return "json"
+	case encoding.GobCodec:
+		return "gob"
+	default:
+		return fmt.Sprintf("%T", codec)
+	}
+}
+
+// Options are the options for Export and Import.
+type Options struct {
+	// Encoding format of the store's values, which is recorded for each exported value
+	// and must match the recorded one when importing.
+	// Optional (encoding.JSON by default).
+	Codec encoding.Codec
+	// Only the key-value pairs whose keys start with the prefix are exported.
+	// Not used by Import.
+	// Optional ("" by default, so all key-value pairs are exported).
+	Prefix string
+	// Export the key-value pairs of a store that supports gokv.ExpiringStore, but not TTLStore,
+	// without their expiry times, so they don't expire after an import.
+	// Not used by Import.
+	// Optional (false by default, so Export returns an error for such a store).
+	IgnoreTTLs bool
+	// The key of the last record that was exported or imported before a failure,
+	// as passed to the Progress function, to resume the export or import after it.
+	// Optional ("" by default, so the export or import starts from the beginning).
+	Checkpoint string
+	// Progress is called after each exported or imported record, for example for logging the progress
+	// or for storing the checkpoint.
+	// Optional (nil by default).
+	Progress func(p Progress)
+}
+
+// DefaultOptions is an Options object with default values.
+// Codec: encoding.JSON
+var DefaultOptions = Options{
+	Codec: encoding.JSON,
+}
+
+// withDefaults returns the given options with default values for the optional fields that aren't set.
+func withDefaults(options Options) Options {
+	// Set default values
+	if options.Codec == nil {
+		options.Codec = DefaultOptions.Codec
+	}
+	return options
+}
@@ -0,0 +1,309 @@
+package dump_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/dump"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestExportImport tests if the exported key-value pairs are imported with their values and TTLs.
+func TestExportImport(t *testing.T) {
+	source := createStore(t, "foo", "bar", "baz")
+	err := source.SetWithTTL("expiring", test.Foo{Bar: "expiring"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []dump.Progress
+	options := dump.DefaultOptions
+	options.Progress = func(p dump.Progress) {
+		progress = append(progress, p)
+	}
+	buf := new(bytes.Buffer)
+	exported, err := dump.Export(context.Background(), source, buf, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported != 4 {
+		t.Errorf("Expected 4 exported records, but was: %v", exported)
+	}
+	// The keys are exported in byte-sorted order
+	expectedProgress := []dump.Progress{
+		{Records: 1, Checkpoint: "bar"},
+		{Records: 2, Checkpoint: "baz"},
+		{Records: 3, Checkpoint: "expiring"},
+		{Records: 4, Checkpoint: "foo"},
+	}
+	checkProgress(t, expectedProgress, progress)
+	if lines := strings.Count(buf.String(), "\n"); lines != 4 {
+		t.Errorf("Expected 4 lines, but was: %v", lines)
+	}
+
+	target := gomap.NewStore(gomap.DefaultOptions)
+	progress = nil
+	imported, err := dump.Import(context.Background(), target, buf, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 4 {
+		t.Errorf("Expected 4 imported records, but was: %v", imported)
+	}
+	checkProgress(t, expectedProgress, progress)
+	for _, k := range []string{"foo", "bar", "baz", "expiring"} {
+		checkValue(t, target, k)
+	}
+	ttl, found, err := target.TTL("expiring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("Expected a TTL of about an hour, but was: %v (found: %v)", ttl, found)
+	}
+	ttl, _, err = target.TTL("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 0 {
+		t.Errorf("Expected no TTL, but was: %v", ttl)
+	}
+}
+
+// TestPrefix tests if only the key-value pairs with the given prefix are exported.
+func TestPrefix(t *testing.T) {
+	source := createStore(t, "foo", "bar", "baz")
+	options := dump.DefaultOptions
+	options.Prefix = "ba"
+	buf := new(bytes.Buffer)
+	exported, err := dump.Export(context.Background(), source, buf, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported != 2 {
+		t.Errorf("Expected 2 exported records, but was: %v", exported)
+	}
+}
+
+// TestResume tests if a failed export and import can be resumed from the last checkpoint.
+func TestResume(t *testing.T) {
+	source := createStore(t, "a", "b", "c", "d", "e")
+
+	// Export until the writer fails
+	checkpoint := ""
+	options := dump.DefaultOptions
+	options.Progress = func(p dump.Progress) {
+		checkpoint = p.Checkpoint
+	}
+	buf := new(bytes.Buffer)
+	_, err := dump.Export(context.Background(), source, &failingWriter{w: buf, n: 2}, options)
+	if err != errWrite {
+		t.Fatalf("Expected %v, but was: %v", errWrite, err)
+	}
+	if checkpoint != "b" {
+		t.Errorf("Expected the checkpoint b, but was: %v", checkpoint)
+	}
+	options.Checkpoint = checkpoint
+	exported, err := dump.Export(context.Background(), source, buf, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported != 3 {
+		t.Errorf("Expected 3 exported records, but was: %v", exported)
+	}
+	full := new(bytes.Buffer)
+	_, err = dump.Export(context.Background(), source, full, dump.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != full.String() {
+		t.Errorf("Expected the resumed export to be equal to the full export, but was:\n%v\ninstead of:\n%v", buf, full)
+	}
+
+	// Import with a store that fails
+	target := &failingStore{
+		Store: gomap.NewStore(gomap.DefaultOptions),
+		n:     3,
+	}
+	options.Checkpoint = ""
+	_, err = dump.Import(context.Background(), target, strings.NewReader(full.String()), options)
+	if err != errWrite {
+		t.Fatalf("Expected %v, but was: %v", errWrite, err)
+	}
+	if checkpoint != "c" {
+		t.Errorf("Expected the checkpoint c, but was: %v", checkpoint)
+	}
+	options.Checkpoint = checkpoint
+	target.n = 2
+	imported, err := dump.Import(context.Background(), target, strings.NewReader(full.String()), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 2 {
+		t.Errorf("Expected 2 imported records, but was: %v", imported)
+	}
+	for _, k := range []string{"a", "b", "c", "d", "e"} {
+		checkValue(t, target.Store, k)
+	}
+
+	// Unknown checkpoint
+	options.Checkpoint = "x"
+	_, err = dump.Import(context.Background(), target, strings.NewReader(full.String()), options)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	source := createStore(t, "foo")
+	buf := new(bytes.Buffer)
+	_, err := dump.Export(context.Background(), source, buf, dump.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dumped := buf.String()
+
+	// Different codec
+	options := dump.DefaultOptions
+	options.Codec = encoding.Gob
+	_, err = dump.Import(context.Background(), gomap.NewStore(gomap.DefaultOptions), strings.NewReader(dumped), options)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Expiry time without TTL support
+	err = source.SetWithTTL("foo", test.Foo{Bar: "foo"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	_, err = dump.Export(context.Background(), source, buf, dump.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := rawStore{gomap.NewStore(gomap.DefaultOptions)}
+	_, err = dump.Import(context.Background(), target, buf, dump.DefaultOptions)
+	if !errors.Is(err, gokv.ErrUnsupported) {
+		t.Errorf("Expected an error matching gokv.ErrUnsupported, but was: %v", err)
+	}
+
+	// Expiring store without TTL support, which is only exported with IgnoreTTLs
+	prefixed := gokv.WithPrefix(source, "f")
+	_, err = dump.Export(context.Background(), prefixed, buf, dump.DefaultOptions)
+	if !errors.Is(err, gokv.ErrUnsupported) {
+		t.Errorf("Expected an error matching gokv.ErrUnsupported, but was: %v", err)
+	}
+	options = dump.DefaultOptions
+	options.IgnoreTTLs = true
+	buf.Reset()
+	exported, err := dump.Export(context.Background(), prefixed, buf, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported != 1 || strings.Contains(buf.String(), "expiry") {
+		t.Errorf("Expected one record without expiry time, but was: %q", buf.String())
+	}
+	// Wrapped store without RawStore support
+	_, err = dump.Import(context.Background(), gokv.WithPrefix(basicStore{source}, "f"), strings.NewReader(dumped), dump.DefaultOptions)
+	if !errors.Is(err, gokv.ErrUnsupported) {
+		t.Errorf("Expected an error matching gokv.ErrUnsupported, but was: %v", err)
+	}
+
+	// Canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dump.Export(ctx, source, buf, dump.DefaultOptions)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, but was: %v", err)
+	}
+	_, err = dump.Import(ctx, source, strings.NewReader(dumped), dump.DefaultOptions)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, but was: %v", err)
+	}
+}
+
+// checkValue checks if the value test.Foo{Bar: k} is stored for the key k.
+func checkValue(t *testing.T, store gokv.Store, k string) {
+	t.Helper()
+	actual := test.Foo{}
+	found, err := store.Get(k, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual.Bar != k {
+		t.Errorf("Expected %v, but was: %v (found: %v)", test.Foo{Bar: k}, actual, found)
+	}
+}
+
+func checkProgress(t *testing.T, expected, actual []dump.Progress) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Errorf("Expected %v, but was: %v", expected, actual)
+		return
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected %v, but was: %v", expected[i], actual[i])
+		}
+	}
+}
+
+// createStore creates a gomap store with the value test.Foo{Bar: k} for each given key k.
+func createStore(t *testing.T, keys ...string) gomap.Store {
+	store := gomap.NewStore(gomap.DefaultOptions)
+	for _, k := range keys {
+		if err := store.Set(k, test.Foo{Bar: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+var errWrite = errors.New("write error")
+
+// failingWriter fails after n writes.
+type failingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, errWrite
+	}
+	w.n--
+	return w.w.Write(p)
+}
+
+// failingStore is a gomap.Store whose SetBytes method fails after n calls.
+type failingStore struct {
+	gomap.Store
+	n int
+}
+
+func (s *failingStore) SetBytes(k string, v []byte) error {
+	if s.n == 0 {
+		return errWrite
+	}
+	s.n--
+	return s.Store.SetBytes(k, v)
+}
+
+// rawStore only implements gokv.RawStore, but not dump.TTLStore.
+type rawStore struct {
+	gokv.RawStore
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
@@ -0,0 +1,10 @@
+module github.com/philippgille/gokv/dump
+
+go 1.13
+
+require (
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/gomap v0.0.0-20191011213304-eb77f15b9c61
+	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
+)
@@ -3,14 +3,20 @@ package dynamodb
 import (
 	"context"
 	"errors"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -21,6 +27,42 @@ var keyAttrName = "k"
 // "v" is used as table column name for the value.
 var valAttrName = "v"
 
+// "e" is used as table column name for the expiry time of key-value pairs with a TTL,
+// as Unix epoch seconds (the format that DynamoDB's TTL feature requires).
+var expAttrName = "e"
+
+// Condition expressions for conditional writes.
+// absentCondition is true if an item doesn't exist or is expired (but not deleted by DynamoDB yet),
+// equalCondition is true if an item exists, isn't expired and has the value ":old",
+// notExpiredCondition is true if an item doesn't exist or isn't expired.
+// Both require the current time as ":now".
+var (
+	absentCondition     = "attribute_not_exists(" + keyAttrName + ") OR " + expAttrName + " <= :now"
+	equalCondition      = valAttrName + " = :old AND (attribute_not_exists(" + expAttrName + ") OR " + expAttrName + " > :now)"
+	notExpiredCondition = "attribute_not_exists(" + expAttrName + ") OR " + expAttrName + " > :now"
+)
+
+// maxTxAttempts is the number of times a transaction is tried when it conflicts with concurrent writes.
+const maxTxAttempts = 3
+
+// DynamoDB's limits for the number of items in a single batch request.
+const (
+	maxWriteBatchSize = 25
+	maxReadBatchSize  = 100
+)
+
+// Unprocessed items and keys of batch requests (for example due to throttling) are sent again
+// after a backoff, which doubles with each attempt, as recommended by AWS.
+// After maxUnprocessedAttempts the batch operation fails.
+const (
+	minUnprocessedBackoff  = 50 * time.Millisecond
+	maxUnprocessedBackoff  = 5 * time.Second
+	maxUnprocessedAttempts = 10
+)
+
+// errUnprocessed is returned when DynamoDB didn't process all items or keys of a batch request after maxUnprocessedAttempts.
+var errUnprocessed = errors.New("The batch request wasn't fully processed by DynamoDB, even after retrying")
+
 // Client is a gokv.Store implementation for DynamoDB.
 type Client struct {
 	c         *awsdynamodb.DynamoDB
@@ -32,6 +74,33 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The context is passed to the AWS SDK.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	return c.set(ctx, k, v, 0)
+}
+
+// SetWithTTL stores the given value for the given key, with the expiry time in an additional attribute ("e").
+// When gokv creates the table and waits for its creation, it enables DynamoDB's TTL feature for that attribute.
+// DynamoDB only deletes expired items eventually (typically within 48 hours),
+// so until then Get treats them as not found.
+// The expiry time has a precision of seconds, the TTL is rounded up.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+	return c.set(context.Background(), k, v, ttl)
+}
+
+// set stores the given value for the given key with the given TTL (0 for no expiration).
+func (c Client) set(ctx context.Context, k string, v interface{}, ttl time.Duration) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -42,22 +111,7 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	item := make(map[string]*awsdynamodb.AttributeValue)
-	item[keyAttrName] = &awsdynamodb.AttributeValue{
-		S: &k,
-	}
-	item[valAttrName] = &awsdynamodb.AttributeValue{
-		B: data,
-	}
-	putItemInput := awsdynamodb.PutItemInput{
-		TableName: &c.tableName,
-		Item:      item,
-	}
-	_, err = c.c.PutItem(&putItemInput)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.put(ctx, k, data, ttl)
 }
 
 // Get retrieves the stored value for the given key.
@@ -67,10 +121,101 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is passed to the AWS SDK.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
+	data, found, err := c.get(ctx, k)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, c.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is passed to the AWS SDK.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	key := make(map[string]*awsdynamodb.AttributeValue)
+	key[keyAttrName] = &awsdynamodb.AttributeValue{
+		S: &k,
+	}
+	deleteItemInput := awsdynamodb.DeleteItemInput{
+		TableName: &c.tableName,
+		Key:       key,
+	}
+	_, err := c.c.DeleteItemWithContext(ctx, &deleteItemInput)
+	return wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// Like with Set, a previously set TTL is removed.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return c.put(context.Background(), k, v, 0)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return c.get(context.Background(), k)
+}
+
+// put stores the given data for the given key with the given TTL (0 for no expiration).
+func (c Client) put(ctx context.Context, k string, data []byte, ttl time.Duration) error {
+	item := make(map[string]*awsdynamodb.AttributeValue)
+	item[keyAttrName] = &awsdynamodb.AttributeValue{
+		S: &k,
+	}
+	item[valAttrName] = &awsdynamodb.AttributeValue{
+		B: data,
+	}
+	// PutItem replaces the whole item, so a Set without TTL removes a previous expiry time.
+	if ttl > 0 {
+		expiry := strconv.FormatInt(time.Now().Add(ttl+time.Second-1).Unix(), 10)
+		item[expAttrName] = &awsdynamodb.AttributeValue{
+			N: &expiry,
+		}
+	}
+	putItemInput := awsdynamodb.PutItemInput{
+		TableName: &c.tableName,
+		Item:      item,
+	}
+	_, err := c.c.PutItemWithContext(ctx, &putItemInput)
+	return wrapError(err)
+}
+
+// get retrieves the stored data for the given key.
+// Expired items are reported as not found.
+func (c Client) get(ctx context.Context, k string) (data []byte, found bool, err error) {
 	key := make(map[string]*awsdynamodb.AttributeValue)
 	key[keyAttrName] = &awsdynamodb.AttributeValue{
 		S: &k,
@@ -79,44 +224,465 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 		TableName: &c.tableName,
 		Key:       key,
 	}
-	getItemOutput, err := c.c.GetItem(&getItemInput)
+	getItemOutput, err := c.c.GetItemWithContext(ctx, &getItemInput)
 	if err != nil {
-		return false, err
+		return nil, false, wrapError(err)
 	} else if getItemOutput.Item == nil {
 		// Return false if the key-value pair doesn't exist
-		return false, nil
+		return nil, false, nil
+	}
+	// DynamoDB deletes expired items with a delay, so they must be filtered here
+	if expired, err := expired(getItemOutput.Item); err != nil {
+		return nil, false, err
+	} else if expired {
+		return nil, false, nil
 	}
 	attributeVal := getItemOutput.Item[valAttrName]
 	if attributeVal == nil {
 		// Return false if there's no value
 		// TODO: Maybe return an error? Behaviour should be consistent across all implementations.
-		return false, nil
+		return nil, false, nil
 	}
-	data := attributeVal.B
+	return attributeData(attributeVal), true, nil
+}
 
-	return true, c.codec.Unmarshal(data, v)
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// It uses a conditional PutItem, so the check and the write are a single atomic operation.
+// An expired item counts as absent, even if DynamoDB hasn't deleted it yet.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	return c.putIf(k, data, absentCondition,
+		map[string]*awsdynamodb.AttributeValue{
+			":now": {N: &now},
+		})
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// It uses a conditional PutItem, so the comparison and the write are a single atomic operation.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	return c.putIf(k, newData, equalCondition,
+		map[string]*awsdynamodb.AttributeValue{
+			":old": {B: oldData},
+			":now": {N: &now},
+		})
+}
+
+// putIf stores the given data for the given key without an expiry time,
+// but only if the given condition expression is true for the stored item.
+// It returns false if the condition wasn't met.
+func (c Client) putIf(k string, data []byte, condition string, values map[string]*awsdynamodb.AttributeValue) (bool, error) {
+	putItemInput := awsdynamodb.PutItemInput{
+		TableName: &c.tableName,
+		Item: map[string]*awsdynamodb.AttributeValue{
+			keyAttrName: {S: &k},
+			valAttrName: {B: data},
+		},
+		ConditionExpression:       &condition,
+		ExpressionAttributeValues: values,
+	}
+	_, err := c.c.PutItem(&putItemInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == awsdynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	} else if err != nil {
+		return false, wrapError(err)
+	}
+	return true, nil
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// It uses an UpdateItem request with an ADD action, which DynamoDB executes atomically.
+// The value is stored as DynamoDB number instead of binary data,
+// so only values that were created by Incr can be incremented, and Incr requires encoding.JSON as codec
+// (otherwise an error matching gokv.ErrUnsupported is returned), so that Get can unmarshal the number.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
 // The key must not be "".
-func (c Client) Delete(k string) error {
+func (c Client) Incr(k string, delta int64) (int64, error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return 0, err
+	}
+	if err := util.CheckCounterCodec(c.codec); err != nil {
+		return 0, err
 	}
 
-	key := make(map[string]*awsdynamodb.AttributeValue)
-	key[keyAttrName] = &awsdynamodb.AttributeValue{
-		S: &k,
+	deltaString := strconv.FormatInt(delta, 10)
+	for {
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		values := map[string]*awsdynamodb.AttributeValue{
+			":delta": {N: &deltaString},
+			":now":   {N: &now},
+		}
+		// Items that don't exist yet are created by the ADD action
+		value, updated, err := c.updateIf(k, "ADD "+valAttrName+" :delta", notExpiredCondition, values)
+		if err != nil || updated {
+			return value, err
+		}
+		// The item is expired, but DynamoDB hasn't deleted it yet, so it's overwritten
+		value, updated, err = c.updateIf(k, "SET "+valAttrName+" = :delta REMOVE "+expAttrName, expAttrName+" <= :now", values)
+		if err != nil || updated {
+			return value, err
+		}
+		// The item was deleted or overwritten in the meantime, so the increment is repeated
 	}
-	deleteItemInput := awsdynamodb.DeleteItemInput{
+}
+
+// updateIf updates the item for the given key with the given update expression,
+// but only if the given condition expression is true for the stored item.
+// It returns the new value, which must be a number, and false if the condition wasn't met.
+func (c Client) updateIf(k string, update, condition string, values map[string]*awsdynamodb.AttributeValue) (int64, bool, error) {
+	updateItemInput := awsdynamodb.UpdateItemInput{
 		TableName: &c.tableName,
-		Key:       key,
+		Key: map[string]*awsdynamodb.AttributeValue{
+			keyAttrName: {S: &k},
+		},
+		UpdateExpression:          &update,
+		ConditionExpression:       &condition,
+		ExpressionAttributeValues: values,
+		ReturnValues:              aws.String(awsdynamodb.ReturnValueUpdatedNew),
+	}
+	updateItemOutput, err := c.c.UpdateItem(&updateItemInput)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == awsdynamodb.ErrCodeConditionalCheckFailedException {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, wrapError(err)
+	}
+	attributeVal := updateItemOutput.Attributes[valAttrName]
+	if attributeVal == nil || attributeVal.N == nil {
+		return 0, false, errors.New("The response to the UpdateItem request doesn't contain the new value")
+	}
+	value, err := strconv.ParseInt(*attributeVal.N, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return value, true, nil
+}
+
+// Update calls fn with a new transaction.
+// DynamoDB transactions can't span multiple requests, so the values are read with strongly consistent reads,
+// and the changes are collected and written with TransactWriteItems when fn returns nil.
+// The writes are conditional on all read items being unchanged,
+// so if a concurrent write modified one of them, fn is called again with a new transaction
+// (up to three attempts in total). If all attempts fail, the returned error matches gokv.ErrConflict.
+// DynamoDB limits the number of items in a transaction (25), which includes the items that were only read.
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	var err error
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		tx := transaction{
+			c:      c,
+			reads:  make(map[string][]byte),
+			writes: make(map[string][]byte),
+		}
+		if err = fn(tx); err != nil {
+			return err
+		}
+		err = tx.commit()
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != awsdynamodb.ErrCodeTransactionCanceledException {
+			return wrapError(err)
+		}
+	}
+	return util.WrapError(gokv.ErrConflict, err)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// It uses a Scan with a filter expression, which reads the whole table
+// (consuming read capacity for all items), so use it sparingly.
+// Expired key-value pairs that DynamoDB hasn't deleted yet are filtered out.
+// The keys are passed in no particular order.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	scanInput := awsdynamodb.ScanInput{
+		TableName:                &c.tableName,
+		ProjectionExpression:     aws.String("#k"),
+		FilterExpression:         aws.String("(attribute_not_exists(#e) OR #e > :now)"),
+		ExpressionAttributeNames: map[string]*string{"#k": &keyAttrName, "#e": &expAttrName},
+		ExpressionAttributeValues: map[string]*awsdynamodb.AttributeValue{
+			":now": {N: &now},
+		},
+	}
+	// Empty strings aren't allowed as attribute values, so the prefix filter is only used with a prefix.
+	if prefix != "" {
+		scanInput.FilterExpression = aws.String(*scanInput.FilterExpression + " AND begins_with(#k, :p)")
+		scanInput.ExpressionAttributeValues[":p"] = &awsdynamodb.AttributeValue{S: &prefix}
+	}
+	var fnErr error
+	err := c.c.ScanPages(&scanInput, func(page *awsdynamodb.ScanOutput, _ bool) bool {
+		for _, item := range page.Items {
+			if fnErr = fn(*item[keyAttrName].S); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if fnErr != nil {
+		return fnErr
 	}
-	_, err := c.c.DeleteItem(&deleteItemInput)
 	return err
 }
 
+// SetMulti stores the given values for the given keys with BatchWriteItem.
+// DynamoDB limits the number of items per request (25),
+// so for many key-value pairs multiple requests are sent.
+// Items that DynamoDB doesn't process (for example due to throttling) are sent again, with exponential backoff.
+// The requests aren't atomic, so when an error occurs, some values might have been stored.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	// A single request must not contain multiple requests for the same key.
+	indexes := util.UniqueKeyIndexes(keys)
+	writeRequests := make([]*awsdynamodb.WriteRequest, len(indexes))
+	for j, i := range indexes {
+		data, err := c.codec.Marshal(vs[i])
+		if err != nil {
+			return err
+		}
+		k := keys[i]
+		writeRequests[j] = &awsdynamodb.WriteRequest{
+			PutRequest: &awsdynamodb.PutRequest{
+				Item: map[string]*awsdynamodb.AttributeValue{
+					keyAttrName: {S: &k},
+					valAttrName: {B: data},
+				},
+			},
+		}
+	}
+	return c.batchWrite(writeRequests)
+}
+
+// GetMulti retrieves the values for the given keys with BatchGetItem.
+// DynamoDB limits the number of items per request (100),
+// so for many keys multiple requests are sent.
+// Keys that DynamoDB doesn't process (for example due to throttling) are requested again, with exponential backoff.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (c Client) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	// A single request must not contain the same key multiple times.
+	indexes := util.UniqueKeyIndexes(keys)
+	data := make(map[string][]byte, len(indexes))
+	for start := 0; start < len(indexes); start += maxReadBatchSize {
+		end := start + maxReadBatchSize
+		if end > len(indexes) {
+			end = len(indexes)
+		}
+		requestKeys := make([]map[string]*awsdynamodb.AttributeValue, 0, end-start)
+		for _, i := range indexes[start:end] {
+			k := keys[i]
+			requestKeys = append(requestKeys, map[string]*awsdynamodb.AttributeValue{
+				keyAttrName: {S: &k},
+			})
+		}
+		requestItems := map[string]*awsdynamodb.KeysAndAttributes{
+			c.tableName: {Keys: requestKeys},
+		}
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if err := waitForUnprocessed(attempt); err != nil {
+				return nil, err
+			}
+			batchGetItemOutput, err := c.c.BatchGetItem(&awsdynamodb.BatchGetItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return nil, wrapError(err)
+			}
+			for _, item := range batchGetItemOutput.Responses[c.tableName] {
+				if expired, err := expired(item); err != nil {
+					return nil, err
+				} else if expired {
+					continue
+				}
+				if attributeVal := item[valAttrName]; attributeVal != nil {
+					data[*item[keyAttrName].S] = attributeData(attributeVal)
+				}
+			}
+			requestItems = batchGetItemOutput.UnprocessedKeys
+		}
+	}
+
+	found = make([]bool, len(keys))
+	for i, k := range keys {
+		d, ok := data[k]
+		if !ok {
+			continue
+		}
+		found[i] = true
+		if err := c.codec.Unmarshal(d, vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys with BatchWriteItem.
+// DynamoDB limits the number of items per request (25),
+// so for many keys multiple requests are sent.
+// Items that DynamoDB doesn't process (for example due to throttling) are sent again, with exponential backoff.
+// The requests aren't atomic, so when an error occurs, some values might have been deleted.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	indexes := util.UniqueKeyIndexes(keys)
+	writeRequests := make([]*awsdynamodb.WriteRequest, len(indexes))
+	for j, i := range indexes {
+		k := keys[i]
+		writeRequests[j] = &awsdynamodb.WriteRequest{
+			DeleteRequest: &awsdynamodb.DeleteRequest{
+				Key: map[string]*awsdynamodb.AttributeValue{
+					keyAttrName: {S: &k},
+				},
+			},
+		}
+	}
+	return c.batchWrite(writeRequests)
+}
+
+// batchWrite sends the given write requests in chunks of the maximum size that DynamoDB allows,
+// and sends unprocessed requests again, with exponential backoff.
+func (c Client) batchWrite(writeRequests []*awsdynamodb.WriteRequest) error {
+	for start := 0; start < len(writeRequests); start += maxWriteBatchSize {
+		end := start + maxWriteBatchSize
+		if end > len(writeRequests) {
+			end = len(writeRequests)
+		}
+		requestItems := map[string][]*awsdynamodb.WriteRequest{
+			c.tableName: writeRequests[start:end],
+		}
+		for attempt := 0; len(requestItems) > 0; attempt++ {
+			if err := waitForUnprocessed(attempt); err != nil {
+				return err
+			}
+			batchWriteItemOutput, err := c.c.BatchWriteItem(&awsdynamodb.BatchWriteItemInput{
+				RequestItems: requestItems,
+			})
+			if err != nil {
+				return wrapError(err)
+			}
+			requestItems = batchWriteItemOutput.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// waitForUnprocessed waits before the given attempt (counting from 0) to send the unprocessed items or keys of a batch request.
+// The first attempt isn't delayed, and for each further attempt the backoff doubles, with a random jitter of up to half of it,
+// so that clients that are throttled at the same time don't retry at the same time.
+// After maxUnprocessedAttempts it returns errUnprocessed.
+func waitForUnprocessed(attempt int) error {
+	if attempt == 0 {
+		return nil
+	} else if attempt >= maxUnprocessedAttempts {
+		return errUnprocessed
+	}
+	backoff := minUnprocessedBackoff << uint(attempt-1)
+	if backoff > maxUnprocessedBackoff {
+		backoff = maxUnprocessedBackoff
+	}
+	backoff -= time.Duration(rand.Int63n(int64(backoff/2) + 1))
+	time.Sleep(backoff)
+	return nil
+}
+
+// wrapError wraps errors of the AWS SDK into gokv's errors where possible.
+// DynamoDB reports exceeded size limits as validation errors,
+// so the messages are checked for the limit of the item size (400 KB)
+// and of the partition key (2048 bytes).
+func wrapError(err error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok || awsErr.Code() != "ValidationException" {
+		return err
+	}
+	if strings.Contains(awsErr.Message(), "Item size has exceeded") {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	} else if strings.Contains(awsErr.Message(), "Size of hashkey has exceeded") {
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	}
+	return err
+}
+
+// attributeData returns the data of the given value attribute.
+// Values are stored as binary data, except for the values of counters (see Incr()),
+// which are stored as numbers, whose string representation is their JSON encoding.
+func attributeData(attributeVal *awsdynamodb.AttributeValue) []byte {
+	if attributeVal.N != nil {
+		return []byte(*attributeVal.N)
+	}
+	return attributeVal.B
+}
+
+// expired returns true if the item was stored with a TTL that has passed.
+func expired(item map[string]*awsdynamodb.AttributeValue) (bool, error) {
+	expiryVal := item[expAttrName]
+	if expiryVal == nil || expiryVal.N == nil {
+		return false, nil
+	}
+	expiry, err := strconv.ParseInt(*expiryVal.N, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Unix() >= expiry, nil
+}
+
+// IsRetryable returns true for errors of the AWS SDK that are transient,
+// like throttling errors (for example when the provisioned throughput is exceeded) and server errors,
+// as well as the error for batch requests that weren't fully processed even after retrying them.
+// It's used by the resilience package, which retries network errors anyway.
+func (c Client) IsRetryable(err error) bool {
+	if errors.Is(err, errUnprocessed) {
+		return true
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	return request.IsErrorRetryable(awsErr) || request.IsErrorThrottle(awsErr)
+}
+
 // Close closes the client.
 // In the DynamoDB implementation this doesn't have any effect.
 func (c Client) Close() error {
@@ -154,6 +720,7 @@ type Options struct {
 	// If WaitForTableCreation is false, gokv returns the client immediately.
 	// In the latter case you need to make sure that you don't read from or write to the table before it's created,
 	// because otherwise you will get ResourceNotFoundException errors.
+	// It also means that DynamoDB's TTL feature isn't enabled for the table (see SetWithTTL()).
 	// Optional (true by default).
 	WaitForTableCreation *bool
 	// AWS access key ID (part of the credentials).
@@ -278,6 +845,40 @@ func NewClient(options Options) (Client, error) {
 	return result, nil
 }
 
+func init() {
+	gokv.Register("dynamodb", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		TableName: u.Host,
+	}
+	if u.User != nil {
+		options.AWSaccessKeyID = u.User.Username()
+		options.AWSsecretAccessKey, _ = u.User.Password()
+	}
+	q := util.NewURLQuery(u)
+	q.String("region", &options.Region)
+	q.Int64("readCapacityUnits", &options.ReadCapacityUnits)
+	q.Int64("writeCapacityUnits", &options.WriteCapacityUnits)
+	var waitForTableCreation bool
+	if q.Bool("waitForTableCreation", &waitForTableCreation) {
+		options.WaitForTableCreation = &waitForTableCreation
+	}
+	q.String("customEndpoint", &options.CustomEndpoint)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 func createTable(tableName string, readCapacityUnits, writeCapacityUnits int64, waitForTableCreation bool, describeTableInput awsdynamodb.DescribeTableInput, svc *awsdynamodb.DynamoDB) error {
 	keyAttrType := "S" // For "string"
 	keyType := "HASH"  // As opposed to "RANGE"
@@ -320,7 +921,180 @@ func createTable(tableName string, readCapacityUnits, writeCapacityUnits int64,
 		if *describeTableOutput.Table.TableStatus == "CREATING" {
 			return errors.New("The DynamoDB table took too long to be created")
 		}
+		// TTL can only be enabled for active tables.
+		// It makes DynamoDB delete key-value pairs that were stored with SetWithTTL() after they expired.
+		updateTimeToLiveInput := awsdynamodb.UpdateTimeToLiveInput{
+			TableName: &tableName,
+			TimeToLiveSpecification: &awsdynamodb.TimeToLiveSpecification{
+				AttributeName: &expAttrName,
+				Enabled:       aws.Bool(true),
+			},
+		}
+		_, err = svc.UpdateTimeToLive(&updateTimeToLiveInput)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transaction is a gokv.Tx for DynamoDB.
+type transaction struct {
+	c Client
+	// Stored values of the keys that were read in the transaction,
+	// with nil values for the keys that didn't exist.
+	reads map[string][]byte
+	// Marshalled values of the keys that were set in the transaction,
+	// with nil values for the keys that were deleted.
+	writes map[string][]byte
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
 	}
 
+	data, err := t.c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.writes[k] = data
 	return nil
 }
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, written := t.writes[k]
+	if !written {
+		var read bool
+		if data, read = t.reads[k]; !read {
+			if data, err = t.read(k); err != nil {
+				return false, err
+			}
+			t.reads[k] = data
+		}
+	}
+	if data == nil {
+		return false, nil
+	}
+	return true, t.c.codec.Unmarshal(data, v)
+}
+
+// read retrieves the stored value for the given key with a strongly consistent read.
+// It returns nil if the item doesn't exist or is expired.
+func (t transaction) read(k string) ([]byte, error) {
+	getItemInput := awsdynamodb.GetItemInput{
+		TableName: &t.c.tableName,
+		Key: map[string]*awsdynamodb.AttributeValue{
+			keyAttrName: {S: &k},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	getItemOutput, err := t.c.c.GetItem(&getItemInput)
+	if err != nil || getItemOutput.Item == nil {
+		return nil, wrapError(err)
+	}
+	if expired, err := expired(getItemOutput.Item); err != nil || expired {
+		return nil, err
+	}
+	if attributeVal := getItemOutput.Item[valAttrName]; attributeVal != nil {
+		return attributeData(attributeVal), nil
+	}
+	return nil, nil
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	t.writes[k] = nil
+	return nil
+}
+
+// commit writes the changes of the transaction with TransactWriteItems,
+// on the condition that the items that were read are unchanged.
+// A transaction without changes doesn't need to be committed.
+func (t transaction) commit() error {
+	if len(t.writes) == 0 {
+		return nil
+	}
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	transactItems := make([]*awsdynamodb.TransactWriteItem, 0, len(t.reads)+len(t.writes))
+	for k := range t.reads {
+		if _, written := t.writes[k]; written {
+			continue
+		}
+		k := k
+		condition, values := t.condition(k, now)
+		transactItems = append(transactItems, &awsdynamodb.TransactWriteItem{
+			ConditionCheck: &awsdynamodb.ConditionCheck{
+				TableName:                 &t.c.tableName,
+				Key:                       map[string]*awsdynamodb.AttributeValue{keyAttrName: {S: &k}},
+				ConditionExpression:       condition,
+				ExpressionAttributeValues: values,
+			},
+		})
+	}
+	for k, data := range t.writes {
+		k := k
+		condition, values := t.condition(k, now)
+		if data == nil {
+			transactItems = append(transactItems, &awsdynamodb.TransactWriteItem{
+				Delete: &awsdynamodb.Delete{
+					TableName:                 &t.c.tableName,
+					Key:                       map[string]*awsdynamodb.AttributeValue{keyAttrName: {S: &k}},
+					ConditionExpression:       condition,
+					ExpressionAttributeValues: values,
+				},
+			})
+		} else {
+			transactItems = append(transactItems, &awsdynamodb.TransactWriteItem{
+				Put: &awsdynamodb.Put{
+					TableName: &t.c.tableName,
+					Item: map[string]*awsdynamodb.AttributeValue{
+						keyAttrName: {S: &k},
+						valAttrName: {B: data},
+					},
+					ConditionExpression:       condition,
+					ExpressionAttributeValues: values,
+				},
+			})
+		}
+	}
+
+	_, err := t.c.c.TransactWriteItems(&awsdynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	return err
+}
+
+// condition returns the condition expression and its values for the item with the given key,
+// which is only true if the item is unchanged since it was read in the transaction.
+// If the item wasn't read, no condition is returned.
+func (t transaction) condition(k, now string) (*string, map[string]*awsdynamodb.AttributeValue) {
+	data, read := t.reads[k]
+	if !read {
+		return nil, nil
+	}
+	values := map[string]*awsdynamodb.AttributeValue{
+		":now": {N: &now},
+	}
+	if data == nil {
+		return &absentCondition, values
+	}
+	values[":old"] = &awsdynamodb.AttributeValue{B: data}
+	return &equalCondition, values
+}
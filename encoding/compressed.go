@@ -0,0 +1,180 @@
+package encoding
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// uncompressed is the header byte of values that CompressedCodec didn't compress because they're smaller than the threshold.
+const uncompressed = 0
+
+// DefaultCompressionThreshold is the size in bytes below which CompressedCodec doesn't compress values,
+// because compressing small values takes time but rarely makes them smaller.
+const DefaultCompressionThreshold = 1024
+
+// MaxDecompressedSize is the maximum size in bytes of a decompressed value.
+// Compressors don't decompress data into larger values, so a small corrupted or malicious value
+// (a "decompression bomb") can't make CompressedCodec allocate an unlimited amount of memory.
+const MaxDecompressedSize = 64 << 20
+
+// ErrTooLarge is returned (or wrapped) by Compressor.Decompress when the data decompresses into more than MaxDecompressedSize bytes.
+var ErrTooLarge = errors.New("The decompressed data is larger than the maximum size")
+
+// Compressor compresses and decompresses slices of bytes with a specific algorithm.
+type Compressor interface {
+	// ID identifies the algorithm in the header byte of the values that were compressed with it.
+	// It must be unique and must not be 0, which is used for uncompressed values.
+	ID() byte
+	// Compress compresses the given data.
+	Compress(data []byte) ([]byte, error)
+	// Decompress decompresses the given data.
+	// It must return an error matching ErrTooLarge instead of decompressing more than MaxDecompressedSize bytes.
+	Decompress(data []byte) ([]byte, error)
+}
+
+var (
+	compressorsLock sync.RWMutex
+	compressors     = map[byte]Compressor{
+		Gzip.ID(): Gzip,
+	}
+)
+
+// RegisterCompressor makes a Compressor available for decompressing values with its ID,
+// so a CompressedCodec can read values that were compressed with a different algorithm than its own,
+// for example after switching from gzip to zstd.
+// Gzip is always registered, and the Compressors of the compress package register themselves when they're imported.
+// If RegisterCompressor is called twice with the same ID or if the ID is 0, it panics.
+func RegisterCompressor(c Compressor) {
+	compressorsLock.Lock()
+	defer compressorsLock.Unlock()
+	if c.ID() == uncompressed {
+		panic("encoding: RegisterCompressor called with ID 0")
+	}
+	if _, dup := compressors[c.ID()]; dup {
+		panic(fmt.Sprintf("encoding: RegisterCompressor called twice for ID %v", c.ID()))
+	}
+	compressors[c.ID()] = c
+}
+
+// GzipCompressor compresses data with gzip.
+// You can use encoding.Gzip instead of creating an instance of this struct.
+type GzipCompressor struct{}
+
+// ID returns 1, the ID of gzip.
+func (c GzipCompressor) ID() byte {
+	return 1
+}
+
+// Compress compresses the given data with gzip.
+func (c GzipCompressor) Compress(data []byte) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	writer := gzip.NewWriter(buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decompress decompresses the given gzip data.
+// If it decompresses into more than MaxDecompressedSize bytes, ErrTooLarge is returned.
+func (c GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	// Read one byte more than the maximum, to detect larger data without reading all of it
+	result, err := ioutil.ReadAll(io.LimitReader(reader, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) > MaxDecompressedSize {
+		return nil, ErrTooLarge
+	}
+	return result, nil
+}
+
+// Gzip is a GzipCompressor that compresses data with gzip.
+var Gzip = GzipCompressor{}
+
+// CompressedCodec wraps another codec and compresses the marshalled values that are at least as large as its threshold.
+// Each value starts with a header byte that indicates the algorithm it was compressed with, or that it's uncompressed,
+// so values that were stored with a different threshold or algorithm can still be read (see RegisterCompressor).
+// Values that were stored without a CompressedCodec can be read as well, as long as their first byte isn't a registered ID,
+// which is the case for all values that were marshalled with encoding.JSON, but not necessarily for encoding.Gob.
+//
+// CompressedCodec doesn't support gokv.Counter.Incr(), because it requires encoding.JSON.
+type CompressedCodec struct {
+	inner     Codec
+	algo      Compressor
+	threshold int
+}
+
+// NewCompressed creates a new CompressedCodec that marshals values with the inner codec and compresses them with algo,
+// if they're at least DefaultCompressionThreshold bytes large.
+func NewCompressed(inner Codec, algo Compressor) CompressedCodec {
+	return CompressedCodec{
+		inner:     inner,
+		algo:      algo,
+		threshold: DefaultCompressionThreshold,
+	}
+}
+
+// WithThreshold returns a copy of the codec that compresses the values that are at least as large as the given threshold in bytes.
+// A threshold of 0 means that all values are compressed.
+func (c CompressedCodec) WithThreshold(threshold int) CompressedCodec {
+	c.threshold = threshold
+	return c
+}
+
+// Marshal encodes a Go value with the inner codec and compresses it if it's large enough.
+func (c CompressedCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < c.threshold {
+		return append([]byte{uncompressed}, data...), nil
+	}
+	compressed, err := c.algo.Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{c.algo.ID()}, compressed...), nil
+}
+
+// Unmarshal decompresses the given data if it's compressed and decodes it with the inner codec.
+// Values that decompress into more than MaxDecompressedSize bytes lead to an error matching ErrTooLarge.
+func (c CompressedCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return c.inner.Unmarshal(data, v)
+	}
+	id := data[0]
+	if id == uncompressed {
+		return c.inner.Unmarshal(data[1:], v)
+	}
+	algo := c.algo
+	if id != algo.ID() {
+		compressorsLock.RLock()
+		registered, ok := compressors[id]
+		compressorsLock.RUnlock()
+		if !ok {
+			// Stored without a CompressedCodec
+			return c.inner.Unmarshal(data, v)
+		}
+		algo = registered
+	}
+	decompressed, err := algo.Decompress(data[1:])
+	if err != nil {
+		return fmt.Errorf("The value can't be decompressed: %w", err)
+	}
+	return c.inner.Unmarshal(decompressed, v)
+}
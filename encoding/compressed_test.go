@@ -0,0 +1,161 @@
+package encoding_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/gokv/encoding"
+)
+
+// TestCompressed tests if values are compressed with gzip and can be decompressed by an encoding.CompressedCodec.
+func TestCompressed(t *testing.T) {
+	codec := encoding.NewCompressed(encoding.JSON, encoding.Gzip)
+
+	// Values that are smaller than the threshold aren't compressed
+	data := marshal(t, codec, foo{Bar: "baz"})
+	if data[0] != 0 || string(data[1:]) != `{"Bar":"baz"}` {
+		t.Errorf("Expected the uncompressed value, but was: %q", data)
+	}
+	checkUnmarshal(t, codec, data, "baz")
+
+	large := strings.Repeat("baz", encoding.DefaultCompressionThreshold)
+	data = marshal(t, codec, foo{Bar: large})
+	if data[0] != encoding.Gzip.ID() {
+		t.Errorf("Expected the header byte %v, but was: %v", encoding.Gzip.ID(), data[0])
+	}
+	if len(data) >= len(large) {
+		t.Errorf("Expected the value to be compressed, but it's %v bytes long", len(data))
+	}
+	checkUnmarshal(t, codec, data, large)
+
+	// A threshold of 0 compresses all values
+	data = marshal(t, codec.WithThreshold(0), foo{Bar: "baz"})
+	if data[0] != encoding.Gzip.ID() {
+		t.Errorf("Expected the header byte %v, but was: %v", encoding.Gzip.ID(), data[0])
+	}
+	checkUnmarshal(t, codec, data, "baz")
+}
+
+// TestUncompressed tests if values that were stored without an encoding.CompressedCodec can be read.
+func TestUncompressed(t *testing.T) {
+	codec := encoding.NewCompressed(encoding.JSON, encoding.Gzip)
+
+	data, err := json.Marshal(foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUnmarshal(t, codec, data, "baz")
+}
+
+// TestRegisterCompressor tests if values that were compressed with a registered algorithm can be read
+// by a codec that compresses with a different one, and if invalid registrations panic.
+func TestRegisterCompressor(t *testing.T) {
+	reverse := reverseCompressor{}
+	reversed := encoding.NewCompressed(encoding.JSON, reverse).WithThreshold(0)
+	codec := encoding.NewCompressed(encoding.JSON, encoding.Gzip)
+
+	data := marshal(t, reversed, foo{Bar: "baz"})
+	if data[0] != reverse.ID() {
+		t.Errorf("Expected the header byte %v, but was: %v", reverse.ID(), data[0])
+	}
+	checkUnmarshal(t, codec, data, "baz")
+	checkUnmarshal(t, reversed, marshal(t, codec.WithThreshold(0), foo{Bar: "baz"}), "baz")
+
+	for name, c := range map[string]encoding.Compressor{
+		"duplicate ID": encoding.Gzip,
+		"ID 0":         zeroCompressor{},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected a panic for the %v", name)
+				}
+			}()
+			encoding.RegisterCompressor(c)
+		}()
+	}
+}
+
+// TestCorrupted tests if values whose compressed data is corrupted lead to an error.
+func TestCorrupted(t *testing.T) {
+	codec := encoding.NewCompressed(encoding.JSON, encoding.Gzip).WithThreshold(0)
+	data := marshal(t, codec, foo{Bar: "baz"})
+
+	for _, corrupted := range [][]byte{
+		data[:1],
+		data[:len(data)/2],
+		append([]byte{data[0]}, bytes.Repeat([]byte{0xff}, len(data)-1)...),
+	} {
+		err := codec.Unmarshal(corrupted, new(foo))
+		if err == nil {
+			t.Errorf("Expected an error for the corrupted value %x", corrupted)
+		}
+	}
+}
+
+// TestTooLarge tests if values that decompress into more than encoding.MaxDecompressedSize bytes lead to an error.
+func TestTooLarge(t *testing.T) {
+	codec := encoding.NewCompressed(encoding.JSON, encoding.Gzip)
+
+	// Zeros compress very well, so the compressed data is small
+	bomb, err := encoding.Gzip.Compress(make([]byte, encoding.MaxDecompressedSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = encoding.Gzip.Decompress(bomb)
+	if !errors.Is(err, encoding.ErrTooLarge) {
+		t.Errorf("Expected an error matching encoding.ErrTooLarge, but was: %v", err)
+	}
+	err = codec.Unmarshal(append([]byte{encoding.Gzip.ID()}, bomb...), new(foo))
+	if !errors.Is(err, encoding.ErrTooLarge) {
+		t.Errorf("Expected an error matching encoding.ErrTooLarge, but was: %v", err)
+	}
+
+	// Data of exactly the maximum size can be decompressed
+	data, err := encoding.Gzip.Compress(make([]byte, encoding.MaxDecompressedSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := encoding.Gzip.Decompress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decompressed) != encoding.MaxDecompressedSize {
+		t.Errorf("Expected %v bytes, but was: %v", encoding.MaxDecompressedSize, len(decompressed))
+	}
+}
+
+func init() {
+	encoding.RegisterCompressor(reverseCompressor{})
+}
+
+// reverseCompressor "compresses" data by reversing it.
+type reverseCompressor struct{}
+
+func (c reverseCompressor) ID() byte {
+	return 200
+}
+
+func (c reverseCompressor) Compress(data []byte) ([]byte, error) {
+	result := make([]byte, len(data))
+	for i, b := range data {
+		result[len(data)-1-i] = b
+	}
+	return result, nil
+}
+
+func (c reverseCompressor) Decompress(data []byte) ([]byte, error) {
+	return c.Compress(data)
+}
+
+// zeroCompressor is a Compressor with the invalid ID 0.
+type zeroCompressor struct {
+	reverseCompressor
+}
+
+func (c zeroCompressor) ID() byte {
+	return 0
+}
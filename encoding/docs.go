@@ -3,5 +3,8 @@ Package encoding is a wrapper for the core functionality of packages like "encod
 
 It contains the Codec interface and multiple implementations for encoding Go values to other formats and decode from other formats to Go values.
 Formats can be JSON, gob etc.
+
+EncryptedCodec wraps any of them and encrypts the encoded values with AES-256-GCM, with keys that can be rotated.
+CompressedCodec wraps any of them and compresses the encoded values that exceed a size threshold, for example with gzip.
 */
 package encoding
@@ -0,0 +1,173 @@
+package encoding
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedVersion is the first byte of the values that are encrypted by EncryptedCodec,
+// so the format can be changed in the future without breaking existing values.
+const encryptedVersion = 1
+
+// KeyProvider provides the keys for an EncryptedCodec, which must be 32 bytes long (for AES-256).
+// Its methods are called for each value, so implementations that fetch the keys from a key management service
+// should cache them.
+type KeyProvider interface {
+	// CurrentKey returns the key that new values are encrypted with, along with its ID.
+	// The ID is stored in each value, so it must not be secret.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given ID, which a value was encrypted with.
+	Key(id string) (key []byte, err error)
+}
+
+// Keyring is a KeyProvider with a fixed set of keys.
+// For rotating keys, a new key is added and made the current one,
+// and the old keys are kept until all values that were encrypted with them were overwritten.
+type Keyring struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+// NewKeyring creates a new Keyring with the given keys by their IDs, of which the one with currentID is used for encrypting.
+// The keys must be 32 bytes long, and the IDs must not be longer than 255 bytes.
+func NewKeyring(currentID string, keys map[string][]byte) (Keyring, error) {
+	result := Keyring{}
+
+	// Precondition check
+	if _, ok := keys[currentID]; !ok {
+		return result, fmt.Errorf("The current key %q isn't in the keys", currentID)
+	}
+	for id, key := range keys {
+		if len(id) > 255 {
+			return result, fmt.Errorf("The key ID %q is longer than 255 bytes", id)
+		}
+		if len(key) != 32 {
+			return result, fmt.Errorf("The key %q must be 32 bytes long, but is %v bytes long", id, len(key))
+		}
+	}
+
+	result.currentID = currentID
+	result.keys = make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		result.keys[id] = key
+	}
+	return result, nil
+}
+
+// CurrentKey returns the key that new values are encrypted with, along with its ID.
+func (k Keyring) CurrentKey() (id string, key []byte, err error) {
+	return k.currentID, k.keys[k.currentID], nil
+}
+
+// Key returns the key with the given ID.
+func (k Keyring) Key(id string) (key []byte, err error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("The key %q isn't in the keyring", id)
+	}
+	return key, nil
+}
+
+// EncryptedCodec wraps another codec and encrypts the marshalled values with AES-256-GCM,
+// which also detects values that were changed.
+// Each value starts with a header that contains the ID of the key that it was encrypted with,
+// so keys can be rotated without re-encrypting the existing values, as long as the KeyProvider still provides the old keys.
+// The header is followed by a random nonce and the encrypted value, which makes values 30 bytes plus the key ID longer.
+//
+// A random nonce is used for each value, so the same value is encrypted differently each time.
+// This means that gokv.AtomicStore.CompareAndSwap() never swaps, because it compares the values in their marshalled form,
+// and that gokv.Counter.Incr() isn't supported, because it requires encoding.JSON.
+type EncryptedCodec struct {
+	inner Codec
+	keys  KeyProvider
+}
+
+// NewEncrypted creates a new EncryptedCodec that marshals values with the inner codec
+// and encrypts them with the keys of the given KeyProvider.
+func NewEncrypted(inner Codec, keys KeyProvider) EncryptedCodec {
+	return EncryptedCodec{
+		inner: inner,
+		keys:  keys,
+	}
+}
+
+// Marshal encodes a Go value with the inner codec and encrypts it with the current key.
+func (c EncryptedCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("The key ID %q is longer than 255 bytes", id)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header, which is authenticated but not encrypted.
+	// The header and the nonce are separate slices, because Seal doesn't allow them to overlap with the output.
+	header := make([]byte, 0, 2+len(id))
+	header = append(header, encryptedVersion, byte(len(id)))
+	header = append(header, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	result := make([]byte, 0, len(header)+len(nonce)+len(data)+aead.Overhead())
+	result = append(result, header...)
+	result = append(result, nonce...)
+	return aead.Seal(result, nonce, data, header), nil
+}
+
+// Unmarshal decrypts the given data with the key that it was encrypted with and decodes it with the inner codec.
+func (c EncryptedCodec) Unmarshal(data []byte, v interface{}) error {
+	if len(data) < 2 || data[0] != encryptedVersion {
+		return errors.New("The value wasn't encrypted by an EncryptedCodec")
+	}
+	headerLength := 2 + int(data[1])
+	if len(data) < headerLength {
+		return errors.New("The value wasn't encrypted by an EncryptedCodec")
+	}
+	header := data[:headerLength]
+	id := string(header[2:])
+
+	key, err := c.keys.Key(id)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if len(data) < headerLength+aead.NonceSize() {
+		return errors.New("The value wasn't encrypted by an EncryptedCodec")
+	}
+	nonce := data[headerLength : headerLength+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[headerLength+aead.NonceSize():], header)
+	if err != nil {
+		return fmt.Errorf("The value can't be decrypted with the key %q: %w", id, err)
+	}
+	return c.inner.Unmarshal(plaintext, v)
+}
+
+// newAEAD creates an AES-256-GCM cipher with the given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("The key must be 32 bytes long, but is %v bytes long", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
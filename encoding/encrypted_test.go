@@ -0,0 +1,177 @@
+package encoding_test
+
+import (
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/gokv/encoding"
+)
+
+type foo struct {
+	Bar string
+}
+
+// TestEncrypted tests if values are encrypted and can be decrypted by an encoding.EncryptedCodec.
+func TestEncrypted(t *testing.T) {
+	codec := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key1", map[string][]byte{"key1": createKey(t)}))
+
+	data := marshal(t, codec, foo{Bar: "secret"})
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected the value to be encrypted, but was: %q", data)
+	}
+	// The header with the version and the key ID isn't encrypted
+	if !strings.HasPrefix(string(data), "\x01\x04key1") {
+		t.Errorf("Expected the header of version 1 with the key ID, but the value was: %q", data)
+	}
+	// The same value is encrypted differently each time, because of the random nonce
+	if string(marshal(t, codec, foo{Bar: "secret"})) == string(data) {
+		t.Error("Expected a different encrypted value for the second encryption")
+	}
+	checkUnmarshal(t, codec, data, "secret")
+}
+
+// TestKeyRotation tests if values that were encrypted with an old key can still be decrypted after a key rotation,
+// and if new values are encrypted with the new key.
+func TestKeyRotation(t *testing.T) {
+	key1, key2 := createKey(t), createKey(t)
+	codec := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key1", map[string][]byte{"key1": key1}))
+	rotated := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key2", map[string][]byte{"key1": key1, "key2": key2}))
+
+	old := marshal(t, codec, foo{Bar: "old"})
+	checkUnmarshal(t, rotated, old, "old")
+
+	// Values that were encrypted with the new key can't be decrypted with only the old key
+	data := marshal(t, rotated, foo{Bar: "new"})
+	checkUnmarshal(t, rotated, data, "new")
+	err := codec.Unmarshal(data, new(foo))
+	if err == nil || !strings.Contains(err.Error(), `"key2"`) {
+		t.Errorf("Expected an error for the unknown key ID, but was: %v", err)
+	}
+}
+
+// TestUnknownKeyID tests if values that were encrypted with a key that isn't in the keyring lead to an error,
+// even if another key has the same bytes.
+func TestUnknownKeyID(t *testing.T) {
+	key := createKey(t)
+	codec := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key1", map[string][]byte{"key1": key}))
+	other := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key2", map[string][]byte{"key2": key}))
+
+	err := other.Unmarshal(marshal(t, codec, foo{Bar: "baz"}), new(foo))
+	if err == nil || !strings.Contains(err.Error(), `"key1"`) {
+		t.Errorf("Expected an error for the unknown key ID, but was: %v", err)
+	}
+}
+
+// TestTampered tests if changes of any byte of an encrypted value are detected,
+// including the bytes of the header, which is authenticated but not encrypted.
+func TestTampered(t *testing.T) {
+	// Two keys whose IDs only differ in their last byte, so changing the key ID in the header can lead to a known key
+	key := createKey(t)
+	codec := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key1", map[string][]byte{"key1": key, "key2": key}))
+	data := marshal(t, codec, foo{Bar: "baz"})
+
+	for i := range data {
+		for _, mask := range []byte{0x01, 0x03, 0x80} {
+			tampered := append([]byte(nil), data...)
+			tampered[i] ^= mask
+			err := codec.Unmarshal(tampered, new(foo))
+			if err == nil {
+				t.Errorf("Expected an error for the value with the changed byte %v (mask %x)", i, mask)
+			}
+		}
+	}
+
+	// Appended bytes
+	err := codec.Unmarshal(append(append([]byte(nil), data...), 0), new(foo))
+	if err == nil {
+		t.Error("Expected an error for the value with an appended byte")
+	}
+}
+
+// TestTruncated tests if truncated values lead to an error instead of a panic,
+// especially values whose header is truncated.
+func TestTruncated(t *testing.T) {
+	codec := encoding.NewEncrypted(encoding.JSON, createKeyring(t, "key1", map[string][]byte{"key1": createKey(t)}))
+	data := marshal(t, codec, foo{Bar: "baz"})
+
+	for i := 0; i < len(data); i++ {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Unmarshal panicked for the value truncated to %v bytes: %v", i, r)
+				}
+			}()
+			err := codec.Unmarshal(data[:i], new(foo))
+			if err == nil {
+				t.Errorf("Expected an error for the value truncated to %v bytes", i)
+			}
+		}()
+	}
+
+	// A header with a key ID length that's larger than the value
+	err := codec.Unmarshal([]byte{1, 255, 'k'}, new(foo))
+	if err == nil {
+		t.Error("Expected an error for the truncated header")
+	}
+	// Values that weren't encrypted
+	err = codec.Unmarshal([]byte(`{"Bar":"baz"}`), new(foo))
+	if err == nil {
+		t.Error("Expected an error for an unencrypted value")
+	}
+}
+
+// TestKeyring tests if invalid keyrings lead to an error.
+func TestKeyring(t *testing.T) {
+	_, err := encoding.NewKeyring("key1", map[string][]byte{"key1": []byte("too short")})
+	if err == nil {
+		t.Error("Expected an error for a key that's too short")
+	}
+	_, err = encoding.NewKeyring("key2", map[string][]byte{"key1": make([]byte, 32)})
+	if err == nil {
+		t.Error("Expected an error for an unknown current key")
+	}
+	_, err = encoding.NewKeyring("key1", map[string][]byte{"key1": make([]byte, 32), strings.Repeat("k", 256): make([]byte, 32)})
+	if err == nil {
+		t.Error("Expected an error for a key ID that's too long")
+	}
+}
+
+// createKey creates a random 32 byte key.
+func createKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func createKeyring(t *testing.T, currentID string, keys map[string][]byte) encoding.Keyring {
+	keyring, err := encoding.NewKeyring(currentID, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func marshal(t *testing.T, codec encoding.Codec, v interface{}) []byte {
+	t.Helper()
+	data, err := codec.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkUnmarshal checks if the given data is unmarshalled into a foo with the expected Bar.
+func checkUnmarshal(t *testing.T, codec encoding.Codec, data []byte, expected string) {
+	t.Helper()
+	actual := foo{}
+	err := codec.Unmarshal(data, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Bar != expected {
+		t.Errorf("Expected %v, but was: %v", foo{Bar: expected}, actual)
+	}
+}
@@ -0,0 +1,30 @@
+package gokv
+
+import (
+	"errors"
+)
+
+// The following errors are returned by the gokv.Store implementations in this repository,
+// either directly or wrapping an error of the store's client library.
+// Check for them with errors.Is(), which works with all implementations.
+var (
+	// ErrEmptyKey is returned when the passed key is an empty string.
+	ErrEmptyKey = errors.New("The passed key is an empty string, which is invalid")
+	// ErrNilValue is returned when the passed value is nil.
+	ErrNilValue = errors.New("The passed value is nil, which is not allowed")
+	// ErrClosed is returned when the store or its connection was closed.
+	ErrClosed = errors.New("The store is closed")
+	// ErrUnsupported is returned by the package-level functions for optional operations
+	// when the passed store doesn't support the operation,
+	// as well as by implementations that can't support an operation with their configuration.
+	ErrUnsupported = errors.New("The operation isn't supported by the store")
+	// ErrKeyTooLong is returned when the key exceeds the maximum length of the store.
+	ErrKeyTooLong = errors.New("The passed key is longer than the store allows")
+	// ErrValueTooLarge is returned when the marshalled value exceeds the maximum size of the store.
+	ErrValueTooLarge = errors.New("The passed value is larger than the store allows")
+	// ErrConflict is returned when an operation failed due to a concurrent modification,
+	// for example a transaction that couldn't be committed even after retrying it.
+	ErrConflict = errors.New("The operation conflicted with a concurrent modification")
+	// ErrInvalidTTL is returned when the passed TTL is invalid, for example negative.
+	ErrInvalidTTL = errors.New("The passed TTL is invalid")
+)
@@ -1,4 +1,9 @@
 /*
 Package etcd contains an implementation of the `gokv.Store` interface for etcd.
+
+The package registers itself for the URL scheme "etcd", so a client can also be created with gokv.Open,
+for example with "etcd://host1:2379,host2:2379?timeout=1s&codec=gob".
+The comma-separated hosts are used as Endpoints and the query parameters "timeout" and "codec" ("json" or "gob")
+correspond to the fields of Options.
 */
 package etcd
@@ -1,18 +1,28 @@
 package etcd
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"net/url"
 	"time"
 
 	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
+	"go.etcd.io/etcd/etcdserver/api/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 var defaultTimeout = 200 * time.Millisecond
 
+// keysPageSize is the maximum number of keys that Keys() retrieves with a single request.
+const keysPageSize = 1000
+
 // Client is a gokv.Store implementation for etcd.
 type Client struct {
 	c       *clientv3.Client
@@ -24,6 +34,16 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	return c.SetContext(ctxWithTimeout, k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The configured timeout isn't applied, the context is used instead.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -34,16 +54,45 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
-	defer cancel()
-	_, err = c.c.Put(ctxWithTimeout, k, string(data))
+	_, err = c.c.Put(ctx, k, string(data))
 	if err != nil {
-		return err
+		return c.wrapError(err)
 	}
 
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key, attached to a new etcd lease with the given TTL.
+// etcd only supports TTLs in full seconds, so the TTL is rounded up.
+// etcd also enforces a minimum TTL (a few seconds, depending on the cluster's election timeout).
+// The configured timeout applies to granting the lease and storing the value together.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return c.Set(k, v)
+	}
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	lease, err := c.c.Grant(ctxWithTimeout, int64((ttl+time.Second-1)/time.Second))
+	if err != nil {
+		return c.wrapError(err)
+	}
+	_, err = c.c.Put(ctxWithTimeout, k, string(data), clientv3.WithLease(lease.ID))
+	return c.wrapError(err)
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -51,15 +100,22 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	return c.GetContext(ctxWithTimeout, k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The configured timeout isn't applied, the context is used instead.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
-	defer cancel()
-	getRes, err := c.c.Get(ctxWithTimeout, k)
+	getRes, err := c.c.Get(ctx, k)
 	if err != nil {
-		return false, err
+		return false, c.wrapError(err)
 	}
 	kvs := getRes.Kvs
 	// If no value was found return false
@@ -75,16 +131,307 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	return c.DeleteContext(ctxWithTimeout, k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The configured timeout isn't applied, the context is used instead.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
+	_, err := c.c.Delete(ctx, k)
+	return c.wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	_, err := c.c.Put(ctxWithTimeout, k, string(v))
+	return c.wrapError(err)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	getRes, err := c.c.Get(ctxWithTimeout, k)
+	if err != nil {
+		return nil, false, c.wrapError(err)
+	}
+	// If no value was found return false
+	if len(getRes.Kvs) == 0 {
+		return nil, false, nil
+	}
+	return getRes.Kvs[0].Value, true, nil
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// It uses a transaction that only writes the value if the key has never been created (CreateRevision 0).
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	txnRes, err := c.c.Txn(ctxWithTimeout).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, string(data))).
+		Commit()
+	if err != nil {
+		return false, c.wrapError(err)
+	}
+	return txnRes.Succeeded, nil
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The stored value is retrieved and compared with the old value,
+// then the new value is stored in a transaction that only succeeds
+// if the key wasn't modified in the meantime (same ModRevision).
+// Otherwise the comparison is repeated.
+// The values are compared in their marshalled form.
+// The configured timeout applies to all requests together.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
 	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
 	defer cancel()
-	_, err := c.c.Delete(ctxWithTimeout, k)
+	for {
+		getRes, err := c.c.Get(ctxWithTimeout, k)
+		if err != nil {
+			return false, c.wrapError(err)
+		}
+		if len(getRes.Kvs) == 0 || !bytes.Equal(getRes.Kvs[0].Value, oldData) {
+			return false, nil
+		}
+		txnRes, err := c.c.Txn(ctxWithTimeout).
+			If(clientv3.Compare(clientv3.ModRevision(k), "=", getRes.Kvs[0].ModRevision)).
+			Then(clientv3.OpPut(k, string(newData))).
+			Commit()
+		if err != nil {
+			return false, c.wrapError(err)
+		}
+		if txnRes.Succeeded {
+			return true, nil
+		}
+	}
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// The stored value is retrieved and incremented, then the new value is stored in a transaction,
+// which only succeeds if the key wasn't modified in the meantime.
+// Otherwise the increment is repeated.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept, because the key's lease is kept.
+// The key must not be "".
+func (c Client) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	for {
+		getRes, err := c.c.Get(ctxWithTimeout, k)
+		if err != nil {
+			return 0, c.wrapError(err)
+		}
+		var data []byte
+		cmp := clientv3.Compare(clientv3.CreateRevision(k), "=", 0)
+		var opts []clientv3.OpOption
+		if len(getRes.Kvs) > 0 {
+			data = getRes.Kvs[0].Value
+			cmp = clientv3.Compare(clientv3.ModRevision(k), "=", getRes.Kvs[0].ModRevision)
+			opts = append(opts, clientv3.WithIgnoreLease())
+		}
+		value, newData, err := util.IncrData(c.codec, data, delta)
+		if err != nil {
+			return 0, err
+		}
+		txnRes, err := c.c.Txn(ctxWithTimeout).
+			If(cmp).
+			Then(clientv3.OpPut(k, string(newData), opts...)).
+			Commit()
+		if err != nil {
+			return 0, c.wrapError(err)
+		}
+		if txnRes.Succeeded {
+			return value, nil
+		}
+	}
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix, using etcd's native watch.
+// The expiry of a key-value pair's lease leads to an EventDelete event.
+// The channel is closed when the context is canceled or the watch fails,
+// for example because the watched revision was compacted.
+func (c Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	key := prefix
+	// "\x00" as start and end of the range means "all keys" in etcd.
+	if key == "" {
+		key = "\x00"
+	}
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	watchChan := c.c.Watch(clientv3.WithRequireLeader(ctx), key, clientv3.WithRange(end))
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		for watchRes := range watchChan {
+			if watchRes.Err() != nil {
+				return
+			}
+			for _, ev := range watchRes.Events {
+				e := gokv.Event{
+					Key: string(ev.Kv.Key),
+				}
+				if ev.Type == clientv3.EventTypeDelete {
+					e.Type = gokv.EventDelete
+				} else {
+					e.Type = gokv.EventPut
+					e.Value = ev.Kv.Value
+				}
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// wrapError wraps errors of the etcd client into gokv's errors where possible.
+// The client's context is canceled when it's closed, which is used to detect the use of a closed client.
+func (c Client) wrapError(err error) error {
+	if err == nil {
+		return nil
+	} else if c.c.Ctx().Err() != nil {
+		return util.WrapError(gokv.ErrClosed, err)
+	} else if err == rpctypes.ErrRequestTooLarge {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
 	return err
 }
 
+// Update calls fn with a new transaction, which is based on etcd's software transactional memory (STM).
+// The keys read in fn are compared with their revision at the time they were read,
+// and the changes are only applied if none of them was modified in the meantime (serializable isolation).
+// Otherwise fn is called again.
+// The configured timeout applies to all attempts together.
+// etcd limits the number of operations in a transaction (128 by default).
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	_, err := concurrency.NewSTM(c.c, func(stm concurrency.STM) error {
+		return fn(transaction{
+			c:   c,
+			stm: stm,
+		})
+	}, concurrency.WithAbortContext(ctxWithTimeout))
+	return c.wrapError(err)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in byte-sorted order.
+// They're retrieved in pages, each of which is subject to the configured timeout.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	key := prefix
+	// "\x00" as start and end of the range means "all keys" in etcd.
+	if key == "" {
+		key = "\x00"
+	}
+	end := clientv3.GetPrefixRangeEnd(prefix)
+	for {
+		ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+		getRes, err := c.c.Get(ctxWithTimeout, key, clientv3.WithRange(end), clientv3.WithKeysOnly(), clientv3.WithLimit(keysPageSize))
+		cancel()
+		if err != nil {
+			return c.wrapError(err)
+		}
+		for _, kv := range getRes.Kvs {
+			if err := fn(string(kv.Key)); err != nil {
+				return err
+			}
+		}
+		if !getRes.More || len(getRes.Kvs) == 0 {
+			return nil
+		}
+		// Continue right after the last key of this page
+		key = string(getRes.Kvs[len(getRes.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// Clear deletes all key-value pairs by deleting the prefix "" (all keys) in a single request.
+func (c Client) Clear() error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	_, err := c.c.Delete(ctxWithTimeout, "", clientv3.WithPrefix())
+	return c.wrapError(err)
+}
+
+// IsRetryable returns true for errors of the etcd client that are transient,
+// like when the cluster has no leader, the leader changed or too many requests are sent.
+// It's used by the resilience package, which retries network errors anyway.
+func (c Client) IsRetryable(err error) bool {
+	if errors.Is(err, clientv3.ErrNoAvailableEndpoints) {
+		return true
+	}
+	code := status.Code(err)
+	var etcdErr rpctypes.EtcdError
+	if errors.As(err, &etcdErr) {
+		code = etcdErr.Code()
+	}
+	return code == codes.Unavailable || code == codes.DeadlineExceeded || code == codes.ResourceExhausted
+}
+
 // Close closes the client.
 // It must be called to shut down all connections to the etcd server.
 func (c Client) Close() error {
@@ -158,3 +505,78 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("etcd", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Endpoints: util.URLHosts(u),
+	}
+	q := util.NewURLQuery(u)
+	var timeout time.Duration
+	if q.Duration("timeout", &timeout) {
+		options.Timeout = &timeout
+	}
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// transaction is a gokv.Tx for etcd.
+type transaction struct {
+	c   Client
+	stm concurrency.STM
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.stm.Put(k, string(data))
+	return nil
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	// The STM returns an empty string for non-existing keys.
+	// Marshalled values are never empty, so that's unambiguous.
+	data := t.stm.Get(k)
+	if data == "" {
+		return false, nil
+	}
+	return true, t.c.codec.Unmarshal([]byte(data), v)
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	t.stm.Del(k)
+	return nil
+}
@@ -213,6 +213,132 @@ func TestDefaultTimeout(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestKeys(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestExpiringStore(client, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestAtomic(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestAtomicStore(client, t)
+}
+
+// TestTransaction tests if transactions work properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestTransaction(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTransactional(client, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestWatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestCounter(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestCounter(client, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+//
+// Note: This test is only executed if the initial connection to etcd works.
+func TestClear(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to etcd could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestClearer(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	// clientv3.New() should block when a DialTimeout is set,
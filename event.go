@@ -0,0 +1,35 @@
+package gokv
+
+// EventType is the type of a change of a key-value pair.
+type EventType int
+
+const (
+	// EventPut means that a value was stored for the key.
+	EventPut EventType = iota + 1
+	// EventDelete means that the key-value pair was deleted, or that it expired.
+	EventDelete
+)
+
+// String returns "put" or "delete".
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "put"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a key-value pair, as sent by a Watcher.
+type Event struct {
+	Key  string
+	Type EventType
+	// Value is the marshalled value for EventPut events,
+	// which can be unmarshalled with the codec that the store was configured with.
+	// It's nil for EventDelete events.
+	// Stores whose notifications don't contain the value retrieve it when the notification arrives,
+	// so it can already be the value of a subsequent change.
+	Value []byte
+}
@@ -1,5 +1,10 @@
 /*
 Package file contains an implementation of the `gokv.Store` interface for local files.
 Each key-value pair is a file with the key as name and the value as content.
+
+The package registers itself for the URL scheme "file", so a store can also be created with gokv.Open,
+for example with "file:///tmp/gokv?filenameExtension=gob&codec=gob" (absolute path) or "file://gokv" (relative path).
+The path is used as Directory and the query parameters "filenameExtension", "sweepInterval" and "codec" ("json" or "gob")
+correspond to the fields of Options. An empty "filenameExtension" disables the filename extension.
 */
 package file
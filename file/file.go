@@ -1,18 +1,36 @@
 package file
 
 import (
+	"context"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 var defaultFilenameExtension = "json"
 
+// expiryDirName is the name of the subdirectory that contains the expiry times
+// of the key-value pairs that were stored with a TTL, one file per key.
+// Escaped keys can't start with a "%" that's not followed by two hex digits,
+// so the name can't clash with a key.
+const expiryDirName = "%expiry"
+
+// tmpFilePrefix is the prefix of the temporary files that SetReader() writes to.
+// Like the name of the expiry directory, it can't clash with an escaped key.
+const tmpFilePrefix = "%tmp"
+
 // Store is a gokv.Store implementation for storing key-value pairs as files.
 type Store struct {
 	// For locking the locks map
@@ -22,6 +40,7 @@ type Store struct {
 	fileLocks         map[string]*sync.RWMutex
 	filenameExtension string
 	directory         string
+	sweeper           *util.Sweeper
 	codec             encoding.Codec
 }
 
@@ -38,6 +57,30 @@ func (s Store) Set(k string, v interface{}) error {
 		return err
 	}
 
+	return s.set(k, data)
+}
+
+// SetWithTTL stores the given value for the given key.
+// The expiry time is stored in a separate file in the "%expiry" subdirectory.
+// After the TTL has passed, the key-value pair is deleted when it's accessed the next time
+// or by the sweeper (if configured), whichever comes first.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return s.Set(k, v)
+	}
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
 	escapedKey := url.PathEscape(k)
 
 	// Prepare file lock.
@@ -49,10 +92,19 @@ func (s Store) Set(k string, v interface{}) error {
 	}
 	filePath := filepath.Clean(s.directory + "/" + filename)
 
+	expiry := strconv.FormatInt(time.Now().Add(ttl).UnixNano(), 10)
+
 	// File lock and file handling.
 	lock.Lock()
 	defer lock.Unlock()
-	return ioutil.WriteFile(filePath, data, 0600)
+	// The expiry must be written first, so the value is never readable without it.
+	if err := os.MkdirAll(filepath.Clean(s.directory+"/"+expiryDirName), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(s.expiryPath(escapedKey), []byte(expiry), 0600); err != nil {
+		return wrapError(err)
+	}
+	return wrapError(ioutil.WriteFile(filePath, data, 0600))
 }
 
 // Get retrieves the stored value for the given key.
@@ -66,6 +118,22 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 		return false, err
 	}
 
+	data, found, err := s.get(k)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
 	escapedKey := url.PathEscape(k)
 
 	// Prepare file lock.
@@ -78,25 +146,38 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 	filePath := filepath.Clean(s.directory + "/" + filename)
 
 	// File lock and file handling.
-	lock.RLock()
-	// Deferring the unlocking would lead to the unmarshalling being done during the lock, which is bad for performance.
-	data, err := ioutil.ReadFile(filePath)
-	lock.RUnlock()
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-		return false, err
+	lock.Lock()
+	defer lock.Unlock()
+	return wrapError(s.deleteFiles(escapedKey, filePath))
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
 	}
 
-	return true, s.codec.Unmarshal(data, v)
+	return s.set(k, v)
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
 // The key must not be "".
-func (s Store) Delete(k string) error {
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
 	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return s.get(k)
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// The bytes are written to a temporary file in the store's directory first, which then replaces the file for the key,
+// so the file isn't locked while reading from r and a failed read doesn't leave a partially written value behind.
+// The key must not be "" and the reader must not be nil.
+func (s Store) SetReader(k string, r io.Reader) error {
+	if err := util.CheckKeyAndReader(k, r); err != nil {
 		return err
 	}
 
@@ -111,19 +192,290 @@ func (s Store) Delete(k string) error {
 	}
 	filePath := filepath.Clean(s.directory + "/" + filename)
 
+	tmpFile, err := ioutil.TempFile(s.directory, tmpFilePrefix)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmpFile, r)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return err
+	}
+
+	// File lock and file handling.
+	lock.Lock()
+	defer lock.Unlock()
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		os.Remove(tmpFile.Name())
+		return wrapError(err)
+	}
+	// Remove a TTL from a previous SetWithTTL()
+	err = os.Remove(s.expiryPath(escapedKey))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// The reader is the opened file, so the value isn't read into memory.
+// On Windows, the value can't be overwritten or deleted while the reader is open.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	escapedKey := url.PathEscape(k)
+
+	// Prepare file lock.
+	lock := s.prepFileLock(escapedKey)
+
+	filename := escapedKey
+	if s.filenameExtension != "" {
+		filename += "." + s.filenameExtension
+	}
+	filePath := filepath.Clean(s.directory + "/" + filename)
+
+	// File lock and file handling.
+	lock.RLock()
+	file, err := os.Open(filePath)
+	if err != nil {
+		lock.RUnlock()
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, wrapError(err)
+	}
+	expired, err := s.expired(escapedKey)
+	lock.RUnlock()
+	if err != nil {
+		file.Close()
+		return nil, false, err
+	} else if expired {
+		file.Close()
+		return nil, false, s.deleteExpired(escapedKey)
+	}
+	return file, true, nil
+}
+
+// set writes the given data to the file for the given key
+// and removes the expiry file of a previous SetWithTTL().
+func (s Store) set(k string, data []byte) error {
+	escapedKey := url.PathEscape(k)
+
+	// Prepare file lock.
+	lock := s.prepFileLock(escapedKey)
+
+	filename := escapedKey
+	if s.filenameExtension != "" {
+		filename += "." + s.filenameExtension
+	}
+	filePath := filepath.Clean(s.directory + "/" + filename)
+
 	// File lock and file handling.
 	lock.Lock()
 	defer lock.Unlock()
+	if err := ioutil.WriteFile(filePath, data, 0600); err != nil {
+		return wrapError(err)
+	}
+	// Remove a TTL from a previous SetWithTTL()
+	err := os.Remove(s.expiryPath(escapedKey))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// get reads the data from the file for the given key.
+// Expired key-value pairs are deleted and reported as not found.
+func (s Store) get(k string) (data []byte, found bool, err error) {
+	escapedKey := url.PathEscape(k)
+
+	// Prepare file lock.
+	lock := s.prepFileLock(escapedKey)
+
+	filename := escapedKey
+	if s.filenameExtension != "" {
+		filename += "." + s.filenameExtension
+	}
+	filePath := filepath.Clean(s.directory + "/" + filename)
+
+	// File lock and file handling.
+	lock.RLock()
+	// Deferring the unlocking would lead to the unmarshalling being done during the lock, which is bad for performance.
+	data, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		lock.RUnlock()
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, wrapError(err)
+	}
+	expired, err := s.expired(escapedKey)
+	lock.RUnlock()
+	if err != nil {
+		return nil, false, err
+	} else if expired {
+		return nil, false, s.deleteExpired(escapedKey)
+	}
+	return data, true, nil
+}
+
+// wrapError wraps file system errors into gokv's errors where possible.
+// Keys are used as filenames, so a filename that's too long means that the key is too long.
+func wrapError(err error) error {
+	if errors.Is(err, syscall.ENAMETOOLONG) {
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	}
+	return err
+}
+
+// SetContext stores the given value for the given key.
+// File system operations can't be canceled, so the context is only checked before accessing the file.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are derived from the names of the files in the store's directory
+// and passed in the order of the filenames.
+// Files that don't have the configured filename extension are ignored.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	fileInfos, err := ioutil.ReadDir(s.directory)
+	if err != nil {
+		return err
+	}
+	suffix := ""
+	if s.filenameExtension != "" {
+		suffix = "." + s.filenameExtension
+	}
+	for _, fileInfo := range fileInfos {
+		filename := fileInfo.Name()
+		if fileInfo.IsDir() || !strings.HasSuffix(filename, suffix) {
+			continue
+		}
+		escapedKey := strings.TrimSuffix(filename, suffix)
+		k, err := url.PathUnescape(escapedKey)
+		if err != nil || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if expired, err := s.expired(escapedKey); err != nil {
+			return err
+		} else if expired {
+			continue
+		}
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiryPath returns the path of the file that contains the expiry time for the given escaped key.
+func (s Store) expiryPath(escapedKey string) string {
+	return filepath.Clean(s.directory + "/" + expiryDirName + "/" + escapedKey)
+}
+
+// expired returns true if the key-value pair for the given escaped key was stored with a TTL
+// that has passed.
+// The caller must hold the file lock for the key.
+func (s Store) expired(escapedKey string) (bool, error) {
+	data, err := ioutil.ReadFile(s.expiryPath(escapedKey))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	expiry, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return time.Now().UnixNano() >= expiry, nil
+}
+
+// deleteExpired deletes the key-value pair for the given escaped key if it's expired.
+// The expiry is checked again after acquiring the write lock,
+// because the key-value pair could have been overwritten in the meantime.
+func (s Store) deleteExpired(escapedKey string) error {
+	filename := escapedKey
+	if s.filenameExtension != "" {
+		filename += "." + s.filenameExtension
+	}
+	filePath := filepath.Clean(s.directory + "/" + filename)
+
+	lock := s.prepFileLock(escapedKey)
+	lock.Lock()
+	defer lock.Unlock()
+	expired, err := s.expired(escapedKey)
+	if err != nil || !expired {
+		return err
+	}
+	return s.deleteFiles(escapedKey, filePath)
+}
+
+// deleteFiles deletes the value file and the expiry file (if it exists) for the given escaped key.
+// The caller must hold the file lock for the key.
+func (s Store) deleteFiles(escapedKey, filePath string) error {
 	err := os.Remove(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.Remove(s.expiryPath(escapedKey))
 	if os.IsNotExist(err) {
 		return nil
 	}
 	return err
 }
 
+// sweep deletes all expired key-value pairs.
+func (s Store) sweep() error {
+	fileInfos, err := ioutil.ReadDir(filepath.Clean(s.directory + "/" + expiryDirName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, fileInfo := range fileInfos {
+		if err := s.deleteExpired(fileInfo.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the store.
 // When called, some resources of the store are left for garbage collection.
 func (s Store) Close() error {
+	if s.sweeper != nil {
+		s.sweeper.Stop()
+	}
 	s.fileLocks = nil
 	return nil
 }
@@ -154,6 +506,10 @@ type Options struct {
 	// Set to "" to disable.
 	// Optional ("json" by default).
 	FilenameExtension *string
+	// Interval in which a background goroutine deletes expired key-value pairs.
+	// Without it, expired key-value pairs are only deleted when they're accessed.
+	// Optional (0 by default, which disables the background sweeping).
+	SweepInterval time.Duration
 	// Encoding format.
 	// Note: When you change this, you should also change the FilenameExtension if it's not empty ("").
 	// Optional (encoding.JSON by default).
@@ -195,6 +551,37 @@ func NewStore(options Options) (Store, error) {
 	result.fileLocks = make(map[string]*sync.RWMutex)
 	result.filenameExtension = *options.FilenameExtension
 	result.codec = options.Codec
+	if options.SweepInterval > 0 {
+		sweeper := util.StartSweeper(options.SweepInterval, result.sweep)
+		result.sweeper = &sweeper
+	}
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("file", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Directory: util.URLPath(u),
+	}
+	q := util.NewURLQuery(u)
+	var filenameExtension string
+	if q.String("filenameExtension", &filenameExtension) {
+		options.FilenameExtension = &filenameExtension
+	}
+	q.Duration("sweepInterval", &options.SweepInterval)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
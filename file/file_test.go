@@ -144,6 +144,46 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestContextStore(store, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+func TestKeys(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestLister(store, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+func TestTTL(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestExpiringStore(store, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+func TestRawStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestRawStore(store, t)
+}
+
+// TestStreamStore tests if storing and retrieving values as streams works properly.
+func TestStreamStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+
+	test.TestStreamStore(store, t)
+}
+
 func createStore(t *testing.T, codec encoding.Codec) (file.Store, string) {
 	path := generateRandomTempDBpath(t)
 	options := file.Options{
@@ -1,4 +1,8 @@
 /*
 Package freecache contains an implementation of the `gokv.Store` interface for FreeCache.
+
+The package registers itself for the URL scheme "freecache", so a store can also be created with gokv.Open,
+for example with "freecache://?size=104857600&codec=gob".
+The query parameters "size" (in bytes) and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package freecache
@@ -1,8 +1,14 @@
 package freecache
 
 import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/coocood/freecache"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -19,16 +25,28 @@ type Store struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (s Store) Set(k string, v interface{}) error {
+	return s.SetWithTTL(k, v, 0)
+}
+
+// SetWithTTL stores the given value for the given key, with FreeCache's native expiration.
+// FreeCache only supports TTLs in full seconds, so the TTL is rounded up.
+// A TTL of 0 means that the key-value pair doesn't expire (but can still be evicted).
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
 
 	data, err := s.codec.Marshal(v)
 	if err != nil {
 		return err
 	}
 
-	return s.s.Set([]byte(k), data, 0)
+	expireSeconds := int((ttl + time.Second - 1) / time.Second)
+	return s.set(k, data, expireSeconds)
 }
 
 // Get retrieves the stored value for the given key.
@@ -65,6 +83,111 @@ func (s Store) Delete(k string) error {
 	return nil
 }
 
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return s.set(k, v, 0)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err := s.s.Get([]byte(k))
+	if err != nil {
+		if err == freecache.ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// set stores the given data for the given key, expiring after the given number of seconds.
+func (s Store) set(k string, data []byte, expireSeconds int) error {
+	err := s.s.Set([]byte(k), data, expireSeconds)
+	switch err {
+	case freecache.ErrLargeKey:
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	case freecache.ErrLargeEntry:
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
+}
+
+// SetContext stores the given value for the given key.
+// FreeCache doesn't support contexts, so the context is only checked before accessing the cache.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in no particular order.
+// Keys that are evicted during the iteration may or may not be passed to fn.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	it := s.s.NewIterator()
+	for entry := it.Next(); entry != nil; entry = it.Next() {
+		k := string(entry.Key)
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear deletes all key-value pairs of the cache.
+func (s Store) Clear() error {
+	s.s.Clear()
+	return nil
+}
+
+// Stats returns the number of entries of the cache and FreeCache's counters for hits, misses and evictions.
+// The counters include the lookups of all methods of the store, for example of GetMulti.
+// Expired entries are counted until they're overwritten or evicted.
+// The size in bytes is unknown (-1), because FreeCache allocates the configured size upfront.
+func (s Store) Stats() (gokv.StoreStats, error) {
+	result := gokv.UnknownStats
+	result.Keys = s.s.EntryCount()
+	result.Hits = s.s.HitCount()
+	result.Misses = s.s.MissCount()
+	result.Evictions = s.s.EvacuateCount()
+	return result, nil
+}
+
 // Close closes the store.
 // When called, the cache is cleared.
 func (s Store) Close() error {
@@ -116,3 +239,20 @@ func NewStore(options Options) Store {
 		codec: options.Codec,
 	}
 }
+
+func init() {
+	gokv.Register("freecache", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.Int("size", &options.Size)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewStore(options), nil
+}
@@ -1,4 +1,8 @@
 /*
 Package gomap contains an implementation of the `gokv.Store` interface for a Go map.
+
+The package registers itself for the URL scheme "gomap", so a store can also be created with gokv.Open,
+for example with "gomap://?sweepInterval=1m&codec=gob".
+The query parameters "sweepInterval" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package gomap
@@ -1,17 +1,28 @@
 package gomap
 
 import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Store is a gokv.Store implementation for a Go map with a sync.RWMutex for concurrent access.
 type Store struct {
-	m     map[string][]byte
-	lock  *sync.RWMutex
-	codec encoding.Codec
+	m map[string][]byte
+	// Expiry times of the key-value pairs that were stored with a TTL.
+	// Guarded by the same lock as m.
+	expiries    map[string]time.Time
+	lock        *sync.RWMutex
+	sweeper     *util.Sweeper
+	broadcaster *util.Broadcaster
+	codec       encoding.Codec
 }
 
 // Set stores the given value for the given key.
@@ -29,7 +40,34 @@ func (s Store) Set(k string, v interface{}) error {
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	s.m[k] = data
+	s.put(k, data)
+	return nil
+}
+
+// SetWithTTL stores the given value for the given key.
+// After the TTL has passed, the key-value pair is deleted when it's accessed the next time
+// or by the sweeper (if configured), whichever comes first.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return s.Set(k, v)
+	}
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.put(k, data)
+	s.expiries[k] = time.Now().Add(ttl)
 	return nil
 }
 
@@ -46,6 +84,7 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 
 	s.lock.RLock()
 	data, found := s.m[k]
+	expiry, hasExpiry := s.expiries[k]
 	// Unlock right after reading instead of with defer(),
 	// because following unmarshalling will take some time
 	// and we don't want to block writing threads until that's done.
@@ -53,6 +92,10 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 	if !found {
 		return false, nil
 	}
+	if hasExpiry && !time.Now().Before(expiry) {
+		s.deleteExpired(k)
+		return false, nil
+	}
 
 	return true, s.codec.Unmarshal(data, v)
 }
@@ -65,7 +108,440 @@ func (s Store) Delete(k string) error {
 		return err
 	}
 
-	delete(s.m, k)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remove(k)
+	return nil
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The bytes are copied, so the passed slice can be reused afterwards.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	data := make([]byte, len(v))
+	copy(data, v)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.put(k, data)
+	return nil
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// The returned slice is a copy, so changing it doesn't change the stored value.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	s.lock.RLock()
+	data, found := s.m[k]
+	expiry, hasExpiry := s.expiries[k]
+	s.lock.RUnlock()
+	if !found {
+		return nil, false, nil
+	}
+	if hasExpiry && !time.Now().Before(expiry) {
+		s.deleteExpired(k)
+		return nil, false, nil
+	}
+
+	v = make([]byte, len(data))
+	copy(v, data)
+	return v, true, nil
+}
+
+// SetBytesWithTTL stores the given bytes for the given key without marshalling them, with the given time to live.
+// It behaves like SetBytes, and like SetWithTTL regarding the TTL.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (s Store) SetBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return s.SetBytes(k, v)
+	}
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	data := make([]byte, len(v))
+	copy(data, v)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.put(k, data)
+	s.expiries[k] = time.Now().Add(ttl)
+	return nil
+}
+
+// TTL returns the remaining time to live of the key-value pair for the given key,
+// or 0 if it was stored without a TTL.
+// If no value is found it returns (0, false, nil).
+// The key must not be "".
+func (s Store) TTL(k string) (ttl time.Duration, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, false, err
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if _, found := s.m[k]; !found {
+		return 0, false, nil
+	}
+	expiry, hasExpiry := s.expiries[k]
+	if !hasExpiry {
+		return 0, true, nil
+	}
+	ttl = time.Until(expiry)
+	if ttl <= 0 {
+		return 0, false, nil
+	}
+	return ttl, true, nil
+}
+
+// SetContext stores the given value for the given key.
+// Operations on the Go map can't be canceled, so the context is only checked before the operation.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in no particular order.
+// The keys are collected before fn is called the first time,
+// so fn can call other methods of the store without leading to a deadlock.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	var keys []string
+	now := time.Now()
+	s.lock.RLock()
+	for k := range s.m {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if expiry, hasExpiry := s.expiries[k]; hasExpiry && !now.Before(expiry) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	s.lock.RUnlock()
+
+	for _, k := range keys {
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetMulti stores the given values for the given keys.
+// All values are marshalled before the map is locked once for storing all of them.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	data := make([][]byte, len(vs))
+	for i, v := range vs {
+		var err error
+		if data[i], err = s.codec.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, k := range keys {
+		s.put(k, data[i])
+	}
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys.
+// The map is locked once for reading all values.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(keys))
+	found = make([]bool, len(keys))
+	var expiredKeys []string
+	now := time.Now()
+	s.lock.RLock()
+	for i, k := range keys {
+		if expiry, hasExpiry := s.expiries[k]; hasExpiry && !now.Before(expiry) {
+			expiredKeys = append(expiredKeys, k)
+			continue
+		}
+		data[i], found[i] = s.m[k]
+	}
+	s.lock.RUnlock()
+
+	for _, k := range expiredKeys {
+		s.deleteExpired(k)
+	}
+	for i, v := range vs {
+		if !found[i] {
+			continue
+		}
+		if err := s.codec.Unmarshal(data[i], v); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// The map is locked once for deleting all of them.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, k := range keys {
+		s.remove(k)
+	}
+	return nil
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// An expired key-value pair counts as absent.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.get(k, time.Now()); found {
+		return false, nil
+	}
+	s.put(k, data)
+	return true, nil
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := s.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := s.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, found := s.get(k, time.Now())
+	if !found || !bytes.Equal(data, oldData) {
+		return false, nil
+	}
+	s.put(k, newData)
+	return true, nil
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// The map is locked while the value is read, incremented and written.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
+// The key must not be "".
+func (s Store) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, found := s.get(k, time.Now())
+	value, newData, err := util.IncrData(s.codec, data, delta)
+	if err != nil {
+		return 0, err
+	}
+	expiry, hasExpiry := s.expiries[k]
+	s.put(k, newData)
+	// put removes the TTL, which must be kept unless the previous value was expired
+	if found && hasExpiry {
+		s.expiries[k] = expiry
+	}
+	return value, nil
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix,
+// including the deletion of expired key-value pairs.
+// The events are queued for each watcher, so a slow receiver doesn't block the store.
+// The channel is closed when the context is canceled or the store is closed.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return s.broadcaster.Watch(ctx, prefix), nil
+}
+
+// Update calls fn with a new transaction.
+// The changes are collected and applied to the map when fn returns nil,
+// or discarded otherwise.
+// The map is locked during the whole transaction, so transactions never conflict,
+// but fn must not call the store's methods, as that would lead to a deadlock.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	tx := transaction{
+		s:      s,
+		writes: make(map[string][]byte),
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	for k, data := range tx.writes {
+		if data == nil {
+			s.remove(k)
+		} else {
+			s.put(k, data)
+		}
+	}
+	return nil
+}
+
+// get returns the stored data for the given key if it isn't expired at the given time.
+// The caller must hold the lock.
+func (s Store) get(k string, now time.Time) (data []byte, found bool) {
+	if expiry, hasExpiry := s.expiries[k]; hasExpiry && !now.Before(expiry) {
+		return nil, false
+	}
+	data, found = s.m[k]
+	return data, found
+}
+
+// deleteExpired deletes the key-value pair for the given key if it's expired.
+// The expiry is checked again after acquiring the write lock,
+// because the key-value pair could have been overwritten in the meantime.
+func (s Store) deleteExpired(k string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if expiry, hasExpiry := s.expiries[k]; hasExpiry && !time.Now().Before(expiry) {
+		s.remove(k)
+	}
+}
+
+// put stores the given data for the given key without an expiry time
+// and notifies the watchers.
+// The caller must hold the write lock.
+func (s Store) put(k string, data []byte) {
+	s.m[k] = data
+	delete(s.expiries, k)
+	s.broadcaster.Publish(gokv.Event{
+		Key:   k,
+		Type:  gokv.EventPut,
+		Value: data,
+	})
+}
+
+// remove deletes the key-value pair for the given key
+// and notifies the watchers if it existed.
+// The caller must hold the write lock.
+func (s Store) remove(k string) {
+	if _, found := s.m[k]; found {
+		delete(s.m, k)
+		s.broadcaster.Publish(gokv.Event{
+			Key:  k,
+			Type: gokv.EventDelete,
+		})
+	}
+	delete(s.expiries, k)
+}
+
+// Clear deletes all key-value pairs.
+// An EventDelete event is sent to the watchers for each key.
+func (s Store) Clear() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for k := range s.m {
+		s.remove(k)
+	}
+	return nil
+}
+
+// Stats returns the number of key-value pairs, including expired ones that weren't deleted yet,
+// and the total size of their keys and marshalled values.
+// The statistics for caches are unknown (-1).
+func (s Store) Stats() (gokv.StoreStats, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	result := gokv.UnknownStats
+	result.Keys = int64(len(s.m))
+	result.Bytes = 0
+	for k, v := range s.m {
+		result.Bytes += int64(len(k) + len(v))
+	}
+	return result, nil
+}
+
+// sweep deletes all expired key-value pairs.
+func (s Store) sweep() error {
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for k, expiry := range s.expiries {
+		if !now.Before(expiry) {
+			s.remove(k)
+		}
+	}
 	return nil
 }
 
@@ -73,6 +549,10 @@ func (s Store) Delete(k string) error {
 // When called, the store's pointer to the internal Go map is set to nil,
 // leading to the map being free for garbage collection.
 func (s Store) Close() error {
+	if s.sweeper != nil {
+		s.sweeper.Stop()
+	}
+	s.broadcaster.Close()
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.m = nil
@@ -81,13 +561,17 @@ func (s Store) Close() error {
 
 // Options are the options for the Go map store.
 type Options struct {
+	// Interval in which a background goroutine deletes expired key-value pairs.
+	// Without it, expired key-value pairs are only deleted when they're accessed.
+	// Optional (0 by default, which disables the background sweeping).
+	SweepInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
 }
 
 // DefaultOptions is an Options object with default values.
-// Codec: encoding.JSON
+// SweepInterval: 0 (disabled), Codec: encoding.JSON
 var DefaultOptions = Options{
 	Codec: encoding.JSON,
 }
@@ -101,9 +585,89 @@ func NewStore(options Options) Store {
 		options.Codec = DefaultOptions.Codec
 	}
 
-	return Store{
-		m:     make(map[string][]byte),
-		lock:  new(sync.RWMutex),
-		codec: options.Codec,
+	result := Store{
+		m:           make(map[string][]byte),
+		expiries:    make(map[string]time.Time),
+		lock:        new(sync.RWMutex),
+		broadcaster: util.NewBroadcaster(),
+		codec:       options.Codec,
+	}
+	if options.SweepInterval > 0 {
+		sweeper := util.StartSweeper(options.SweepInterval, result.sweep)
+		result.sweeper = &sweeper
+	}
+
+	return result
+}
+
+func init() {
+	gokv.Register("gomap", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.Duration("sweepInterval", &options.SweepInterval)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewStore(options), nil
+}
+
+// transaction is a gokv.Tx for the Go map store.
+// The lock of the store is held by Update while the transaction is used.
+type transaction struct {
+	s Store
+	// Marshalled values of the keys that were set in the transaction,
+	// with nil values for the keys that were deleted.
+	writes map[string][]byte
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.writes[k] = data
+	return nil
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, written := t.writes[k]
+	if written {
+		found = data != nil
+	} else {
+		data, found = t.s.get(k, time.Now())
 	}
+	if !found {
+		return false, nil
+	}
+	return true, t.s.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	t.writes[k] = nil
+	return nil
 }
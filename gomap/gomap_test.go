@@ -3,6 +3,7 @@ package gomap_test
 import (
 	"testing"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/gomap"
 	"github.com/philippgille/gokv/test"
@@ -130,6 +131,172 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestContextStore(store, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+func TestKeys(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestLister(store, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+func TestTTL(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestExpiringStore(store, t)
+}
+
+// TestBytesWithTTL tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+func TestBytesWithTTL(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestTTLStore(store, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+func TestBatch(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestBatchStore(store, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+func TestAtomic(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestAtomicStore(store, t)
+}
+
+// TestTransaction tests if transactions work properly.
+func TestTransaction(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestTransactional(store, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+func TestWatch(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestWatcher(store, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+func TestRawStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestRawStore(store, t)
+}
+
+// TestOpen tests if a store can be created with gokv.Open.
+func TestOpen(t *testing.T) {
+	store, err := gokv.Open("gomap://?codec=gob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.(gomap.Store); !ok {
+		t.Errorf("Expected a gomap.Store, but was %T", store)
+	}
+
+	test.TestStore(store, t)
+
+	_, err = gokv.Open("gomap://?foo=bar")
+	if err == nil {
+		t.Error("Expected an error for an unknown query parameter, but was nil")
+	}
+}
+
+// TestCounter tests if incrementing values works properly.
+func TestCounter(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestCounter(store, t)
+}
+
+// TestPrefix tests if gokv.WithPrefix works properly with a gomap.Store.
+func TestPrefix(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	prefixed := gokv.WithPrefix(store, "tenantA:")
+	test.TestStore(prefixed, t)
+	test.TestLister(prefixed, t)
+	test.TestBatchStore(prefixed, t)
+	test.TestAtomicStore(prefixed, t)
+	test.TestCounter(prefixed, t)
+	test.TestTransactional(prefixed, t)
+	test.TestWatcher(prefixed, t)
+	test.TestNamespaces(prefixed, gokv.WithPrefix(store, "tenantB:"), t)
+	test.TestStatsStore(prefixed, t)
+
+	// Clearing the prefixed store must only delete the key-value pairs with the prefix
+	err := store.Set("tenantB:foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.TestClearer(prefixed, t)
+	found, err := store.Get("tenantB:foo", new(test.Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+}
+
+// TestSupports tests if gokv.Supports detects the capabilities of the store that's wrapped by a gokv.PrefixStore.
+func TestSupports(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	prefixed := gokv.WithPrefix(store, "tenantA:")
+	basic := gokv.WithPrefix(basicStore{store}, "tenantA:")
+	tests := []struct {
+		iface    interface{}
+		expected bool
+	}{
+		{(*gokv.Watcher)(nil), false},
+		{(*gokv.ExpiringStore)(nil), false},
+		{(*gokv.Lister)(nil), false},
+		{(*gokv.Clearer)(nil), false},
+		{(*gokv.BatchStore)(nil), true},
+		{(*gokv.ContextStore)(nil), true},
+	}
+	for _, test := range tests {
+		if !gokv.Supports(prefixed, test.iface) {
+			t.Errorf("Expected the PrefixStore of a gomap.Store to support %T", test.iface)
+		}
+		if actual := gokv.Supports(basic, test.iface); actual != test.expected {
+			t.Errorf("Expected %v for %T, but was: %v", test.expected, test.iface, actual)
+		}
+	}
+	if gokv.Supports(basicStore{store}, (*gokv.Lister)(nil)) {
+		t.Error("Expected a store that doesn't implement gokv.Lister not to support it")
+	}
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+func TestClear(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestClearer(store, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+func TestStats(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestStatsStore(store, t)
+}
+
 func createStore(t *testing.T, codec encoding.Codec) gomap.Store {
 	options := gomap.Options{
 		Codec: codec,
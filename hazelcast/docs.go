@@ -1,4 +1,9 @@
 /*
 Package hazelcast contains an implementation of the `gokv.Store` interface for Hazelcast.
+
+The package registers itself for the URL scheme "hazelcast", so a client can also be created with gokv.Open,
+for example with "hazelcast://localhost:5701/gokv?codec=gob".
+The host is used as Address, the path as MapName
+and the query parameter "codec" ("json" or "gob") corresponds to the field of Options.
 */
 package hazelcast
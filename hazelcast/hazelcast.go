@@ -1,13 +1,18 @@
 package hazelcast
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	hazelcast "github.com/hazelcast/hazelcast-go-client"
 	"github.com/hazelcast/hazelcast-go-client/config/property"
 	"github.com/hazelcast/hazelcast-go-client/core"
 	"github.com/hazelcast/hazelcast-go-client/core/logger"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -42,12 +47,35 @@ func (c Client) Set(k string, v interface{}) error {
 
 	err = c.m.Set(k, data)
 	if err != nil {
-		return err
+		return c.wrapError(err)
 	}
 
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key, with Hazelcast's native expiration.
+// Setting the key-value pair again with Set applies the map's default TTL,
+// which is "no expiration" unless configured differently on the server.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return c.Set(k, v)
+	}
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.wrapError(c.m.SetWithTTL(k, data, ttl))
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -61,7 +89,7 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 
 	hazelcastValue, err := c.m.Get(k)
 	if err != nil {
-		return false, err
+		return false, c.wrapError(err)
 	}
 	// If no value was found return false
 	if hazelcastValue == nil {
@@ -84,7 +112,77 @@ func (c Client) Delete(k string) error {
 		return err
 	}
 
-	return c.m.Delete(k)
+	return c.wrapError(c.m.Delete(k))
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// Like with Set, the map's default TTL is applied.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return c.wrapError(c.m.Set(k, v))
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	hazelcastValue, err := c.m.Get(k)
+	if err != nil {
+		return nil, false, c.wrapError(err)
+	}
+	// If no value was found return false
+	if hazelcastValue == nil {
+		return nil, false, nil
+	}
+	data, ok := hazelcastValue.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("The returned value for key %v was expected to be a slice of bytes, but was type: %T", k, hazelcastValue)
+	}
+	return data, true, nil
+}
+
+// wrapError wraps errors of the Hazelcast client into gokv's errors where possible.
+// Errors that occur after the client was shut down are wrapped into gokv.ErrClosed.
+func (c Client) wrapError(err error) error {
+	if err != nil && !c.c.LifecycleService().IsRunning() {
+		return util.WrapError(gokv.ErrClosed, err)
+	}
+	return err
+}
+
+// SetContext stores the given value for the given key.
+// The Hazelcast Go client doesn't support contexts, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
 }
 
 // Close closes the client.
@@ -161,3 +259,26 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("hazelcast", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Address: u.Host,
+		MapName: strings.Trim(u.Path, "/"),
+	}
+	q := util.NewURLQuery(u)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -170,6 +170,48 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to Hazelcast works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Hazelcast could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+//
+// Note: This test is only executed if the initial connection to Hazelcast works.
+func TestTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Hazelcast could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestExpiringStore(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to Hazelcast works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Hazelcast could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	config := hazelcastOrig.NewConfig()
@@ -5,6 +5,7 @@ go 1.13
 require (
 	github.com/amsokol/ignite-go-client v0.12.2
 	github.com/google/uuid v1.1.1 // indirect
+	github.com/philippgille/gokv v0.5.1-0.20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/encoding v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/test v0.0.0-20191011213304-eb77f15b9c61
 	github.com/philippgille/gokv/util v0.0.0-20191011213304-eb77f15b9c61
@@ -1,12 +1,17 @@
 package ignite
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	ignite "github.com/amsokol/ignite-go-client/binary/v1"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -32,7 +37,7 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	return c.c.CachePut(c.cacheName, true, k, data)
+	return c.wrapError(c.c.CachePut(c.cacheName, true, k, data))
 }
 
 // Get retrieves the stored value for the given key.
@@ -48,7 +53,7 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 
 	dataIface, err := c.c.CacheGet(c.cacheName, true, k)
 	if err != nil {
-		return false, err
+		return false, c.wrapError(err)
 	}
 	// If no value was found return false.
 	// Due to the used package we can't differentiate between a nil value and a value that's not found.
@@ -74,12 +79,78 @@ func (c Client) Delete(k string) error {
 	}
 
 	_, err := c.c.CacheRemoveKey(c.cacheName, false, k)
-	if err != nil {
+	return c.wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
 		return err
 	}
+
+	return c.wrapError(c.c.CachePut(c.cacheName, true, k, v))
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	dataIface, err := c.c.CacheGet(c.cacheName, true, k)
+	if err != nil {
+		return nil, false, c.wrapError(err)
+	}
+	// If no value was found return false (see Get for why nil is treated as not found).
+	if dataIface == nil {
+		return nil, false, nil
+	}
+	data, ok := dataIface.([]byte)
+	if !ok {
+		return nil, true, fmt.Errorf("The value for key %v is expected to be a slice of bytes, but its type is: %T", k, dataIface)
+	}
+	return data, true, nil
+}
+
+// wrapError wraps errors of the Apache Ignite client into gokv's errors where possible.
+// Errors that occur when the client isn't connected (anymore) are wrapped into gokv.ErrClosed.
+func (c Client) wrapError(err error) error {
+	if err != nil && !c.c.Connected() {
+		return util.WrapError(gokv.ErrClosed, err)
+	}
 	return err
 }
 
+// SetContext stores the given value for the given key.
+// The Apache Ignite Go client doesn't support contexts, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
+}
+
 // Close closes the client.
 // It must be called to shut down all connections to the Apache Ignite server.
 func (c Client) Close() error {
@@ -170,3 +241,36 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("ignite", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Host:      u.Hostname(),
+		CacheName: strings.Trim(u.Path, "/"),
+	}
+	if port := u.Port(); port != "" {
+		var err error
+		if options.Port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("Invalid port in the URL: %w", err)
+		}
+	}
+	if u.User != nil {
+		options.Username = u.User.Username()
+		options.Password, _ = u.User.Password()
+	}
+	q := util.NewURLQuery(u)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
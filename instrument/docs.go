@@ -0,0 +1,20 @@
+/*
+Package instrument contains a gokv.Store implementation that wraps any other store
+and reports the latency, errors, value sizes and hits/misses of each operation.
+
+The operations are reported to an Observer, for example an ExpvarObserver or an adapter for your metrics library,
+and to a Tracer, which starts an OpenTelemetry-style span for each operation.
+The name of the backend (like "redis") is included in each observation and set as span attribute,
+so the metrics of multiple stores can be told apart.
+
+	client, err := redis.NewClient(redis.DefaultOptions)
+	...
+	options := instrument.DefaultOptions
+	options.Store = client
+	options.Observer = instrument.NewExpvarObserver("gokv")
+	store, err := instrument.NewStore(options)
+
+The sizes of values that are passed to and retrieved by methods like Set and Get are only reported
+when a Codec is configured, because they have to be marshalled an additional time.
+*/
+package instrument
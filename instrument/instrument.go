@@ -0,0 +1,526 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+)
+
+// Store is a gokv.Store that wraps another store and reports each operation to an Observer and a Tracer.
+//
+// Store implements all optional interfaces of the gokv package, so that it can wrap any store.
+// Methods of interfaces that the wrapped store doesn't implement fall back to the basic methods
+// or return gokv.ErrUnsupported, and the operations are reported with the name of the called method either way.
+// gokv.Supports() reports which of the interfaces are actually supported.
+type Store struct {
+	store    gokv.Store
+	backend  string
+	observer Observer
+	tracer   Tracer
+	codec    encoding.Codec
+}
+
+// Set stores the given value for the given key.
+// See gokv.Store.Set() for details.
+func (s Store) Set(k string, v interface{}) error {
+	c := s.start(context.Background(), "Set")
+	err := s.store.Set(k, v)
+	c.stop(err)
+	c.o.Size = s.size(v, err)
+	s.report(c)
+	return err
+}
+
+// Get retrieves the value for the given key.
+// See gokv.Store.Get() for details.
+func (s Store) Get(k string, v interface{}) (found bool, err error) {
+	c := s.start(context.Background(), "Get")
+	found, err = s.store.Get(k, v)
+	c.stop(err)
+	c.found(found)
+	if found {
+		c.o.Size = s.size(v, err)
+	}
+	s.report(c)
+	return found, err
+}
+
+// Delete deletes the stored value for the given key.
+// See gokv.Store.Delete() for details.
+func (s Store) Delete(k string) error {
+	c := s.start(context.Background(), "Delete")
+	err := s.store.Delete(k)
+	c.stop(err)
+	s.report(c)
+	return err
+}
+
+// Close closes the wrapped store.
+// It's not reported.
+func (s Store) Close() error {
+	return s.store.Close()
+}
+
+// Unwrap returns the wrapped store.
+func (s Store) Unwrap() gokv.Store {
+	return s.store
+}
+
+// Supports reports whether the store supports the optional interface that iface points to.
+// See the package-level function gokv.Supports() for details.
+func (s Store) Supports(iface interface{}) bool {
+	switch iface.(type) {
+	case *gokv.BatchStore, *gokv.ContextStore:
+		return true
+	case *gokv.Clearer, *gokv.StatsStore:
+		return gokv.Supports(s.store, iface) || gokv.Supports(s.store, (*gokv.Lister)(nil))
+	}
+	return gokv.Supports(s.store, iface)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// See gokv.RawStore.SetBytes() for details.
+func (s Store) SetBytes(k string, v []byte) error {
+	c := s.start(context.Background(), "SetBytes")
+	err := gokv.ErrUnsupported
+	if rawStore, ok := s.store.(gokv.RawStore); ok {
+		err = rawStore.SetBytes(k, v)
+	}
+	c.stop(err)
+	if err == nil {
+		c.o.Size = int64(len(v))
+	}
+	s.report(c)
+	return err
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// See gokv.RawStore.GetBytes() for details.
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	c := s.start(context.Background(), "GetBytes")
+	err = gokv.ErrUnsupported
+	if rawStore, ok := s.store.(gokv.RawStore); ok {
+		v, found, err = rawStore.GetBytes(k)
+	}
+	c.stop(err)
+	c.found(found)
+	if found {
+		c.o.Size = int64(len(v))
+	}
+	s.report(c)
+	return v, found, err
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// See gokv.StreamStore.SetReader() for details.
+func (s Store) SetReader(k string, r io.Reader) error {
+	c := s.start(context.Background(), "SetReader")
+	err := gokv.ErrUnsupported
+	cr := &countingReader{r: r}
+	if streamStore, ok := s.store.(gokv.StreamStore); ok {
+		err = streamStore.SetReader(k, cr)
+	}
+	c.stop(err)
+	if err == nil {
+		c.o.Size = cr.n
+	}
+	s.report(c)
+	return err
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// The size of the value is unknown, because it's only read after GetReader returned.
+// See gokv.StreamStore.GetReader() for details.
+func (s Store) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	c := s.start(context.Background(), "GetReader")
+	err = gokv.ErrUnsupported
+	if streamStore, ok := s.store.(gokv.StreamStore); ok {
+		r, found, err = streamStore.GetReader(k)
+	}
+	c.stop(err)
+	c.found(found)
+	s.report(c)
+	return r, found, err
+}
+
+// SetContext stores the given value for the given key.
+// The context that's returned by the Tracer is passed to the wrapped store.
+// If the wrapped store doesn't implement gokv.ContextStore, the context is only checked before the value is stored.
+// See gokv.ContextStore.SetContext() for details.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	c := s.start(ctx, "SetContext")
+	var err error
+	if contextStore, ok := s.store.(gokv.ContextStore); ok {
+		err = contextStore.SetContext(c.ctx, k, v)
+	} else if err = c.ctx.Err(); err == nil {
+		err = s.store.Set(k, v)
+	}
+	c.stop(err)
+	c.o.Size = s.size(v, err)
+	s.report(c)
+	return err
+}
+
+// GetContext retrieves the value for the given key.
+// The context that's returned by the Tracer is passed to the wrapped store.
+// If the wrapped store doesn't implement gokv.ContextStore, the context is only checked before the value is retrieved.
+// See gokv.ContextStore.GetContext() for details.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	c := s.start(ctx, "GetContext")
+	if contextStore, ok := s.store.(gokv.ContextStore); ok {
+		found, err = contextStore.GetContext(c.ctx, k, v)
+	} else if err = c.ctx.Err(); err == nil {
+		found, err = s.store.Get(k, v)
+	}
+	c.stop(err)
+	c.found(found)
+	if found {
+		c.o.Size = s.size(v, err)
+	}
+	s.report(c)
+	return found, err
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context that's returned by the Tracer is passed to the wrapped store.
+// If the wrapped store doesn't implement gokv.ContextStore, the context is only checked before the value is deleted.
+// See gokv.ContextStore.DeleteContext() for details.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	c := s.start(ctx, "DeleteContext")
+	var err error
+	if contextStore, ok := s.store.(gokv.ContextStore); ok {
+		err = contextStore.DeleteContext(c.ctx, k)
+	} else if err = c.ctx.Err(); err == nil {
+		err = s.store.Delete(k)
+	}
+	c.stop(err)
+	s.report(c)
+	return err
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The reported duration includes the time that's spent in fn.
+// See gokv.Lister.Keys() for details.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	c := s.start(context.Background(), "Keys")
+	err := gokv.ErrUnsupported
+	if lister, ok := s.store.(gokv.Lister); ok {
+		err = lister.Keys(prefix, fn)
+	}
+	c.stop(err)
+	s.report(c)
+	return err
+}
+
+// Clear deletes all key-value pairs of the wrapped store.
+// See the package-level function gokv.Clear() for details.
+func (s Store) Clear() error {
+	c := s.start(context.Background(), "Clear")
+	err := gokv.Clear(s.store)
+	c.stop(err)
+	s.report(c)
+	return err
+}
+
+// Stats returns statistics about the wrapped store.
+// See the package-level function gokv.Stats() for details.
+func (s Store) Stats() (gokv.StoreStats, error) {
+	c := s.start(context.Background(), "Stats")
+	stats, err := gokv.Stats(s.store)
+	c.stop(err)
+	s.report(c)
+	return stats, err
+}
+
+// SetWithTTL stores the given value for the given key, with the given time to live.
+// See gokv.ExpiringStore.SetWithTTL() for details.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	c := s.start(context.Background(), "SetWithTTL")
+	err := gokv.ErrUnsupported
+	if expiringStore, ok := s.store.(gokv.ExpiringStore); ok {
+		err = expiringStore.SetWithTTL(k, v, ttl)
+	}
+	c.stop(err)
+	c.o.Size = s.size(v, err)
+	s.report(c)
+	return err
+}
+
+// SetMulti stores the given values for the given keys.
+// See the package-level function gokv.SetMulti() for details.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	c := s.start(context.Background(), "SetMulti")
+	err := gokv.SetMulti(s.store, keys, vs)
+	c.stop(err)
+	c.o.Size = s.sizes(vs, nil, err)
+	s.report(c)
+	return err
+}
+
+// GetMulti retrieves the values for the given keys.
+// See the package-level function gokv.GetMulti() for details.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	c := s.start(context.Background(), "GetMulti")
+	found, err = gokv.GetMulti(s.store, keys, vs)
+	c.stop(err)
+	for _, f := range found {
+		c.found(f)
+	}
+	c.o.Size = s.sizes(vs, found, err)
+	s.report(c)
+	return found, err
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// See the package-level function gokv.DeleteMulti() for details.
+func (s Store) DeleteMulti(keys []string) error {
+	c := s.start(context.Background(), "DeleteMulti")
+	err := gokv.DeleteMulti(s.store, keys)
+	c.stop(err)
+	s.report(c)
+	return err
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// See gokv.AtomicStore.SetIfAbsent() for details.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	c := s.start(context.Background(), "SetIfAbsent")
+	stored, err = gokv.SetIfAbsent(s.store, k, v)
+	c.stop(err)
+	if stored {
+		c.o.Size = s.size(v, err)
+	}
+	s.report(c)
+	return stored, err
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// See gokv.AtomicStore.CompareAndSwap() for details.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	c := s.start(context.Background(), "CompareAndSwap")
+	swapped, err = gokv.CompareAndSwap(s.store, k, old, new)
+	c.stop(err)
+	if swapped {
+		c.o.Size = s.size(new, err)
+	}
+	s.report(c)
+	return swapped, err
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// See gokv.Counter.Incr() for details.
+func (s Store) Incr(k string, delta int64) (int64, error) {
+	c := s.start(context.Background(), "Incr")
+	result, err := gokv.Incr(s.store, k, delta)
+	c.stop(err)
+	s.report(c)
+	return result, err
+}
+
+// Update calls fn with a new transaction of the wrapped store.
+// The transaction is reported as a whole, including the time that's spent in fn.
+// See gokv.Transactional.Update() for details.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	c := s.start(context.Background(), "Update")
+	err := gokv.Update(s.store, fn)
+	c.stop(err)
+	s.report(c)
+	return err
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// Only the start of the watch is reported, not the events.
+// See gokv.Watcher.Watch() for details.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	c := s.start(ctx, "Watch")
+	var events <-chan gokv.Event
+	err := gokv.ErrUnsupported
+	if watcher, ok := s.store.(gokv.Watcher); ok {
+		// The span only covers the start of the watch, so the original context is passed,
+		// which isn't canceled when the span ends.
+		events, err = watcher.Watch(ctx, prefix)
+	}
+	c.stop(err)
+	s.report(c)
+	return events, err
+}
+
+// call is an operation that's being reported.
+type call struct {
+	// ctx is the context that's returned by the Tracer, or the original one if there's no Tracer.
+	ctx   context.Context
+	span  Span
+	start time.Time
+	o     Observation
+}
+
+// start starts a span for the given operation and the time measurement.
+func (s Store) start(ctx context.Context, operation string) *call {
+	c := &call{
+		ctx: ctx,
+		o: Observation{
+			Backend:   s.backend,
+			Operation: operation,
+			Size:      -1,
+		},
+	}
+	if s.tracer != nil {
+		c.ctx, c.span = s.tracer.Start(ctx, "gokv."+operation)
+	}
+	c.start = time.Now()
+	return c
+}
+
+// stop stops the time measurement and records the error of the operation.
+// It must be called right after the operation, so that determining the sizes of the values isn't measured.
+func (c *call) stop(err error) {
+	c.o.Duration = time.Since(c.start)
+	c.o.Err = err
+}
+
+// found counts a hit or a miss, unless the operation failed.
+func (c *call) found(found bool) {
+	if c.o.Err != nil {
+		return
+	}
+	if found {
+		c.o.Hits++
+	} else {
+		c.o.Misses++
+	}
+}
+
+// report ends the span and passes the observation to the Observer.
+func (s Store) report(c *call) {
+	if c.span != nil {
+		c.span.SetAttribute(AttributeBackend, c.o.Backend)
+		c.span.SetAttribute(AttributeOperation, c.o.Operation)
+		if c.o.Size >= 0 {
+			c.span.SetAttribute(AttributeSize, c.o.Size)
+		}
+		if c.o.Hits > 0 || c.o.Misses > 0 {
+			c.span.SetAttribute(AttributeHits, c.o.Hits)
+			c.span.SetAttribute(AttributeMisses, c.o.Misses)
+		}
+		if c.o.Err != nil {
+			c.span.RecordError(c.o.Err)
+		}
+		c.span.End()
+	}
+	if s.observer != nil {
+		s.observer.Observe(c.o)
+	}
+}
+
+// size returns the size of the given value in its marshalled form,
+// or -1 if no Codec is configured or the operation failed.
+func (s Store) size(v interface{}, err error) int64 {
+	if s.codec == nil || err != nil {
+		return -1
+	}
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return -1
+	}
+	return int64(len(data))
+}
+
+// sizes returns the sum of the sizes of the given values, only counting the found ones if found isn't nil,
+// or -1 if no Codec is configured or the operation failed.
+func (s Store) sizes(vs []interface{}, found []bool, err error) int64 {
+	if s.codec == nil || err != nil {
+		return -1
+	}
+	var result int64
+	for i, v := range vs {
+		if found != nil && !found[i] {
+			continue
+		}
+		size := s.size(v, nil)
+		if size < 0 {
+			return -1
+		}
+		result += size
+	}
+	return result
+}
+
+// countingReader counts the bytes that are read from the wrapped reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// backendName returns the name of the package of the given store's type, for example "redis".
+func backendName(store gokv.Store) string {
+	t := reflect.TypeOf(store)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return path.Base(t.PkgPath())
+}
+
+// Options are the options for the instrumented store.
+type Options struct {
+	// The store to instrument.
+	Store gokv.Store
+	// Name of the backend, which is passed to the Observer and set as span attribute.
+	// Optional (the name of the store's package by default, for example "redis").
+	Backend string
+	// Observer that's notified about each finished operation.
+	// Optional (nil by default).
+	Observer Observer
+	// Tracer that starts a span for each operation.
+	// Optional (nil by default).
+	Tracer Tracer
+	// Codec that's used to determine the sizes of values that are passed to methods like Set and retrieved by methods like Get,
+	// by marshalling them an additional time. It should be the codec of the wrapped store.
+	// Marshalling costs time and memory, so only configure it when the sizes are needed.
+	// Optional (nil by default, which means that sizes are only reported for raw bytes).
+	Codec encoding.Codec
+}
+
+// DefaultOptions is an Options object with default values.
+// Backend: the store's package name, Observer: nil, Tracer: nil, Codec: nil
+var DefaultOptions = Options{
+	// No need to set any fields because their Go zero values are fine.
+}
+
+// NewStore creates a new instrumented store that wraps the store in the options.
+// Closing the returned store closes the wrapped store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	// Precondition check
+	if options.Store == nil {
+		return result, errors.New("The Store in the options must not be nil")
+	}
+
+	// Set default values
+	if options.Backend == "" {
+		options.Backend = backendName(options.Store)
+	}
+
+	result.store = options.Store
+	result.backend = options.Backend
+	result.observer = options.Observer
+	result.tracer = options.Tracer
+	result.codec = options.Codec
+
+	return result, nil
+}
@@ -0,0 +1,370 @@
+package instrument_test
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/instrument"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	store, _ := createStore(t, encoding.JSON)
+	defer store.Close()
+	test.TestStore(store, t)
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, encoding.JSON)
+		defer store.Close()
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, encoding.Gob)
+		defer store.Close()
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _ := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestOptionalInterfaces tests if the optional interfaces of the wrapped store work through the instrumented store.
+func TestOptionalInterfaces(t *testing.T) {
+	store, _ := createStore(t, encoding.JSON)
+	defer store.Close()
+	test.TestRawStore(store, t)
+	test.TestBatchStore(store, t)
+	test.TestLister(store, t)
+	test.TestClearer(store, t)
+	test.TestStatsStore(store, t)
+
+	// Only the interfaces that the wrapped store supports or that fall back to the basic methods are supported
+	basic, err := instrument.NewStore(instrument.Options{
+		Store: basicStore{gomap.NewStore(gomap.DefaultOptions)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gokv.Supports(store, (*gokv.Watcher)(nil)) {
+		t.Error("Expected the instrumented gomap.Store to support gokv.Watcher")
+	}
+	if gokv.Supports(basic, (*gokv.Watcher)(nil)) || gokv.Supports(basic, (*gokv.RawStore)(nil)) || gokv.Supports(basic, (*gokv.Clearer)(nil)) {
+		t.Error("Expected the instrumented basic store not to support gokv.Watcher, gokv.RawStore and gokv.Clearer")
+	}
+	if !gokv.Supports(basic, (*gokv.BatchStore)(nil)) {
+		t.Error("Expected the instrumented basic store to support gokv.BatchStore")
+	}
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test missing store
+	_, err := instrument.NewStore(instrument.DefaultOptions)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test empty key, which must be reported as error
+	store, observer := createStore(t, encoding.JSON)
+	defer store.Close()
+	err = store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	o := observer.last(t)
+	if o.Err != err {
+		t.Errorf("Expected the error %v to be reported, but was: %v", err, o.Err)
+	}
+	if o.Size != -1 {
+		t.Errorf("Expected the size to be unknown, but was: %v", o.Size)
+	}
+}
+
+// TestObservations tests if the operations are reported with their backend, sizes and hits/misses.
+func TestObservations(t *testing.T) {
+	store, observer := createStore(t, encoding.JSON)
+	defer store.Close()
+
+	err := store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := observer.last(t)
+	expectedSize := int64(len(`{"Bar":"baz"}`))
+	if o.Backend != "gomap" || o.Operation != "Set" || o.Err != nil || o.Size != expectedSize || o.Hits != 0 || o.Misses != 0 {
+		t.Errorf("Unexpected observation: %+v", o)
+	}
+
+	_, err = store.Get("foo", new(test.Foo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = observer.last(t)
+	if o.Operation != "Get" || o.Size != expectedSize || o.Hits != 1 || o.Misses != 0 {
+		t.Errorf("Unexpected observation: %+v", o)
+	}
+
+	_, err = store.Get("qux", new(test.Foo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = observer.last(t)
+	if o.Operation != "Get" || o.Size != -1 || o.Hits != 0 || o.Misses != 1 {
+		t.Errorf("Unexpected observation: %+v", o)
+	}
+
+	_, err = store.GetMulti([]string{"foo", "qux"}, []interface{}{new(test.Foo), new(test.Foo)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = observer.last(t)
+	if o.Operation != "GetMulti" || o.Size != expectedSize || o.Hits != 1 || o.Misses != 1 {
+		t.Errorf("Unexpected observation: %+v", o)
+	}
+
+	err = store.SetBytes("raw", []byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = observer.last(t)
+	if o.Operation != "SetBytes" || o.Size != 3 {
+		t.Errorf("Unexpected observation: %+v", o)
+	}
+
+	// Without a Codec the sizes of values are unknown, and the backend can be set explicitly
+	options := instrument.Options{
+		Store:    gomap.NewStore(gomap.DefaultOptions),
+		Backend:  "cache",
+		Observer: observer,
+	}
+	store, err = instrument.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o = observer.last(t)
+	if o.Backend != "cache" || o.Size != -1 {
+		t.Errorf("Unexpected observation: %+v", o)
+	}
+}
+
+// TestTracer tests if a span is started and ended for each operation,
+// and if the context that contains the span is passed to the wrapped store.
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	options := instrument.Options{
+		Store:  gomap.NewStore(gomap.DefaultOptions),
+		Tracer: tracer,
+	}
+	store, err := instrument.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	_, err = store.GetContext(context.Background(), "foo", new(test.Foo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The context of the span must be passed to the wrapped store
+	tracer.err = errors.New("span error")
+	err = store.DeleteContext(context.Background(), "foo")
+	if err != tracer.err {
+		t.Errorf("Expected the error of the span's context, but was: %v", err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("Expected 2 spans, but was: %v", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "gokv.GetContext" || !span.ended || span.err != nil {
+		t.Errorf("Unexpected span: %+v", span)
+	}
+	if span.attributes[instrument.AttributeBackend] != "gomap" || span.attributes[instrument.AttributeOperation] != "GetContext" {
+		t.Errorf("Unexpected attributes: %v", span.attributes)
+	}
+	if span.attributes[instrument.AttributeHits] != 0 || span.attributes[instrument.AttributeMisses] != 1 {
+		t.Errorf("Unexpected attributes: %v", span.attributes)
+	}
+	if _, ok := span.attributes[instrument.AttributeSize]; ok {
+		t.Errorf("Expected no size attribute, but was: %v", span.attributes[instrument.AttributeSize])
+	}
+	span = tracer.spans[1]
+	if span.name != "gokv.DeleteContext" || !span.ended || span.err != tracer.err {
+		t.Errorf("Unexpected span: %+v", span)
+	}
+}
+
+// TestExpvarObserver tests if the ExpvarObserver publishes the observations.
+func TestExpvarObserver(t *testing.T) {
+	observer := instrument.NewExpvarObserver("gokv_test")
+	// The map is reused when the test is run multiple times
+	m := expvar.Get("gokv_test").(*expvar.Map)
+	m.Init()
+	options := instrument.Options{
+		Store:    gomap.NewStore(gomap.DefaultOptions),
+		Observer: observer,
+		Codec:    encoding.JSON,
+	}
+	store, err := instrument.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err = store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"foo", "qux", "baz"} {
+		_, err = store.Get(k, new(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	expected := map[string]string{
+		"gomap.Set.calls":     "1",
+		"gomap.Set.bytes":     "5",
+		"gomap.Get.calls":     "3",
+		"gomap.Get.hits":      "1",
+		"gomap.Get.misses":    "2",
+		"gomap.Get.bytes":     "5",
+		"gomap.Delete.calls":  "1",
+		"gomap.Delete.errors": "1",
+	}
+	for k, v := range expected {
+		actual := m.Get(k)
+		if actual == nil {
+			t.Errorf("Expected a variable for %v", k)
+		} else if actual.String() != v {
+			t.Errorf("Expected %v for %v, but was: %v", v, k, actual)
+		}
+	}
+	if m.Get("gomap.Set.errors") != nil {
+		t.Error("Expected no variable for gomap.Set.errors")
+	}
+	if !strings.Contains(m.String(), "gomap.Get.duration_ns") {
+		t.Error("Expected a variable for gomap.Get.duration_ns")
+	}
+}
+
+func createStore(t *testing.T, codec encoding.Codec) (instrument.Store, *testObserver) {
+	observer := &testObserver{}
+	options := instrument.Options{
+		Store: gomap.NewStore(gomap.Options{
+			Codec: codec,
+		}),
+		Observer: observer,
+		Codec:    codec,
+	}
+	store, err := instrument.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, observer
+}
+
+// testObserver records all observations.
+type testObserver struct {
+	lock         sync.Mutex
+	observations []instrument.Observation
+}
+
+func (o *testObserver) Observe(observation instrument.Observation) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.observations = append(o.observations, observation)
+}
+
+func (o *testObserver) last(t *testing.T) instrument.Observation {
+	t.Helper()
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if len(o.observations) == 0 {
+		t.Fatal("Expected an observation")
+	}
+	return o.observations[len(o.observations)-1]
+}
+
+// testTracer records all spans.
+// If err is set, the contexts of the spans are canceled with it.
+type testTracer struct {
+	spans []*testSpan
+	err   error
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, instrument.Span) {
+	span := &testSpan{
+		name:       name,
+		attributes: make(map[string]interface{}),
+	}
+	t.spans = append(t.spans, span)
+	if t.err != nil {
+		return errorContext{ctx, t.err}, span
+	}
+	return ctx, span
+}
+
+type testSpan struct {
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.attributes[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.err = err
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+// errorContext is a context whose Err method returns the given error.
+type errorContext struct {
+	context.Context
+	err error
+}
+
+func (c errorContext) Err() error {
+	return c.err
+}
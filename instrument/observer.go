@@ -0,0 +1,95 @@
+package instrument
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Observation describes a finished operation of an instrumented store.
+type Observation struct {
+	// Name of the backend, for example "redis", which can be used as label.
+	Backend string
+	// Name of the operation, which is the name of the called method, for example "Get" or "SetMulti".
+	Operation string
+	// Time the operation took.
+	Duration time.Duration
+	// Error that the operation returned, nil if it succeeded.
+	Err error
+	// Number of bytes of the written or read values in their marshalled form.
+	// -1 if it's unknown, for example because no Codec is configured or the operation doesn't involve a value.
+	Size int64
+	// Number of values that were found by a retrieving operation.
+	Hits int
+	// Number of values that weren't found by a retrieving operation.
+	Misses int
+}
+
+// Observer is notified about each finished operation of an instrumented store.
+// Observe is called synchronously after the operation, so it should return quickly,
+// for example by only updating counters.
+// It must be safe for concurrent use.
+type Observer interface {
+	Observe(o Observation)
+}
+
+// ObserverFunc is an adapter that allows ordinary functions to be used as Observer.
+type ObserverFunc func(o Observation)
+
+// Observe calls f(o).
+func (f ObserverFunc) Observe(o Observation) {
+	f(o)
+}
+
+// ExpvarObserver is an Observer that publishes the observations as expvar variables,
+// which are available at /debug/vars when the expvar package's handler is registered.
+// All variables are contained in one map, with the keys being "<backend>.<operation>.<metric>",
+// for example "redis.Get.hits". The metrics are "calls", "errors", "hits", "misses",
+// "bytes" (the sum of the known sizes) and "duration_ns" (the sum of the durations in nanoseconds).
+type ExpvarObserver struct {
+	m *expvar.Map
+}
+
+// expvarLock prevents that two ExpvarObservers with the same name publish a map concurrently.
+var expvarLock sync.Mutex
+
+// NewExpvarObserver creates a new ExpvarObserver that publishes its map with the given name.
+// If a map with the given name was already published, for example by another ExpvarObserver, it's used,
+// so that multiple instrumented stores can share one map with a distinct backend name each.
+// If a variable with the given name that's not a map was published, it panics.
+func NewExpvarObserver(name string) ExpvarObserver {
+	expvarLock.Lock()
+	defer expvarLock.Unlock()
+	v := expvar.Get(name)
+	if v == nil {
+		return ExpvarObserver{
+			m: expvar.NewMap(name),
+		}
+	}
+	m, ok := v.(*expvar.Map)
+	if !ok {
+		panic("instrument: The expvar variable " + name + " isn't a map")
+	}
+	return ExpvarObserver{
+		m: m,
+	}
+}
+
+// Observe adds the observation to the expvar variables.
+func (e ExpvarObserver) Observe(o Observation) {
+	prefix := o.Backend + "." + o.Operation + "."
+	e.m.Add(prefix+"calls", 1)
+	if o.Err != nil {
+		e.m.Add(prefix+"errors", 1)
+	}
+	if o.Hits > 0 {
+		e.m.Add(prefix+"hits", int64(o.Hits))
+	}
+	if o.Misses > 0 {
+		e.m.Add(prefix+"misses", int64(o.Misses))
+	}
+	if o.Size > 0 {
+		e.m.Add(prefix+"bytes", o.Size)
+	}
+	e.m.Add(prefix+"duration_ns", int64(o.Duration))
+}
@@ -0,0 +1,38 @@
+package instrument
+
+import (
+	"context"
+)
+
+// Tracer starts spans for the operations of an instrumented store.
+// Its methods are modeled after the ones of OpenTelemetry, so that an OpenTelemetry tracer
+// (or one of another tracing library) can be used with a small adapter.
+type Tracer interface {
+	// Start starts a span with the given name, for example "gokv.Get",
+	// and returns a context that contains the span.
+	// For the context-aware methods of the store the returned context is passed to the underlying store,
+	// so spans that it starts are children of this span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span that was started by a Tracer.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	// The attributes are "gokv.backend", "gokv.operation" and,
+	// if they're known or relevant for the operation, "gokv.size", "gokv.hits" and "gokv.misses".
+	SetAttribute(key string, value interface{})
+	// RecordError records the error that the operation returned.
+	// It's only called when the operation failed.
+	RecordError(err error)
+	// End ends the span.
+	End()
+}
+
+// Attribute keys that are set on the spans.
+const (
+	AttributeBackend   = "gokv.backend"
+	AttributeOperation = "gokv.operation"
+	AttributeSize      = "gokv.size"
+	AttributeHits      = "gokv.hits"
+	AttributeMisses    = "gokv.misses"
+)
@@ -1,4 +1,8 @@
 /*
 Package leveldb contains an implementation of the `gokv.Store` interface for LevelDB.
+
+The package registers itself for the URL scheme "leveldb", so a store can also be created with gokv.Open,
+for example with "leveldb:///tmp/gokv?writeSync=true&codec=gob" (absolute path) or "leveldb://leveldb" (relative path).
+The path is used as Path and the query parameters "writeSync" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package leveldb
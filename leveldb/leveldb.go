@@ -1,9 +1,14 @@
 package leveldb
 
 import (
+	"context"
+	"net/url"
+
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	lutil "github.com/syndtr/goleveldb/leveldb/util"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -29,13 +34,7 @@ func (s Store) Set(k string, v interface{}) error {
 		return err
 	}
 
-	var writeOptions *opt.WriteOptions
-	if s.writeSync {
-		writeOptions = &opt.WriteOptions{
-			Sync: true,
-		}
-	}
-	return s.db.Put([]byte(k), data, writeOptions)
+	return s.set(k, data)
 }
 
 // Get retrieves the stored value for the given key.
@@ -54,7 +53,7 @@ func (s Store) Get(k string, v interface{}) (found bool, err error) {
 		if err == leveldb.ErrNotFound {
 			return false, nil
 		}
-		return false, err
+		return false, wrapError(err)
 	}
 
 	return true, s.codec.Unmarshal(data, v)
@@ -74,7 +73,192 @@ func (s Store) Delete(k string) error {
 			Sync: true,
 		}
 	}
-	return s.db.Delete([]byte(k), writeOptions)
+	return wrapError(s.db.Delete([]byte(k), writeOptions))
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return s.set(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err := s.db.Get([]byte(k), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, wrapError(err)
+	}
+
+	return data, true, nil
+}
+
+// set stores the given data for the given key.
+func (s Store) set(k string, data []byte) error {
+	var writeOptions *opt.WriteOptions
+	if s.writeSync {
+		writeOptions = &opt.WriteOptions{
+			Sync: true,
+		}
+	}
+	return wrapError(s.db.Put([]byte(k), data, writeOptions))
+}
+
+// SetContext stores the given value for the given key.
+// goleveldb doesn't support contexts, so the context is only checked before accessing the DB.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in byte-sorted order.
+// The iteration works on a snapshot of the DB, so fn can modify the store.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	iter := s.db.NewIterator(lutil.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := fn(string(iter.Key())); err != nil {
+			return err
+		}
+	}
+	return wrapError(iter.Error())
+}
+
+// SetMulti stores the given values for the given keys with a single atomic batch write.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for i, k := range keys {
+		data, err := s.codec.Marshal(vs[i])
+		if err != nil {
+			return err
+		}
+		batch.Put([]byte(k), data)
+	}
+
+	var writeOptions *opt.WriteOptions
+	if s.writeSync {
+		writeOptions = &opt.WriteOptions{
+			Sync: true,
+		}
+	}
+	return wrapError(s.db.Write(batch, writeOptions))
+}
+
+// GetMulti retrieves the values for the given keys from a single snapshot of the DB.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer snapshot.Release()
+
+	found = make([]bool, len(keys))
+	for i, k := range keys {
+		data, err := snapshot.Get([]byte(k), nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, wrapError(err)
+		}
+		found[i] = true
+		if err := s.codec.Unmarshal(data, vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys with a single atomic batch write.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for _, k := range keys {
+		batch.Delete([]byte(k))
+	}
+
+	var writeOptions *opt.WriteOptions
+	if s.writeSync {
+		writeOptions = &opt.WriteOptions{
+			Sync: true,
+		}
+	}
+	return wrapError(s.db.Write(batch, writeOptions))
+}
+
+// Update calls fn with a new transaction, which is based on a LevelDB transaction.
+// The transaction is committed if fn returns nil, and discarded otherwise.
+// LevelDB blocks all other writes while a transaction is open, so transactions never conflict,
+// but fn must not call the store's methods for writing, as that would lead to a deadlock.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	tr, err := s.db.OpenTransaction()
+	if err != nil {
+		return wrapError(err)
+	}
+	if err := fn(transaction{s: s, tr: tr}); err != nil {
+		tr.Discard()
+		return err
+	}
+	return wrapError(tr.Commit())
+}
+
+// wrapError wraps LevelDB errors into gokv's errors where possible.
+func wrapError(err error) error {
+	if err == leveldb.ErrClosed {
+		return util.WrapError(gokv.ErrClosed, err)
+	}
+	return err
 }
 
 // Close closes the store.
@@ -134,3 +318,73 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("leveldb", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Path: util.URLPath(u),
+	}
+	q := util.NewURLQuery(u)
+	q.Bool("writeSync", &options.WriteSync)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// transaction is a gokv.Tx for LevelDB.
+type transaction struct {
+	s  Store
+	tr *leveldb.Transaction
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return wrapError(t.tr.Put([]byte(k), data, nil))
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := t.tr.Get([]byte(k), nil)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, wrapError(err)
+	}
+	return true, t.s.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	return wrapError(t.tr.Delete([]byte(k), nil))
+}
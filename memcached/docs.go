@@ -1,4 +1,9 @@
 /*
 Package memcached contains an implementation of the `gokv.Store` interface for Memcached.
+
+The package registers itself for the URL scheme "memcached", so a client can also be created with gokv.Open,
+for example with "memcached://host1:11211,host2:11211?timeout=500ms&maxIdleConns=50&codec=gob".
+The comma-separated hosts are used as Addresses and the query parameters "timeout", "maxIdleConns" and "codec" ("json" or "gob")
+correspond to the fields of Options.
 */
 package memcached
@@ -1,16 +1,30 @@
 package memcached
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 var defaultTimeout = 200 * time.Millisecond
 
+// maxRelativeExpiration is the maximum expiration that Memcached interprets as relative to the current time.
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+// maxKeyLength is the maximum length of a key in bytes that Memcached allows.
+const maxKeyLength = 250
+
 // Client is a gokv.Store implementation for Memcached.
 type Client struct {
 	c     *memcache.Client
@@ -22,9 +36,21 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	return c.SetWithTTL(k, v, 0)
+}
+
+// SetWithTTL stores the given value for the given key, with Memcached's native expiration.
+// Memcached only supports TTLs in full seconds, so the TTL is rounded up.
+// A TTL of 0 means that the key-value pair doesn't expire (but can still be evicted).
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
 
 	// First turn the passed object into something that Memcached can handle
 	data, err := c.codec.Marshal(v)
@@ -32,13 +58,21 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
+	// Memcached interprets expiration values of more than 30 days as Unix timestamp.
+	var expiration int32
+	if ttl > maxRelativeExpiration {
+		expiration = int32(time.Now().Add(ttl).Unix())
+	} else {
+		expiration = int32((ttl + time.Second - 1) / time.Second)
+	}
 	item := memcache.Item{
-		Key:   k,
-		Value: data,
+		Key:        k,
+		Value:      data,
+		Expiration: expiration,
 	}
 	err = c.c.Set(&item)
 	if err != nil {
-		return err
+		return wrapError(k, err)
 	}
 
 	return nil
@@ -61,7 +95,7 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 	if err == memcache.ErrCacheMiss {
 		return false, nil
 	} else if err != nil {
-		return false, err
+		return false, wrapError(k, err)
 	}
 	data := item.Value
 
@@ -81,6 +115,282 @@ func (c Client) Delete(k string) error {
 	if err == memcache.ErrCacheMiss {
 		return nil
 	}
+	return wrapError(k, err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// Like with Set, a previously set TTL is removed.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	item := memcache.Item{
+		Key:   k,
+		Value: v,
+	}
+	return wrapError(k, c.c.Set(&item))
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	item, err := c.c.Get(k)
+	// If no value was found return false
+	if err == memcache.ErrCacheMiss {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, wrapError(k, err)
+	}
+	return item.Value, true, nil
+}
+
+// SetContext stores the given value for the given key.
+// The gomemcache package doesn't support contexts, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
+}
+
+// SetMulti stores the given values for the given keys.
+// Memcached doesn't have a command for storing multiple values at once,
+// so they're stored one after another, stopping at the first error.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	for i, k := range keys {
+		if err := c.Set(k, vs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys with a single request per Memcached server.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (c Client) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return []bool{}, nil
+	}
+
+	items, err := c.c.GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+	found = make([]bool, len(keys))
+	for i, k := range keys {
+		item, ok := items[k]
+		if !ok {
+			continue
+		}
+		found[i] = true
+		if err := c.codec.Unmarshal(item.Value, vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// Memcached doesn't have a command for deleting multiple values at once,
+// so they're deleted one after another, stopping at the first error.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := c.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetIfAbsent stores the given value for the given key with Memcached's "add" command,
+// which only stores the value if no value is stored for the key yet.
+// It returns true if the value was stored.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	item := memcache.Item{
+		Key:   k,
+		Value: data,
+	}
+	err = c.c.Add(&item)
+	if err == memcache.ErrNotStored {
+		return false, nil
+	} else if err != nil {
+		return false, wrapError(k, err)
+	}
+	return true, nil
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The stored value is retrieved and compared with the old value,
+// then the new value is stored with Memcached's "cas" command,
+// which only succeeds if the key wasn't modified in the meantime.
+// Otherwise the comparison is repeated.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		item, err := c.c.Get(k)
+		if err == memcache.ErrCacheMiss {
+			return false, nil
+		} else if err != nil {
+			return false, wrapError(k, err)
+		}
+		if !bytes.Equal(item.Value, oldData) {
+			return false, nil
+		}
+		// The item contains the CAS ID from the Get call
+		item.Value = newData
+		item.Expiration = 0
+		err = c.c.CompareAndSwap(item)
+		if err == nil {
+			return true, nil
+		} else if err == memcache.ErrNotStored {
+			// The key-value pair was deleted or expired in the meantime
+			return false, nil
+		} else if err != memcache.ErrCASConflict {
+			return false, wrapError(k, err)
+		}
+	}
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value,
+// using Memcached's "incr" and "decr" commands.
+// Memcached stores the value as decimal number, so Incr requires encoding.JSON as codec,
+// otherwise an error matching gokv.ErrUnsupported is returned.
+// Memcached only supports unsigned values, so decrementing stops at 0,
+// and values that were stored as negative numbers with Set can't be incremented.
+// If no value is stored for the key (or it's expired), the new value is delta (or 0 if delta is negative).
+// A TTL that was set with SetWithTTL is kept.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "".
+func (c Client) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+	if err := util.CheckCounterCodec(c.codec); err != nil {
+		return 0, err
+	}
+
+	for {
+		var value uint64
+		var err error
+		if delta >= 0 {
+			value, err = c.c.Increment(k, uint64(delta))
+		} else {
+			value, err = c.c.Decrement(k, uint64(-delta))
+		}
+		if err == nil {
+			if value > math.MaxInt64 {
+				return 0, errors.New("The incremented value overflows int64")
+			}
+			return int64(value), nil
+		} else if err != memcache.ErrCacheMiss {
+			return 0, wrapError(k, err)
+		}
+		// "incr" and "decr" don't create missing values, so the initial value is added,
+		// unless another client added a value in the meantime.
+		initial := delta
+		if initial < 0 {
+			initial = 0
+		}
+		item := memcache.Item{
+			Key:   k,
+			Value: []byte(strconv.FormatInt(initial, 10)),
+		}
+		err = c.c.Add(&item)
+		if err == nil {
+			return initial, nil
+		} else if err != memcache.ErrNotStored {
+			return 0, wrapError(k, err)
+		}
+	}
+}
+
+// wrapError wraps errors of the Memcached client into gokv's errors where possible.
+// The client returns the same error for keys that are too long and keys with invalid characters,
+// so the key's length is checked.
+// Memcached responds with a server error when a value exceeds its item size limit (1 MB by default),
+// which the client only returns as message.
+func wrapError(k string, err error) error {
+	if err == memcache.ErrMalformedKey && len(k) > maxKeyLength {
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	} else if err != nil && strings.Contains(err.Error(), "object too large") {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
 	return err
 }
 
@@ -149,3 +459,30 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("memcached", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Addresses: util.URLHosts(u),
+	}
+	q := util.NewURLQuery(u)
+	var timeout time.Duration
+	if q.Duration("timeout", &timeout) {
+		options.Timeout = &timeout
+	}
+	q.Int("maxIdleConns", &options.MaxIdleConns)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
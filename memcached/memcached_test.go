@@ -204,6 +204,90 @@ func TestDefaultTimeout(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to Memcached works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Memcached could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+//
+// Note: This test is only executed if the initial connection to Memcached works.
+func TestTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Memcached could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestExpiringStore(client, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+//
+// Note: This test is only executed if the initial connection to Memcached works.
+func TestBatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Memcached could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestBatchStore(client, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+//
+// Note: This test is only executed if the initial connection to Memcached works.
+func TestAtomic(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Memcached could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestAtomicStore(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to Memcached works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Memcached could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+//
+// Note: This test is only executed if the initial connection to Memcached works.
+func TestCounter(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Memcached could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestCounter(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	mc := memcache.New("localhost:11211")
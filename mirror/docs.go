@@ -0,0 +1,28 @@
+/*
+Package mirror contains a gokv.Store implementation that mirrors all changes from a primary store to a secondary store,
+for example for migrating from one implementation to another (like from mongodb to postgresql) without downtime.
+
+Values are written to the primary and then to the secondary store (dual-write), and read from the primary store,
+optionally falling back to the secondary store when they're not found.
+Differences between the stores, like failed writes to the secondary store, are reported to a callback.
+
+	oldStore, err := mongodb.NewClient(mongodb.DefaultOptions)
+	...
+	newStore, err := postgresql.NewClient(postgresql.DefaultOptions)
+	...
+	options := mirror.DefaultOptions
+	options.Primary = oldStore
+	options.Secondary = newStore
+	options.OnDivergence = func(d mirror.Divergence) {
+		log.Printf("%v for key %q in %v: %v", d.Kind, d.Key, d.Operation, d.Err)
+	}
+	store, err := mirror.NewStore(options)
+	...
+	// When all instances mirror their changes, copy the existing key-value pairs.
+	copied, err := store.Backfill(ctx)
+
+A migration without downtime then consists of the following steps:
+Mirror all changes to the new store, backfill the existing key-value pairs,
+switch the stores (optionally with FallbackOnMiss and CompareReads to verify the new store) and finally remove the old store.
+*/
+package mirror
@@ -0,0 +1,654 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/philippgille/gokv"
+)
+
+// numLocks is the number of locks that the keys are distributed over.
+const numLocks = 64
+
+// DivergenceKind is the kind of a divergence between the primary and the secondary store.
+type DivergenceKind int
+
+const (
+	// SecondaryWriteFailed means that a change was made in the primary store, but failed in the secondary store.
+	SecondaryWriteFailed DivergenceKind = iota
+	// SecondaryReadFailed means that a value couldn't be read from the secondary store for a comparison or as fallback.
+	SecondaryReadFailed
+	// MissingInPrimary means that a value was found in the secondary store, but not in the primary store.
+	MissingInPrimary
+	// MissingInSecondary means that a value was found in the primary store, but not in the secondary store.
+	MissingInSecondary
+	// ValueMismatch means that different values were found in the primary and the secondary store.
+	ValueMismatch
+)
+
+func (k DivergenceKind) String() string {
+	switch k {
+	case SecondaryWriteFailed:
+		return "SecondaryWriteFailed"
+	case SecondaryReadFailed:
+		return "SecondaryReadFailed"
+	case MissingInPrimary:
+		return "MissingInPrimary"
+	case MissingInSecondary:
+		return "MissingInSecondary"
+	case ValueMismatch:
+		return "ValueMismatch"
+	default:
+		return fmt.Sprintf("DivergenceKind(%d)", int(k))
+	}
+}
+
+// Divergence describes a difference between the primary and the secondary store that was noticed by a Store.
+type Divergence struct {
+	Kind DivergenceKind
+	// Name of the operation that noticed the divergence, which is the name of the called method, for example "Set".
+	Operation string
+	// Key of the diverged key-value pair, or "" if the operation affected all key-value pairs (like Clear).
+	Key string
+	// Error of the secondary store, for SecondaryWriteFailed and SecondaryReadFailed.
+	Err error
+}
+
+// Store is a gokv.Store that mirrors all changes from a primary store to a secondary store,
+// for example for migrating from one implementation to another without downtime.
+// Values are read from the primary store, which is the source of truth.
+// Errors of the primary store are returned, while errors of the secondary store are reported as Divergence,
+// so the secondary store doesn't affect the availability of the store.
+//
+// Concurrent changes of the same key are applied to both stores in the same order,
+// as long as all changes are made through the same Store (or copies of it).
+// Changes that are made by other instances or directly to one of the stores can lead to divergences,
+// which is why migrations should be finished with a Backfill when all instances mirror their changes.
+//
+// Store implements the optional interfaces of the gokv package whose changes can be mirrored,
+// which excludes gokv.StreamStore and gokv.Transactional.
+// If the primary store doesn't implement one of them, the corresponding methods
+// fall back to the basic methods or return gokv.ErrUnsupported, so gokv.Supports() reports the primary store's capabilities.
+// If only the secondary store doesn't implement one, the change is reported as SecondaryWriteFailed.
+type Store struct {
+	primary        gokv.Store
+	secondary      gokv.Store
+	fallbackOnMiss bool
+	compareReads   bool
+	onDivergence   func(d Divergence)
+	locks          *[numLocks]sync.Mutex
+}
+
+// Set stores the given value for the given key in the primary and then in the secondary store.
+// See gokv.Store.Set() for details.
+func (s Store) Set(k string, v interface{}) error {
+	return s.write("Set", []string{k}, func() error {
+		return s.primary.Set(k, v)
+	}, func() error {
+		return s.secondary.Set(k, v)
+	})
+}
+
+// Get retrieves the value for the given key from the primary store.
+// Depending on the options, it's retrieved from the secondary store when it's not found
+// or to compare it with the value of the primary store.
+// See gokv.Store.Get() for details.
+func (s Store) Get(k string, v interface{}) (found bool, err error) {
+	return s.read("Get", k, v, func(store gokv.Store, v interface{}) (bool, error) {
+		return store.Get(k, v)
+	})
+}
+
+// Delete deletes the stored value for the given key from the primary and then from the secondary store.
+// See gokv.Store.Delete() for details.
+func (s Store) Delete(k string) error {
+	return s.write("Delete", []string{k}, func() error {
+		return s.primary.Delete(k)
+	}, func() error {
+		return s.secondary.Delete(k)
+	})
+}
+
+// Close closes the primary and the secondary store.
+func (s Store) Close() error {
+	primaryErr := s.primary.Close()
+	if err := s.secondary.Close(); err != nil && primaryErr == nil {
+		return err
+	}
+	return primaryErr
+}
+
+// Supports reports whether the store supports the optional interface that iface points to,
+// which depends on the primary store, except for gokv.BatchStore and gokv.ContextStore, which are always supported.
+// See the package-level function gokv.Supports() for details.
+func (s Store) Supports(iface interface{}) bool {
+	switch iface.(type) {
+	case *gokv.BatchStore, *gokv.ContextStore:
+		return true
+	case *gokv.Clearer, *gokv.StatsStore:
+		return gokv.Supports(s.primary, iface) || gokv.Supports(s.primary, (*gokv.Lister)(nil))
+	}
+	return gokv.Supports(s.primary, iface)
+}
+
+// SetBytes stores the given bytes for the given key in the primary and then in the secondary store.
+// See gokv.RawStore.SetBytes() for details.
+func (s Store) SetBytes(k string, v []byte) error {
+	rawStore, ok := s.primary.(gokv.RawStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return s.write("SetBytes", []string{k}, func() error {
+		return rawStore.SetBytes(k, v)
+	}, func() error {
+		secondary, ok := s.secondary.(gokv.RawStore)
+		if !ok {
+			return gokv.ErrUnsupported
+		}
+		return secondary.SetBytes(k, v)
+	})
+}
+
+// GetBytes retrieves the stored bytes for the given key from the primary store,
+// and depending on the options from the secondary store like Get.
+// See gokv.RawStore.GetBytes() for details.
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if _, ok := s.primary.(gokv.RawStore); !ok {
+		return nil, false, gokv.ErrUnsupported
+	}
+	found, err = s.read("GetBytes", k, &v, func(store gokv.Store, v interface{}) (bool, error) {
+		rawStore, ok := store.(gokv.RawStore)
+		if !ok {
+			return false, gokv.ErrUnsupported
+		}
+		data, found, err := rawStore.GetBytes(k)
+		*v.(*[]byte) = data
+		return found, err
+	})
+	return v, found, err
+}
+
+// SetContext stores the given value for the given key in the primary and then in the secondary store.
+// If a store doesn't implement gokv.ContextStore, the context is only checked before the value is stored.
+// See gokv.ContextStore.SetContext() for details.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	return s.write("SetContext", []string{k}, func() error {
+		return setContext(ctx, s.primary, k, v)
+	}, func() error {
+		return setContext(ctx, s.secondary, k, v)
+	})
+}
+
+// GetContext retrieves the value for the given key from the primary store,
+// and depending on the options from the secondary store like Get.
+// If a store doesn't implement gokv.ContextStore, the context is only checked before the value is retrieved.
+// See gokv.ContextStore.GetContext() for details.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	return s.read("GetContext", k, v, func(store gokv.Store, v interface{}) (bool, error) {
+		if contextStore, ok := store.(gokv.ContextStore); ok {
+			return contextStore.GetContext(ctx, k, v)
+		}
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return store.Get(k, v)
+	})
+}
+
+// DeleteContext deletes the stored value for the given key from the primary and then from the secondary store.
+// If a store doesn't implement gokv.ContextStore, the context is only checked before the value is deleted.
+// See gokv.ContextStore.DeleteContext() for details.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	return s.write("DeleteContext", []string{k}, func() error {
+		return deleteContext(ctx, s.primary, k)
+	}, func() error {
+		return deleteContext(ctx, s.secondary, k)
+	})
+}
+
+// Keys calls fn for each key of the primary store that starts with the given prefix.
+// See gokv.Lister.Keys() for details.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	lister, ok := s.primary.(gokv.Lister)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return lister.Keys(prefix, fn)
+}
+
+// Clear deletes all key-value pairs of the primary and then of the secondary store.
+// See the package-level function gokv.Clear() for details.
+func (s Store) Clear() error {
+	return s.write("Clear", nil, func() error {
+		return gokv.Clear(s.primary)
+	}, func() error {
+		return gokv.Clear(s.secondary)
+	})
+}
+
+// Stats returns statistics about the primary store.
+// See the package-level function gokv.Stats() for details.
+func (s Store) Stats() (gokv.StoreStats, error) {
+	return gokv.Stats(s.primary)
+}
+
+// SetWithTTL stores the given value for the given key, with the given time to live,
+// in the primary and then in the secondary store.
+// See gokv.ExpiringStore.SetWithTTL() for details.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	expiringStore, ok := s.primary.(gokv.ExpiringStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return s.write("SetWithTTL", []string{k}, func() error {
+		return expiringStore.SetWithTTL(k, v, ttl)
+	}, func() error {
+		secondary, ok := s.secondary.(gokv.ExpiringStore)
+		if !ok {
+			return gokv.ErrUnsupported
+		}
+		return secondary.SetWithTTL(k, v, ttl)
+	})
+}
+
+// SetMulti stores the given values for the given keys in the primary and then in the secondary store.
+// See the package-level function gokv.SetMulti() for details.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	return s.write("SetMulti", keys, func() error {
+		return gokv.SetMulti(s.primary, keys, vs)
+	}, func() error {
+		return gokv.SetMulti(s.secondary, keys, vs)
+	})
+}
+
+// GetMulti retrieves the values for the given keys from the primary store.
+// If FallbackOnMiss is set, the values that aren't found are retrieved from the secondary store.
+// Values aren't compared with the ones of the secondary store, even if CompareReads is set.
+// See the package-level function gokv.GetMulti() for details.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	found, err = gokv.GetMulti(s.primary, keys, vs)
+	if err != nil || !s.fallbackOnMiss {
+		return found, err
+	}
+
+	var missingKeys []string
+	var missingVs []interface{}
+	var missingIndexes []int
+	for i, f := range found {
+		if !f {
+			missingKeys = append(missingKeys, keys[i])
+			missingVs = append(missingVs, vs[i])
+			missingIndexes = append(missingIndexes, i)
+		}
+	}
+	if len(missingKeys) == 0 {
+		return found, nil
+	}
+	secondaryFound, err := gokv.GetMulti(s.secondary, missingKeys, missingVs)
+	if err != nil {
+		for _, k := range missingKeys {
+			s.report(Divergence{Kind: SecondaryReadFailed, Operation: "GetMulti", Key: k, Err: err})
+		}
+		return found, nil
+	}
+	for i, f := range secondaryFound {
+		if f {
+			found[missingIndexes[i]] = true
+			s.report(Divergence{Kind: MissingInPrimary, Operation: "GetMulti", Key: missingKeys[i]})
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys from the primary and then from the secondary store.
+// See the package-level function gokv.DeleteMulti() for details.
+func (s Store) DeleteMulti(keys []string) error {
+	return s.write("DeleteMulti", keys, func() error {
+		return gokv.DeleteMulti(s.primary, keys)
+	}, func() error {
+		return gokv.DeleteMulti(s.secondary, keys)
+	})
+}
+
+// SetIfAbsent stores the given value for the given key in the primary store,
+// but only if no value is stored for the key yet, and if it was stored, also in the secondary store.
+// See gokv.AtomicStore.SetIfAbsent() for details.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	err = s.write("SetIfAbsent", []string{k}, func() error {
+		stored, err = gokv.SetIfAbsent(s.primary, k, v)
+		return err
+	}, func() error {
+		if !stored {
+			return nil
+		}
+		return s.setSecondary(k, v)
+	})
+	return stored, err
+}
+
+// CompareAndSwap stores the new value for the given key in the primary store,
+// but only if the currently stored value is equal to the old value, and if it was stored, also in the secondary store.
+// See gokv.AtomicStore.CompareAndSwap() for details.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	err = s.write("CompareAndSwap", []string{k}, func() error {
+		swapped, err = gokv.CompareAndSwap(s.primary, k, old, new)
+		return err
+	}, func() error {
+		if !swapped {
+			return nil
+		}
+		return s.setSecondary(k, new)
+	})
+	return swapped, err
+}
+
+// Incr adds delta to the int64 value that's stored for the given key in the primary store,
+// and then in the secondary store, so that the TTL of the value is kept in both stores.
+// If the secondary store's result differs from the primary store's, a ValueMismatch is reported.
+// If the secondary store doesn't implement gokv.Counter, the new value is stored in it instead,
+// with the remaining TTL of the primary store's value if the primary store can report it (like dump.TTLStore).
+// See gokv.Counter.Incr() for details.
+func (s Store) Incr(k string, delta int64) (result int64, err error) {
+	mismatch := false
+	err = s.write("Incr", []string{k}, func() error {
+		result, err = gokv.Incr(s.primary, k, delta)
+		return err
+	}, func() error {
+		if !gokv.Supports(s.secondary, (*gokv.Counter)(nil)) {
+			return s.setSecondary(k, result)
+		}
+		secondaryResult, err := s.secondary.(gokv.Counter).Incr(k, delta)
+		if err != nil {
+			return err
+		}
+		mismatch = secondaryResult != result
+		return nil
+	})
+	if mismatch {
+		s.report(Divergence{Kind: ValueMismatch, Operation: "Incr", Key: k})
+	}
+	return result, err
+}
+
+// ttlReader is implemented by stores that can report the remaining TTL of a key-value pair,
+// like the implementations of dump.TTLStore.
+type ttlReader interface {
+	TTL(k string) (ttl time.Duration, found bool, err error)
+}
+
+// ttlWriter is implemented by stores that can store raw bytes with a TTL,
+// like the implementations of dump.TTLStore.
+type ttlWriter interface {
+	SetBytesWithTTL(k string, v []byte, ttl time.Duration) error
+}
+
+// setSecondary stores the given value for the given key in the secondary store,
+// with the remaining TTL of the key-value pair in the primary store
+// if the primary store can report it and the secondary store supports gokv.ExpiringStore.
+func (s Store) setSecondary(k string, v interface{}) error {
+	if !gokv.Supports(s.primary, (*ttlReader)(nil)) || !gokv.Supports(s.secondary, (*gokv.ExpiringStore)(nil)) {
+		return s.secondary.Set(k, v)
+	}
+	ttl, found, err := s.primary.(ttlReader).TTL(k)
+	if err != nil {
+		return err
+	}
+	if !found || ttl <= 0 {
+		return s.secondary.Set(k, v)
+	}
+	return s.secondary.(gokv.ExpiringStore).SetWithTTL(k, v, ttl)
+}
+
+// Watch returns a channel on which an event is sent for each change of a key-value pair of the primary store
+// whose key starts with the given prefix.
+// See gokv.Watcher.Watch() for details.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	watcher, ok := s.primary.(gokv.Watcher)
+	if !ok {
+		return nil, gokv.ErrUnsupported
+	}
+	return watcher.Watch(ctx, prefix)
+}
+
+// Backfill copies all key-value pairs from the primary store to the secondary store,
+// overwriting the values of the secondary store, and returns the number of copied key-value pairs.
+// It's meant to be called after all instances started to mirror their changes,
+// so that the secondary store contains all key-value pairs afterwards.
+// Changes that are made through this Store while Backfill is running are mirrored as usual,
+// and each key is locked while it's copied, so no outdated values are copied.
+// The values are copied as raw bytes, so both stores must implement gokv.RawStore
+// (and use the same codec), and the primary store must implement gokv.Lister.
+// TTLs are copied as well, which requires the primary store to implement the TTL method of dump.TTLStore
+// if it implements gokv.ExpiringStore, and the secondary store to implement its SetBytesWithTTL method
+// if a key-value pair has a TTL, because the key-value pair would otherwise never expire in the secondary store.
+// When the context is done, Backfill stops and returns the context's error.
+func (s Store) Backfill(ctx context.Context) (copied int, err error) {
+	if !gokv.Supports(s.primary, (*gokv.Lister)(nil)) {
+		return 0, fmt.Errorf("The primary store must implement gokv.Lister: %w", gokv.ErrUnsupported)
+	}
+	if !gokv.Supports(s.primary, (*gokv.RawStore)(nil)) {
+		return 0, fmt.Errorf("The primary store must implement gokv.RawStore: %w", gokv.ErrUnsupported)
+	}
+	if !gokv.Supports(s.secondary, (*gokv.RawStore)(nil)) {
+		return 0, fmt.Errorf("The secondary store must implement gokv.RawStore: %w", gokv.ErrUnsupported)
+	}
+	hasTTLs := gokv.Supports(s.primary, (*ttlReader)(nil))
+	if gokv.Supports(s.primary, (*gokv.ExpiringStore)(nil)) && !hasTTLs {
+		return 0, fmt.Errorf("The primary store supports TTLs, but doesn't implement TTL, so they can't be copied: %w", gokv.ErrUnsupported)
+	}
+	primary := s.primary.(gokv.RawStore)
+	secondary := s.secondary.(gokv.RawStore)
+
+	err = s.primary.(gokv.Lister).Keys("", func(k string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		unlock := s.lock([]string{k})
+		defer unlock()
+		var ttl time.Duration
+		if hasTTLs {
+			var found bool
+			var err error
+			ttl, found, err = s.primary.(ttlReader).TTL(k)
+			if err != nil {
+				return err
+			} else if !found {
+				// Deleted or expired in the meantime
+				return nil
+			}
+		}
+		data, found, err := primary.GetBytes(k)
+		if err != nil {
+			return err
+		} else if !found {
+			// Deleted or expired in the meantime
+			return nil
+		}
+		if ttl > 0 {
+			if !gokv.Supports(s.secondary, (*ttlWriter)(nil)) {
+				return fmt.Errorf("The secondary store must implement SetBytesWithTTL for copying the key %q with its TTL: %w", k, gokv.ErrUnsupported)
+			}
+			err = s.secondary.(ttlWriter).SetBytesWithTTL(k, data, ttl)
+		} else {
+			err = secondary.SetBytes(k, data)
+		}
+		if err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	return copied, err
+}
+
+// write changes the given keys in the primary store with writePrimary and, if that succeeded,
+// in the secondary store with writeSecondary. If the latter fails, the failure is reported for each key.
+// The keys are locked during both changes, so concurrent changes of a key are applied in the same order.
+// nil keys lock all keys.
+func (s Store) write(operation string, keys []string, writePrimary func() error, writeSecondary func() error) error {
+	unlock := s.lock(keys)
+	if err := writePrimary(); err != nil {
+		unlock()
+		return err
+	}
+	err := writeSecondary()
+	unlock()
+
+	if err != nil {
+		if keys == nil {
+			s.report(Divergence{Kind: SecondaryWriteFailed, Operation: operation, Err: err})
+		}
+		for _, k := range keys {
+			s.report(Divergence{Kind: SecondaryWriteFailed, Operation: operation, Key: k, Err: err})
+		}
+	}
+	return nil
+}
+
+// read reads the value for the given key from the primary store with get,
+// and depending on the options from the secondary store, to compare the values or as fallback.
+func (s Store) read(operation string, k string, v interface{}, get func(store gokv.Store, v interface{}) (bool, error)) (bool, error) {
+	found, err := get(s.primary, v)
+	if err != nil || !s.compareReads && (found || !s.fallbackOnMiss) {
+		return found, err
+	}
+
+	// The value of the secondary store is read into a new object of the same type,
+	// so the value of the primary store isn't overwritten.
+	secondaryV := reflect.New(reflect.TypeOf(v).Elem())
+	secondaryFound, err := get(s.secondary, secondaryV.Interface())
+	if err != nil {
+		s.report(Divergence{Kind: SecondaryReadFailed, Operation: operation, Key: k, Err: err})
+		return found, nil
+	}
+	if !found && secondaryFound {
+		s.report(Divergence{Kind: MissingInPrimary, Operation: operation, Key: k})
+		if s.fallbackOnMiss {
+			reflect.ValueOf(v).Elem().Set(secondaryV.Elem())
+			return true, nil
+		}
+	} else if s.compareReads && found && !secondaryFound {
+		s.report(Divergence{Kind: MissingInSecondary, Operation: operation, Key: k})
+	} else if s.compareReads && found && !reflect.DeepEqual(v, secondaryV.Interface()) {
+		s.report(Divergence{Kind: ValueMismatch, Operation: operation, Key: k})
+	}
+	return found, nil
+}
+
+// report passes the divergence to the OnDivergence function, if one is configured.
+func (s Store) report(d Divergence) {
+	if s.onDivergence != nil {
+		s.onDivergence(d)
+	}
+}
+
+// lock locks the given keys and returns a function that unlocks them.
+// The locks are always locked in the same order, so concurrent calls don't deadlock.
+// nil keys lock all keys.
+func (s Store) lock(keys []string) func() {
+	var indexes []int
+	if keys == nil {
+		for i := range s.locks {
+			indexes = append(indexes, i)
+		}
+	} else {
+		seen := make(map[int]bool, len(keys))
+		for _, k := range keys {
+			h := fnv.New32a()
+			h.Write([]byte(k))
+			i := int(h.Sum32() % numLocks)
+			if !seen[i] {
+				seen[i] = true
+				indexes = append(indexes, i)
+			}
+		}
+		sort.Ints(indexes)
+	}
+	for _, i := range indexes {
+		s.locks[i].Lock()
+	}
+	return func() {
+		for _, i := range indexes {
+			s.locks[i].Unlock()
+		}
+	}
+}
+
+// setContext stores the given value in the given store with SetContext if it implements gokv.ContextStore,
+// and with Set after checking the context otherwise.
+func setContext(ctx context.Context, store gokv.Store, k string, v interface{}) error {
+	if contextStore, ok := store.(gokv.ContextStore); ok {
+		return contextStore.SetContext(ctx, k, v)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return store.Set(k, v)
+}
+
+// deleteContext deletes the given key from the given store with DeleteContext if it implements gokv.ContextStore,
+// and with Delete after checking the context otherwise.
+func deleteContext(ctx context.Context, store gokv.Store, k string) error {
+	if contextStore, ok := store.(gokv.ContextStore); ok {
+		return contextStore.DeleteContext(ctx, k)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return store.Delete(k)
+}
+
+// Options are the options for the mirroring store.
+type Options struct {
+	// Store that's the source of truth, for example the store that's migrated from.
+	Primary gokv.Store
+	// Store to which all changes are mirrored, for example the store that's migrated to.
+	Secondary gokv.Store
+	// Retrieve values from the secondary store when they're not found in the primary store,
+	// for example after switching the stores during a migration, while the backfill isn't done yet.
+	// Optional (false by default).
+	FallbackOnMiss bool
+	// Retrieve values from the secondary store as well and compare them with the ones of the primary store
+	// (with reflect.DeepEqual), which reports differences as Divergence.
+	// This doubles the number of reads, so it's meant for verifying a migration.
+	// Only applies to Get, GetBytes and GetContext.
+	// Optional (false by default).
+	CompareReads bool
+	// Function that's called for each noticed divergence between the primary and the secondary store.
+	// It's called synchronously, after the operation, so it should return quickly.
+	// It must be safe for concurrent use.
+	// Optional (nil by default, which means divergences are ignored).
+	OnDivergence func(d Divergence)
+}
+
+// DefaultOptions is an Options object with default values.
+// FallbackOnMiss: false, CompareReads: false, OnDivergence: nil
+var DefaultOptions = Options{
+	// No need to set any fields because their Go zero values are fine.
+}
+
+// NewStore creates a new mirroring store.
+// Closing the returned store closes both the primary and the secondary store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	// Precondition check
+	if options.Primary == nil || options.Secondary == nil {
+		return result, errors.New("The Primary and Secondary stores in the options must not be nil")
+	}
+
+	result.primary = options.Primary
+	result.secondary = options.Secondary
+	result.fallbackOnMiss = options.FallbackOnMiss
+	result.compareReads = options.CompareReads
+	result.onDivergence = options.OnDivergence
+	result.locks = new([numLocks]sync.Mutex)
+
+	return result, nil
+}
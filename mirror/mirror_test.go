@@ -0,0 +1,467 @@
+package mirror_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/mirror"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	store, _, _, _ := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+	test.TestStore(store, t)
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	store, _, _, _ := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+	test.TestTypes(store, t)
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _, _, _ := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestOptionalInterfaces tests if the optional interfaces work with the mirroring store.
+func TestOptionalInterfaces(t *testing.T) {
+	store, _, _, _ := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+	test.TestRawStore(store, t)
+	test.TestContextStore(store, t)
+	test.TestLister(store, t)
+	test.TestExpiringStore(store, t)
+	test.TestBatchStore(store, t)
+	test.TestAtomicStore(store, t)
+	test.TestCounter(store, t)
+	test.TestClearer(store, t)
+
+	// The capabilities of the primary store are reported
+	basic, err := mirror.NewStore(mirror.Options{
+		Primary:   basicStore{gomap.NewStore(gomap.DefaultOptions)},
+		Secondary: gomap.NewStore(gomap.DefaultOptions),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gokv.Supports(store, (*gokv.Lister)(nil)) || !gokv.Supports(store, (*gokv.StatsStore)(nil)) {
+		t.Error("Expected the mirroring store to support gokv.Lister and gokv.StatsStore")
+	}
+	if gokv.Supports(basic, (*gokv.Lister)(nil)) || gokv.Supports(basic, (*gokv.StatsStore)(nil)) {
+		t.Error("Expected the mirroring store with a basic primary store not to support gokv.Lister and gokv.StatsStore")
+	}
+	if !gokv.Supports(basic, (*gokv.BatchStore)(nil)) {
+		t.Error("Expected the mirroring store with a basic primary store to support gokv.BatchStore")
+	}
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test missing stores
+	_, err := mirror.NewStore(mirror.DefaultOptions)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test empty key
+	store, _, _, _ := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+	err = store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestMirroring tests if all changes are made in both stores, and if the secondary store's errors are reported.
+func TestMirroring(t *testing.T) {
+	store, primary, secondary, divergences := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+
+	err := store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Incr("counter", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, primary, "foo", "baz")
+	checkValue(t, secondary, "foo", "baz")
+	var counter int64
+	found, err := secondary.Get("counter", &counter)
+	if err != nil {
+		t.Fatal(err)
+	} else if !found || counter != 2 {
+		t.Errorf("Expected the counter to be 2 in the secondary store, but was: %v (found: %v)", counter, found)
+	}
+
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, primary, "foo", "")
+	checkValue(t, secondary, "foo", "")
+
+	// The secondary store's errors are reported, but not returned
+	secondaryErr := errors.New("secondary error")
+	secondary.err = secondaryErr
+	err = store.Set("foo", test.Foo{Bar: "qux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, primary, "foo", "qux")
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.SecondaryWriteFailed,
+		Operation: "Set",
+		Key:       "foo",
+		Err:       secondaryErr,
+	})
+	secondary.err = nil
+
+	// The primary store's errors are returned, and the secondary store isn't changed
+	err = store.Set("", test.Foo{Bar: "qux"})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	divergences.expect(t)
+}
+
+// TestTTL tests if the TTL of a value is kept in the secondary store when the value is changed with Incr.
+func TestTTL(t *testing.T) {
+	store, _, secondary, divergences := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+
+	err := store.SetWithTTL("counter", 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Incr("counter", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCounter(t, secondary.Store, "counter", 3)
+	divergences.expect(t)
+
+	// A different result of the secondary store is reported
+	err = secondary.Store.Set("counter", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Incr("counter", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.ValueMismatch,
+		Operation: "Incr",
+		Key:       "counter",
+	})
+
+	// If the secondary store doesn't implement gokv.Counter, the new value is stored with the primary store's TTL
+	primary := gomap.NewStore(gomap.Options{
+		Codec: encoding.JSON,
+	})
+	expiringSecondary := gomap.NewStore(gomap.Options{
+		Codec: encoding.JSON,
+	})
+	store, err = mirror.NewStore(mirror.Options{
+		Primary:   primary,
+		Secondary: expiringStore{expiringSecondary},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SetWithTTL("counter", 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Incr("counter", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkCounter(t, expiringSecondary, "counter", 3)
+}
+
+// checkCounter checks if the given counter value is stored for the given key with a TTL.
+func checkCounter(t *testing.T, store gomap.Store, k string, expected int64) {
+	t.Helper()
+	var actual int64
+	found, err := store.Get(k, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual != expected {
+		t.Errorf("Expected %v, but was: %v (found: %v)", expected, actual, found)
+	}
+	ttl, _, err := store.TTL(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected a TTL of up to an hour, but was: %v", ttl)
+	}
+}
+
+// TestFallbackOnMiss tests if values are retrieved from the secondary store when they're not found in the primary store.
+func TestFallbackOnMiss(t *testing.T) {
+	store, _, secondary, divergences := createStore(t, mirror.Options{
+		FallbackOnMiss: true,
+	})
+	defer store.Close()
+
+	err := secondary.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "baz")
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.MissingInPrimary,
+		Operation: "Get",
+		Key:       "foo",
+	})
+
+	found, err := store.GetMulti([]string{"foo", "qux"}, []interface{}{new(test.Foo), new(test.Foo)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found[0] || found[1] {
+		t.Errorf("Expected [true false], but was: %v", found)
+	}
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.MissingInPrimary,
+		Operation: "GetMulti",
+		Key:       "foo",
+	})
+}
+
+// TestCompareReads tests if differences between the values of both stores are reported.
+func TestCompareReads(t *testing.T) {
+	store, primary, secondary, divergences := createStore(t, mirror.Options{
+		CompareReads: true,
+	})
+	defer store.Close()
+
+	err := store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "baz")
+	divergences.expect(t)
+
+	err = secondary.Set("foo", test.Foo{Bar: "qux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "baz")
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.ValueMismatch,
+		Operation: "Get",
+		Key:       "foo",
+	})
+
+	err = primary.Set("bar", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "bar", "baz")
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.MissingInSecondary,
+		Operation: "Get",
+		Key:       "bar",
+	})
+
+	// Without FallbackOnMiss, values that are only in the secondary store are reported, but not returned
+	err = secondary.Set("qux", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "qux", "")
+	divergences.expect(t, mirror.Divergence{
+		Kind:      mirror.MissingInPrimary,
+		Operation: "Get",
+		Key:       "qux",
+	})
+}
+
+// TestBackfill tests if all key-value pairs are copied from the primary to the secondary store.
+func TestBackfill(t *testing.T) {
+	store, primary, secondary, _ := createStore(t, mirror.DefaultOptions)
+	defer store.Close()
+
+	for _, k := range []string{"foo", "bar", "baz"} {
+		err := primary.Set(k, test.Foo{Bar: k})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := primary.SetWithTTL("qux", test.Foo{Bar: "qux"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = secondary.Set("foo", test.Foo{Bar: "outdated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := store.Backfill(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != 4 {
+		t.Errorf("Expected 4 copied key-value pairs, but was: %v", copied)
+	}
+	for _, k := range []string{"foo", "bar", "baz", "qux"} {
+		checkValue(t, secondary, k, k)
+	}
+	// The TTL is copied
+	ttl, _, err := secondary.TTL("qux")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected a TTL of up to an hour, but was: %v", ttl)
+	}
+	ttl, _, err = secondary.TTL("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 0 {
+		t.Errorf("Expected no TTL, but was: %v", ttl)
+	}
+
+	// A primary store that supports TTLs but can't report them can't be backfilled,
+	// and neither can a secondary store that can't store raw bytes with a TTL (like a gokv.PrefixStore)
+	for _, options := range []mirror.Options{
+		{Primary: gokv.WithPrefix(primary, ""), Secondary: secondary},
+		{Primary: primary, Secondary: gokv.WithPrefix(secondary, "")},
+	} {
+		mirrored, err := mirror.NewStore(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = mirrored.Backfill(context.Background())
+		if !errors.Is(err, gokv.ErrUnsupported) {
+			t.Errorf("Expected gokv.ErrUnsupported, but was: %v", err)
+		}
+	}
+
+	// Test canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.Backfill(ctx)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, but was: %v", err)
+	}
+}
+
+// checkValue checks if the given store contains the expected value for the given key,
+// or no value if expected is "".
+func checkValue(t *testing.T, store interface {
+	Get(k string, v interface{}) (bool, error)
+}, key, expected string) {
+	t.Helper()
+	actual := test.Foo{}
+	found, err := store.Get(key, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected == "" {
+		if found {
+			t.Errorf("A value was found, but no value was expected")
+		}
+		return
+	}
+	if !found {
+		t.Errorf("No value was found, but should have been")
+	} else if actual.Bar != expected {
+		t.Errorf("Expected %v, but was: %v", expected, actual.Bar)
+	}
+}
+
+func createStore(t *testing.T, options mirror.Options) (mirror.Store, gomap.Store, *failingStore, *divergenceRecorder) {
+	primary := gomap.NewStore(gomap.Options{
+		Codec: encoding.JSON,
+	})
+	secondary := &failingStore{
+		Store: gomap.NewStore(gomap.Options{
+			Codec: encoding.JSON,
+		}),
+	}
+	divergences := &divergenceRecorder{}
+	options.Primary = primary
+	options.Secondary = secondary
+	options.OnDivergence = divergences.record
+	store, err := mirror.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, primary, secondary, divergences
+}
+
+// expiringStore hides all methods of a store except the ones of gokv.ExpiringStore.
+type expiringStore struct {
+	gokv.ExpiringStore
+}
+
+// failingStore is a gomap.Store whose Set method fails with err if it's set.
+type failingStore struct {
+	gomap.Store
+	err error
+}
+
+func (s *failingStore) Set(k string, v interface{}) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Store.Set(k, v)
+}
+
+// divergenceRecorder records the reported divergences.
+type divergenceRecorder struct {
+	lock        sync.Mutex
+	divergences []mirror.Divergence
+}
+
+func (r *divergenceRecorder) record(d mirror.Divergence) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.divergences = append(r.divergences, d)
+}
+
+// expect checks if exactly the expected divergences were reported since the last call, and resets them.
+func (r *divergenceRecorder) expect(t *testing.T, expected ...mirror.Divergence) {
+	t.Helper()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.divergences) != len(expected) {
+		t.Errorf("Expected %v divergences, but was: %v", expected, r.divergences)
+	} else {
+		for i := range expected {
+			if r.divergences[i] != expected[i] {
+				t.Errorf("Expected %v, but was: %v", expected[i], r.divergences[i])
+			}
+		}
+	}
+	r.divergences = nil
+}
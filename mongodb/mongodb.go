@@ -1,10 +1,15 @@
 package mongodb
 
 import (
+	"context"
+	"net/url"
+	"regexp"
 	"time"
 
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -69,7 +74,7 @@ func (c Client) Set(k string, v interface{}) error {
 	}
 	_, err = c.c.UpsertId(k, item)
 	if err != nil {
-		return err
+		return wrapError(err)
 	}
 
 	return nil
@@ -92,7 +97,7 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 	if err == mgo.ErrNotFound {
 		return false, nil
 	} else if err != nil {
-		return false, err
+		return false, wrapError(err)
 	}
 	data := item.V
 
@@ -109,11 +114,129 @@ func (c Client) Delete(k string) error {
 
 	err := c.c.RemoveId(k)
 	if err != mgo.ErrNotFound {
-		return err
+		return wrapError(err)
 	}
 	return nil
 }
 
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	item := item{
+		K: k,
+		V: v,
+	}
+	_, err := c.c.UpsertId(k, item)
+	return wrapError(err)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	item := new(item)
+	err = c.c.FindId(k).One(item)
+	// If no value was found return false
+	if err == mgo.ErrNotFound {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, wrapError(err)
+	}
+	return item.V, true, nil
+}
+
+// SetContext stores the given value for the given key.
+// The mgo package doesn't support contexts, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in byte-sorted order.
+// The prefix is matched with an anchored regular expression, which can use the index of the "_id" field.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	query := bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}}
+	iter := c.c.Find(query).Select(bson.M{"_id": 1}).Sort("_id").Iter()
+	result := new(item)
+	for iter.Next(result) {
+		if err := fn(result.K); err != nil {
+			iter.Close()
+			return err
+		}
+	}
+	return wrapError(iter.Close())
+}
+
+// MongoDB error codes that are wrapped into gokv's errors, see wrapError().
+const (
+	errCodeKeyTooLong                  = 17280
+	errCodeBSONObjectTooLarge          = 10334
+	errCodeDocumentAfterUpdateTooLarge = 17419
+)
+
+// wrapError wraps errors of mgo into gokv's errors where possible.
+// MongoDB limits the size of indexed values (like the "_id" field that contains the key)
+// and the size of documents (16 MB).
+func wrapError(err error) error {
+	var code int
+	switch mgoErr := err.(type) {
+	case *mgo.LastError:
+		code = mgoErr.Code
+	case *mgo.QueryError:
+		code = mgoErr.Code
+	default:
+		return err
+	}
+	switch code {
+	case errCodeKeyTooLong:
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	case errCodeBSONObjectTooLarge, errCodeDocumentAfterUpdateTooLarge:
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
+}
+
+// Collection returns a client that uses the collection with the given name in the same database,
+// instead of the client's own collection (see Options.CollectionName).
+// This allows to use separate namespaces in the same database, for example one for each tenant.
+//
+// The returned client shares the session with the original client,
+// so closing either of them closes the session.
+func (c Client) Collection(name string) Client {
+	c.c = c.c.Database.C(name)
+	return c
+}
+
 // Close closes the client.
 // It must be called to release any open resources.
 func (c Client) Close() error {
@@ -183,3 +306,33 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("mongodb", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+// The query parameters that don't correspond to fields of Options are kept in the connection string.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.String("databaseName", &options.DatabaseName)
+	q.String("collectionName", &options.CollectionName)
+	q.Codec(&options.Codec)
+	rest := q.Rest()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	// Use the default connection string if the URL only contains options for gokv
+	if u.User != nil || u.Host != "" || u.Path != "" || len(rest) > 0 {
+		connectionURL := *u
+		connectionURL.RawQuery = rest.Encode()
+		options.ConnectionString = connectionURL.String()
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
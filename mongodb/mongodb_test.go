@@ -186,6 +186,65 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to MongoDB works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to MongoDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to MongoDB works.
+func TestKeys(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to MongoDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to MongoDB works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to MongoDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestCollection tests if separate collections work properly as namespaces.
+//
+// Note: This test is only executed if the initial connection to MongoDB works.
+func TestCollection(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to MongoDB could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	tenantA := client.Collection("tenantA")
+	test.TestStore(tenantA, t)
+	test.TestLister(tenantA, t)
+	test.TestNamespaces(tenantA, client.Collection("tenantB"), t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	session, err := mgo.DialWithTimeout("localhost", 2*time.Second)
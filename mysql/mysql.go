@@ -1,14 +1,21 @@
 package mysql
 
 import (
+	"context"
 	gosql "database/sql"
+	"io"
+	"net/url"
+	"strings"
+	"time"
 
 	// Usually a blank import is enough as it calls the package's init() function and loads the driver,
 	// but we'll use the package's ParseDNS() function so we make this an actual import.
 	gosqldriver "github.com/go-sql-driver/mysql"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/sql"
+	"github.com/philippgille/gokv/util"
 )
 
 const defaultDBname = "gokv"
@@ -19,6 +26,19 @@ const keyLength = "255"
 // in neither of the two packages (database/sql and github.com/go-sql-driver/mysql).
 const errDBnotFound = 1049
 
+// Further error numbers that are wrapped into gokv's errors, see wrapError().
+const (
+	errDataTooLong = 1406
+	errDeadlock    = 1213
+)
+
+// nowMillis is the SQL expression for the database's current time in Unix epoch milliseconds.
+// The database's clock is used so that multiple clients agree on whether a key-value pair is expired.
+const nowMillis = "CAST(UNIX_TIMESTAMP(NOW(3)) * 1000 AS SIGNED)"
+
+// notExpired is the SQL condition for rows that don't have an expiry time or whose expiry time hasn't passed yet.
+const notExpired = "(e IS NULL OR e > " + nowMillis + ")"
+
 // Client is a gokv.Store implementation for MySQL.
 type Client struct {
 	c *sql.Client
@@ -63,6 +83,173 @@ func (c Client) Delete(k string) error {
 	return c.c.Delete(k)
 }
 
+// SetContext stores the given value for the given key.
+// The context is passed to the database driver.
+// Apart from that it works like Set.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	return c.c.SetContext(ctx, k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is passed to the database driver.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	return c.c.GetContext(ctx, k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is passed to the database driver.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	return c.c.DeleteContext(ctx, k)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	return c.c.SetBytes(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	return c.c.GetBytes(k)
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// The value is incremented by MySQL and then retrieved, in a single transaction.
+// The value is stored as decimal number, so Incr requires encoding.JSON as codec,
+// otherwise an error matching gokv.ErrUnsupported is returned.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) Incr(k string, delta int64) (int64, error) {
+	return c.c.Incr(k, delta)
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// The value is read into memory first, because database/sql requires the whole value.
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the reader must not be nil.
+func (c Client) SetReader(k string, r io.Reader) error {
+	return c.c.SetReader(k, r)
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// The value is read into memory, because database/sql retrieves the whole value.
+// If no value is found it returns (nil, false, nil).
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	return c.c.GetReader(k)
+}
+
+// SetWithTTL stores the given value for the given key.
+// After the TTL has passed, the key-value pair isn't returned anymore,
+// but it's only deleted when it's overwritten or by the sweeper (if configured).
+// The TTL is passed to MySQL in milliseconds, rounded up.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The length of the key must not exceed 255 characters.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	return c.c.SetWithTTL(k, v, ttl)
+}
+
+// SetBytesWithTTL stores the given bytes for the given key without marshalling them.
+// After the TTL has passed, the key-value pair isn't returned anymore.
+// The TTL is passed to MySQL in milliseconds, rounded up.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The length of the key must not exceed 255 characters.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	return c.c.SetBytesWithTTL(k, v, ttl)
+}
+
+// TTL returns the remaining TTL of the key-value pair for the given key, with millisecond precision.
+// If the key-value pair doesn't expire it returns (0, true, nil).
+// If no value is found it returns (0, false, nil).
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) TTL(k string) (ttl time.Duration, found bool, err error) {
+	return c.c.TTL(k)
+}
+
+// SetMulti stores the given values for the given keys with a multi-row INSERT statement per sql.MaxMultiKeys keys.
+// Multiple statements are executed in a single transaction.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// The length of the keys must not exceed 255 characters.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(keys []string, vs []interface{}) error {
+	return c.c.SetMulti(keys, vs)
+}
+
+// GetMulti retrieves the values for the given keys with a SELECT statement per sql.MaxMultiKeys keys.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (c Client) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	return c.c.GetMulti(keys, vs)
+}
+
+// DeleteMulti deletes the stored values for the given keys with a DELETE statement per sql.MaxMultiKeys keys.
+// Multiple statements are executed in a single transaction.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	return c.c.DeleteMulti(keys)
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// An expired key-value pair counts as absent.
+// It returns true if the value was stored.
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	return c.c.SetIfAbsent(k, v)
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	return c.c.CompareAndSwap(k, old, new)
+}
+
+// Clear deletes all key-value pairs by deleting all rows of the table.
+func (c Client) Clear() error {
+	return c.c.Clear()
+}
+
+// Stats returns the number of key-value pairs that aren't expired
+// and the size of the table's data and indexes as estimated by MySQL.
+// The statistics for caches are unknown (-1).
+func (c Client) Stats() (gokv.StoreStats, error) {
+	return c.c.Stats()
+}
+
+// Update calls fn with a new transaction,
+// which is committed if fn returns nil, and rolled back otherwise.
+// The transaction has MySQL's default isolation level (REPEATABLE READ by default).
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	return c.c.Update(fn)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in byte-wise order.
+// The keys are compared as binary strings, so the prefix matching is case-sensitive regardless of the table's collation.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	return c.c.Keys(prefix, fn)
+}
+
 // Close closes the client.
 // It must be called to return all open connections to the connection pool and to release any open resources.
 func (c Client) Close() error {
@@ -91,6 +278,10 @@ type Options struct {
 	// -1 for no limit. 0 will lead to the default value (100) being set.
 	// Optional (100 by default).
 	MaxOpenConnections int
+	// Interval in which a background goroutine deletes expired key-value pairs.
+	// Without it, expired key-value pairs are only filtered out, but stay in the table until they're overwritten.
+	// Optional (0 by default, which disables the background sweeping).
+	SweepInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
@@ -180,7 +371,13 @@ func NewClient(options Options) (Client, error) {
 	// If yes, allow the user to define a key length via the options.
 	// Also: There's no hard character limit, but byte limit.
 	// So the 255 characters come from 255 utf8mb3 characters.
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k VARCHAR(" + keyLength + ") PRIMARY KEY, v BLOB NOT NULL)")
+	// The column "e" contains the expiry time (in Unix epoch milliseconds) of key-value pairs with a TTL.
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k VARCHAR(" + keyLength + ") PRIMARY KEY, v BLOB NOT NULL, e BIGINT)")
+	if err != nil {
+		return result, err
+	}
+	// Tables that were created by previous versions of gokv don't have the column yet.
+	err = addExpiryColumn(db, options.TableName)
 	if err != nil {
 		return result, err
 	}
@@ -189,11 +386,19 @@ func NewClient(options Options) (Client, error) {
 	// Note: Prepared statements are handled differently from other programming languages in Go,
 	// see: http://go-database-sql.org/prepared.html.
 	// TODO: Prepared statements might prevent the use of other databases that are compatible with the MySQL protocol.
-	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES (?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v)")
+	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES (?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v), e = NULL")
+	if err != nil {
+		return result, err
+	}
+	setWithTTLStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, e) VALUES (?, ?, " + nowMillis + " + ?) ON DUPLICATE KEY UPDATE v = VALUES(v), e = VALUES(e)")
 	if err != nil {
 		return result, err
 	}
-	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = ?")
+	ttlStmt, err := db.Prepare("SELECT e - " + nowMillis + " FROM " + options.TableName + " WHERE k = ? AND " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = ? AND " + notExpired)
 	if err != nil {
 		return result, err
 	}
@@ -201,13 +406,72 @@ func NewClient(options Options) (Client, error) {
 	if err != nil {
 		return result, err
 	}
+	keysStmt, err := db.Prepare("SELECT k FROM " + options.TableName + " WHERE CAST(k AS BINARY) LIKE ? ESCAPE '!' AND " + notExpired + " ORDER BY CAST(k AS BINARY)")
+	if err != nil {
+		return result, err
+	}
+	deleteExpiredStmt, err := db.Prepare("DELETE FROM " + options.TableName + " WHERE e <= " + nowMillis)
+	if err != nil {
+		return result, err
+	}
+	// Expired rows count as absent, so they're overwritten.
+	// The value must be assigned before the expiry time, because MySQL uses updated values in subsequent assignments.
+	setIfAbsentStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES (?, ?) ON DUPLICATE KEY UPDATE v = IF(" + notExpired + ", v, VALUES(v)), e = IF(" + notExpired + ", e, NULL)")
+	if err != nil {
+		return result, err
+	}
+	compareAndSwapStmt, err := db.Prepare("UPDATE " + options.TableName + " SET v = ?, e = NULL WHERE k = ? AND v = ? AND " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	// The value is converted from BLOB to a number and stored as decimal number again,
+	// with the delta being the value for new and expired rows.
+	incrStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES (?, ?) ON DUPLICATE KEY UPDATE v = IF(" + notExpired + ", CAST(v AS SIGNED) + CAST(VALUES(v) AS SIGNED), VALUES(v)), e = IF(" + notExpired + ", e, NULL)")
+	if err != nil {
+		return result, err
+	}
+	incrResultStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = ?")
+	if err != nil {
+		return result, err
+	}
+	clearStmt, err := db.Prepare("DELETE FROM " + options.TableName)
+	if err != nil {
+		return result, err
+	}
+	countStmt, err := db.Prepare("SELECT COUNT(*) FROM " + options.TableName + " WHERE " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	// The table statistics are estimates that MySQL updates periodically.
+	sizeStmt, err := db.Prepare("SELECT COALESCE(data_length + index_length, 0) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = '" + options.TableName + "'")
+	if err != nil {
+		return result, err
+	}
 
 	c := sql.Client{
-		C:          db,
-		UpsertStmt: upsertStmt,
-		GetStmt:    getStmt,
-		DeleteStmt: deleteStmt,
-		Codec:      options.Codec,
+		C:                  db,
+		UpsertStmt:         upsertStmt,
+		GetStmt:            getStmt,
+		DeleteStmt:         deleteStmt,
+		KeysStmt:           keysStmt,
+		SetWithTTLStmt:     setWithTTLStmt,
+		TTLStmt:            ttlStmt,
+		DeleteExpiredStmt:  deleteExpiredStmt,
+		SetIfAbsentStmt:    setIfAbsentStmt,
+		CompareAndSwapStmt: compareAndSwapStmt,
+		IncrStmt:           incrStmt,
+		IncrResultStmt:     incrResultStmt,
+		ClearStmt:          clearStmt,
+		CountStmt:          countStmt,
+		SizeStmt:           sizeStmt,
+		Codec:              options.Codec,
+		MultiStmts:         multiStmts(options.TableName),
+		WrapError:          wrapError,
+	}
+	if options.SweepInterval > 0 {
+		if err = c.StartSweeper(options.SweepInterval); err != nil {
+			return result, err
+		}
 	}
 
 	result.c = &c
@@ -215,6 +479,103 @@ func NewClient(options Options) (Client, error) {
 	return result, nil
 }
 
+// multiStmts returns the statements for operating on multiple rows of the given table at once.
+func multiStmts(tableName string) *sql.MultiStmts {
+	return &sql.MultiStmts{
+		Upsert: func(n int) string {
+			return "INSERT INTO " + tableName + " (k, v) VALUES " + sql.ValuesList(n, 2, sql.QuestionMark) + " ON DUPLICATE KEY UPDATE v = VALUES(v), e = NULL"
+		},
+		Get: func(n int) string {
+			return "SELECT k, v FROM " + tableName + " WHERE k IN (" + sql.InList(n, sql.QuestionMark) + ") AND " + notExpired
+		},
+		Delete: func(n int) string {
+			return "DELETE FROM " + tableName + " WHERE k IN (" + sql.InList(n, sql.QuestionMark) + ")"
+		},
+	}
+}
+
+func init() {
+	gokv.Register("mysql", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+// The query parameters that don't correspond to fields of Options are kept in the data source name.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.String("tableName", &options.TableName)
+	q.Int("maxOpenConnections", &options.MaxOpenConnections)
+	q.Duration("sweepInterval", &options.SweepInterval)
+	q.Codec(&options.Codec)
+	rest := q.Rest()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	// Use the default data source name if the URL only contains options for gokv
+	if u.User != nil || u.Host != "" || u.Path != "" || len(rest) > 0 {
+		// Format: [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
+		dsn := ""
+		if u.User != nil {
+			dsn = u.User.Username()
+			if password, isSet := u.User.Password(); isSet {
+				dsn += ":" + password
+			}
+			dsn += "@"
+		}
+		if u.Host != "" {
+			dsn += "tcp(" + u.Host + ")"
+		}
+		dsn += "/" + strings.TrimPrefix(u.Path, "/")
+		if len(rest) > 0 {
+			dsn += "?" + rest.Encode()
+		}
+		options.DataSourceName = dsn
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// wrapError wraps MySQL errors into gokv's errors where possible.
+// Values that are too large can either exceed the column or the maximum packet size.
+func wrapError(err error) error {
+	if err == gosqldriver.ErrPktTooLarge {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	driverErr, ok := err.(*gosqldriver.MySQLError)
+	if !ok {
+		return err
+	}
+	switch driverErr.Number {
+	case errDataTooLong:
+		if strings.Contains(driverErr.Message, "'k'") {
+			return util.WrapError(gokv.ErrKeyTooLong, err)
+		}
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	case errDeadlock:
+		return util.WrapError(gokv.ErrConflict, err)
+	}
+	return err
+}
+
+// addExpiryColumn adds the column for expiry times to the table if it doesn't exist yet.
+// MySQL doesn't support "ADD COLUMN IF NOT EXISTS", so the information schema is checked first.
+func addExpiryColumn(db *gosql.DB, tableName string) error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'e'", tableName).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err = db.Exec("ALTER TABLE " + tableName + " ADD COLUMN e BIGINT")
+	return err
+}
+
 // createDB creates a DB when the DB name is given in the config.
 func createDB(cfg *gosqldriver.Config, db *gosql.DB) error {
 	// We can't use the existing db object, because that would lead to the same error again.
@@ -1,21 +1,137 @@
 package postgresql
 
 import (
+	"context"
 	gosql "database/sql"
+	"net/url"
+	"strings"
+	"time"
 
 	// Usually a blank import is enough as it calls the package's init() function and loads the driver,
-	// but we'll use the package's ParseDNS() function so we make this an actual import.
-	_ "github.com/lib/pq"
+	// but we'll use the package's Listener for Watch() so we make this an actual import.
+	"github.com/lib/pq"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/sql"
+	"github.com/philippgille/gokv/util"
 )
 
 const defaultDBname = "gokv"
 
+// nowMillis is the SQL expression for the database's current time in Unix epoch milliseconds.
+// The database's clock is used so that multiple clients agree on whether a key-value pair is expired.
+const nowMillis = "(EXTRACT(EPOCH FROM CLOCK_TIMESTAMP()) * 1000)::BIGINT"
+
+// notExpired is the SQL condition for rows that don't have an expiry time or whose expiry time hasn't passed yet.
+const notExpired = "(e IS NULL OR e > " + nowMillis + ")"
+
 // Client is a gokv.Store implementation for PostgreSQL.
 type Client struct {
 	*sql.Client
+	connectionURL string
+	tableName     string
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// It uses PostgreSQL's LISTEN/NOTIFY. The notifications are sent by a trigger on the table,
+// which is created by the first call of Watch and stays in place afterwards.
+// The trigger notifies about the changes of all clients, but not about changes made by a TRUNCATE.
+// The notifications don't contain values, so the value is retrieved when the notification arrives.
+// If the key-value pair doesn't exist anymore at that time, no EventPut event is sent, but an EventDelete event follows.
+// Expired key-value pairs only lead to an EventDelete event when they're deleted by the sweeper or overwritten.
+// The listening uses its own connection, which isn't limited by MaxOpenConnections.
+// The channel is closed when the context is canceled or the listening connection is lost,
+// because notifications that are sent while it's reconnecting are lost.
+func (c Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	if err := c.createNotifyTrigger(); err != nil {
+		return nil, err
+	}
+
+	listener := pq.NewListener(c.connectionURL, time.Second, time.Minute, nil)
+	if err := listener.Listen(c.notifyChannel()); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer listener.Close()
+		for {
+			var notification *pq.Notification
+			select {
+			case notification = <-listener.Notify:
+			case <-ctx.Done():
+				return
+			}
+			// A nil notification is sent after reconnecting
+			if notification == nil {
+				return
+			}
+			// The payload is the operation ("p" for put, "d" for delete) followed by the key
+			if len(notification.Extra) < 2 || !strings.HasPrefix(notification.Extra[1:], prefix) {
+				continue
+			}
+			e := gokv.Event{
+				Key:  notification.Extra[1:],
+				Type: gokv.EventDelete,
+			}
+			if notification.Extra[0] == 'p' {
+				var data []byte
+				err := c.GetStmt.QueryRowContext(ctx, e.Key).Scan(&data)
+				if err == gosql.ErrNoRows {
+					continue
+				} else if err != nil {
+					return
+				}
+				e.Type = gokv.EventPut
+				e.Value = data
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// notifyChannel returns the name of the channel that the trigger of the table sends its notifications to.
+func (c Client) notifyChannel() string {
+	return "gokv_" + c.tableName
+}
+
+// createNotifyTrigger creates the trigger that sends a notification for each change of the table,
+// unless it already exists.
+func (c Client) createNotifyTrigger() error {
+	_, err := c.C.Exec(`CREATE OR REPLACE FUNCTION gokv_notify_` + c.tableName + `() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		PERFORM pg_notify('` + c.notifyChannel() + `', 'd' || OLD.k);
+	ELSE
+		PERFORM pg_notify('` + c.notifyChannel() + `', 'p' || NEW.k);
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	err = c.C.QueryRow("SELECT COUNT(*) FROM pg_trigger WHERE tgname = 'gokv_notify' AND tgrelid = $1::regclass", c.tableName).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = c.C.Exec("CREATE TRIGGER gokv_notify AFTER INSERT OR UPDATE OR DELETE ON " + c.tableName + " FOR EACH ROW EXECUTE PROCEDURE gokv_notify_" + c.tableName + "()")
+	// Another client might have created the trigger in the meantime
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "42710" {
+		return nil
+	}
+	return err
 }
 
 // Options are the options for the PostgreSQL client.
@@ -40,6 +156,10 @@ type Options struct {
 	// -1 for no limit. 0 will lead to the default value (100) being set.
 	// Optional (100 by default).
 	MaxOpenConnections int
+	// Interval in which a background goroutine deletes expired key-value pairs.
+	// Without it, expired key-value pairs are only filtered out, but stay in the table until they're overwritten.
+	// Optional (0 by default, which disables the background sweeping).
+	SweepInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
@@ -91,7 +211,13 @@ func NewClient(options Options) (Client, error) {
 	db.SetMaxOpenConns(options.MaxOpenConnections)
 
 	// Create table if it doesn't exist yet.
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k TEXT PRIMARY KEY, v BYTEA NOT NULL)")
+	// The column "e" contains the expiry time (in Unix epoch milliseconds) of key-value pairs with a TTL.
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k TEXT PRIMARY KEY, v BYTEA NOT NULL, e BIGINT)")
+	if err != nil {
+		return result, err
+	}
+	// Tables that were created by previous versions of gokv don't have the column yet.
+	_, err = db.Exec("ALTER TABLE " + options.TableName + " ADD COLUMN IF NOT EXISTS e BIGINT")
 	if err != nil {
 		return result, err
 	}
@@ -100,11 +226,19 @@ func NewClient(options Options) (Client, error) {
 	// Note: Prepared statements are handled differently from other programming languages in Go,
 	// see: http://go-database-sql.org/prepared.html.
 	// TODO: Prepared statements might prevent the use of other databases that are compatible with the PostgreSQL protocol.
-	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = $2")
+	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = $2, e = NULL")
+	if err != nil {
+		return result, err
+	}
+	setWithTTLStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, e) VALUES ($1, $2, " + nowMillis + " + $3) ON CONFLICT (k) DO UPDATE SET v = $2, e = " + nowMillis + " + $3")
 	if err != nil {
 		return result, err
 	}
-	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1")
+	ttlStmt, err := db.Prepare("SELECT e - " + nowMillis + " FROM " + options.TableName + " WHERE k = $1 AND " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1 AND " + notExpired)
 	if err != nil {
 		return result, err
 	}
@@ -112,16 +246,143 @@ func NewClient(options Options) (Client, error) {
 	if err != nil {
 		return result, err
 	}
+	keysStmt, err := db.Prepare("SELECT k FROM " + options.TableName + " WHERE k LIKE $1 ESCAPE '!' AND " + notExpired + " ORDER BY k")
+	if err != nil {
+		return result, err
+	}
+	deleteExpiredStmt, err := db.Prepare("DELETE FROM " + options.TableName + " WHERE e <= " + nowMillis)
+	if err != nil {
+		return result, err
+	}
+	// Expired rows count as absent, so they're overwritten
+	setIfAbsentStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = $2, e = NULL WHERE " + options.TableName + ".e <= " + nowMillis)
+	if err != nil {
+		return result, err
+	}
+	compareAndSwapStmt, err := db.Prepare("UPDATE " + options.TableName + " SET v = $1, e = NULL WHERE k = $2 AND v = $3 AND " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	// The expiry time is checked twice, so the statement's start time is used instead of the current time,
+	// which could differ between the checks.
+	// The value is converted from BYTEA to a number and back, with the delta being the value for new and expired rows.
+	stmtNowMillis := "(EXTRACT(EPOCH FROM STATEMENT_TIMESTAMP()) * 1000)::BIGINT"
+	incrStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, CONVERT_TO($2::BIGINT::TEXT, 'UTF8')) ON CONFLICT (k) DO UPDATE SET " +
+		"v = CASE WHEN " + options.TableName + ".e <= " + stmtNowMillis + " THEN EXCLUDED.v ELSE CONVERT_TO((CONVERT_FROM(" + options.TableName + ".v, 'UTF8')::BIGINT + $2::BIGINT)::TEXT, 'UTF8') END, " +
+		"e = CASE WHEN " + options.TableName + ".e <= " + stmtNowMillis + " THEN NULL ELSE " + options.TableName + ".e END")
+	if err != nil {
+		return result, err
+	}
+	incrResultStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1")
+	if err != nil {
+		return result, err
+	}
+	clearStmt, err := db.Prepare("DELETE FROM " + options.TableName)
+	if err != nil {
+		return result, err
+	}
+	countStmt, err := db.Prepare("SELECT COUNT(*) FROM " + options.TableName + " WHERE " + notExpired)
+	if err != nil {
+		return result, err
+	}
+	sizeStmt, err := db.Prepare("SELECT pg_total_relation_size('" + options.TableName + "')")
+	if err != nil {
+		return result, err
+	}
 
 	c := sql.Client{
-		C:          db,
-		UpsertStmt: upsertStmt,
-		GetStmt:    getStmt,
-		DeleteStmt: deleteStmt,
-		Codec:      options.Codec,
+		C:                  db,
+		UpsertStmt:         upsertStmt,
+		GetStmt:            getStmt,
+		DeleteStmt:         deleteStmt,
+		KeysStmt:           keysStmt,
+		SetWithTTLStmt:     setWithTTLStmt,
+		TTLStmt:            ttlStmt,
+		DeleteExpiredStmt:  deleteExpiredStmt,
+		SetIfAbsentStmt:    setIfAbsentStmt,
+		CompareAndSwapStmt: compareAndSwapStmt,
+		IncrStmt:           incrStmt,
+		IncrResultStmt:     incrResultStmt,
+		ClearStmt:          clearStmt,
+		CountStmt:          countStmt,
+		SizeStmt:           sizeStmt,
+		Codec:              options.Codec,
+		MultiStmts:         multiStmts(options.TableName),
+		WrapError:          wrapError,
+	}
+	if options.SweepInterval > 0 {
+		if err = c.StartSweeper(options.SweepInterval); err != nil {
+			return result, err
+		}
 	}
 
 	result.Client = &c
+	result.connectionURL = options.ConnectionURL
+	result.tableName = options.TableName
 
 	return result, nil
 }
+
+// multiStmts returns the statements for operating on multiple rows of the given table at once.
+func multiStmts(tableName string) *sql.MultiStmts {
+	return &sql.MultiStmts{
+		Upsert: func(n int) string {
+			return "INSERT INTO " + tableName + " (k, v) VALUES " + sql.ValuesList(n, 2, sql.Dollar) + " ON CONFLICT (k) DO UPDATE SET v = excluded.v, e = NULL"
+		},
+		Get: func(n int) string {
+			return "SELECT k, v FROM " + tableName + " WHERE k IN (" + sql.InList(n, sql.Dollar) + ") AND " + notExpired
+		},
+		Delete: func(n int) string {
+			return "DELETE FROM " + tableName + " WHERE k IN (" + sql.InList(n, sql.Dollar) + ")"
+		},
+	}
+}
+
+func init() {
+	gokv.Register("postgres", newClientFromURL)
+	gokv.Register("postgresql", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+// The query parameters that don't correspond to fields of Options are kept in the connection URL.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.String("tableName", &options.TableName)
+	q.Int("maxOpenConnections", &options.MaxOpenConnections)
+	q.Duration("sweepInterval", &options.SweepInterval)
+	q.Codec(&options.Codec)
+	rest := q.Rest()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	// Use the default connection URL if the URL only contains options for gokv
+	if u.User != nil || u.Host != "" || u.Path != "" || len(rest) > 0 {
+		connectionURL := *u
+		connectionURL.RawQuery = rest.Encode()
+		options.ConnectionURL = connectionURL.String()
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// wrapError wraps PostgreSQL errors into gokv's errors where possible.
+func wrapError(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return err
+	}
+	switch pqErr.Code {
+	// serialization_failure and deadlock_detected
+	case "40001", "40P01":
+		return util.WrapError(gokv.ErrConflict, err)
+	// program_limit_exceeded, which occurs for keys that are too long for the primary key index
+	case "54000":
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	}
+	return err
+}
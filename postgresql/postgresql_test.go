@@ -188,6 +188,188 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestKeys(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestExpiringStore(client, t)
+}
+
+// TestBytesWithTTL tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestBytesWithTTL(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTTLStore(client, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestBatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestBatchStore(client, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestAtomic(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestAtomicStore(client, t)
+}
+
+// TestTransaction tests if transactions work properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestTransaction(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTransactional(client, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestWatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestStreamStore tests if storing and retrieving values as streams works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestStreamStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestStreamStore(client, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestCounter(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestCounter(client, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestClear(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestClearer(client, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+//
+// Note: This test is only executed if the initial connection to PostgreSQL works.
+func TestStats(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to PostgreSQL could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestStatsStore(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	// Need to use port 5433 because 5432 is already used by another service on Travis CI
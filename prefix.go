@@ -0,0 +1,363 @@
+package gokv
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// PrefixStore is a wrapper for a Store that prepends a prefix to all keys,
+// so that multiple tenants or components can share one store without key collisions.
+// Keys that are returned by the store (by Keys and Watch) don't contain the prefix.
+//
+// PrefixStore implements all optional interfaces of this package.
+// If the wrapped store doesn't implement one of them, the corresponding methods
+// fall back to the basic methods where that's possible without a race condition
+// (like for BatchStore and ContextStore), and return ErrUnsupported otherwise.
+// Use Supports() instead of a type assertion to find out which ones are actually supported.
+type PrefixStore struct {
+	store  Store
+	prefix string
+}
+
+// WithPrefix creates a new PrefixStore that wraps the given store
+// and prepends the given prefix to all keys, for example "tenantA:".
+// Closing the returned store closes the wrapped store.
+func WithPrefix(store Store, prefix string) PrefixStore {
+	return PrefixStore{
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+// Set stores the given value for the given key.
+// See Store.Set() for details.
+func (s PrefixStore) Set(k string, v interface{}) error {
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return s.store.Set(s.prefix+k, v)
+}
+
+// Get retrieves the value for the given key.
+// See Store.Get() for details.
+func (s PrefixStore) Get(k string, v interface{}) (found bool, err error) {
+	if k == "" {
+		return false, ErrEmptyKey
+	}
+	return s.store.Get(s.prefix+k, v)
+}
+
+// Delete deletes the stored value for the given key.
+// See Store.Delete() for details.
+func (s PrefixStore) Delete(k string) error {
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return s.store.Delete(s.prefix + k)
+}
+
+// Close closes the wrapped store.
+func (s PrefixStore) Close() error {
+	return s.store.Close()
+}
+
+// Unwrap returns the wrapped store.
+func (s PrefixStore) Unwrap() Store {
+	return s.store
+}
+
+// Supports reports whether the PrefixStore supports the optional interface that iface points to.
+// BatchStore and ContextStore are always supported, Clearer and StatsStore if the wrapped store implements Lister,
+// and all other interfaces if the wrapped store supports them.
+// See the package-level function Supports() for details.
+func (s PrefixStore) Supports(iface interface{}) bool {
+	switch iface.(type) {
+	case *BatchStore, *ContextStore:
+		return true
+	case *Clearer, *StatsStore:
+		return Supports(s.store, (*Lister)(nil))
+	}
+	return Supports(s.store, iface)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// See RawStore.SetBytes() for details.
+func (s PrefixStore) SetBytes(k string, v []byte) error {
+	rawStore, ok := s.store.(RawStore)
+	if !ok {
+		return ErrUnsupported
+	}
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return rawStore.SetBytes(s.prefix+k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// See RawStore.GetBytes() for details.
+func (s PrefixStore) GetBytes(k string) (v []byte, found bool, err error) {
+	rawStore, ok := s.store.(RawStore)
+	if !ok {
+		return nil, false, ErrUnsupported
+	}
+	if k == "" {
+		return nil, false, ErrEmptyKey
+	}
+	return rawStore.GetBytes(s.prefix + k)
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// See StreamStore.SetReader() for details.
+func (s PrefixStore) SetReader(k string, r io.Reader) error {
+	streamStore, ok := s.store.(StreamStore)
+	if !ok {
+		return ErrUnsupported
+	}
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return streamStore.SetReader(s.prefix+k, r)
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// See StreamStore.GetReader() for details.
+func (s PrefixStore) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	streamStore, ok := s.store.(StreamStore)
+	if !ok {
+		return nil, false, ErrUnsupported
+	}
+	if k == "" {
+		return nil, false, ErrEmptyKey
+	}
+	return streamStore.GetReader(s.prefix + k)
+}
+
+// SetContext stores the given value for the given key.
+// If the wrapped store doesn't implement ContextStore, the context is only checked before the value is stored.
+// See ContextStore.SetContext() for details.
+func (s PrefixStore) SetContext(ctx context.Context, k string, v interface{}) error {
+	contextStore, ok := s.store.(ContextStore)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.Set(k, v)
+	}
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return contextStore.SetContext(ctx, s.prefix+k, v)
+}
+
+// GetContext retrieves the value for the given key.
+// If the wrapped store doesn't implement ContextStore, the context is only checked before the value is retrieved.
+// See ContextStore.GetContext() for details.
+func (s PrefixStore) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	contextStore, ok := s.store.(ContextStore)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return s.Get(k, v)
+	}
+	if k == "" {
+		return false, ErrEmptyKey
+	}
+	return contextStore.GetContext(ctx, s.prefix+k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// If the wrapped store doesn't implement ContextStore, the context is only checked before the value is deleted.
+// See ContextStore.DeleteContext() for details.
+func (s PrefixStore) DeleteContext(ctx context.Context, k string) error {
+	contextStore, ok := s.store.(ContextStore)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.Delete(k)
+	}
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return contextStore.DeleteContext(ctx, s.prefix+k)
+}
+
+// Keys calls fn for each key that starts with the given prefix, without the PrefixStore's prefix.
+// See Lister.Keys() for details.
+func (s PrefixStore) Keys(prefix string, fn func(k string) error) error {
+	lister, ok := s.store.(Lister)
+	if !ok {
+		return ErrUnsupported
+	}
+	return lister.Keys(s.prefix+prefix, func(k string) error {
+		return fn(strings.TrimPrefix(k, s.prefix))
+	})
+}
+
+// Clear deletes all key-value pairs whose key starts with the prefix,
+// by deleting each key that's listed by the wrapped store.
+// The wrapped store must implement Lister, otherwise ErrUnsupported is returned.
+func (s PrefixStore) Clear() error {
+	return deleteKeys(s)
+}
+
+// Stats returns the number of key-value pairs whose key starts with the prefix,
+// by counting the keys that are listed by the wrapped store.
+// All other statistics are unknown (-1), because the wrapped store's statistics aren't specific to the prefix.
+// The wrapped store must implement Lister, otherwise ErrUnsupported is returned.
+func (s PrefixStore) Stats() (StoreStats, error) {
+	return countKeys(s)
+}
+
+// SetWithTTL stores the given value for the given key, with the given time to live.
+// See ExpiringStore.SetWithTTL() for details.
+func (s PrefixStore) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	expiringStore, ok := s.store.(ExpiringStore)
+	if !ok {
+		return ErrUnsupported
+	}
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return expiringStore.SetWithTTL(s.prefix+k, v, ttl)
+}
+
+// SetMulti stores the given values for the given keys.
+// See the package-level function SetMulti() for details.
+func (s PrefixStore) SetMulti(keys []string, vs []interface{}) error {
+	prefixedKeys, err := s.prefixKeys(keys)
+	if err != nil {
+		return err
+	}
+	return SetMulti(s.store, prefixedKeys, vs)
+}
+
+// GetMulti retrieves the values for the given keys.
+// See the package-level function GetMulti() for details.
+func (s PrefixStore) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	prefixedKeys, err := s.prefixKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	return GetMulti(s.store, prefixedKeys, vs)
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// See the package-level function DeleteMulti() for details.
+func (s PrefixStore) DeleteMulti(keys []string) error {
+	prefixedKeys, err := s.prefixKeys(keys)
+	if err != nil {
+		return err
+	}
+	return DeleteMulti(s.store, prefixedKeys)
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// See AtomicStore.SetIfAbsent() for details.
+func (s PrefixStore) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if k == "" {
+		return false, ErrEmptyKey
+	}
+	return SetIfAbsent(s.store, s.prefix+k, v)
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// See AtomicStore.CompareAndSwap() for details.
+func (s PrefixStore) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if k == "" {
+		return false, ErrEmptyKey
+	}
+	return CompareAndSwap(s.store, s.prefix+k, old, new)
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// See Counter.Incr() for details.
+func (s PrefixStore) Incr(k string, delta int64) (int64, error) {
+	if k == "" {
+		return 0, ErrEmptyKey
+	}
+	return Incr(s.store, s.prefix+k, delta)
+}
+
+// Update calls fn with a new transaction, in which the prefix is prepended to all keys.
+// See Transactional.Update() for details.
+func (s PrefixStore) Update(fn func(tx Tx) error) error {
+	return Update(s.store, func(tx Tx) error {
+		return fn(prefixTx{
+			tx:     tx,
+			prefix: s.prefix,
+		})
+	})
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// The keys of the events don't contain the PrefixStore's prefix.
+// See Watcher.Watch() for details.
+func (s PrefixStore) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	watcher, ok := s.store.(Watcher)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	prefixedEvents, err := watcher.Watch(ctx, s.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		for e := range prefixedEvents {
+			e.Key = strings.TrimPrefix(e.Key, s.prefix)
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// prefixKeys returns the given keys with the prefix prepended.
+func (s PrefixStore) prefixKeys(keys []string) ([]string, error) {
+	prefixedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		if k == "" {
+			return nil, ErrEmptyKey
+		}
+		prefixedKeys[i] = s.prefix + k
+	}
+	return prefixedKeys, nil
+}
+
+// prefixTx is a transaction that prepends the prefix of a PrefixStore to all keys.
+type prefixTx struct {
+	tx     Tx
+	prefix string
+}
+
+func (t prefixTx) Set(k string, v interface{}) error {
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return t.tx.Set(t.prefix+k, v)
+}
+
+func (t prefixTx) Get(k string, v interface{}) (found bool, err error) {
+	if k == "" {
+		return false, ErrEmptyKey
+	}
+	return t.tx.Get(t.prefix+k, v)
+}
+
+func (t prefixTx) Delete(k string) error {
+	if k == "" {
+		return ErrEmptyKey
+	}
+	return t.tx.Delete(t.prefix + k)
+}
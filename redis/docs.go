@@ -1,4 +1,9 @@
 /*
 Package redis contains an implementation of the `gokv.Store` interface for Redis.
+
+The package registers itself for the URL scheme "redis", so a client can also be created with gokv.Open,
+for example with "redis://:password@localhost:6379/15?keyPrefix=tenantA:&codec=gob".
+The host is used as Address, the password as Password and the path as DB,
+and the query parameters "keyPrefix" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package redis
@@ -1,22 +1,55 @@
 package redis
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/go-redis/redis"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Client is a gokv.Store implementation for Redis.
 type Client struct {
-	c     *redis.Client
-	codec encoding.Codec
+	c         *redis.Client
+	keyPrefix string
+	codec     encoding.Codec
 }
 
 // Set stores the given value for the given key.
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The context is passed to the go-redis client.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	return c.set(ctx, k, v, 0)
+}
+
+// SetWithTTL stores the given value for the given key, with Redis' native expiration.
+// Redis supports TTLs with a precision of milliseconds.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+	return c.set(context.Background(), k, v, ttl)
+}
+
+// set stores the given value for the given key with the given TTL (0 for no expiration).
+func (c Client) set(ctx context.Context, k string, v interface{}, ttl time.Duration) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -30,10 +63,16 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	err = c.c.Set(k, string(data), 0).Err()
-	if err != nil {
+	// go-redis doesn't abort commands when their context is canceled,
+	// so we check the context here to at least not send the command.
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+	k = c.keyPrefix + k
+	err = c.c.WithContext(ctx).Set(k, string(data), ttl).Err()
+	if err != nil {
+		return wrapError(err)
+	}
 	return nil
 }
 
@@ -44,16 +83,27 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is passed to the go-redis client.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	dataString, err := c.c.Get(k).Result()
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	k = c.keyPrefix + k
+	dataString, err := c.c.WithContext(ctx).Get(k).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return false, nil
 		}
-		return false, err
+		return false, wrapError(err)
 	}
 
 	return true, c.codec.Unmarshal([]byte(dataString), v)
@@ -63,14 +113,489 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is passed to the go-redis client.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	k = c.keyPrefix + k
+	_, err := c.c.WithContext(ctx).Del(k).Result()
+	return wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// Like with Set, a previously set TTL is removed.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	k = c.keyPrefix + k
+	return wrapError(c.c.Set(k, v, 0).Err())
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
 	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	k = c.keyPrefix + k
+	data, err := c.c.Get(k).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, wrapError(err)
+	}
+	return data, true, nil
+}
+
+// SetBytesWithTTL stores the given bytes for the given key without marshalling them, with Redis' native expiration.
+// It behaves like SetBytes, and like SetWithTTL regarding the TTL.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
 		return err
 	}
 
-	_, err := c.c.Del(k).Result()
+	k = c.keyPrefix + k
+	return wrapError(c.c.Set(k, v, ttl).Err())
+}
+
+// TTL returns the remaining time to live of the key-value pair for the given key with a PTTL command,
+// or 0 if it was stored without a TTL.
+// If no value is found it returns (0, false, nil).
+// The key must not be "".
+func (c Client) TTL(k string) (ttl time.Duration, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, false, err
+	}
+
+	k = c.keyPrefix + k
+	ttl, err = c.c.PTTL(k).Result()
+	if err != nil {
+		return 0, false, wrapError(err)
+	}
+	// Redis returns -2 if the key doesn't exist and -1 if it has no TTL, which go-redis multiplies with the precision.
+	// A remaining TTL of 0 means that the key-value pair is about to expire.
+	switch {
+	case ttl == -1*time.Millisecond:
+		return 0, true, nil
+	case ttl <= 0:
+		return 0, false, nil
+	}
+	return ttl, true, nil
+}
+
+// SetMulti stores the given values for the given keys with a single MSET command.
+// Like with Set, previously set TTLs are removed.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pairs := make([]interface{}, 0, 2*len(keys))
+	for i, k := range keys {
+		data, err := c.codec.Marshal(vs[i])
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, c.keyPrefix+k, string(data))
+	}
+	return wrapError(c.c.MSet(pairs...).Err())
+}
+
+// GetMulti retrieves the values for the given keys with a single MGET command.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (c Client) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return []bool{}, nil
+	}
+
+	results, err := c.c.MGet(c.prefixKeys(keys)...).Result()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	found = make([]bool, len(keys))
+	for i, result := range results {
+		// Redis returns nil for non-existing keys
+		dataString, ok := result.(string)
+		if !ok {
+			continue
+		}
+		found[i] = true
+		if err := c.codec.Unmarshal([]byte(dataString), vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys with a single DEL command.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return wrapError(c.c.Del(c.prefixKeys(keys)...).Err())
+}
+
+// SetIfAbsent stores the given value for the given key with SETNX,
+// but only if no value is stored for the key yet.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	k = c.keyPrefix + k
+	stored, err = c.c.SetNX(k, string(data), 0).Result()
+	return stored, wrapError(err)
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// The key is watched (WATCH) while the stored value is compared with the old one,
+// and the new value is stored in a transaction (MULTI/EXEC), which fails if the key was changed in the meantime.
+// In that case the comparison is repeated.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+
+	oldData, err := c.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := c.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	k = c.keyPrefix + k
+	for {
+		swapped = false
+		err = c.c.Watch(func(tx *redis.Tx) error {
+			data, err := tx.Get(k).Result()
+			if err == redis.Nil || (err == nil && data != string(oldData)) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(func(pipe redis.Pipeliner) error {
+				pipe.Set(k, string(newData), 0)
+				return nil
+			})
+			if err == nil {
+				swapped = true
+			}
+			return err
+		}, k)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return swapped, nil
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value,
+// using Redis' INCRBY command.
+// Redis stores the value as decimal number, so Incr requires encoding.JSON as codec,
+// otherwise an error matching gokv.ErrUnsupported is returned.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
+// The key must not be "".
+func (c Client) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+	if err := util.CheckCounterCodec(c.codec); err != nil {
+		return 0, err
+	}
+
+	k = c.keyPrefix + k
+	value, err := c.c.IncrBy(k, delta).Result()
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	return value, nil
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// It uses the SCAN command, so the keys are passed in no particular order,
+// and it doesn't block the Redis server like the KEYS command would.
+// As documented for SCAN, a key can be passed more than once
+// if the keyspace is modified during the iteration.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	match := globEscaper.Replace(c.keyPrefix+prefix) + "*"
+	var cursor uint64
+	for {
+		keys, next, err := c.c.Scan(cursor, match, 100).Result()
+		if err != nil {
+			return wrapError(err)
+		}
+		for _, k := range keys {
+			if err := fn(strings.TrimPrefix(k, c.keyPrefix)); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Clear deletes all key-value pairs of the client's DB with the FLUSHDB command.
+// If the client has a key prefix (see Options.KeyPrefix and WithPrefix),
+// only the keys with the prefix are deleted, by scanning for them with SCAN and deleting them with DEL.
+func (c Client) Clear() error {
+	if c.keyPrefix == "" {
+		return wrapError(c.c.FlushDB().Err())
+	}
+
+	match := globEscaper.Replace(c.keyPrefix) + "*"
+	var cursor uint64
+	for {
+		keys, next, err := c.c.Scan(cursor, match, 100).Result()
+		if err != nil {
+			return wrapError(err)
+		}
+		if len(keys) > 0 {
+			if err := c.c.Del(keys...).Err(); err != nil {
+				return wrapError(err)
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Stats returns the number of keys in the client's DB (DBSIZE)
+// and the memory usage and keyspace hits, misses and evictions of the Redis server (INFO).
+// The latter apply to the whole server, not only to the client's DB.
+// If the client has a key prefix (see Options.KeyPrefix and WithPrefix),
+// the keys with the prefix are counted with SCAN instead.
+func (c Client) Stats() (gokv.StoreStats, error) {
+	result := gokv.UnknownStats
+	if c.keyPrefix == "" {
+		keys, err := c.c.DBSize().Result()
+		if err != nil {
+			return result, wrapError(err)
+		}
+		result.Keys = keys
+	} else {
+		var keys int64
+		err := c.Keys("", func(k string) error {
+			keys++
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Keys = keys
+	}
+
+	info, err := c.c.Info("memory").Result()
+	if err != nil {
+		return result, wrapError(err)
+	}
+	result.Bytes = infoValue(info, "used_memory")
+	info, err = c.c.Info("stats").Result()
+	if err != nil {
+		return result, wrapError(err)
+	}
+	result.Hits = infoValue(info, "keyspace_hits")
+	result.Misses = infoValue(info, "keyspace_misses")
+	result.Evictions = infoValue(info, "evicted_keys")
+	return result, nil
+}
+
+// infoValue returns the integer value of the given field in the output of the INFO command,
+// or -1 if the field is missing.
+func infoValue(info, field string) int64 {
+	for _, line := range strings.Split(info, "\r\n") {
+		if !strings.HasPrefix(line, field+":") {
+			continue
+		}
+		value, err := strconv.ParseInt(strings.TrimPrefix(line, field+":"), 10, 64)
+		if err != nil {
+			return -1
+		}
+		return value
+	}
+	return -1
+}
+
+// wrapError wraps errors of the go-redis client into gokv's errors where possible.
+// go-redis doesn't export the error for a closed client, so its message is compared.
+func wrapError(err error) error {
+	if err != nil && err.Error() == "redis: client is closed" {
+		return util.WrapError(gokv.ErrClosed, err)
+	}
 	return err
 }
 
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// It subscribes to Redis' keyspace notifications, which must be enabled on the server
+// with the "notify-keyspace-events" setting, including at least the classes "K", "$", "g" and "x"
+// (for example "K$gx"). Otherwise no events are sent.
+// The notifications don't contain values, so the value is retrieved when the notification arrives.
+// If the key doesn't exist anymore at that time, no EventPut event is sent, but an EventDelete event follows.
+// Expired and evicted key-value pairs lead to EventDelete events.
+// The go-redis client reconnects automatically when the connection is lost,
+// but notifications that are published during the reconnection are lost, which Redis doesn't report.
+// The channel is closed when the context is canceled.
+func (c Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	keyspacePrefix := "__keyspace@" + strconv.Itoa(c.c.Options().DB) + "__:"
+	pubSub := c.c.PSubscribe(keyspacePrefix + globEscaper.Replace(c.keyPrefix+prefix) + "*")
+	// Wait for the confirmation, so that no changes after returning are missed
+	if _, err := pubSub.Receive(); err != nil {
+		pubSub.Close()
+		return nil, wrapError(err)
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer pubSub.Close()
+		messages := pubSub.Channel()
+		for {
+			var msg *redis.Message
+			var ok bool
+			select {
+			case msg, ok = <-messages:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			k := strings.TrimPrefix(msg.Channel, keyspacePrefix)
+			e := gokv.Event{
+				Key: strings.TrimPrefix(k, c.keyPrefix),
+			}
+			switch msg.Payload {
+			case "set", "rename_to", "incrby", "incrbyfloat", "append", "setrange":
+				// INCR, DECR and DECRBY are notified as "incrby" as well
+				data, err := c.c.Get(k).Bytes()
+				if err == redis.Nil {
+					continue
+				} else if err != nil {
+					return
+				}
+				e.Type = gokv.EventPut
+				e.Value = data
+			case "del", "expired", "evicted", "rename_from":
+				e.Type = gokv.EventDelete
+			default:
+				// Other commands like EXPIRE don't change the value
+				continue
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// prefixKeys returns the given keys with the key prefix prepended.
+func (c Client) prefixKeys(keys []string) []string {
+	if c.keyPrefix == "" {
+		return keys
+	}
+	result := make([]string, len(keys))
+	for i, k := range keys {
+		result[i] = c.keyPrefix + k
+	}
+	return result
+}
+
+// WithPrefix returns a client that prepends the given prefix to all keys,
+// in addition to the client's own key prefix (see Options.KeyPrefix).
+// This allows to use separate namespaces in the same Redis DB, for example one for each tenant.
+// Keys that are returned by Keys and Watch don't contain the prefix.
+//
+// The returned client shares the connection pool with the original client,
+// so closing either of them closes the connection pool.
+func (c Client) WithPrefix(prefix string) Client {
+	c.keyPrefix += prefix
+	return c
+}
+
+// globEscaper escapes the characters that have a special meaning in Redis' glob-style patterns.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// retryableErrPrefixes are the prefixes of the Redis server's errors that are transient,
+// for example while the server loads the dataset into memory or during a failover.
+var retryableErrPrefixes = []string{"LOADING ", "READONLY ", "MASTERDOWN ", "TRYAGAIN ", "CLUSTERDOWN "}
+
+// IsRetryable returns true for errors of the Redis server that are transient,
+// like when the server is still loading the dataset into memory or a replica was promoted during a failover.
+// It's used by the resilience package, which retries network errors anyway.
+func (c Client) IsRetryable(err error) bool {
+	for _, prefix := range retryableErrPrefixes {
+		if strings.HasPrefix(err.Error(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // Close closes the client.
 // It must be called to release any open resources.
 func (c Client) Close() error {
@@ -88,17 +613,21 @@ type Options struct {
 	// DB to use.
 	// Optional (0 by default).
 	DB int
+	// Prefix that's prepended to all keys, for example "tenantA:".
+	// Can be used as namespace when multiple applications or tenants share a Redis DB.
+	// Optional ("" by default).
+	KeyPrefix string
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
 }
 
 // DefaultOptions is an Options object with default values.
-// Address: "localhost:6379", Password: "", DB: 0, Codec: encoding.JSON
+// Address: "localhost:6379", Password: "", DB: 0, KeyPrefix: "", Codec: encoding.JSON
 var DefaultOptions = Options{
 	Address: "localhost:6379",
 	Codec:   encoding.JSON,
-	// No need to set Password or DB because their Go zero values are fine for that.
+	// No need to set Password, DB or KeyPrefix because their Go zero values are fine for that.
 }
 
 // NewClient creates a new Redis client.
@@ -127,7 +656,40 @@ func NewClient(options Options) (Client, error) {
 	}
 
 	result.c = client
+	result.keyPrefix = options.KeyPrefix
 	result.codec = options.Codec
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("redis", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Address: u.Host,
+	}
+	if u.User != nil {
+		options.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		var err error
+		if options.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("Invalid DB in the URL path: %w", err)
+		}
+	}
+	q := util.NewURLQuery(u)
+	q.String("keyPrefix", &options.KeyPrefix)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
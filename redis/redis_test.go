@@ -181,6 +181,181 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestContext(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestKeys(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestLister(client, t)
+}
+
+// TestTTL tests if storing key-value pairs with a TTL works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestTTL(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestExpiringStore(client, t)
+}
+
+// TestBytesWithTTL tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestBytesWithTTL(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestTTLStore(client, t)
+}
+
+// TestBatch tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestBatch(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestBatchStore(client, t)
+}
+
+// TestAtomic tests if the conditional storing of values works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestAtomic(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestAtomicStore(client, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestWatch(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
+// TestCounter tests if incrementing values works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestCounter(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestCounter(client, t)
+}
+
+// TestPrefix tests if key prefixes work properly as namespaces.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestPrefix(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	tenantA := client.WithPrefix("tenantA:")
+	test.TestStore(tenantA, t)
+	test.TestLister(tenantA, t)
+	test.TestBatchStore(tenantA, t)
+	test.TestAtomicStore(tenantA, t)
+	test.TestCounter(tenantA, t)
+	test.TestNamespaces(tenantA, client.WithPrefix("tenantB:"), t)
+	test.TestClearer(tenantA, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestClear(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestClearer(client, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+//
+// Note: This test is only executed if the initial connection to Redis works.
+func TestStats(t *testing.T) {
+	if !checkConnection(testDbNumber) {
+		t.Skip("No connection to Redis could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestStatsStore(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection(number int) bool {
 	client := goredis.NewClient(&goredis.Options{
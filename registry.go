@@ -0,0 +1,74 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Factory creates a Store from the given URL.
+// The URL's scheme is the one the factory was registered for.
+type Factory func(u *url.URL) (Store, error)
+
+var (
+	factoriesLock sync.RWMutex
+	factories     = make(map[string]Factory)
+)
+
+// Register makes a Store implementation available by the given URL scheme for Open.
+// The packages in this repository register themselves when they're imported,
+// so importing a package for its side effect is enough:
+//
+//	import _ "github.com/philippgille/gokv/redis"
+//
+// If Register is called twice with the same scheme or if factory is nil, it panics.
+func Register(scheme string, factory Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	if factory == nil {
+		panic("gokv: Register factory is nil")
+	}
+	if _, dup := factories[scheme]; dup {
+		panic("gokv: Register called twice for scheme " + scheme)
+	}
+	factories[scheme] = factory
+}
+
+// Schemes returns a sorted list of the registered URL schemes.
+func Schemes() []string {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+	result := make([]string, 0, len(factories))
+	for scheme := range factories {
+		result = append(result, scheme)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Open creates a Store from the given URL, for example "redis://localhost:6379/15?codec=gob".
+// The URL's scheme selects the implementation, which must have been registered with Register,
+// typically by importing its package.
+// How the rest of the URL is mapped to the implementation's options is described in the package documentation
+// of each implementation. Query parameters are generally named like the fields of the implementation's Options,
+// starting with a lowercase letter, and unknown query parameters lead to an error.
+// You must call the Close() method on the store when you're done working with it.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, errors.New("The URL doesn't contain a scheme")
+	}
+
+	factoriesLock.RLock()
+	factory, found := factories[u.Scheme]
+	factoriesLock.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("No store is registered for the scheme %q (forgotten import?)", u.Scheme)
+	}
+	return factory(u)
+}
@@ -0,0 +1,63 @@
+package resilience
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a circuit breaker that opens after a number of consecutive failures.
+// While it's open, calls are rejected. After the timeout it lets a single trial call through (half-open),
+// which closes it again when it succeeds and opens it again when it fails.
+type breaker struct {
+	lock      sync.Mutex
+	threshold int
+	timeout   time.Duration
+	// failures is the number of consecutive failures while the breaker is closed.
+	failures int
+	// openedAt is the time when the breaker was opened, or the zero time while it's closed.
+	openedAt time.Time
+	// trial is true while the trial call of the half-open breaker is running.
+	trial bool
+}
+
+// allow returns ErrOpen if the call must be rejected.
+func (b *breaker) allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	if b.trial || time.Since(b.openedAt) < b.timeout {
+		return ErrOpen
+	}
+	b.trial = true
+	return nil
+}
+
+// release records that a call that was allowed was aborted by the caller,
+// which says nothing about the availability of the store.
+// If it was the trial call, the next call is the trial call instead.
+func (b *breaker) release() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.trial = false
+}
+
+// record records the result of a call that was allowed.
+// Only failures that indicate that the store is unavailable count, other errors count as success.
+func (b *breaker) record(failed bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !failed {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.trial = false
+		return
+	}
+	b.failures++
+	if b.trial || b.failures >= b.threshold {
+		b.failures = 0
+		b.openedAt = time.Now()
+		b.trial = false
+	}
+}
@@ -0,0 +1,20 @@
+/*
+Package resilience contains a gokv.Store implementation that wraps any other store
+and makes its failure behavior consistent across backends:
+Operations that failed with a transient error are retried with exponential backoff and jitter,
+and a circuit breaker rejects operations (with ErrOpen) while the store seems to be unavailable.
+
+	client, err := dynamodb.NewClient(dynamodb.DefaultOptions)
+	...
+	options := resilience.DefaultOptions
+	options.Store = client
+	options.MaxRetries = 5
+	store, err := resilience.NewStore(options)
+
+Which errors are transient is decided per backend: Network errors and timeouts are retryable by default,
+and stores that implement Classifier (like the dynamodb, etcd, redis, tablestorage and tablestore clients)
+additionally classify the errors of their client library, like throttling errors.
+Codec errors and the errors of the gokv package (like gokv.ErrEmptyKey) are never retried.
+A not found value isn't an error in gokv, so it's not retried either.
+*/
+package resilience
@@ -0,0 +1,560 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/philippgille/gokv"
+)
+
+// ErrOpen is returned when the circuit breaker is open, so the call wasn't passed to the wrapped store.
+var ErrOpen = errors.New("The circuit breaker is open")
+
+// Classifier is implemented by stores that can tell which of their errors are transient,
+// for example because the store's client library defines error codes for throttling or unavailability.
+// Store implementations don't need to import this package to implement it.
+type Classifier interface {
+	// IsRetryable returns true if the operation that returned err can be retried.
+	// It's only called for errors that aren't retryable according to DefaultIsRetryable,
+	// so it only needs to handle the errors of the store's client library.
+	IsRetryable(err error) bool
+}
+
+// nonRetryableErrors are errors that are never retried,
+// because retrying the operation would lead to the same error.
+var nonRetryableErrors = []error{
+	gokv.ErrEmptyKey,
+	gokv.ErrNilValue,
+	gokv.ErrClosed,
+	gokv.ErrUnsupported,
+	gokv.ErrKeyTooLong,
+	gokv.ErrValueTooLarge,
+	gokv.ErrConflict,
+	context.Canceled,
+	ErrOpen,
+}
+
+// DefaultIsRetryable returns true for network errors, including timeouts (like context.DeadlineExceeded).
+// Errors that are caused by the caller's context being done are never retried, see Store.
+// Codec errors, errors of the gokv package (like gokv.ErrEmptyKey) and other errors aren't retryable.
+func DefaultIsRetryable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Store is a gokv.Store that wraps another store and retries failed operations with exponential backoff and jitter.
+// A circuit breaker rejects operations while the wrapped store seems to be unavailable,
+// so that callers fail fast instead of waiting for timeouts and retries.
+//
+// Only operations that failed with a retryable error are retried, see Options.IsRetryable.
+// Operations that aren't idempotent (SetIfAbsent, CompareAndSwap, Incr, Update and Keys, which calls its fn again)
+// are only retried if Options.RetryNonIdempotent is set, and SetReader is never retried, because the reader is consumed.
+// When the context that's passed to a context-aware method is done, the operation isn't retried,
+// and its error doesn't count as a failure for the circuit breaker.
+//
+// Store implements all optional interfaces of the gokv package, whether the wrapped store implements them or not.
+// Use gokv.Supports() to find out which ones can actually be used.
+type Store struct {
+	store              gokv.Store
+	maxRetries         int
+	initialBackoff     time.Duration
+	maxBackoff         time.Duration
+	multiplier         float64
+	jitter             float64
+	retryNonIdempotent bool
+	isRetryable        func(err error) bool
+	// breaker is nil if the circuit breaker is disabled.
+	breaker *breaker
+}
+
+// Set stores the given value for the given key.
+// See gokv.Store.Set() for details.
+func (s Store) Set(k string, v interface{}) error {
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return s.store.Set(k, v)
+	})
+}
+
+// Get retrieves the value for the given key.
+// See gokv.Store.Get() for details.
+func (s Store) Get(k string, v interface{}) (found bool, err error) {
+	err = s.do(context.Background(), true, func(ctx context.Context) error {
+		found, err = s.store.Get(k, v)
+		return err
+	})
+	return found, err
+}
+
+// Delete deletes the stored value for the given key.
+// See gokv.Store.Delete() for details.
+func (s Store) Delete(k string) error {
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return s.store.Delete(k)
+	})
+}
+
+// Close closes the wrapped store.
+// It's neither retried nor rejected by the circuit breaker.
+func (s Store) Close() error {
+	return s.store.Close()
+}
+
+// Unwrap returns the wrapped store.
+func (s Store) Unwrap() gokv.Store {
+	return s.store
+}
+
+// Supports reports whether the store supports the optional interface that iface points to,
+// which is the case if the wrapped store supports it or if the methods fall back to the basic methods.
+// See the package-level function gokv.Supports() for details.
+func (s Store) Supports(iface interface{}) bool {
+	switch iface.(type) {
+	case *gokv.BatchStore, *gokv.ContextStore:
+		return true
+	case *gokv.Clearer, *gokv.StatsStore:
+		return gokv.Supports(s.store, iface) || gokv.Supports(s.store, (*gokv.Lister)(nil))
+	}
+	return gokv.Supports(s.store, iface)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// See gokv.RawStore.SetBytes() for details.
+func (s Store) SetBytes(k string, v []byte) error {
+	rawStore, ok := s.store.(gokv.RawStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return rawStore.SetBytes(k, v)
+	})
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// See gokv.RawStore.GetBytes() for details.
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	rawStore, ok := s.store.(gokv.RawStore)
+	if !ok {
+		return nil, false, gokv.ErrUnsupported
+	}
+	err = s.do(context.Background(), true, func(ctx context.Context) error {
+		v, found, err = rawStore.GetBytes(k)
+		return err
+	})
+	return v, found, err
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// It's never retried, because the bytes were already read from r.
+// See gokv.StreamStore.SetReader() for details.
+func (s Store) SetReader(k string, r io.Reader) error {
+	streamStore, ok := s.store.(gokv.StreamStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return s.do(context.Background(), false, func(ctx context.Context) error {
+		return streamStore.SetReader(k, r)
+	})
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// Only retrieving the reader is retried, not reading from it.
+// See gokv.StreamStore.GetReader() for details.
+func (s Store) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	streamStore, ok := s.store.(gokv.StreamStore)
+	if !ok {
+		return nil, false, gokv.ErrUnsupported
+	}
+	err = s.do(context.Background(), true, func(ctx context.Context) error {
+		r, found, err = streamStore.GetReader(k)
+		return err
+	})
+	return r, found, err
+}
+
+// SetContext stores the given value for the given key.
+// The operation isn't retried anymore when the context is done.
+// If the wrapped store doesn't implement gokv.ContextStore, the context is only checked before each attempt.
+// See gokv.ContextStore.SetContext() for details.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	return s.do(ctx, true, func(ctx context.Context) error {
+		if contextStore, ok := s.store.(gokv.ContextStore); ok {
+			return contextStore.SetContext(ctx, k, v)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.store.Set(k, v)
+	})
+}
+
+// GetContext retrieves the value for the given key.
+// The operation isn't retried anymore when the context is done.
+// If the wrapped store doesn't implement gokv.ContextStore, the context is only checked before each attempt.
+// See gokv.ContextStore.GetContext() for details.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	err = s.do(ctx, true, func(ctx context.Context) error {
+		if contextStore, ok := s.store.(gokv.ContextStore); ok {
+			found, err = contextStore.GetContext(ctx, k, v)
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		found, err = s.store.Get(k, v)
+		return err
+	})
+	return found, err
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The operation isn't retried anymore when the context is done.
+// If the wrapped store doesn't implement gokv.ContextStore, the context is only checked before each attempt.
+// See gokv.ContextStore.DeleteContext() for details.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	return s.do(ctx, true, func(ctx context.Context) error {
+		if contextStore, ok := s.store.(gokv.ContextStore); ok {
+			return contextStore.DeleteContext(ctx, k)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.store.Delete(k)
+	})
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// It's only retried if Options.RetryNonIdempotent is set, because fn is called again for the keys of the failed attempt.
+// See gokv.Lister.Keys() for details.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	lister, ok := s.store.(gokv.Lister)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return s.do(context.Background(), s.retryNonIdempotent, func(ctx context.Context) error {
+		return lister.Keys(prefix, fn)
+	})
+}
+
+// Clear deletes all key-value pairs of the wrapped store.
+// See the package-level function gokv.Clear() for details.
+func (s Store) Clear() error {
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return gokv.Clear(s.store)
+	})
+}
+
+// Stats returns statistics about the wrapped store.
+// See the package-level function gokv.Stats() for details.
+func (s Store) Stats() (stats gokv.StoreStats, err error) {
+	err = s.do(context.Background(), true, func(ctx context.Context) error {
+		stats, err = gokv.Stats(s.store)
+		return err
+	})
+	return stats, err
+}
+
+// SetWithTTL stores the given value for the given key, with the given time to live.
+// The TTL starts again with each attempt.
+// See gokv.ExpiringStore.SetWithTTL() for details.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	expiringStore, ok := s.store.(gokv.ExpiringStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return expiringStore.SetWithTTL(k, v, ttl)
+	})
+}
+
+// SetMulti stores the given values for the given keys.
+// See the package-level function gokv.SetMulti() for details.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return gokv.SetMulti(s.store, keys, vs)
+	})
+}
+
+// GetMulti retrieves the values for the given keys.
+// See the package-level function gokv.GetMulti() for details.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	err = s.do(context.Background(), true, func(ctx context.Context) error {
+		found, err = gokv.GetMulti(s.store, keys, vs)
+		return err
+	})
+	return found, err
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// See the package-level function gokv.DeleteMulti() for details.
+func (s Store) DeleteMulti(keys []string) error {
+	return s.do(context.Background(), true, func(ctx context.Context) error {
+		return gokv.DeleteMulti(s.store, keys)
+	})
+}
+
+// SetIfAbsent stores the given value for the given key,
+// but only if no value is stored for the key yet.
+// It's only retried if Options.RetryNonIdempotent is set,
+// because the failed attempt might have stored the value, so a retry would return false.
+// See gokv.AtomicStore.SetIfAbsent() for details.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	err = s.do(context.Background(), s.retryNonIdempotent, func(ctx context.Context) error {
+		stored, err = gokv.SetIfAbsent(s.store, k, v)
+		return err
+	})
+	return stored, err
+}
+
+// CompareAndSwap stores the new value for the given key,
+// but only if the currently stored value is equal to the old value.
+// It's only retried if Options.RetryNonIdempotent is set,
+// because the failed attempt might have swapped the value, so a retry would return false.
+// See gokv.AtomicStore.CompareAndSwap() for details.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	err = s.do(context.Background(), s.retryNonIdempotent, func(ctx context.Context) error {
+		swapped, err = gokv.CompareAndSwap(s.store, k, old, new)
+		return err
+	})
+	return swapped, err
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// It's only retried if Options.RetryNonIdempotent is set,
+// because the failed attempt might have incremented the value, so a retry would increment it twice.
+// See gokv.Counter.Incr() for details.
+func (s Store) Incr(k string, delta int64) (result int64, err error) {
+	err = s.do(context.Background(), s.retryNonIdempotent, func(ctx context.Context) error {
+		result, err = gokv.Incr(s.store, k, delta)
+		return err
+	})
+	return result, err
+}
+
+// Update calls fn with a new transaction of the wrapped store.
+// It's only retried if Options.RetryNonIdempotent is set,
+// because the transaction of the failed attempt might have been committed.
+// See gokv.Transactional.Update() for details.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	return s.do(context.Background(), s.retryNonIdempotent, func(ctx context.Context) error {
+		return gokv.Update(s.store, fn)
+	})
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// Only starting the watch is retried, a watch that fails later closes the channel as usual.
+// See gokv.Watcher.Watch() for details.
+func (s Store) Watch(ctx context.Context, prefix string) (events <-chan gokv.Event, err error) {
+	watcher, ok := s.store.(gokv.Watcher)
+	if !ok {
+		return nil, gokv.ErrUnsupported
+	}
+	err = s.do(ctx, true, func(ctx context.Context) error {
+		events, err = watcher.Watch(ctx, prefix)
+		return err
+	})
+	return events, err
+}
+
+// do calls fn until it succeeds, fails with an error that's not retryable or the retries are exhausted,
+// waiting with exponential backoff between the attempts.
+// When the context is done, no further attempt is made and the error of the last attempt is returned.
+// An error that's caused by the context being done (context.Canceled or context.DeadlineExceeded)
+// is neither retried nor counted as a failure by the circuit breaker, because it's the caller that gave up, not the store.
+// If retry is false, fn is called only once.
+func (s Store) do(ctx context.Context, retry bool, fn func(ctx context.Context) error) error {
+	backoff := s.initialBackoff
+	for attempt := 0; ; attempt++ {
+		if s.breaker != nil {
+			if err := s.breaker.allow(); err != nil {
+				return err
+			}
+		}
+		err := fn(ctx)
+		if err != nil && ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			if s.breaker != nil {
+				s.breaker.release()
+			}
+			return err
+		}
+		retryable := err != nil && s.retryable(err)
+		if s.breaker != nil {
+			s.breaker.record(retryable)
+		}
+		if !retryable || !retry || attempt >= s.maxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(s.withJitter(backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = time.Duration(float64(backoff) * s.multiplier)
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// retryable returns true if the operation that returned err can be retried.
+func (s Store) retryable(err error) bool {
+	for _, nonRetryableErr := range nonRetryableErrors {
+		if errors.Is(err, nonRetryableErr) {
+			return false
+		}
+	}
+	return s.isRetryable(err)
+}
+
+// withJitter returns the given backoff, randomly increased or decreased by up to the jitter factor,
+// so that clients that failed at the same time don't retry at the same time.
+func (s Store) withJitter(backoff time.Duration) time.Duration {
+	return time.Duration(float64(backoff) * (1 + s.jitter*(2*rand.Float64()-1)))
+}
+
+// classifier returns the Classifier of the given store or of a store that's wrapped by it,
+// for example by a gokv.PrefixStore, or nil if there's none.
+func classifier(store gokv.Store) Classifier {
+	for store != nil {
+		if c, ok := store.(Classifier); ok {
+			return c
+		}
+		unwrapper, ok := store.(interface{ Unwrap() gokv.Store })
+		if !ok {
+			return nil
+		}
+		store = unwrapper.Unwrap()
+	}
+	return nil
+}
+
+// Options are the options for the resilient store.
+type Options struct {
+	// The store to wrap.
+	Store gokv.Store
+	// Maximum number of retries after the first attempt of an operation.
+	// A negative value disables retries, which is useful if only the circuit breaker is needed.
+	// Optional (3 by default).
+	MaxRetries int
+	// Time to wait before the first retry.
+	// Optional (100 milliseconds by default).
+	InitialBackoff time.Duration
+	// Maximum time to wait before a retry.
+	// Optional (5 seconds by default).
+	MaxBackoff time.Duration
+	// Factor by which the time to wait is multiplied after each retry. Must be at least 1.
+	// Optional (2 by default).
+	Multiplier float64
+	// Factor by which the time to wait is randomly increased or decreased. Must be between 0 and 1.
+	// Optional (0.2 by default).
+	Jitter float64
+	// Retry operations that aren't idempotent (SetIfAbsent, CompareAndSwap, Incr, Update and Keys),
+	// for which a retry can lead to a different result if the failed attempt was applied by the store
+	// despite the error, for example after a timeout.
+	// Optional (false by default).
+	RetryNonIdempotent bool
+	// Returns true if the operation that returned err can be retried.
+	// By default an error is retryable if DefaultIsRetryable returns true for it
+	// or if the store implements Classifier and its IsRetryable method returns true.
+	// If set, only this function is used.
+	// Errors of the gokv package (like gokv.ErrEmptyKey), context.Canceled and ErrOpen are never retried.
+	// Optional (nil by default).
+	IsRetryable func(err error) bool
+	// Number of consecutive failed attempts (with a retryable error) after which the circuit breaker opens.
+	// Other errors, like codec errors, don't count as failures, because the store was available.
+	// A negative value disables the circuit breaker.
+	// Optional (5 by default).
+	BreakerThreshold int
+	// Time after which an open circuit breaker lets a trial operation through,
+	// which closes it if it succeeds and opens it again if it fails.
+	// Optional (30 seconds by default).
+	BreakerTimeout time.Duration
+}
+
+// DefaultOptions is an Options object with default values.
+// MaxRetries: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 5 * time.Second, Multiplier: 2, Jitter: 0.2,
+// RetryNonIdempotent: false, IsRetryable: nil, BreakerThreshold: 5, BreakerTimeout: 30 * time.Second
+var DefaultOptions = Options{
+	MaxRetries:       3,
+	InitialBackoff:   100 * time.Millisecond,
+	MaxBackoff:       5 * time.Second,
+	Multiplier:       2,
+	Jitter:           0.2,
+	BreakerThreshold: 5,
+	BreakerTimeout:   30 * time.Second,
+	// No need to set RetryNonIdempotent or IsRetryable because their Go zero values are fine.
+}
+
+// NewStore creates a new resilient store that wraps the store in the options.
+// Closing the returned store closes the wrapped store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	// Precondition check
+	if options.Store == nil {
+		return result, errors.New("The Store in the options must not be nil")
+	}
+	if options.InitialBackoff < 0 || options.MaxBackoff < 0 || options.BreakerTimeout < 0 {
+		return result, errors.New("The InitialBackoff, MaxBackoff and BreakerTimeout in the options must not be negative")
+	}
+	if options.Multiplier != 0 && options.Multiplier < 1 {
+		return result, fmt.Errorf("The Multiplier in the options must be at least 1, but was: %v", options.Multiplier)
+	}
+	if options.Jitter < 0 || options.Jitter > 1 {
+		return result, fmt.Errorf("The Jitter in the options must be between 0 and 1, but was: %v", options.Jitter)
+	}
+
+	// Set default values
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DefaultOptions.MaxRetries
+	}
+	if options.InitialBackoff == 0 {
+		options.InitialBackoff = DefaultOptions.InitialBackoff
+	}
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = DefaultOptions.MaxBackoff
+	}
+	if options.Multiplier == 0 {
+		options.Multiplier = DefaultOptions.Multiplier
+	}
+	if options.Jitter == 0 {
+		options.Jitter = DefaultOptions.Jitter
+	}
+	if options.BreakerThreshold == 0 {
+		options.BreakerThreshold = DefaultOptions.BreakerThreshold
+	}
+	if options.BreakerTimeout == 0 {
+		options.BreakerTimeout = DefaultOptions.BreakerTimeout
+	}
+	if options.IsRetryable == nil {
+		c := classifier(options.Store)
+		options.IsRetryable = func(err error) bool {
+			return DefaultIsRetryable(err) || c != nil && c.IsRetryable(err)
+		}
+	}
+
+	result.store = options.Store
+	result.maxRetries = options.MaxRetries
+	result.initialBackoff = options.InitialBackoff
+	result.maxBackoff = options.MaxBackoff
+	result.multiplier = options.Multiplier
+	result.jitter = options.Jitter
+	result.retryNonIdempotent = options.RetryNonIdempotent
+	result.isRetryable = options.IsRetryable
+	if options.BreakerThreshold > 0 {
+		result.breaker = &breaker{
+			threshold: options.BreakerThreshold,
+			timeout:   options.BreakerTimeout,
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,427 @@
+package resilience_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/resilience"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	store := createStore(t, gomap.NewStore(gomap.DefaultOptions), resilience.DefaultOptions)
+	defer store.Close()
+	test.TestStore(store, t)
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	store := createStore(t, gomap.NewStore(gomap.DefaultOptions), resilience.DefaultOptions)
+	defer store.Close()
+	test.TestTypes(store, t)
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store := createStore(t, gomap.NewStore(gomap.DefaultOptions), resilience.DefaultOptions)
+	defer store.Close()
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test missing store
+	_, err := resilience.NewStore(resilience.DefaultOptions)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test invalid options
+	invalidOptions := []resilience.Options{
+		{InitialBackoff: -time.Second},
+		{Multiplier: 0.5},
+		{Jitter: 1.5},
+	}
+	for _, options := range invalidOptions {
+		options.Store = gomap.NewStore(gomap.DefaultOptions)
+		_, err = resilience.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for options %+v", options)
+		}
+	}
+}
+
+// TestRetry tests if operations that failed with a retryable error are retried until they succeed
+// or the retries are exhausted, and if operations that failed with other errors aren't retried.
+func TestRetry(t *testing.T) {
+	flaky := newFlakyStore()
+	store := createStore(t, flaky, resilience.Options{
+		MaxRetries:       3,
+		BreakerThreshold: -1,
+	})
+	defer store.Close()
+
+	// Retryable error, with success after the retries
+	flaky.fail(3, &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky.expectCalls(t, 4)
+
+	// Retryable error, with the retries being exhausted
+	flaky.fail(10, context.DeadlineExceeded)
+	_, err = store.Get("foo", new(string))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+	flaky.expectCalls(t, 4)
+
+	// Errors that aren't retryable
+	nonRetryableErrs := []error{
+		&json.UnsupportedTypeError{Type: reflect.TypeOf(make(chan int))},
+		errors.New("some error"),
+		gokv.ErrValueTooLarge,
+	}
+	for _, nonRetryableErr := range nonRetryableErrs {
+		flaky.fail(10, nonRetryableErr)
+		err = store.Set("foo", "bar")
+		if err != nonRetryableErr {
+			t.Errorf("Expected %v, but was: %v", nonRetryableErr, err)
+		}
+		flaky.expectCalls(t, 1)
+	}
+
+	// Errors of the wrapped store, even if the error is classified as retryable
+	flaky.fail(0, nil)
+	flaky.retryableErr = gokv.ErrEmptyKey
+	err = store.Set("", "bar")
+	if err != gokv.ErrEmptyKey {
+		t.Errorf("Expected gokv.ErrEmptyKey, but was: %v", err)
+	}
+	flaky.expectCalls(t, 1)
+}
+
+// TestClassifier tests if the store's IsRetryable method is used to classify errors,
+// also when the store is wrapped, and if the IsRetryable option replaces it.
+func TestClassifier(t *testing.T) {
+	throttled := errors.New("throttled")
+	flaky := newFlakyStore()
+	flaky.retryableErr = throttled
+	store := createStore(t, gokv.WithPrefix(flaky, "prefix:"), resilience.Options{
+		BreakerThreshold: -1,
+	})
+	defer store.Close()
+
+	flaky.fail(2, throttled)
+	err := store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky.expectCalls(t, 3)
+
+	store = createStore(t, flaky, resilience.Options{
+		BreakerThreshold: -1,
+		IsRetryable: func(err error) bool {
+			return false
+		},
+	})
+	flaky.fail(2, throttled)
+	err = store.Delete("foo")
+	if err != throttled {
+		t.Errorf("Expected %v, but was: %v", throttled, err)
+	}
+	flaky.expectCalls(t, 1)
+}
+
+// TestNonIdempotent tests if operations that aren't idempotent are only retried if it's configured.
+func TestNonIdempotent(t *testing.T) {
+	flaky := newFlakyStore()
+	store := createStore(t, flaky, resilience.Options{
+		BreakerThreshold: -1,
+	})
+	defer store.Close()
+
+	flaky.fail(1, context.DeadlineExceeded)
+	_, err := store.Incr("foo", 1)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+	flaky.expectCalls(t, 1)
+
+	store = createStore(t, flaky, resilience.Options{
+		BreakerThreshold:   -1,
+		RetryNonIdempotent: true,
+	})
+	flaky.fail(1, context.DeadlineExceeded)
+	result, err := store.Incr("foo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 1 {
+		t.Errorf("Expected 1, but was: %v", result)
+	}
+	flaky.expectCalls(t, 2)
+}
+
+// TestContext tests if no further attempt is made when the context is done.
+func TestContext(t *testing.T) {
+	flaky := newFlakyStore()
+	store := createStore(t, flaky, resilience.Options{
+		InitialBackoff:   time.Hour,
+		MaxBackoff:       time.Hour,
+		BreakerThreshold: -1,
+	})
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	flaky.fail(10, context.DeadlineExceeded)
+	err := store.SetContext(ctx, "foo", "bar")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+	flaky.expectCalls(t, 1)
+}
+
+// TestContextBreaker tests if errors that are caused by the caller's context
+// don't count as failures for the circuit breaker, even though context.DeadlineExceeded is a net.Error.
+func TestContextBreaker(t *testing.T) {
+	flaky := newFlakyStore()
+	store := createStore(t, flaky, resilience.Options{
+		MaxRetries:       -1,
+		BreakerThreshold: 1,
+		BreakerTimeout:   100 * time.Millisecond,
+	})
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	flaky.fail(3, context.DeadlineExceeded)
+	for i := 0; i < 3; i++ {
+		err := store.SetContext(ctx, "foo", "bar")
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+		}
+	}
+	// The breaker is still closed
+	err := store.SetContext(context.Background(), "foo", "bar")
+	if err != nil {
+		t.Error(err)
+	}
+	flaky.expectCalls(t, 4)
+
+	// The same error with a context that isn't done opens it
+	flaky.fail(1, context.DeadlineExceeded)
+	err = store.SetContext(context.Background(), "foo", "bar")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+	err = store.SetContext(context.Background(), "foo", "bar")
+	if err != resilience.ErrOpen {
+		t.Errorf("Expected resilience.ErrOpen, but was: %v", err)
+	}
+	flaky.expectCalls(t, 1)
+
+	// A trial operation whose context is done lets the next operation be the trial operation
+	time.Sleep(150 * time.Millisecond)
+	flaky.fail(1, context.DeadlineExceeded)
+	err = store.SetContext(ctx, "foo", "bar")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+	err = store.SetContext(context.Background(), "foo", "bar")
+	if err != nil {
+		t.Error(err)
+	}
+	flaky.expectCalls(t, 2)
+}
+
+// TestBreaker tests if the circuit breaker opens after consecutive failures,
+// rejects operations while it's open and closes again after a successful trial operation.
+func TestBreaker(t *testing.T) {
+	flaky := newFlakyStore()
+	store := createStore(t, flaky, resilience.Options{
+		MaxRetries:       -1,
+		BreakerThreshold: 2,
+		BreakerTimeout:   100 * time.Millisecond,
+	})
+	defer store.Close()
+
+	// Errors that aren't retryable don't open the breaker
+	flaky.fail(3, errors.New("some error"))
+	for i := 0; i < 3; i++ {
+		store.Set("foo", "bar")
+	}
+	flaky.expectCalls(t, 3)
+
+	// Retryable errors do
+	flaky.fail(2, context.DeadlineExceeded)
+	for i := 0; i < 2; i++ {
+		store.Set("foo", "bar")
+	}
+	err := store.Set("foo", "bar")
+	if err != resilience.ErrOpen {
+		t.Errorf("Expected resilience.ErrOpen, but was: %v", err)
+	}
+	flaky.expectCalls(t, 2)
+
+	// After the timeout, a failed trial operation opens it again
+	time.Sleep(150 * time.Millisecond)
+	flaky.fail(1, context.DeadlineExceeded)
+	err = store.Set("foo", "bar")
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+	err = store.Set("foo", "bar")
+	if err != resilience.ErrOpen {
+		t.Errorf("Expected resilience.ErrOpen, but was: %v", err)
+	}
+	flaky.expectCalls(t, 1)
+
+	// A successful trial operation closes it
+	time.Sleep(150 * time.Millisecond)
+	flaky.fail(0, nil)
+	for i := 0; i < 3; i++ {
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	flaky.expectCalls(t, 3)
+}
+
+// TestSupports tests if the store only reports the optional interfaces as supported that the wrapped store supports,
+// also when the wrapped store is itself a wrapper.
+func TestSupports(t *testing.T) {
+	store := createStore(t, gomap.NewStore(gomap.DefaultOptions), resilience.Options{})
+	if !gokv.Supports(store, (*gokv.Watcher)(nil)) {
+		t.Error("Expected the resilient gomap.Store to support gokv.Watcher")
+	}
+
+	basic := createStore(t, gokv.WithPrefix(basicStore{store}, "prefix:"), resilience.Options{})
+	for _, iface := range []interface{}{(*gokv.Watcher)(nil), (*gokv.ExpiringStore)(nil), (*gokv.Counter)(nil), (*gokv.StatsStore)(nil)} {
+		if gokv.Supports(basic, iface) {
+			t.Errorf("Expected %T not to be supported", iface)
+		}
+	}
+	if !gokv.Supports(basic, (*gokv.ContextStore)(nil)) {
+		t.Error("Expected gokv.ContextStore to be supported")
+	}
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
+
+func createStore(t *testing.T, store gokv.Store, options resilience.Options) resilience.Store {
+	options.Store = store
+	if options.InitialBackoff == 0 {
+		options.InitialBackoff = time.Millisecond
+	}
+	result, err := resilience.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// flakyStore is a gomap.Store whose Set, SetContext, Get, Delete and Incr methods fail a configured number of times.
+type flakyStore struct {
+	gomap.Store
+	lock     sync.Mutex
+	failures int
+	err      error
+	calls    int
+	// retryableErr is classified as retryable by IsRetryable.
+	retryableErr error
+}
+
+func newFlakyStore() *flakyStore {
+	return &flakyStore{
+		Store: gomap.NewStore(gomap.DefaultOptions),
+	}
+}
+
+// fail lets the next n calls fail with the given error and resets the number of calls.
+func (s *flakyStore) fail(n int, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures = n
+	s.err = err
+	s.calls = 0
+}
+
+func (s *flakyStore) expectCalls(t *testing.T, expected int) {
+	t.Helper()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.calls != expected {
+		t.Errorf("Expected %v calls, but was: %v", expected, s.calls)
+	}
+}
+
+func (s *flakyStore) call() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return s.err
+	}
+	return nil
+}
+
+func (s *flakyStore) Set(k string, v interface{}) error {
+	if err := s.call(); err != nil {
+		return err
+	}
+	return s.Store.Set(k, v)
+}
+
+func (s *flakyStore) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := s.call(); err != nil {
+		return err
+	}
+	return s.Store.SetContext(ctx, k, v)
+}
+
+func (s *flakyStore) Get(k string, v interface{}) (bool, error) {
+	if err := s.call(); err != nil {
+		return false, err
+	}
+	return s.Store.Get(k, v)
+}
+
+func (s *flakyStore) Delete(k string) error {
+	if err := s.call(); err != nil {
+		return err
+	}
+	return s.Store.Delete(k)
+}
+
+func (s *flakyStore) Incr(k string, delta int64) (int64, error) {
+	if err := s.call(); err != nil {
+		return 0, err
+	}
+	return s.Store.Incr(k, delta)
+}
+
+func (s *flakyStore) IsRetryable(err error) bool {
+	return s.retryableErr != nil && err == s.retryableErr
+}
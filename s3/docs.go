@@ -1,4 +1,9 @@
 /*
 Package s3 contains an implementation of the `gokv.Store` interface for Amazon S3 and other S3-compatible services.
+
+The package registers itself for the URL scheme "s3", so a client can also be created with gokv.Open,
+for example with "s3://accessKeyID:secretAccessKey@gokv?region=foo&customEndpoint=http://localhost:9000&usePathStyleAddressing".
+The host is used as BucketName and the user info as AWSaccessKeyID and AWSsecretAccessKey,
+and the query parameters "region", "customEndpoint", "usePathStyleAddressing" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package s3
@@ -2,15 +2,21 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net/url"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -18,6 +24,7 @@ import (
 // Client is a gokv.Store implementation for S3.
 type Client struct {
 	c          *awss3.S3
+	uploader   *s3manager.Uploader
 	bucketName string
 	codec      encoding.Codec
 }
@@ -26,6 +33,14 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The context is passed to the AWS SDK.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -36,17 +51,7 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	pubObjectInput := awss3.PutObjectInput{
-		Body:   bytes.NewReader(data),
-		Bucket: &c.bucketName,
-		Key:    &k,
-	}
-	_, err = c.c.PutObject(&pubObjectInput)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return c.put(ctx, k, data)
 }
 
 // Get retrieves the stored value for the given key.
@@ -56,10 +61,99 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is passed to the AWS SDK.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
+	data, found, err := c.get(ctx, k)
+	if err != nil || !found {
+		return found, err
+	}
+
+	return true, c.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is passed to the AWS SDK.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	deleteObjectInput := awss3.DeleteObjectInput{
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	_, err := c.c.DeleteObjectWithContext(ctx, &deleteObjectInput)
+	return wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return c.put(context.Background(), k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return c.get(context.Background(), k)
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// The bytes are uploaded with the upload manager of the AWS SDK,
+// which uploads large values in multiple parts, so only the current parts are held in memory.
+// The key must not be "" and the reader must not be nil.
+func (c Client) SetReader(k string, r io.Reader) error {
+	if err := util.CheckKeyAndReader(k, r); err != nil {
+		return err
+	}
+
+	uploadInput := s3manager.UploadInput{
+		Body:   r,
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	_, err := c.uploader.Upload(&uploadInput)
+	if readErr := unwrapReadError(err); readErr != nil {
+		return readErr
+	}
+	return wrapError(err)
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// The reader is the body of the response, so the value isn't read into memory.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
 	getObjectInput := awss3.GetObjectInput{
 		Bucket: &c.bucketName,
 		Key:    &k,
@@ -68,36 +162,143 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 	if err != nil {
 		aerr, ok := err.(awserr.Error)
 		if ok && aerr.Code() == awss3.ErrCodeNoSuchKey {
-			return false, nil
+			return nil, false, nil
 		}
-		return false, err
+		return nil, false, wrapError(err)
+	}
+	if getObjectOutput.Body == nil {
+		return nil, false, nil
+	}
+	return getObjectOutput.Body, true, nil
+}
+
+// put uploads the given data as object for the given key.
+func (c Client) put(ctx context.Context, k string, data []byte) error {
+	pubObjectInput := awss3.PutObjectInput{
+		Body:   bytes.NewReader(data),
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	_, err := c.c.PutObjectWithContext(ctx, &pubObjectInput)
+	return wrapError(err)
+}
+
+// get downloads the object for the given key.
+func (c Client) get(ctx context.Context, k string) (data []byte, found bool, err error) {
+	getObjectInput := awss3.GetObjectInput{
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	getObjectOutput, err := c.c.GetObjectWithContext(ctx, &getObjectInput)
+	if err != nil {
+		aerr, ok := err.(awserr.Error)
+		if ok && aerr.Code() == awss3.ErrCodeNoSuchKey {
+			return nil, false, nil
+		}
+		return nil, false, wrapError(err)
 	}
 	if getObjectOutput.Body == nil {
 		// Return false if there's no value
 		// TODO: Maybe return an error? Behaviour should be consistent across all implementations.
-		return false, nil
+		return nil, false, nil
 	}
-	data, err := ioutil.ReadAll(getObjectOutput.Body)
+	defer getObjectOutput.Body.Close()
+	data, err = ioutil.ReadAll(getObjectOutput.Body)
 	if err != nil {
-		return true, err
+		return nil, true, err
 	}
+	return data, true, nil
+}
 
-	return true, c.codec.Unmarshal(data, v)
+// unwrapReadError returns the error of the reader if it caused the given error of the upload manager, otherwise nil.
+// The upload manager wraps it in an awserr.Error, which doesn't support errors.Is.
+func unwrapReadError(err error) error {
+	for err != nil {
+		aerr, ok := err.(awserr.Error)
+		if !ok {
+			return nil
+		}
+		if aerr.Code() == "ReadRequestBody" {
+			return aerr.OrigErr()
+		}
+		err = aerr.OrigErr()
+	}
+	return nil
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
-// The key must not be "".
-func (c Client) Delete(k string) error {
-	if err := util.CheckKey(k); err != nil {
+// wrapError wraps errors of the AWS SDK into gokv's errors where possible.
+// S3 limits the length of keys (1024 bytes) and the size of objects that are uploaded with a single request (5 GB).
+func wrapError(err error) error {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
 		return err
 	}
+	switch aerr.Code() {
+	case "KeyTooLongError":
+		return util.WrapError(gokv.ErrKeyTooLong, err)
+	case "EntityTooLarge":
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
+	return err
+}
 
-	deleteObjectInput := awss3.DeleteObjectInput{
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in the order in which S3 lists them, which is byte-sorted order.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	listObjectsInput := awss3.ListObjectsV2Input{
 		Bucket: &c.bucketName,
-		Key:    &k,
+		Prefix: &prefix,
+	}
+	var fnErr error
+	err := c.c.ListObjectsV2Pages(&listObjectsInput, func(page *awss3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			if fnErr = fn(*object.Key); fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return err
+}
+
+// Clear deletes all objects of the bucket.
+// The objects are listed in pages of up to 1000 objects, and each page is deleted with a single DeleteObjects request.
+func (c Client) Clear() error {
+	listObjectsInput := awss3.ListObjectsV2Input{
+		Bucket: &c.bucketName,
+	}
+	var deleteErr error
+	err := c.c.ListObjectsV2Pages(&listObjectsInput, func(page *awss3.ListObjectsV2Output, _ bool) bool {
+		if len(page.Contents) == 0 {
+			return true
+		}
+		objects := make([]*awss3.ObjectIdentifier, len(page.Contents))
+		for i, object := range page.Contents {
+			objects[i] = &awss3.ObjectIdentifier{
+				Key: object.Key,
+			}
+		}
+		deleteObjectsInput := awss3.DeleteObjectsInput{
+			Bucket: &c.bucketName,
+			Delete: &awss3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		}
+		var output *awss3.DeleteObjectsOutput
+		output, deleteErr = c.c.DeleteObjects(&deleteObjectsInput)
+		if deleteErr == nil && len(output.Errors) > 0 {
+			deleteErr = fmt.Errorf("Deleting the object %q failed: %v", aws.StringValue(output.Errors[0].Key), aws.StringValue(output.Errors[0].Message))
+		}
+		return deleteErr == nil
+	})
+	if deleteErr != nil {
+		return deleteErr
 	}
-	_, err := c.c.DeleteObject(&deleteObjectInput)
 	return err
 }
 
@@ -231,12 +432,42 @@ func NewClient(options Options) (Client, error) {
 	}
 
 	result.c = svc
+	result.uploader = s3manager.NewUploaderWithClient(svc)
 	result.bucketName = options.BucketName
 	result.codec = options.Codec
 
 	return result, nil
 }
 
+func init() {
+	gokv.Register("s3", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		BucketName: u.Host,
+	}
+	if u.User != nil {
+		options.AWSaccessKeyID = u.User.Username()
+		options.AWSsecretAccessKey, _ = u.User.Password()
+	}
+	q := util.NewURLQuery(u)
+	q.String("region", &options.Region)
+	q.String("customEndpoint", &options.CustomEndpoint)
+	q.Bool("usePathStyleAddressing", &options.UsePathStyleAddressing)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // createBucket creates the bucket if it doesn't exist yet.
 //
 // When using actual Amazon S3, and trying to create a bucket you already own,
@@ -0,0 +1,26 @@
+/*
+Package shard contains a gokv.Store implementation that distributes the keys over multiple stores (shards),
+for example over several Redis or memcached servers to scale beyond a single server,
+or over several bbolt or BadgerDB files, which can only be opened by one process and only written to by one writer at a time.
+
+The keys are distributed with a consistent hash ring with virtual nodes,
+so adding or removing a shard only requires moving the keys of that shard, which Rebalance does.
+
+	options := shard.DefaultOptions
+	options.Shards = map[string]gokv.Store{
+		"shard-1": store1,
+		"shard-2": store2,
+	}
+	store, err := shard.NewStore(options)
+	...
+	// Adding a shard
+	options.Shards = map[string]gokv.Store{
+		"shard-1": store1,
+		"shard-2": store2,
+		"shard-3": store3,
+	}
+	newStore, err := shard.NewStore(options)
+	...
+	moved, err := shard.Rebalance(ctx, store, newStore)
+*/
+package shard
@@ -0,0 +1,77 @@
+package shard
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// ring is a consistent hash ring, on which each shard is placed multiple times (as virtual nodes),
+// so that the keys are evenly distributed and adding or removing a shard only moves the keys of its virtual nodes.
+type ring struct {
+	// points are the sorted hashes of the virtual nodes.
+	points []uint64
+	// owners are the names of the shards of the virtual nodes, in the order of the points.
+	owners []string
+}
+
+// newRing creates a ring with the given number of virtual nodes per shard.
+func newRing(names []string, virtualNodes int) ring {
+	type node struct {
+		point uint64
+		owner string
+	}
+	nodes := make([]node, 0, len(names)*virtualNodes)
+	for _, name := range names {
+		for i := 0; i < virtualNodes; i++ {
+			nodes = append(nodes, node{
+				point: hash(name + "#" + strconv.Itoa(i)),
+				owner: name,
+			})
+		}
+	}
+	// Ties are broken by the name, so the ring doesn't depend on the order of the names.
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].point != nodes[j].point {
+			return nodes[i].point < nodes[j].point
+		}
+		return nodes[i].owner < nodes[j].owner
+	})
+
+	result := ring{
+		points: make([]uint64, len(nodes)),
+		owners: make([]string, len(nodes)),
+	}
+	for i, n := range nodes {
+		result.points[i] = n.point
+		result.owners[i] = n.owner
+	}
+	return result
+}
+
+// owner returns the name of the shard that the given key belongs to,
+// which is the shard of the first virtual node at or after the key's hash.
+func (r ring) owner(k string) string {
+	h := hash(k)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// hash returns the 64-bit FNV-1a hash of s, with the bits mixed by the finalizer of SplitMix64,
+// because FNV alone distributes similar strings (like "shard#1" and "shard#2") poorly.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,555 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/philippgille/gokv"
+)
+
+// Store is a gokv.Store that distributes the keys over multiple stores (shards) with a consistent hash ring,
+// for example over several Redis or memcached servers, or over several bbolt or BadgerDB files.
+//
+// Store implements the optional interfaces of the gokv package whose operations can be distributed over the shards,
+// which excludes gokv.Transactional. Operations for a single key are passed to the shard of the key,
+// operations for multiple keys (like SetMulti) are passed to each affected shard, and operations for all keys
+// (like Keys and Clear) to all shards, so they're not atomic anymore.
+// If a shard doesn't implement an optional interface, the corresponding methods
+// fall back to the basic methods or return gokv.ErrUnsupported, so only the interfaces
+// that gokv.Supports() reports for a Store can be used with all keys.
+type Store struct {
+	shards map[string]gokv.Store
+	// names are the sorted names of the shards.
+	names []string
+	ring  ring
+}
+
+// Set stores the given value for the given key in the key's shard.
+// See gokv.Store.Set() for details.
+func (s Store) Set(k string, v interface{}) error {
+	return s.shard(k).Set(k, v)
+}
+
+// Get retrieves the value for the given key from the key's shard.
+// See gokv.Store.Get() for details.
+func (s Store) Get(k string, v interface{}) (found bool, err error) {
+	return s.shard(k).Get(k, v)
+}
+
+// Delete deletes the stored value for the given key from the key's shard.
+// See gokv.Store.Delete() for details.
+func (s Store) Delete(k string) error {
+	return s.shard(k).Delete(k)
+}
+
+// Supports reports whether all shards support the optional interface that iface points to.
+// gokv.BatchStore and gokv.ContextStore are always supported, and gokv.Clearer and gokv.StatsStore
+// if each shard supports either them or gokv.Lister.
+// See the package-level function gokv.Supports() for details.
+func (s Store) Supports(iface interface{}) bool {
+	switch iface.(type) {
+	case *gokv.BatchStore, *gokv.ContextStore:
+		return true
+	}
+	for _, name := range s.names {
+		shard := s.shards[name]
+		switch iface.(type) {
+		case *gokv.Clearer, *gokv.StatsStore:
+			if !gokv.Supports(shard, iface) && !gokv.Supports(shard, (*gokv.Lister)(nil)) {
+				return false
+			}
+		default:
+			if !gokv.Supports(shard, iface) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Close closes all shards.
+// If closing a shard fails, the other shards are closed anyway and the first error is returned.
+func (s Store) Close() error {
+	var result error
+	for _, name := range s.names {
+		if err := s.shards[name].Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// Shard returns the name of the shard that the given key belongs to.
+func (s Store) Shard(k string) string {
+	return s.ring.owner(k)
+}
+
+// SetBytes stores the given bytes for the given key in the key's shard without marshalling them.
+// See gokv.RawStore.SetBytes() for details.
+func (s Store) SetBytes(k string, v []byte) error {
+	rawStore, ok := s.shard(k).(gokv.RawStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return rawStore.SetBytes(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key from the key's shard without unmarshalling them.
+// See gokv.RawStore.GetBytes() for details.
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	rawStore, ok := s.shard(k).(gokv.RawStore)
+	if !ok {
+		return nil, false, gokv.ErrUnsupported
+	}
+	return rawStore.GetBytes(k)
+}
+
+// SetReader stores the bytes read from r for the given key in the key's shard without marshalling them.
+// See gokv.StreamStore.SetReader() for details.
+func (s Store) SetReader(k string, r io.Reader) error {
+	streamStore, ok := s.shard(k).(gokv.StreamStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return streamStore.SetReader(k, r)
+}
+
+// GetReader returns a reader for the stored bytes for the given key from the key's shard without unmarshalling them.
+// See gokv.StreamStore.GetReader() for details.
+func (s Store) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	streamStore, ok := s.shard(k).(gokv.StreamStore)
+	if !ok {
+		return nil, false, gokv.ErrUnsupported
+	}
+	return streamStore.GetReader(k)
+}
+
+// SetContext stores the given value for the given key in the key's shard.
+// If the shard doesn't implement gokv.ContextStore, the context is only checked before the value is stored.
+// See gokv.ContextStore.SetContext() for details.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	shard := s.shard(k)
+	if contextStore, ok := shard.(gokv.ContextStore); ok {
+		return contextStore.SetContext(ctx, k, v)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return shard.Set(k, v)
+}
+
+// GetContext retrieves the value for the given key from the key's shard.
+// If the shard doesn't implement gokv.ContextStore, the context is only checked before the value is retrieved.
+// See gokv.ContextStore.GetContext() for details.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	shard := s.shard(k)
+	if contextStore, ok := shard.(gokv.ContextStore); ok {
+		return contextStore.GetContext(ctx, k, v)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return shard.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key from the key's shard.
+// If the shard doesn't implement gokv.ContextStore, the context is only checked before the value is deleted.
+// See gokv.ContextStore.DeleteContext() for details.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	shard := s.shard(k)
+	if contextStore, ok := shard.(gokv.ContextStore); ok {
+		return contextStore.DeleteContext(ctx, k)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return shard.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix, shard after shard.
+// All shards must implement gokv.Lister, otherwise gokv.ErrUnsupported is returned.
+// See gokv.Lister.Keys() for details.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	listers, err := s.listers()
+	if err != nil {
+		return err
+	}
+	for _, lister := range listers {
+		if err := lister.Keys(prefix, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear deletes all key-value pairs of all shards, stopping at the first error.
+// See the package-level function gokv.Clear() for details.
+func (s Store) Clear() error {
+	for _, name := range s.names {
+		if err := gokv.Clear(s.shards[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats returns the sums of the statistics of all shards.
+// A statistic that's unknown (-1) for one of the shards is unknown for the whole store.
+// See the package-level function gokv.Stats() for details.
+func (s Store) Stats() (gokv.StoreStats, error) {
+	result := gokv.StoreStats{}
+	for _, name := range s.names {
+		stats, err := gokv.Stats(s.shards[name])
+		if err != nil {
+			return gokv.UnknownStats, err
+		}
+		result.Keys = addStat(result.Keys, stats.Keys)
+		result.Bytes = addStat(result.Bytes, stats.Bytes)
+		result.Hits = addStat(result.Hits, stats.Hits)
+		result.Misses = addStat(result.Misses, stats.Misses)
+		result.Evictions = addStat(result.Evictions, stats.Evictions)
+	}
+	return result, nil
+}
+
+// SetWithTTL stores the given value for the given key in the key's shard, with the given time to live.
+// See gokv.ExpiringStore.SetWithTTL() for details.
+func (s Store) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	expiringStore, ok := s.shard(k).(gokv.ExpiringStore)
+	if !ok {
+		return gokv.ErrUnsupported
+	}
+	return expiringStore.SetWithTTL(k, v, ttl)
+}
+
+// SetMulti stores the given values for the given keys, with one call per affected shard.
+// It stops at the first error, so the values might be stored in some shards but not in others.
+// See the package-level function gokv.SetMulti() for details.
+func (s Store) SetMulti(keys []string, vs []interface{}) error {
+	if len(keys) != len(vs) {
+		return errLengthMismatch
+	}
+	for name, indexes := range s.group(keys) {
+		shardKeys, shardVs := subset(keys, vs, indexes)
+		if err := gokv.SetMulti(s.shards[name], shardKeys, shardVs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMulti retrieves the values for the given keys, with one call per affected shard.
+// See the package-level function gokv.GetMulti() for details.
+func (s Store) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if len(keys) != len(vs) {
+		return nil, errLengthMismatch
+	}
+	found = make([]bool, len(keys))
+	for name, indexes := range s.group(keys) {
+		shardKeys, shardVs := subset(keys, vs, indexes)
+		shardFound, err := gokv.GetMulti(s.shards[name], shardKeys, shardVs)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range indexes {
+			found[index] = shardFound[i]
+		}
+	}
+	return found, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys, with one call per affected shard.
+// It stops at the first error, so the values might be deleted from some shards but not from others.
+// See the package-level function gokv.DeleteMulti() for details.
+func (s Store) DeleteMulti(keys []string) error {
+	for name, indexes := range s.group(keys) {
+		shardKeys, _ := subset(keys, nil, indexes)
+		if err := gokv.DeleteMulti(s.shards[name], shardKeys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetIfAbsent stores the given value for the given key in the key's shard,
+// but only if no value is stored for the key yet.
+// See gokv.AtomicStore.SetIfAbsent() for details.
+func (s Store) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	return gokv.SetIfAbsent(s.shard(k), k, v)
+}
+
+// CompareAndSwap stores the new value for the given key in the key's shard,
+// but only if the currently stored value is equal to the old value.
+// See gokv.AtomicStore.CompareAndSwap() for details.
+func (s Store) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	return gokv.CompareAndSwap(s.shard(k), k, old, new)
+}
+
+// Incr adds delta to the int64 value that's stored for the given key in the key's shard and returns the new value.
+// See gokv.Counter.Incr() for details.
+func (s Store) Incr(k string, delta int64) (int64, error) {
+	return gokv.Incr(s.shard(k), k, delta)
+}
+
+// Watch returns a channel on which the events of all shards are sent,
+// for each change of a key-value pair whose key starts with the given prefix.
+// All shards must implement gokv.Watcher, otherwise gokv.ErrUnsupported is returned.
+// When the watch of one shard fails, the watches of the other shards are stopped as well and the channel is closed,
+// because the events of one shard are lost.
+// See gokv.Watcher.Watch() for details.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	var watchers []gokv.Watcher
+	for _, name := range s.names {
+		watcher, ok := s.shards[name].(gokv.Watcher)
+		if !ok {
+			return nil, gokv.ErrUnsupported
+		}
+		watchers = append(watchers, watcher)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	var shardEvents []<-chan gokv.Event
+	for _, watcher := range watchers {
+		c, err := watcher.Watch(watchCtx, prefix)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		shardEvents = append(shardEvents, c)
+	}
+
+	events := make(chan gokv.Event)
+	var wg sync.WaitGroup
+	wg.Add(len(shardEvents))
+	for _, c := range shardEvents {
+		go func(c <-chan gokv.Event) {
+			defer wg.Done()
+			// Stop the other watches when this one fails
+			defer cancel()
+			for e := range c {
+				select {
+				case events <- e:
+				case <-watchCtx.Done():
+					return
+				}
+			}
+		}(c)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(events)
+	}()
+	return events, nil
+}
+
+// shard returns the shard that the given key belongs to.
+func (s Store) shard(k string) gokv.Store {
+	return s.shards[s.ring.owner(k)]
+}
+
+// group returns the indexes of the given keys, grouped by the name of the shard that they belong to.
+func (s Store) group(keys []string) map[string][]int {
+	result := make(map[string][]int)
+	for i, k := range keys {
+		name := s.ring.owner(k)
+		result[name] = append(result[name], i)
+	}
+	return result
+}
+
+// listers returns all shards as gokv.Lister, or gokv.ErrUnsupported if one of them doesn't implement it.
+func (s Store) listers() ([]gokv.Lister, error) {
+	var result []gokv.Lister
+	for _, name := range s.names {
+		lister, ok := s.shards[name].(gokv.Lister)
+		if !ok {
+			return nil, gokv.ErrUnsupported
+		}
+		result = append(result, lister)
+	}
+	return result, nil
+}
+
+// subset returns the keys and values with the given indexes.
+// If vs is nil, the returned values are nil as well.
+func subset(keys []string, vs []interface{}, indexes []int) ([]string, []interface{}) {
+	subKeys := make([]string, len(indexes))
+	var subVs []interface{}
+	if vs != nil {
+		subVs = make([]interface{}, len(indexes))
+	}
+	for i, index := range indexes {
+		subKeys[i] = keys[index]
+		if vs != nil {
+			subVs[i] = vs[index]
+		}
+	}
+	return subKeys, subVs
+}
+
+// addStat adds the given statistics, unless one of them is unknown (-1).
+func addStat(a, b int64) int64 {
+	if a < 0 || b < 0 {
+		return -1
+	}
+	return a + b
+}
+
+var errLengthMismatch = errors.New("The number of passed keys and values differs")
+
+// ttlStore is implemented by shards that can store raw bytes with a TTL and return the remaining TTL of a key-value pair,
+// like the implementations of dump.TTLStore.
+type ttlStore interface {
+	SetBytesWithTTL(k string, v []byte, ttl time.Duration) error
+	TTL(k string) (ttl time.Duration, found bool, err error)
+}
+
+// Rebalance moves the key-value pairs that belong to a different shard in the new store than in the old store,
+// for example after adding a shard to or removing a shard from the options, and returns the number of moved key-value pairs.
+// Shards are identified by their names, and shards with the same name must be the same store in both,
+// for example the same bbolt store, which can't be opened twice.
+// Thanks to the consistent hashing, only the key-value pairs of the virtual nodes of an added or removed shard are moved.
+//
+// The key-value pairs are moved by copying them as raw bytes to their new shard and deleting them from their old one,
+// so all shards must implement gokv.RawStore (and use the same codec), and the shards of the old store must implement gokv.Lister.
+// TTLs are moved as well, which requires the shards to implement the methods of dump.TTLStore
+// (SetBytesWithTTL and TTL, like the badgerdb, bbolt, gomap, redis and SQL stores) if they implement gokv.ExpiringStore,
+// because key-value pairs with a TTL would otherwise never expire after they were moved.
+// Shards that don't implement gokv.Lister (like memcached) can't be rebalanced at all,
+// so after adding or removing a shard, the key-value pairs whose shard changed aren't found until they're set again,
+// which is only acceptable for caches.
+// While Rebalance is running, the moved key-value pairs might not be found by either store,
+// and changes made through the new store might be overwritten, so it should be called before the new store is used.
+// When the context is done, Rebalance stops and returns the context's error.
+func Rebalance(ctx context.Context, old, new Store) (moved int, err error) {
+	for _, name := range old.names {
+		shard := old.shards[name]
+		if !gokv.Supports(shard, (*gokv.Lister)(nil)) {
+			return moved, fmt.Errorf("The shard %q must implement gokv.Lister: %w", name, gokv.ErrUnsupported)
+		}
+		if !gokv.Supports(shard, (*gokv.RawStore)(nil)) {
+			return moved, fmt.Errorf("The shard %q must implement gokv.RawStore: %w", name, gokv.ErrUnsupported)
+		}
+		hasTTLs := gokv.Supports(shard, (*ttlStore)(nil))
+		if gokv.Supports(shard, (*gokv.ExpiringStore)(nil)) && !hasTTLs {
+			return moved, fmt.Errorf("The shard %q supports TTLs, but doesn't implement TTL, so they can't be moved: %w", name, gokv.ErrUnsupported)
+		}
+		lister := shard.(gokv.Lister)
+		from := shard.(gokv.RawStore)
+		fromTTLs, _ := shard.(ttlStore)
+		// The keys are collected first, because some stores (like bbolt) can't be changed while their keys are listed.
+		var keys []string
+		err = lister.Keys("", func(k string) error {
+			if new.ring.owner(k) != name {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return moved, err
+		}
+		for _, k := range keys {
+			if err := ctx.Err(); err != nil {
+				return moved, err
+			}
+			newName := new.ring.owner(k)
+			if !gokv.Supports(new.shards[newName], (*gokv.RawStore)(nil)) {
+				return moved, fmt.Errorf("The shard %q must implement gokv.RawStore: %w", newName, gokv.ErrUnsupported)
+			}
+			to := new.shards[newName].(gokv.RawStore)
+			var ttl time.Duration
+			if hasTTLs {
+				var found bool
+				ttl, found, err = fromTTLs.TTL(k)
+				if err != nil {
+					return moved, err
+				} else if !found {
+					// Deleted or expired in the meantime
+					continue
+				}
+			}
+			data, found, err := from.GetBytes(k)
+			if err != nil {
+				return moved, err
+			} else if !found {
+				// Deleted or expired in the meantime
+				continue
+			}
+			if ttl > 0 {
+				if !gokv.Supports(to, (*ttlStore)(nil)) {
+					return moved, fmt.Errorf("The shard %q must implement SetBytesWithTTL for moving the key %q with its TTL: %w", newName, k, gokv.ErrUnsupported)
+				}
+				err = to.(ttlStore).SetBytesWithTTL(k, data, ttl)
+			} else {
+				err = to.SetBytes(k, data)
+			}
+			if err != nil {
+				return moved, err
+			}
+			if err := from.Delete(k); err != nil {
+				return moved, err
+			}
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// Options are the options for the sharded store.
+type Options struct {
+	// Shards by their names, for example "redis-1" or the file path of a bbolt store.
+	// The names determine the distribution of the keys, so they must stay the same when the store is created again
+	// (unlike for example the addresses of servers), and adding or removing a shard requires a Rebalance.
+	Shards map[string]gokv.Store
+	// Number of virtual nodes per shard on the hash ring.
+	// More virtual nodes distribute the keys more evenly, but make the ring larger.
+	// Optional (128 by default).
+	VirtualNodes int
+}
+
+// DefaultOptions is an Options object with default values.
+// VirtualNodes: 128
+var DefaultOptions = Options{
+	VirtualNodes: 128,
+	// No need to set Shards because it has to be set by the user.
+}
+
+// NewStore creates a new sharded store.
+// Closing the returned store closes all shards.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	// Precondition check
+	if len(options.Shards) == 0 {
+		return result, errors.New("The Shards in the options must not be empty")
+	}
+	for name, shard := range options.Shards {
+		if shard == nil {
+			return result, fmt.Errorf("The shard %q in the options must not be nil", name)
+		}
+	}
+	if options.VirtualNodes < 0 {
+		return result, errors.New("The VirtualNodes in the options must not be negative")
+	}
+
+	// Set default values
+	if options.VirtualNodes == 0 {
+		options.VirtualNodes = DefaultOptions.VirtualNodes
+	}
+
+	result.shards = make(map[string]gokv.Store, len(options.Shards))
+	for name, shard := range options.Shards {
+		result.shards[name] = shard
+		result.names = append(result.names, name)
+	}
+	sort.Strings(result.names)
+	result.ring = newRing(result.names, options.VirtualNodes)
+
+	return result, nil
+}
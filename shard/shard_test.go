@@ -0,0 +1,286 @@
+package shard_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/shard"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	store, _ := createStore(t, "a", "b", "c")
+	defer store.Close()
+	test.TestStore(store, t)
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	store, _ := createStore(t, "a", "b", "c")
+	defer store.Close()
+	test.TestTypes(store, t)
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _ := createStore(t, "a", "b", "c")
+	defer store.Close()
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestOptionalInterfaces tests if the optional interfaces work with the sharded store.
+func TestOptionalInterfaces(t *testing.T) {
+	store, _ := createStore(t, "a", "b", "c")
+	defer store.Close()
+	test.TestRawStore(store, t)
+	test.TestContextStore(store, t)
+	test.TestLister(store, t)
+	test.TestExpiringStore(store, t)
+	test.TestBatchStore(store, t)
+	test.TestAtomicStore(store, t)
+	test.TestCounter(store, t)
+	test.TestClearer(store, t)
+	test.TestStatsStore(store, t)
+	test.TestWatcher(store, t)
+
+	// An interface is only supported if all shards support it
+	mixed, err := shard.NewStore(shard.Options{
+		Shards: map[string]gokv.Store{
+			"a": gomap.NewStore(gomap.DefaultOptions),
+			"b": gokv.WithPrefix(basicStore{gomap.NewStore(gomap.DefaultOptions)}, "b:"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gokv.Supports(store, (*gokv.Watcher)(nil)) {
+		t.Error("Expected the sharded store to support gokv.Watcher")
+	}
+	if gokv.Supports(mixed, (*gokv.Watcher)(nil)) || gokv.Supports(mixed, (*gokv.Clearer)(nil)) {
+		t.Error("Expected the sharded store with a basic shard not to support gokv.Watcher and gokv.Clearer")
+	}
+	if !gokv.Supports(mixed, (*gokv.BatchStore)(nil)) {
+		t.Error("Expected the sharded store with a basic shard to support gokv.BatchStore")
+	}
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test missing shards
+	_, err := shard.NewStore(shard.DefaultOptions)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test nil shard
+	_, err = shard.NewStore(shard.Options{
+		Shards: map[string]gokv.Store{"a": nil},
+	})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test empty key
+	store, _ := createStore(t, "a")
+	defer store.Close()
+	err = store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestDistribution tests if the keys are distributed over all shards, and if each key is stored in its shard.
+func TestDistribution(t *testing.T) {
+	store, shards := createStore(t, "a", "b", "c")
+	defer store.Close()
+
+	keyCount := 3000
+	for i := 0; i < keyCount; i++ {
+		k := strconv.Itoa(i)
+		err := store.Set(k, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found, err := shards[store.Shard(k)].Get(k, new(string))
+		if err != nil {
+			t.Fatal(err)
+		} else if !found {
+			t.Errorf("Expected key %v to be stored in shard %v", k, store.Shard(k))
+		}
+	}
+	for name, s := range shards {
+		count := countKeys(t, s)
+		// Each shard should have about a third of the keys
+		if count < keyCount/5 || count > keyCount/2 {
+			t.Errorf("Expected about %v keys in shard %v, but was: %v", keyCount/3, name, count)
+		}
+	}
+}
+
+// TestRebalance tests if only the affected keys are moved when a shard is added or removed.
+func TestRebalance(t *testing.T) {
+	shards := map[string]gomap.Store{
+		"a": gomap.NewStore(gomap.DefaultOptions),
+		"b": gomap.NewStore(gomap.DefaultOptions),
+		"c": gomap.NewStore(gomap.DefaultOptions),
+	}
+	before := newStore(t, shards, "a", "b")
+
+	// Every tenth key-value pair has a TTL
+	keyCount := 1000
+	for i := 0; i < keyCount; i++ {
+		k := strconv.Itoa(i)
+		var err error
+		if i%10 == 0 {
+			err = before.SetWithTTL(k, k, time.Hour)
+		} else {
+			err = before.Set(k, k)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Adding a shard only moves keys to the new shard
+	after := newStore(t, shards, "a", "b", "c")
+	expectedMoved := 0
+	for i := 0; i < keyCount; i++ {
+		k := strconv.Itoa(i)
+		if after.Shard(k) == "c" {
+			expectedMoved++
+		} else if after.Shard(k) != before.Shard(k) {
+			t.Errorf("Expected key %v to stay in shard %v, but it belongs to shard %v", k, before.Shard(k), after.Shard(k))
+		}
+	}
+	moved, err := shard.Rebalance(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != expectedMoved || moved == 0 {
+		t.Errorf("Expected %v moved keys, but was: %v", expectedMoved, moved)
+	}
+	if count := countKeys(t, shards["c"]); count != moved {
+		t.Errorf("Expected %v keys in the new shard, but was: %v", moved, count)
+	}
+	checkKeys(t, after, keyCount)
+	checkTTLs(t, shards, after, keyCount)
+
+	// Removing the shard moves its keys back
+	before = after
+	after = newStore(t, shards, "a", "b")
+	moved, err = shard.Rebalance(context.Background(), before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != expectedMoved {
+		t.Errorf("Expected %v moved keys, but was: %v", expectedMoved, moved)
+	}
+	if count := countKeys(t, shards["c"]); count != 0 {
+		t.Errorf("Expected no keys in the removed shard, but was: %v", count)
+	}
+	checkKeys(t, after, keyCount)
+	checkTTLs(t, shards, after, keyCount)
+
+	// Test canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = shard.Rebalance(ctx, after, before)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, but was: %v", err)
+	}
+
+	// Shards that support TTLs but can't report them (like a gokv.PrefixStore) can't be rebalanced
+	prefixed, err := shard.NewStore(shard.Options{
+		Shards: map[string]gokv.Store{"a": gokv.WithPrefix(shards["a"], "")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = shard.Rebalance(context.Background(), prefixed, after)
+	if !errors.Is(err, gokv.ErrUnsupported) {
+		t.Errorf("Expected gokv.ErrUnsupported, but was: %v", err)
+	}
+}
+
+// checkKeys checks if the values for the keys "0" to keyCount-1 are found in the store.
+func checkKeys(t *testing.T, store shard.Store, keyCount int) {
+	t.Helper()
+	for i := 0; i < keyCount; i++ {
+		k := strconv.Itoa(i)
+		v := ""
+		found, err := store.Get(k, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || v != k {
+			t.Errorf("Expected %v, but was: %v (found: %v)", k, v, found)
+		}
+	}
+}
+
+// checkTTLs checks if every tenth of the keys "0" to keyCount-1 has a TTL in its shard, and the others don't.
+func checkTTLs(t *testing.T, shards map[string]gomap.Store, store shard.Store, keyCount int) {
+	t.Helper()
+	for i := 0; i < keyCount; i++ {
+		k := strconv.Itoa(i)
+		ttl, _, err := shards[store.Shard(k)].TTL(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%10 == 0 && (ttl <= 0 || ttl > time.Hour) {
+			t.Errorf("Expected a TTL of up to an hour for key %v, but was: %v", k, ttl)
+		} else if i%10 != 0 && ttl != 0 {
+			t.Errorf("Expected no TTL for key %v, but was: %v", k, ttl)
+		}
+	}
+}
+
+func countKeys(t *testing.T, store gokv.Lister) int {
+	t.Helper()
+	count := 0
+	err := store.Keys("", func(string) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func createStore(t *testing.T, names ...string) (shard.Store, map[string]gomap.Store) {
+	shards := make(map[string]gomap.Store, len(names))
+	for _, name := range names {
+		shards[name] = gomap.NewStore(gomap.DefaultOptions)
+	}
+	return newStore(t, shards, names...), shards
+}
+
+// newStore creates a sharded store with the shards of the given names.
+func newStore(t *testing.T, shards map[string]gomap.Store, names ...string) shard.Store {
+	options := shard.DefaultOptions
+	options.Shards = make(map[string]gokv.Store, len(names))
+	for _, name := range names {
+		options.Shards[name] = shards[name]
+	}
+	store, err := shard.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
@@ -1,25 +1,182 @@
 package sql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Client is a gokv.Store implementation for SQL databases.
+//
+// To support key-value pairs with a TTL, the table needs a column for the expiry time,
+// the UpsertStmt must reset it and the GetStmt and KeysStmt must exclude expired rows.
+// The expiry time should be determined by the database's clock,
+// so that multiple clients on different hosts agree on it.
 type Client struct {
 	C          *sql.DB
 	UpsertStmt *sql.Stmt
 	GetStmt    *sql.Stmt
 	DeleteStmt *sql.Stmt
-	Codec      encoding.Codec
+	// KeysStmt must select the keys matching a LIKE pattern,
+	// in which "!" is used as escape character, e.g.
+	// "SELECT k FROM table WHERE k LIKE ? ESCAPE '!' ORDER BY k".
+	// Optional (only required for Keys()).
+	KeysStmt *sql.Stmt
+	// SetWithTTLStmt must upsert a key, a value and a TTL in milliseconds.
+	// Optional (only required for SetWithTTL() and SetBytesWithTTL()).
+	SetWithTTLStmt *sql.Stmt
+	// TTLStmt must select the remaining TTL in milliseconds of a row that isn't expired,
+	// or NULL if the row doesn't have an expiry time.
+	// Optional (only required for TTL()).
+	TTLStmt *sql.Stmt
+	// DeleteExpiredStmt must delete all expired rows.
+	// Optional (only required for the sweeper, see StartSweeper()).
+	DeleteExpiredStmt *sql.Stmt
+	// SetIfAbsentStmt must insert a key and a value, or overwrite an expired row,
+	// but not modify a row that isn't expired.
+	// The number of affected rows must be 0 if nothing was stored.
+	// Optional (only required for SetIfAbsent()).
+	SetIfAbsentStmt *sql.Stmt
+	// CompareAndSwapStmt must update the value of a row that isn't expired
+	// and reset its expiry time, but only if the stored value is equal to the passed old value.
+	// The parameters are the new value, the key and the old value, in this order.
+	// Optional (only required for CompareAndSwap()).
+	CompareAndSwapStmt *sql.Stmt
+	// IncrStmt must add a delta to the value of a row that isn't expired and keep its expiry time,
+	// or insert the delta as value without expiry time, overwriting an expired row.
+	// The value must be stored as decimal number, which is how encoding.JSON marshals an int64.
+	// The parameters are the key and the delta, in this order.
+	// Optional (only required for Incr()).
+	IncrStmt *sql.Stmt
+	// IncrResultStmt must select the value for a key, regardless of its expiry time.
+	// It's executed after the IncrStmt in the same transaction, to retrieve the new value.
+	// Optional (only required for Incr()).
+	IncrResultStmt *sql.Stmt
+	// ClearStmt must delete all rows, e.g. "DELETE FROM table".
+	// Optional (only required for Clear()).
+	ClearStmt *sql.Stmt
+	// CountStmt must select the number of rows that aren't expired, e.g. "SELECT COUNT(*) FROM table".
+	// Optional (only required for Stats()).
+	CountStmt *sql.Stmt
+	// SizeStmt must select the approximate size of the table in bytes.
+	// Optional (the size is unknown without it).
+	SizeStmt *sql.Stmt
+	Codec    encoding.Codec
+	// MultiStmts builds the statements for operating on multiple rows at once,
+	// which SetMulti(), GetMulti() and DeleteMulti() execute for up to MaxMultiKeys keys each.
+	// Optional (the UpsertStmt, GetStmt and DeleteStmt are executed for each key in a transaction without it).
+	MultiStmts *MultiStmts
+	// WrapError is called with each error of the database driver
+	// that isn't already recognized by the client, like a closed database.
+	// It can wrap errors into gokv's errors (like gokv.ErrKeyTooLong), e.g. with util.WrapError(),
+	// and must return all other errors unchanged.
+	// Optional.
+	WrapError func(err error) error
+	sweeper   *util.Sweeper
+}
+
+// MultiStmts builds statements for a number of rows, which is passed to each function.
+// See ValuesList() and InList() for building the parameter placeholders.
+type MultiStmts struct {
+	// Upsert must return a statement that upserts n rows and resets their expiry time,
+	// with the keys and values as parameters in the order k1, v1, k2, v2, ...,
+	// e.g. "INSERT INTO table (k, v) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v), e = NULL".
+	// The keys are unique.
+	Upsert func(n int) string
+	// Get must return a statement that selects the keys and values of the rows that aren't expired for n keys,
+	// e.g. "SELECT k, v FROM table WHERE k IN (?, ?) AND (e IS NULL OR e > ...)".
+	Get func(n int) string
+	// Delete must return a statement that deletes the rows for n keys,
+	// e.g. "DELETE FROM table WHERE k IN (?, ?)".
+	Delete func(n int) string
+}
+
+// MaxMultiKeys is the maximum number of keys per statement of MultiStmts.
+// SetMulti(), GetMulti() and DeleteMulti() split larger numbers of keys into multiple statements,
+// so the number of parameters stays far below the limits of the databases (65535 for MySQL and PostgreSQL).
+const MaxMultiKeys = 500
+
+// PlaceholderStyle is the style of the parameter placeholders of a database.
+type PlaceholderStyle int
+
+const (
+	// QuestionMark placeholders are used by MySQL, e.g. "?, ?".
+	QuestionMark PlaceholderStyle = iota
+	// Dollar placeholders are used by PostgreSQL and CockroachDB, e.g. "$1, $2".
+	Dollar
+)
+
+// ValuesList returns the placeholders for n rows with the given number of columns,
+// e.g. "(?, ?), (?, ?)" or "($1, $2), ($3, $4)" for 2 rows with 2 columns.
+func ValuesList(n, columns int, style PlaceholderStyle) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		writePlaceholders(&b, i*columns, columns, style)
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// InList returns the placeholders for n parameters, e.g. "?, ?" or "$1, $2" for 2 parameters.
+func InList(n int, style PlaceholderStyle) string {
+	var b strings.Builder
+	writePlaceholders(&b, 0, n, style)
+	return b.String()
+}
+
+// writePlaceholders writes n placeholders, of which the first one is for the parameter with the given offset.
+func writePlaceholders(b *strings.Builder, offset, n int, style PlaceholderStyle) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if style == Dollar {
+			b.WriteString("$" + strconv.Itoa(offset+i+1))
+		} else {
+			b.WriteString("?")
+		}
+	}
+}
+
+// chunks splits the given number of keys into ranges [start, end) of at most MaxMultiKeys keys.
+func chunks(n int) [][2]int {
+	var result [][2]int
+	for start := 0; start < n; start += MaxMultiKeys {
+		end := start + MaxMultiKeys
+		if end > n {
+			end = n
+		}
+		result = append(result, [2]int{start, end})
+	}
+	return result
 }
 
 // Set stores the given value for the given key.
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v interface{}) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key.
+// The context is passed to the database driver.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -30,14 +187,97 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	_, err = c.UpsertStmt.Exec(k, data)
+	_, err = c.UpsertStmt.ExecContext(ctx, k, data)
 	if err != nil {
-		return err
+		return c.wrapError(err)
 	}
 
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key.
+// After the TTL has passed, the key-value pair isn't returned anymore,
+// but it's only deleted when it's overwritten or by the sweeper (if started).
+// The TTL is passed to the database in milliseconds, rounded up.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetWithTTL(k string, v interface{}, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return c.Set(k, v)
+	}
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if c.SetWithTTLStmt == nil {
+		return fmt.Errorf("The SetWithTTLStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.setBytesWithTTL(k, data, ttl)
+}
+
+// SetBytesWithTTL stores the given bytes for the given key without marshalling them.
+// After the TTL has passed, the key-value pair isn't returned anymore.
+// A TTL of 0 means that the key-value pair doesn't expire.
+// The key must not be "", the value must not be nil and the TTL must not be negative.
+func (c Client) SetBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	} else if ttl == 0 {
+		return c.SetBytes(k, v)
+	}
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+	if c.SetWithTTLStmt == nil {
+		return fmt.Errorf("The SetWithTTLStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	return c.setBytesWithTTL(k, v, ttl)
+}
+
+func (c Client) setBytesWithTTL(k string, v []byte, ttl time.Duration) error {
+	ttlMillis := int64((ttl + time.Millisecond - 1) / time.Millisecond)
+	_, err := c.SetWithTTLStmt.Exec(k, v, ttlMillis)
+	return c.wrapError(err)
+}
+
+// TTL returns the remaining TTL of the key-value pair for the given key.
+// If the key-value pair doesn't expire it returns (0, true, nil).
+// If no value is found it returns (0, false, nil).
+// The key must not be "".
+func (c Client) TTL(k string) (ttl time.Duration, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, false, err
+	}
+	if c.TTLStmt == nil {
+		return 0, false, fmt.Errorf("The TTLStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	var ttlMillis sql.NullInt64
+	err = c.TTLStmt.QueryRow(k).Scan(&ttlMillis)
+	// If no value was found return false
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, c.wrapError(err)
+	}
+	if !ttlMillis.Valid {
+		return 0, true, nil
+	}
+	// The row can expire between the check of the query and the calculation of the TTL
+	if ttlMillis.Int64 <= 0 {
+		return 0, false, nil
+	}
+	return time.Duration(ttlMillis.Int64) * time.Millisecond, true, nil
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -45,18 +285,25 @@ func (c Client) Set(k string, v interface{}) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is passed to the database driver.
+// Apart from that it works like Get.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
 	// TODO: Consider using RawBytes.
 	dataPtr := new([]byte)
-	err = c.GetStmt.QueryRow(k).Scan(dataPtr)
+	err = c.GetStmt.QueryRowContext(ctx, k).Scan(dataPtr)
 	// If no value was found return false
 	if err == sql.ErrNoRows {
 		return false, nil
 	} else if err != nil {
-		return false, err
+		return false, c.wrapError(err)
 	}
 	data := *dataPtr
 
@@ -67,17 +314,516 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is passed to the database driver.
+// Apart from that it works like Delete.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	_, err := c.DeleteStmt.ExecContext(ctx, k)
+	return c.wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	_, err := c.UpsertStmt.Exec(k, v)
+	return c.wrapError(err)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
 	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	err = c.GetStmt.QueryRow(k).Scan(&v)
+	// If no value was found return false
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, c.wrapError(err)
+	}
+	return v, true, nil
+}
+
+// SetReader stores the bytes read from r for the given key without marshalling them.
+// database/sql requires the whole value as query argument, so it's read into memory first.
+// The key must not be "" and the reader must not be nil.
+func (c Client) SetReader(k string, r io.Reader) error {
+	if err := util.CheckKeyAndReader(k, r); err != nil {
+		return err
+	}
+
+	v, err := ioutil.ReadAll(r)
+	if err != nil {
 		return err
 	}
+	_, err = c.UpsertStmt.Exec(k, v)
+	return c.wrapError(err)
+}
+
+// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+// database/sql retrieves the whole value, so the reader reads from memory.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetReader(k string) (r io.ReadCloser, found bool, err error) {
+	v, found, err := c.GetBytes(k)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(v)), true, nil
+}
 
-	_, err := c.DeleteStmt.Exec(k)
+// SetMulti stores the given values for the given keys.
+// With MultiStmts, the values are stored with one statement per MaxMultiKeys keys,
+// otherwise the UpsertStmt is executed for each key-value pair.
+// Multiple statements are executed in a single transaction, so either all values are stored or none.
+// vs[i] is the value for keys[i], so both slices must have the same length.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(keys []string, vs []interface{}) error {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return err
+	}
+
+	// Marshal all values before starting the transaction
+	data := make([][]byte, len(vs))
+	for i, v := range vs {
+		var err error
+		if data[i], err = c.Codec.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	if c.MultiStmts == nil {
+		return c.inTx(func(tx *sql.Tx) error {
+			stmt := tx.Stmt(c.UpsertStmt)
+			for i, k := range keys {
+				if _, err := stmt.Exec(k, data[i]); err != nil {
+					return c.wrapError(err)
+				}
+			}
+			return nil
+		})
+	}
+
+	// Upserting the same key twice in one statement isn't allowed by all databases
+	indexes := util.UniqueKeyIndexes(keys)
+	return c.execChunks(len(indexes), func(start, end int) (string, []interface{}) {
+		args := make([]interface{}, 0, 2*(end-start))
+		for _, i := range indexes[start:end] {
+			args = append(args, keys[i], data[i])
+		}
+		return c.MultiStmts.Upsert(end - start), args
+	})
+}
+
+// GetMulti retrieves the values for the given keys.
+// With MultiStmts, the values are retrieved with one statement per MaxMultiKeys keys,
+// otherwise the GetStmt is executed for each key in a single transaction.
+// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+// found[i] reports whether a value was found for keys[i].
+// No key must be "" and no pointer must be nil.
+func (c Client) GetMulti(keys []string, vs []interface{}) (found []bool, err error) {
+	if err := util.CheckKeysAndValues(keys, vs); err != nil {
+		return nil, err
+	}
+
+	data := make([][]byte, len(keys))
+	found = make([]bool, len(keys))
+	if c.MultiStmts != nil {
+		err = c.getChunks(keys, data, found)
+	} else {
+		err = c.getEach(keys, data, found)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		if !found[i] {
+			continue
+		}
+		if err := c.Codec.Unmarshal(data[i], vs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// getEach executes the GetStmt for each key in a single transaction
+// and sets the data and found entries for each key that has a value.
+func (c Client) getEach(keys []string, data [][]byte, found []bool) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		stmt := tx.Stmt(c.GetStmt)
+		for i, k := range keys {
+			err := stmt.QueryRow(k).Scan(&data[i])
+			if err == sql.ErrNoRows {
+				continue
+			} else if err != nil {
+				return c.wrapError(err)
+			}
+			found[i] = true
+		}
+		return nil
+	})
+}
+
+// getChunks executes the Get statement of the MultiStmts for each chunk of unique keys
+// and sets the data and found entries for each key that has a value.
+func (c Client) getChunks(keys []string, data [][]byte, found []bool) error {
+	indexes := make(map[string][]int, len(keys))
+	var unique []string
+	for i, k := range keys {
+		if _, ok := indexes[k]; !ok {
+			unique = append(unique, k)
+		}
+		indexes[k] = append(indexes[k], i)
+	}
+
+	for _, chunk := range chunks(len(unique)) {
+		args := make([]interface{}, 0, chunk[1]-chunk[0])
+		for _, k := range unique[chunk[0]:chunk[1]] {
+			args = append(args, k)
+		}
+		rows, err := c.C.Query(c.MultiStmts.Get(len(args)), args...)
+		if err != nil {
+			return c.wrapError(err)
+		}
+		for rows.Next() {
+			var k string
+			var v []byte
+			if err := rows.Scan(&k, &v); err != nil {
+				rows.Close()
+				return c.wrapError(err)
+			}
+			for _, i := range indexes[k] {
+				data[i] = v
+				found[i] = true
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return c.wrapError(err)
+		}
+		if err := rows.Err(); err != nil {
+			return c.wrapError(err)
+		}
+	}
+	return nil
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// With MultiStmts, the values are deleted with one statement per MaxMultiKeys keys,
+// otherwise the DeleteStmt is executed for each key.
+// Multiple statements are executed in a single transaction, so either all values are deleted or none.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	if c.MultiStmts == nil {
+		return c.inTx(func(tx *sql.Tx) error {
+			stmt := tx.Stmt(c.DeleteStmt)
+			for _, k := range keys {
+				if _, err := stmt.Exec(k); err != nil {
+					return c.wrapError(err)
+				}
+			}
+			return nil
+		})
+	}
+
+	return c.execChunks(len(keys), func(start, end int) (string, []interface{}) {
+		args := make([]interface{}, 0, end-start)
+		for _, k := range keys[start:end] {
+			args = append(args, k)
+		}
+		return c.MultiStmts.Delete(end - start), args
+	})
+}
+
+// execChunks executes the statements that stmt returns for each chunk [start, end) of n keys.
+// A single statement is executed on its own, multiple statements in a transaction.
+func (c Client) execChunks(n int, stmt func(start, end int) (query string, args []interface{})) error {
+	chunks := chunks(n)
+	if len(chunks) == 0 {
+		return nil
+	} else if len(chunks) == 1 {
+		query, args := stmt(chunks[0][0], chunks[0][1])
+		_, err := c.C.Exec(query, args...)
+		return c.wrapError(err)
+	}
+	return c.inTx(func(tx *sql.Tx) error {
+		for _, chunk := range chunks {
+			query, args := stmt(chunk[0], chunk[1])
+			if _, err := tx.Exec(query, args...); err != nil {
+				return c.wrapError(err)
+			}
+		}
+		return nil
+	})
+}
+
+// SetIfAbsent stores the given value for the given key with the SetIfAbsentStmt,
+// but only if no value is stored for the key yet.
+// It returns true if the value was stored.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfAbsent(k string, v interface{}) (stored bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+	if c.SetIfAbsentStmt == nil {
+		return false, fmt.Errorf("The SetIfAbsentStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.SetIfAbsentStmt.Exec(k, data)
+	if err != nil {
+		return false, c.wrapError(err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// CompareAndSwap stores the new value for the given key with the CompareAndSwapStmt,
+// but only if the currently stored value is equal to the old value.
+// The values are compared in their marshalled form.
+// If no value is stored for the key, the value isn't stored.
+// It returns true if the new value was stored.
+// The key must not be "" and neither of the values must be nil.
+func (c Client) CompareAndSwap(k string, old, new interface{}) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, old); err != nil {
+		return false, err
+	}
+	if err := util.CheckVal(new); err != nil {
+		return false, err
+	}
+	if c.CompareAndSwapStmt == nil {
+		return false, fmt.Errorf("The CompareAndSwapStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	oldData, err := c.Codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := c.Codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.CompareAndSwapStmt.Exec(newData, k, oldData)
+	if err != nil {
+		return false, c.wrapError(err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected > 0 || !bytes.Equal(oldData, newData) {
+		return rowsAffected > 0, nil
+	}
+	// Some databases (like MySQL by default) only count rows whose values were actually changed,
+	// so when the old and new value are equal, the stored value must be compared separately.
+	// That's not a race condition, because in that case the swap doesn't change anything.
+	var data []byte
+	err = c.GetStmt.QueryRow(k).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, c.wrapError(err)
+	}
+	return bytes.Equal(data, oldData), nil
+}
+
+// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+// The IncrStmt and the IncrResultStmt are executed in a single transaction.
+// The value is stored as decimal number, so Incr requires encoding.JSON as codec,
+// otherwise an error matching gokv.ErrUnsupported is returned.
+// If no value is stored for the key (or it's expired), the new value is delta.
+// A TTL that was set with SetWithTTL is kept.
+// The key must not be "".
+func (c Client) Incr(k string, delta int64) (int64, error) {
+	if err := util.CheckKey(k); err != nil {
+		return 0, err
+	}
+	if c.IncrStmt == nil || c.IncrResultStmt == nil {
+		return 0, fmt.Errorf("The IncrStmt or IncrResultStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+	if err := util.CheckCounterCodec(c.Codec); err != nil {
+		return 0, err
+	}
+
+	var value int64
+	err := c.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Stmt(c.IncrStmt).Exec(k, delta); err != nil {
+			return c.wrapError(err)
+		}
+		var data []byte
+		if err := tx.Stmt(c.IncrResultStmt).QueryRow(k).Scan(&data); err != nil {
+			return c.wrapError(err)
+		}
+		return c.Codec.Unmarshal(data, &value)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// Clear deletes all key-value pairs with the ClearStmt.
+func (c Client) Clear() error {
+	if c.ClearStmt == nil {
+		return fmt.Errorf("The ClearStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	_, err := c.ClearStmt.Exec()
+	return c.wrapError(err)
+}
+
+// Stats returns the number of key-value pairs that aren't expired with the CountStmt
+// and the size of the table with the SizeStmt, if it's set.
+// The statistics for caches are unknown (-1).
+func (c Client) Stats() (gokv.StoreStats, error) {
+	result := gokv.UnknownStats
+	if c.CountStmt == nil {
+		return result, fmt.Errorf("The CountStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	if err := c.CountStmt.QueryRow().Scan(&result.Keys); err != nil {
+		return gokv.UnknownStats, c.wrapError(err)
+	}
+	if c.SizeStmt != nil {
+		if err := c.SizeStmt.QueryRow().Scan(&result.Bytes); err != nil {
+			return gokv.UnknownStats, c.wrapError(err)
+		}
+	}
+	return result, nil
+}
+
+// Update calls fn with a new transaction, which is based on a database/sql transaction
+// with the database's default isolation level.
+// The UpsertStmt, GetStmt and DeleteStmt are used within the transaction.
+// The transaction is committed if fn returns nil, and rolled back otherwise.
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	return c.inTx(func(tx *sql.Tx) error {
+		return fn(transaction{
+			c:  c,
+			tx: tx,
+		})
+	})
+}
+
+// inTx calls fn with a new transaction, which is committed if fn returns nil
+// and rolled back otherwise.
+func (c Client) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := c.C.Begin()
+	if err != nil {
+		return c.wrapError(err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return c.wrapError(tx.Commit())
+}
+
+// wrapError wraps errors of the database driver into gokv's errors where possible.
+// database/sql doesn't export the error for a closed database, so its message is compared.
+func (c Client) wrapError(err error) error {
+	if err == nil {
+		return nil
+	} else if err == sql.ErrConnDone || err.Error() == "sql: database is closed" {
+		return util.WrapError(gokv.ErrClosed, err)
+	} else if c.WrapError != nil {
+		return c.WrapError(err)
+	}
 	return err
 }
 
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in the order defined by the KeysStmt.
+// The keys are collected before fn is called the first time,
+// so fn can call other methods of the client without needing a second connection.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (c Client) Keys(prefix string, fn func(k string) error) error {
+	if c.KeysStmt == nil {
+		return fmt.Errorf("The KeysStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+
+	rows, err := c.KeysStmt.Query(likeEscaper.Replace(prefix) + "%")
+	if err != nil {
+		return c.wrapError(err)
+	}
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			rows.Close()
+			return c.wrapError(err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Close(); err != nil {
+		return c.wrapError(err)
+	}
+	if err := rows.Err(); err != nil {
+		return c.wrapError(err)
+	}
+
+	for _, k := range keys {
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// likeEscaper escapes the characters that have a special meaning in LIKE patterns,
+// using "!" as escape character.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// StartSweeper starts a goroutine that deletes expired key-value pairs every interval,
+// using the DeleteExpiredStmt.
+// Without it, expired key-value pairs stay in the table until they're overwritten.
+// The goroutine is stopped by Close().
+func (c *Client) StartSweeper(interval time.Duration) error {
+	if c.DeleteExpiredStmt == nil {
+		return fmt.Errorf("The DeleteExpiredStmt of the client is nil: %w", gokv.ErrUnsupported)
+	}
+	sweeper := util.StartSweeper(interval, func() error {
+		_, err := c.DeleteExpiredStmt.Exec()
+		return err
+	})
+	c.sweeper = &sweeper
+	return nil
+}
+
 // Close closes the client.
 // It must be called to return all open connections to the connection pool and to release any open resources.
 func (c Client) Close() error {
+	if c.sweeper != nil {
+		c.sweeper.Stop()
+	}
 	return c.C.Close()
 }
 
@@ -95,3 +841,53 @@ func CreateDB(db *sql.DB, dbName string) error {
 	}
 	return nil
 }
+
+// transaction is a gokv.Tx for SQL databases.
+type transaction struct {
+	c  Client
+	tx *sql.Tx
+}
+
+// Set stores the given value for the given key in the transaction.
+// The key must not be "" and the value must not be nil.
+func (t transaction) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.c.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = t.tx.Stmt(t.c.UpsertStmt).Exec(k, data)
+	return t.c.wrapError(err)
+}
+
+// Get retrieves the value for the given key,
+// including the changes made earlier in the transaction.
+// The key must not be "" and the pointer must not be nil.
+func (t transaction) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	var data []byte
+	err = t.tx.Stmt(t.c.GetStmt).QueryRow(k).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, t.c.wrapError(err)
+	}
+	return true, t.c.Codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key in the transaction.
+// The key must not be "".
+func (t transaction) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	_, err := t.tx.Stmt(t.c.DeleteStmt).Exec(k)
+	return t.c.wrapError(err)
+}
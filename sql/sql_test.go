@@ -0,0 +1,309 @@
+package sql_test
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/sql"
+)
+
+// TestPlaceholders tests if the placeholders for multi-row statements are built properly.
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		actual   string
+		expected string
+	}{
+		{sql.ValuesList(2, 2, sql.QuestionMark), "(?, ?), (?, ?)"},
+		{sql.ValuesList(2, 3, sql.Dollar), "($1, $2, $3), ($4, $5, $6)"},
+		{sql.InList(3, sql.QuestionMark), "?, ?, ?"},
+		{sql.InList(3, sql.Dollar), "$1, $2, $3"},
+		{sql.InList(0, sql.Dollar), ""},
+	}
+	for _, test := range tests {
+		if test.actual != test.expected {
+			t.Errorf("Expected %q, but was: %q", test.expected, test.actual)
+		}
+	}
+}
+
+// TestMulti tests if SetMulti, GetMulti and DeleteMulti use the multi-row statements
+// and split them into chunks of sql.MaxMultiKeys keys.
+func TestMulti(t *testing.T) {
+	db, client := createClient(t)
+
+	// Few keys in a single statement without transaction, where the last value of a duplicate key wins
+	err := client.SetMulti([]string{"a", "b", "a"}, []interface{}{"1", "2", "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.expect(t, 0, "UPSERT 2")
+
+	// Many keys in multiple statements in a transaction
+	n := 2*sql.MaxMultiKeys + 1
+	keys := make([]string, n)
+	vs := make([]interface{}, n)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(i)
+		vs[i] = strconv.Itoa(i)
+	}
+	err = client.SetMulti(keys, vs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.expect(t, 1, "UPSERT "+strconv.Itoa(sql.MaxMultiKeys), "UPSERT "+strconv.Itoa(sql.MaxMultiKeys), "UPSERT 1")
+
+	// Duplicate and missing keys
+	getKeys := append([]string{"a", "missing", "a", "b"}, keys...)
+	getVs := make([]interface{}, len(getKeys))
+	for i := range getVs {
+		getVs[i] = new(string)
+	}
+	found, err := client.GetMulti(getKeys, getVs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.expect(t, 0, "GET "+strconv.Itoa(sql.MaxMultiKeys), "GET "+strconv.Itoa(sql.MaxMultiKeys), "GET 4")
+	expected := append([]string{"3", "", "3", "2"}, make([]string, n)...)
+	for i := range keys {
+		expected[i+4] = strconv.Itoa(i)
+	}
+	for i, k := range getKeys {
+		actual := *(getVs[i].(*string))
+		if found[i] != (k != "missing") || actual != expected[i] {
+			t.Errorf("Expected %q for key %v, but was: %q (found: %v)", expected[i], k, actual, found[i])
+		}
+	}
+
+	err = client.DeleteMulti(append(keys, "a", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.expect(t, 1, "DELETE "+strconv.Itoa(sql.MaxMultiKeys), "DELETE "+strconv.Itoa(sql.MaxMultiKeys), "DELETE 3")
+	if len(db.rows) != 0 {
+		t.Errorf("Expected all rows to be deleted, but %v are left", len(db.rows))
+	}
+
+	// No keys, no statement
+	err = client.DeleteMulti(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.expect(t, 0)
+}
+
+// TestMultiRollback tests if the transaction of multiple statements is rolled back when a statement fails.
+func TestMultiRollback(t *testing.T) {
+	db, client := createClient(t)
+	db.failOn = 2
+
+	n := sql.MaxMultiKeys + 1
+	keys := make([]string, n)
+	vs := make([]interface{}, n)
+	for i := range keys {
+		keys[i] = "k" + strconv.Itoa(i)
+		vs[i] = "v"
+	}
+	err := client.SetMulti(keys, vs)
+	if err != errFake {
+		t.Errorf("Expected %v, but was: %v", errFake, err)
+	}
+	if db.rollbacks != 1 {
+		t.Errorf("Expected 1 rollback, but was: %v", db.rollbacks)
+	}
+}
+
+func createClient(t *testing.T) (*fakeDB, sql.Client) {
+	name := t.Name()
+	db := &fakeDB{rows: make(map[string][]byte)}
+	fakeDBs.Store(name, db)
+	c, err := gosql.Open("gokvfake", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := sql.Client{
+		C:     c,
+		Codec: encoding.JSON,
+		MultiStmts: &sql.MultiStmts{
+			Upsert: func(n int) string {
+				return "UPSERT " + strconv.Itoa(n)
+			},
+			Get: func(n int) string {
+				return "GET " + strconv.Itoa(n)
+			},
+			Delete: func(n int) string {
+				return "DELETE " + strconv.Itoa(n)
+			},
+		},
+	}
+	return db, client
+}
+
+var errFake = errors.New("fake error")
+
+// fakeDBs contains the fakeDB for each data source name.
+var fakeDBs sync.Map
+
+func init() {
+	gosql.Register("gokvfake", fakeDriver{})
+}
+
+// fakeDB is a key-value table that records the executed statements.
+// The statements are the ones of the MultiStmts of createClient.
+type fakeDB struct {
+	lock      sync.Mutex
+	rows      map[string][]byte
+	stmts     []string
+	txs       int
+	rollbacks int
+	// failOn is the number of the statement that fails, counting from 1, or 0 if none fails.
+	failOn int
+}
+
+// expect checks if the given statements were executed with the given number of transactions,
+// and resets the recorded statements and transactions.
+func (db *fakeDB) expect(t *testing.T, txs int, stmts ...string) {
+	t.Helper()
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if db.txs != txs {
+		t.Errorf("Expected %v transactions, but was: %v", txs, db.txs)
+	}
+	if strings.Join(db.stmts, "; ") != strings.Join(stmts, "; ") {
+		t.Errorf("Expected the statements %q, but was: %q", stmts, db.stmts)
+	}
+	db.stmts = nil
+	db.txs = 0
+}
+
+// exec executes the given statement and returns the rows for GET statements.
+func (db *fakeDB) exec(query string, args []driver.Value) ([][]driver.Value, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	db.stmts = append(db.stmts, query)
+	if len(db.stmts) == db.failOn {
+		return nil, errFake
+	}
+	fields := strings.Fields(query)
+	if n, _ := strconv.Atoi(fields[1]); fields[0] == "UPSERT" && 2*n != len(args) || fields[0] != "UPSERT" && n != len(args) {
+		return nil, errors.New("Unexpected number of arguments for " + query + ": " + strconv.Itoa(len(args)))
+	}
+
+	var result [][]driver.Value
+	switch fields[0] {
+	case "UPSERT":
+		for i := 0; i < len(args); i += 2 {
+			db.rows[args[i].(string)] = args[i+1].([]byte)
+		}
+	case "GET":
+		for _, k := range args {
+			if v, ok := db.rows[k.(string)]; ok {
+				result = append(result, []driver.Value{k, v})
+			}
+		}
+	case "DELETE":
+		for _, k := range args {
+			delete(db.rows, k.(string))
+		}
+	}
+	return result, nil
+}
+
+type fakeDriver struct{}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, errors.New("Unknown database " + name)
+	}
+	return fakeConn{db.(*fakeDB)}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.db.lock.Lock()
+	defer c.db.lock.Unlock()
+	c.db.txs++
+	return fakeTx{c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDB
+}
+
+func (tx fakeTx) Commit() error {
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.lock.Lock()
+	defer tx.db.lock.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.db.exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.db.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"k", "v"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
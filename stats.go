@@ -0,0 +1,61 @@
+package gokv
+
+// StoreStats are statistics about a store, for example for monitoring the size of a cache.
+// Each implementation documents which statistics it reports and what they include.
+// Statistics that the implementation can't determine are -1.
+type StoreStats struct {
+	// Keys is the number of key-value pairs.
+	// Depending on the implementation it includes expired key-value pairs that weren't deleted yet.
+	Keys int64
+	// Bytes is the approximate size of the stored data in bytes,
+	// which depending on the implementation includes the overhead of the underlying store.
+	Bytes int64
+	// Hits is the number of lookups that found a value.
+	Hits int64
+	// Misses is the number of lookups that didn't find a value.
+	Misses int64
+	// Evictions is the number of key-value pairs that were removed to make room for new ones.
+	Evictions int64
+}
+
+// UnknownStats are StoreStats in which all statistics are unknown,
+// which implementations can use as starting point.
+var UnknownStats = StoreStats{
+	Keys:      -1,
+	Bytes:     -1,
+	Hits:      -1,
+	Misses:    -1,
+	Evictions: -1,
+}
+
+// Stats returns statistics about the store.
+// If the store implements StatsStore, its Stats method is used.
+// Otherwise, if the store implements Lister, the keys are counted
+// and all other statistics are unknown (-1).
+// If the store implements neither, ErrUnsupported is returned.
+func Stats(store Store) (StoreStats, error) {
+	if statsStore, ok := store.(StatsStore); ok {
+		return statsStore.Stats()
+	}
+	lister, ok := store.(Lister)
+	if !ok {
+		return UnknownStats, ErrUnsupported
+	}
+	return countKeys(lister)
+}
+
+// countKeys returns StoreStats with the number of keys that are listed by the given Lister
+// and all other statistics unknown.
+func countKeys(lister Lister) (StoreStats, error) {
+	result := UnknownStats
+	var keys int64
+	err := lister.Keys("", func(k string) error {
+		keys++
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Keys = keys
+	return result, nil
+}
@@ -1,5 +1,11 @@
 package gokv
 
+import (
+	"context"
+	"io"
+	"time"
+)
+
 // Store is an abstraction for different key-value store implementations.
 // A store must be able to store, retrieve and delete key-value pairs,
 // with the key being a string and the value being any Go interface{}.
@@ -38,3 +44,213 @@ type Store interface {
 	// is passed to your method, so you should always call it.
 	Close() error
 }
+
+// RawStore is a Store that can store and retrieve values as raw bytes, bypassing the codec.
+// This is useful for values that are already serialized, like protobuf messages,
+// images or compressed data, which would otherwise be encoded a second time.
+// Values stored with SetBytes are retrieved unchanged by GetBytes,
+// and values stored with Set are retrieved by GetBytes in their marshalled form.
+type RawStore interface {
+	Store
+	// SetBytes stores the given bytes for the given key without marshalling them.
+	// Like Set, it removes a previously set TTL.
+	// The key must not be "" and the value must not be nil.
+	SetBytes(k string, v []byte) error
+	// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+	// If no value is found it returns (nil, false, nil).
+	// The key must not be "".
+	GetBytes(k string) (v []byte, found bool, err error)
+}
+
+// StreamStore is a Store that can store and retrieve values as streams of raw bytes, bypassing the codec.
+// This is useful for large values like files or artifacts, which would otherwise be held in memory as a whole.
+// Like with RawStore, values stored with SetReader are retrieved unchanged by GetReader,
+// and values stored with Set are retrieved by GetReader in their marshalled form.
+// Depending on the implementation, the underlying store requires the whole value at once,
+// in which case it's buffered in memory after all.
+type StreamStore interface {
+	Store
+	// SetReader stores the bytes read from r until io.EOF for the given key without marshalling them.
+	// Like Set, it removes a previously set TTL.
+	// If reading from r fails, the error is returned and the previously stored value is kept.
+	// The key must not be "" and the reader must not be nil.
+	SetReader(k string, r io.Reader) error
+	// GetReader returns a reader for the stored bytes for the given key without unmarshalling them.
+	// The reader must be closed when you're done reading from it.
+	// If no value is found it returns (nil, false, nil).
+	// The key must not be "".
+	GetReader(k string) (r io.ReadCloser, found bool, err error)
+}
+
+// ContextStore is a Store that additionally offers context-aware variants of the methods
+// for storing, retrieving and deleting key-value pairs.
+// The context is passed to the underlying driver where possible,
+// so it can be used to cancel a call or to set a deadline for it.
+// Implementations whose underlying driver doesn't support contexts check the context
+// before executing the operation, so a canceled context still leads to an error.
+type ContextStore interface {
+	Store
+	// SetContext stores the given value for the given key.
+	// It behaves like Set, but uses the given context for the call to the underlying store.
+	SetContext(ctx context.Context, k string, v interface{}) error
+	// GetContext retrieves the value for the given key.
+	// It behaves like Get, but uses the given context for the call to the underlying store.
+	GetContext(ctx context.Context, k string, v interface{}) (found bool, err error)
+	// DeleteContext deletes the stored value for the given key.
+	// It behaves like Delete, but uses the given context for the call to the underlying store.
+	DeleteContext(ctx context.Context, k string) error
+}
+
+// Lister is a Store that can list the keys of its key-value pairs.
+type Lister interface {
+	Store
+	// Keys calls fn for each key that starts with the given prefix.
+	// An empty prefix matches all keys.
+	// The order in which the keys are passed to fn depends on the implementation.
+	// If fn returns an error, the iteration is stopped and the error is returned.
+	// It's safe to call Delete for the passed key from within fn,
+	// for example for cleaning up stale entries.
+	Keys(prefix string, fn func(k string) error) error
+}
+
+// ExpiringStore is a Store that can store key-value pairs with a time to live (TTL).
+// After the TTL has passed, the key-value pair is treated as if it was deleted.
+// Depending on the implementation, expired key-value pairs are deleted by the underlying store,
+// lazily when they're accessed or by a background sweeper.
+type ExpiringStore interface {
+	Store
+	// SetWithTTL stores the given value for the given key, with the given time to live.
+	// It behaves like Set, but the key-value pair expires after the TTL.
+	// A TTL of 0 means that the key-value pair doesn't expire.
+	// Setting a key-value pair with Set removes a previously set TTL.
+	// The precision of the TTL depends on the implementation,
+	// many round it up to full seconds.
+	// The TTL must not be negative.
+	SetWithTTL(k string, v interface{}, ttl time.Duration) error
+}
+
+// BatchStore is a Store that can store, retrieve and delete multiple key-value pairs at once,
+// typically with a single round trip to the underlying store or in a single transaction.
+// Use the package-level functions SetMulti, GetMulti and DeleteMulti
+// to work with any Store, including the ones that don't implement BatchStore.
+type BatchStore interface {
+	Store
+	// SetMulti stores the given values for the given keys.
+	// vs[i] is the value for keys[i], so both slices must have the same length.
+	// If a key occurs multiple times, the last value for it is stored.
+	// No key must be "" and no value must be nil.
+	SetMulti(keys []string, vs []interface{}) error
+	// GetMulti retrieves the values for the given keys.
+	// vs[i] must be a pointer for the value of keys[i], so both slices must have the same length.
+	// found[i] reports whether a value was found for keys[i].
+	// No key must be "" and no pointer must be nil.
+	GetMulti(keys []string, vs []interface{}) (found []bool, err error)
+	// DeleteMulti deletes the stored values for the given keys.
+	// Deleting non-existing key-value pairs does NOT lead to an error.
+	// No key must be "".
+	DeleteMulti(keys []string) error
+}
+
+// AtomicStore is a Store that can conditionally store key-value pairs in a single atomic operation,
+// which enables optimistic concurrency control.
+// Use the package-level functions SetIfAbsent and CompareAndSwap
+// to get ErrUnsupported for stores that don't implement AtomicStore.
+type AtomicStore interface {
+	Store
+	// SetIfAbsent stores the given value for the given key,
+	// but only if no value is stored for the key yet.
+	// An expired key-value pair counts as absent.
+	// It returns true if the value was stored.
+	// The key must not be "" and the value must not be nil.
+	SetIfAbsent(k string, v interface{}) (stored bool, err error)
+	// CompareAndSwap stores the new value for the given key,
+	// but only if the currently stored value is equal to the old value.
+	// The values are compared in their marshalled form,
+	// so the codec must marshal equal values to equal bytes
+	// (which isn't the case for gob and values containing maps, for example).
+	// If no value is stored for the key, the value isn't stored.
+	// Like Set, it removes a previously set TTL.
+	// It returns true if the new value was stored.
+	// The key must not be "" and neither of the values must be nil.
+	CompareAndSwap(k string, old, new interface{}) (swapped bool, err error)
+}
+
+// Counter is a Store that can atomically increment and decrement integer values,
+// for example for rate limits or view counters.
+// Use the package-level function Incr to get ErrUnsupported for stores that don't implement Counter.
+type Counter interface {
+	Store
+	// Incr adds delta to the int64 value that's stored for the given key and returns the new value.
+	// A negative delta decrements the value.
+	// If no value is stored for the key (or it's expired), the new value is delta.
+	// Unlike Set, it keeps a TTL that was set with SetWithTTL,
+	// so a counter can be used for fixed time windows, for example for rate limiting.
+	// The value can be retrieved with Get into an *int64.
+	// Incrementing a value that isn't an integer leads to an error,
+	// as does a result that overflows int64.
+	// The key must not be "".
+	Incr(k string, delta int64) (int64, error)
+}
+
+// Clearer is a Store that can delete all of its key-value pairs at once,
+// for example between integration tests or when offboarding a tenant.
+// Use the package-level function Clear to fall back to deleting the keys that are listed by a Lister.
+type Clearer interface {
+	Store
+	// Clear deletes all key-value pairs of the store.
+	// Which key-value pairs belong to the store depends on the implementation and its options,
+	// for example the table of an SQL store, the bucket of a bbolt store or the DB of a Redis store.
+	// Key-value pairs that are stored concurrently might not be deleted.
+	Clear() error
+}
+
+// StatsStore is a Store that can report statistics about itself, like the number of key-value pairs.
+// Use the package-level function Stats to fall back to counting the keys that are listed by a Lister.
+type StatsStore interface {
+	Store
+	// Stats returns statistics about the store.
+	// Statistics that the implementation can't determine are -1.
+	Stats() (StoreStats, error)
+}
+
+// Tx is a transaction of a Transactional store.
+// Its methods work like the ones of Store,
+// but the changes only become visible to others when the transaction is committed.
+// Get sees the changes made earlier in the same transaction.
+// A Tx must only be used within the function it was passed to.
+type Tx interface {
+	Set(k string, v interface{}) error
+	Get(k string, v interface{}) (found bool, err error)
+	Delete(k string) error
+}
+
+// Transactional is a Store that can store, retrieve and delete multiple key-value pairs in a transaction,
+// so that either all or none of the changes are applied.
+// Use the package-level function Update to get ErrUnsupported for stores that don't implement Transactional.
+type Transactional interface {
+	Store
+	// Update calls fn with a new transaction.
+	// If fn returns nil, the transaction is committed, otherwise it's rolled back
+	// and the error is returned.
+	// Depending on the implementation, fn is called again when the transaction
+	// conflicts with a concurrent one, so fn shouldn't have side effects other than using tx.
+	// The number and size of the operations in a transaction can be limited by the underlying store.
+	Update(fn func(tx Tx) error) error
+}
+
+// Watcher is a Store that can notify about changes of its key-value pairs.
+type Watcher interface {
+	Store
+	// Watch returns a channel on which an Event is sent for each change
+	// of a key-value pair whose key starts with the given prefix.
+	// An empty prefix matches all keys. For watching a single key, pass the key as prefix
+	// and ignore the events for other keys.
+	// Only changes that happen after Watch returned are sent.
+	// The channel is closed when the context is canceled,
+	// or when the watch fails (for example because the connection to the store was lost).
+	// Events that happened in the meantime are lost, so after the channel was closed,
+	// callers should consider all keys as changed, for example by invalidating their caches.
+	// The events must be received in a timely manner,
+	// as depending on the implementation they're either buffered or block the underlying notification mechanism.
+	Watch(ctx context.Context, prefix string) (<-chan Event, error)
+}
@@ -0,0 +1,43 @@
+package gokv
+
+import (
+	"reflect"
+)
+
+// Supports reports whether the given store supports the optional interface that iface points to,
+// for example gokv.Supports(store, (*gokv.Watcher)(nil)).
+// Wrappers like PrefixStore implement all optional interfaces and return ErrUnsupported
+// if the wrapped store doesn't support one of them, so a type assertion isn't enough to detect a capability.
+//
+// If the store doesn't implement the interface, Supports returns false.
+// Otherwise, if the store has a method Supports(iface interface{}) bool, its result is returned,
+// which wrappers use for interfaces that they support by falling back to other methods.
+// Otherwise, if the store has a method Unwrap() Store, the wrapped store is checked the same way.
+// Otherwise Supports returns true.
+//
+// Supports panics if iface isn't a non-nil pointer to an interface type.
+func Supports(store Store, iface interface{}) bool {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Ptr || ifaceType.Elem().Kind() != reflect.Interface {
+		panic("gokv: Supports called with an iface that isn't a pointer to an interface type")
+	}
+	for store != nil {
+		if !reflect.TypeOf(store).Implements(ifaceType.Elem()) {
+			return false
+		}
+		if s, ok := store.(supporter); ok {
+			return s.Supports(iface)
+		}
+		unwrapper, ok := store.(interface{ Unwrap() Store })
+		if !ok {
+			return true
+		}
+		store = unwrapper.Unwrap()
+	}
+	return false
+}
+
+// supporter is a store that reports which optional interfaces it supports, see Supports().
+type supporter interface {
+	Supports(iface interface{}) bool
+}
@@ -1,4 +1,8 @@
 /*
 Package syncmap contains an implementation of the `gokv.Store` interface for a Go `sync.Map`.
+
+The package registers itself for the URL scheme "syncmap", so a store can also be created with gokv.Open,
+for example with "syncmap://?codec=gob".
+The query parameter "codec" ("json" or "gob") corresponds to the field of Options.
 */
 package syncmap
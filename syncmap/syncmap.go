@@ -1,16 +1,25 @@
 package syncmap
 
 import (
+	"context"
+	"net/url"
+	"strings"
 	"sync"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Store is a gokv.Store implementation for a Go sync.Map.
 type Store struct {
-	m     *sync.Map
-	codec encoding.Codec
+	m *sync.Map
+	// writeLock serializes the changes together with the publishing of their events,
+	// so that watchers receive the events in the order of the changes.
+	// Reading doesn't require the lock.
+	writeLock   *sync.Mutex
+	broadcaster *util.Broadcaster
+	codec       encoding.Codec
 }
 
 // Set stores the given value for the given key.
@@ -26,7 +35,7 @@ func (s Store) Set(k string, v interface{}) error {
 		return err
 	}
 
-	s.m.Store(k, data)
+	s.set(k, data)
 	return nil
 }
 
@@ -60,14 +69,154 @@ func (s Store) Delete(k string) error {
 		return err
 	}
 
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	if _, found := s.m.Load(k); !found {
+		return nil
+	}
 	s.m.Delete(k)
+	s.broadcaster.Publish(gokv.Event{
+		Key:  k,
+		Type: gokv.EventDelete,
+	})
+	return nil
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The bytes are copied, so the passed slice can be reused afterwards.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	data := make([]byte, len(v))
+	copy(data, v)
+	s.set(k, data)
+	return nil
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// The returned slice is a copy, so changing it doesn't change the stored value.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	dataInterface, found := s.m.Load(k)
+	if !found {
+		return nil, false, nil
+	}
+	data := dataInterface.([]byte)
+
+	v = make([]byte, len(data))
+	copy(v, data)
+	return v, true, nil
+}
+
+// SetContext stores the given value for the given key.
+// Operations on the sync.Map can't be canceled, so the context is only checked before the operation.
+func (s Store) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (s Store) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return s.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(k)
+}
+
+// Keys calls fn for each key that starts with the given prefix.
+// The keys are passed in no particular order.
+// Like with sync.Map.Range, keys that are stored or deleted concurrently
+// may or may not be passed to fn.
+// If fn returns an error, the iteration is stopped and the error is returned.
+func (s Store) Keys(prefix string, fn func(k string) error) error {
+	var err error
+	s.m.Range(func(key, _ interface{}) bool {
+		k := key.(string)
+		if !strings.HasPrefix(k, prefix) {
+			return true
+		}
+		err = fn(k)
+		return err == nil
+	})
+	return err
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// The events are queued for each watcher, so a slow receiver doesn't block the store.
+// The channel is closed when the context is canceled or the store is closed.
+func (s Store) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return s.broadcaster.Watch(ctx, prefix), nil
+}
+
+// set stores the given data for the given key and notifies the watchers.
+func (s Store) set(k string, data []byte) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.m.Store(k, data)
+	s.broadcaster.Publish(gokv.Event{
+		Key:   k,
+		Type:  gokv.EventPut,
+		Value: data,
+	})
+}
+
+// Clear deletes all key-value pairs.
+// An EventDelete event is sent to the watchers for each key.
+func (s Store) Clear() error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.m.Range(func(k, _ interface{}) bool {
+		s.m.Delete(k)
+		s.broadcaster.Publish(gokv.Event{
+			Key:  k.(string),
+			Type: gokv.EventDelete,
+		})
+		return true
+	})
 	return nil
 }
 
+// Stats returns the number of key-value pairs and the total size of their keys and marshalled values.
+// Like with Keys, key-value pairs that are stored or deleted concurrently may or may not be counted.
+// The statistics for caches are unknown (-1).
+func (s Store) Stats() (gokv.StoreStats, error) {
+	result := gokv.UnknownStats
+	result.Keys = 0
+	result.Bytes = 0
+	s.m.Range(func(k, v interface{}) bool {
+		result.Keys++
+		result.Bytes += int64(len(k.(string)) + len(v.([]byte)))
+		return true
+	})
+	return result, nil
+}
+
 // Close closes the store.
 // When called, the store's pointer to the internal Go map is set to nil,
 // leading to the map being free for garbage collection.
 func (s Store) Close() error {
+	s.broadcaster.Close()
 	s.m = nil
 	return nil
 }
@@ -95,7 +244,25 @@ func NewStore(options Options) Store {
 	}
 
 	return Store{
-		m:     &sync.Map{},
-		codec: options.Codec,
+		m:           &sync.Map{},
+		writeLock:   new(sync.Mutex),
+		broadcaster: util.NewBroadcaster(),
+		codec:       options.Codec,
 	}
 }
+
+func init() {
+	gokv.Register("syncmap", newStoreFromURL)
+}
+
+// newStoreFromURL creates a new store from a URL that's passed to gokv.Open.
+func newStoreFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{}
+	q := util.NewURLQuery(u)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewStore(options), nil
+}
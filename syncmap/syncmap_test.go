@@ -129,6 +129,48 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestContextStore(store, t)
+}
+
+// TestKeys tests if listing the keys works properly.
+func TestKeys(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestLister(store, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+func TestWatch(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestWatcher(store, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+func TestRawStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestRawStore(store, t)
+}
+
+// TestClear tests if deleting all key-value pairs works properly.
+func TestClear(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestClearer(store, t)
+}
+
+// TestStats tests if the statistics of the store are plausible.
+func TestStats(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	test.TestStatsStore(store, t)
+}
+
 func createStore(t *testing.T, codec encoding.Codec) syncmap.Store {
 	options := syncmap.Options{
 		Codec: codec,
@@ -1,4 +1,8 @@
 /*
 Package tablestorage contains an implementation of the `gokv.Store` interface for Azure Table Storage.
+
+The package registers itself for the URL scheme "tablestorage", so a client can also be created with gokv.Open,
+for example with "tablestorage://gokv?connectionString=...", where the connection string must be URL-encoded.
+The host is used as TableName, and the query parameters "connectionString" and "codec" ("json" or "gob") correspond to the fields of Options.
 */
 package tablestorage
@@ -1,13 +1,17 @@
 package tablestorage
 
 import (
+	"context"
 	"crypto/md5"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/Azure/azure-sdk-for-go/storage"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -44,19 +48,7 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	partitionKey := c.partitionKeySupplier(k)
-	entity := c.c.GetEntityReference(partitionKey, k)
-	valMap := make(map[string]interface{})
-	valMap[valAttrName] = data
-	entity.Properties = valMap
-	entityOptions := storage.EntityOptions{
-		Timeout: opTimeout,
-	}
-	err = entity.InsertOrReplace(&entityOptions)
-	if err != nil {
-		return err
-	}
-	return nil
+	return c.set(k, data)
 }
 
 // Get retrieves the stored value for the given key.
@@ -70,63 +62,160 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 		return false, err
 	}
 
+	data, found, err := c.get(k)
+	if err != nil || !found {
+		return found, err
+	}
+
+	return true, c.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (c Client) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
 	partitionKey := c.partitionKeySupplier(k)
 	entity := c.c.GetEntityReference(partitionKey, k)
-	getEntityOptions := storage.GetEntityOptions{
-		Select: []string{valAttrName},
+	entityOptions := storage.EntityOptions{
+		Timeout: opTimeout,
 	}
-	timeout := uint(opTimeout)
-	err = entity.Get(timeout, storage.FullMetadata, &getEntityOptions)
+	err := entity.Delete(true, &entityOptions)
 	if err != nil {
 		storageErr, ok := err.(storage.AzureStorageServiceError)
 		if !ok {
-			return false, err
+			return err
 		}
 		// Handle AzureStorageServiceError.
 		// Return false if the key-value pair doesn't exist.
 		if storageErr.Code == "ResourceNotFound" {
-			return false, nil
+			return nil
 		}
-		return false, err
 	}
-	retrievedVal := entity.Properties[valAttrName]
-	data, ok := retrievedVal.([]byte)
-	if !ok {
-		return true, fmt.Errorf("The value belonging to the key was expected to be a slice of bytes, but wasn't. Key: %v", k)
+
+	return wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
 	}
 
-	return true, c.codec.Unmarshal(data, v)
+	return c.set(k, v)
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
 // The key must not be "".
-func (c Client) Delete(k string) error {
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return nil, false, err
 	}
 
+	return c.get(k)
+}
+
+// set stores the given data in the entity for the given key.
+func (c Client) set(k string, data []byte) error {
 	partitionKey := c.partitionKeySupplier(k)
 	entity := c.c.GetEntityReference(partitionKey, k)
+	valMap := make(map[string]interface{})
+	valMap[valAttrName] = data
+	entity.Properties = valMap
 	entityOptions := storage.EntityOptions{
 		Timeout: opTimeout,
 	}
-	err := entity.Delete(true, &entityOptions)
+	return wrapError(entity.InsertOrReplace(&entityOptions))
+}
+
+// get retrieves the stored data from the entity for the given key.
+func (c Client) get(k string) (data []byte, found bool, err error) {
+	partitionKey := c.partitionKeySupplier(k)
+	entity := c.c.GetEntityReference(partitionKey, k)
+	getEntityOptions := storage.GetEntityOptions{
+		Select: []string{valAttrName},
+	}
+	timeout := uint(opTimeout)
+	err = entity.Get(timeout, storage.FullMetadata, &getEntityOptions)
 	if err != nil {
 		storageErr, ok := err.(storage.AzureStorageServiceError)
 		if !ok {
-			return err
+			return nil, false, err
 		}
 		// Handle AzureStorageServiceError.
 		// Return false if the key-value pair doesn't exist.
 		if storageErr.Code == "ResourceNotFound" {
-			return nil
+			return nil, false, nil
 		}
+		return nil, false, wrapError(err)
+	}
+	retrievedVal := entity.Properties[valAttrName]
+	data, ok := retrievedVal.([]byte)
+	if !ok {
+		return nil, true, fmt.Errorf("The value belonging to the key was expected to be a slice of bytes, but wasn't. Key: %v", k)
 	}
 
+	return data, true, nil
+}
+
+// wrapError wraps errors of the Azure SDK into gokv's errors where possible.
+// Table Storage limits the size of a property (64 KiB) and of an entity (1 MiB).
+func wrapError(err error) error {
+	storageErr, ok := err.(storage.AzureStorageServiceError)
+	if ok && (storageErr.Code == "PropertyValueTooLarge" || storageErr.Code == "EntityTooLarge") {
+		return util.WrapError(gokv.ErrValueTooLarge, err)
+	}
 	return err
 }
 
+// SetContext stores the given value for the given key.
+// The Azure Storage SDK doesn't support contexts for Table Storage, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
+}
+
+// IsRetryable returns true for errors of Table Storage that are transient,
+// like timeouts of the service (which are likely with the short timeout of the operations) and when the server is busy.
+// It's used by the resilience package, which retries network errors anyway.
+func (c Client) IsRetryable(err error) bool {
+	var storageErr storage.AzureStorageServiceError
+	if !errors.As(err, &storageErr) {
+		return false
+	}
+	switch storageErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
 // Close closes the client.
 // In the Table Storage implementation this doesn't have any effect.
 func (c Client) Close() error {
@@ -241,6 +330,29 @@ func NewClient(options Options) (Client, error) {
 	return result, nil
 }
 
+func init() {
+	gokv.Register("tablestorage", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		TableName: u.Host,
+	}
+	q := util.NewURLQuery(u)
+	q.String("connectionString", &options.ConnectionString)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // EmptyPartitionKeySupplier returns an empty string as partition key for any given value.
 func EmptyPartitionKeySupplier(_ string) string {
 	return ""
@@ -1,18 +1,28 @@
 package tablestore
 
 import (
+	"context"
 	"errors"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/aliyun/aliyun-tablestore-go-sdk/tablestore"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 var keyAttrName = "k"
 
+// Table Store's limits for the size of a primary key column (1 KB) and of an attribute column (2 MB).
+// Requests that exceed them are rejected before they're sent.
+const (
+	maxKeyLength = 1024
+	maxValueSize = 2 * 1024 * 1024
+)
+
 // Client is a gokv.Store implementation for Table Store.
 type Client struct {
 	c         *tablestore.TableStoreClient
@@ -34,6 +44,88 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
+	return c.set(k, data)
+}
+
+// Get retrieves the stored value for the given key.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, found, err := c.get(k)
+	if err != nil || !found {
+		return found, err
+	}
+
+	return true, c.codec.Unmarshal(data, v)
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (c Client) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	if len(k) > maxKeyLength {
+		return gokv.ErrKeyTooLong
+	}
+
+	deleteRowRequest := tablestore.DeleteRowRequest{
+		DeleteRowChange: &tablestore.DeleteRowChange{
+			Condition: &tablestore.RowCondition{
+				RowExistenceExpectation: tablestore.RowExistenceExpectation_IGNORE,
+			},
+			PrimaryKey: &tablestore.PrimaryKey{
+				PrimaryKeys: []*tablestore.PrimaryKeyColumn{{
+					ColumnName: keyAttrName,
+					Value:      k,
+				}},
+			},
+			TableName: c.tableName,
+		},
+	}
+	_, err := c.c.DeleteRow(&deleteRowRequest)
+
+	return err
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return c.set(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return c.get(k)
+}
+
+// set stores the given data for the given key.
+func (c Client) set(k string, data []byte) error {
+	if len(k) > maxKeyLength {
+		return gokv.ErrKeyTooLong
+	}
+	if len(data) > maxValueSize {
+		return gokv.ErrValueTooLarge
+	}
+
 	putRowRequest := tablestore.PutRowRequest{
 		PutRowChange: &tablestore.PutRowChange{
 			Condition: &tablestore.RowCondition{
@@ -52,19 +144,14 @@ func (c Client) Set(k string, v interface{}) error {
 			TableName: c.tableName,
 		},
 	}
-	_, err = c.c.PutRow(&putRowRequest)
+	_, err := c.c.PutRow(&putRowRequest)
 	return err
 }
 
-// Get retrieves the stored value for the given key.
-// You need to pass a pointer to the value, so in case of a struct
-// the automatic unmarshalling can populate the fields of the object
-// that v points to with the values of the retrieved object's values.
-// If no value is found it returns (false, nil).
-// The key must not be "" and the pointer must not be nil.
-func (c Client) Get(k string, v interface{}) (found bool, err error) {
-	if err := util.CheckKeyAndValue(k, v); err != nil {
-		return false, err
+// get retrieves the stored data for the given key.
+func (c Client) get(k string) (data []byte, found bool, err error) {
+	if len(k) > maxKeyLength {
+		return nil, false, gokv.ErrKeyTooLong
 	}
 
 	getRowRequest := tablestore.GetRowRequest{
@@ -82,52 +169,75 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 	}
 	getRowResponse, err := c.c.GetRow(&getRowRequest)
 	if err != nil {
-		return false, err
+		return nil, false, err
 	}
 	// Return false if no value was found
 	if len(getRowResponse.Columns) == 0 {
-		return false, nil
+		return nil, false, nil
 	} else if len(getRowResponse.Columns) > 1 {
-		return false, errors.New("The returned GetRowResponse should only contain one column, but it contains more than one")
+		return nil, false, errors.New("The returned GetRowResponse should only contain one column, but it contains more than one")
 	}
 	attributeColumn := getRowResponse.Columns[0]
 	if attributeColumn == nil {
-		return true, errors.New("A key-value pair for the key was found, but it didn't contain a value")
+		return nil, true, errors.New("A key-value pair for the key was found, but it didn't contain a value")
 	}
 	dataIface := attributeColumn.Value
 	data, ok := dataIface.([]byte)
 	if !ok {
-		return false, errors.New("The returned value was expected to be a slice of bytes, but it wasn't")
+		return nil, false, errors.New("The returned value was expected to be a slice of bytes, but it wasn't")
 	}
 
-	return true, c.codec.Unmarshal(data, v)
+	return data, true, nil
 }
 
-// Delete deletes the stored value for the given key.
-// Deleting a non-existing key-value pair does NOT lead to an error.
-// The key must not be "".
-func (c Client) Delete(k string) error {
-	if err := util.CheckKey(k); err != nil {
+// SetContext stores the given value for the given key.
+// The Table Store SDK doesn't support contexts, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
+	return c.Set(k, v)
+}
 
-	deleteRowRequest := tablestore.DeleteRowRequest{
-		DeleteRowChange: &tablestore.DeleteRowChange{
-			Condition: &tablestore.RowCondition{
-				RowExistenceExpectation: tablestore.RowExistenceExpectation_IGNORE,
-			},
-			PrimaryKey: &tablestore.PrimaryKey{
-				PrimaryKeys: []*tablestore.PrimaryKeyColumn{{
-					ColumnName: keyAttrName,
-					Value:      k,
-				}},
-			},
-			TableName: c.tableName,
-		},
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
 	}
-	_, err := c.c.DeleteRow(&deleteRowRequest)
+	return c.Get(k, v)
+}
 
-	return err
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
+}
+
+// retryableErrCodes are the error codes of Table Store that are transient.
+// The client library retries them only once (see NewClient) and only for some operations.
+var retryableErrCodes = map[string]bool{
+	tablestore.ROW_OPERATION_CONFLICT:   true,
+	tablestore.NOT_ENOUGH_CAPACITY_UNIT: true,
+	tablestore.TABLE_NOT_READY:          true,
+	tablestore.PARTITION_UNAVAILABLE:    true,
+	tablestore.SERVER_BUSY:              true,
+	tablestore.STORAGE_SERVER_BUSY:      true,
+	tablestore.QUOTA_EXHAUSTED:          true,
+	tablestore.STORAGE_TIMEOUT:          true,
+	tablestore.SERVER_UNAVAILABLE:       true,
+	tablestore.INTERNAL_SERVER_ERROR:    true,
+}
+
+// IsRetryable returns true for errors of Table Store that are transient,
+// like when the server is busy, the capacity units are exhausted or a partition is unavailable.
+// It's used by the resilience package, which retries network errors anyway.
+func (c Client) IsRetryable(err error) bool {
+	var otsErr *tablestore.OtsError
+	return errors.As(err, &otsErr) && retryableErrCodes[otsErr.Code]
 }
 
 // Close closes the client.
@@ -246,3 +356,34 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("tablestore", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		EndpointURL:  "https://" + u.Host,
+		InstanceName: strings.SplitN(u.Hostname(), ".", 2)[0],
+		TableName:    strings.Trim(u.Path, "/"),
+	}
+	if u.User != nil {
+		options.AccessKeyID = u.User.Username()
+		options.AccessKeySecret, _ = u.User.Password()
+	}
+	q := util.NewURLQuery(u)
+	q.String("instanceName", &options.InstanceName)
+	q.Int("reservedReadCap", &options.ReservedReadCap)
+	q.Int("reservedWriteCap", &options.ReservedWriteCap)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
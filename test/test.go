@@ -1,10 +1,18 @@
 package test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
 	"math/rand"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 
@@ -85,6 +93,14 @@ func TestStore(store gokv.Store, t *testing.T) {
 	if found {
 		t.Error("A value was found, but no value was expected")
 	}
+
+	// Invalid parameters must lead to the corresponding sentinel errors
+	if err = store.Set("", val); !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected an error matching gokv.ErrEmptyKey, but was: %v", err)
+	}
+	if err = store.Set(key, nil); !errors.Is(err, gokv.ErrNilValue) {
+		t.Errorf("Expected an error matching gokv.ErrNilValue, but was: %v", err)
+	}
 }
 
 // TestTypes tests if setting and getting values works with all Go types.
@@ -354,3 +370,1242 @@ func InteractWithStore(store gokv.Store, key string, t *testing.T, waitGroup *sy
 		t.Error(err)
 	}
 }
+
+// TestRawStore tests if storing and retrieving raw bytes works properly,
+// and if raw values and marshalled values are interchangeable.
+func TestRawStore(store gokv.RawStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Invalid parameters
+	if err := store.SetBytes("", []byte("foo")); !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected an error matching gokv.ErrEmptyKey, but was: %v", err)
+	}
+	if err := store.SetBytes(key, nil); !errors.Is(err, gokv.ErrNilValue) {
+		t.Errorf("Expected an error matching gokv.ErrNilValue, but was: %v", err)
+	}
+	if _, _, err := store.GetBytes(""); !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected an error matching gokv.ErrEmptyKey, but was: %v", err)
+	}
+
+	// Initially the key shouldn't exist
+	data, found, err := store.GetBytes(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found || data != nil {
+		t.Error("A value was found, but no value was expected")
+	}
+
+	// Store bytes that aren't valid in any encoding format.
+	// Changing the passed slice afterwards must not change the stored value.
+	expected := []byte{0x00, 0xff, 0xfe, '{', 0x80}
+	val := append([]byte(nil), expected...)
+	if err = store.SetBytes(key, val); err != nil {
+		t.Error(err)
+	}
+	val[0] = 'x'
+	data, found, err = store.GetBytes(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Expected: %v, but was: %v", expected, data)
+	}
+
+	// Values stored with Set must be retrievable in their marshalled form,
+	// and storing that form with SetBytes must lead to the same value.
+	foo := Foo{
+		Bar: "baz",
+	}
+	if err = store.Set(key, foo); err != nil {
+		t.Error(err)
+	}
+	data, found, err = store.GetBytes(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if err = store.Delete(key); err != nil {
+		t.Error(err)
+	}
+	if err = store.SetBytes(key, data); err != nil {
+		t.Error(err)
+	}
+	actualPtr := new(Foo)
+	found, err = store.Get(key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != foo {
+		t.Errorf("Expected: %v, but was: %v", foo, *actualPtr)
+	}
+
+	// Delete
+	if err = store.Delete(key); err != nil {
+		t.Error(err)
+	}
+	// Key-value pair shouldn't exist anymore
+	_, found, err = store.GetBytes(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+}
+
+// TestStreamStore tests if storing and retrieving values as streams works properly,
+// if a failing reader keeps the previously stored value
+// and if streamed values and marshalled values are interchangeable.
+func TestStreamStore(store gokv.StreamStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Invalid parameters
+	if err := store.SetReader("", bytes.NewReader([]byte("foo"))); !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected an error matching gokv.ErrEmptyKey, but was: %v", err)
+	}
+	if err := store.SetReader(key, nil); !errors.Is(err, gokv.ErrNilValue) {
+		t.Errorf("Expected an error matching gokv.ErrNilValue, but was: %v", err)
+	}
+	if _, _, err := store.GetReader(""); !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected an error matching gokv.ErrEmptyKey, but was: %v", err)
+	}
+
+	// Initially the key shouldn't exist
+	r, found, err := store.GetReader(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found || r != nil {
+		t.Error("A value was found, but no value was expected")
+	}
+
+	// Store a value that's larger than typical buffer sizes
+	expected := make([]byte, 1<<20)
+	rand.Read(expected)
+	if err = store.SetReader(key, bytes.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	data := readStream(store, key, t)
+	if !bytes.Equal(data, expected) {
+		t.Errorf("Expected a value of %v bytes, but was a different value of %v bytes", len(expected), len(data))
+	}
+
+	// A failing reader must lead to an error and the previous value must be kept
+	readErr := errors.New("read error")
+	failingReader := io.MultiReader(bytes.NewReader([]byte("foo")), errReader{readErr})
+	if err = store.SetReader(key, failingReader); !errors.Is(err, readErr) {
+		t.Errorf("Expected an error matching the reader's error, but was: %v", err)
+	}
+	data = readStream(store, key, t)
+	if !bytes.Equal(data, expected) {
+		t.Error("The previously stored value was changed by the failing reader")
+	}
+
+	// Values stored with Set must be retrievable in their marshalled form,
+	// and storing that form with SetReader must lead to the same value.
+	foo := Foo{
+		Bar: "baz",
+	}
+	if err = store.Set(key, foo); err != nil {
+		t.Error(err)
+	}
+	data = readStream(store, key, t)
+	if err = store.SetReader(key, bytes.NewReader(data)); err != nil {
+		t.Error(err)
+	}
+	actualPtr := new(Foo)
+	found, err = store.Get(key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != foo {
+		t.Errorf("Expected: %v, but was: %v", foo, *actualPtr)
+	}
+
+	// Delete
+	if err = store.Delete(key); err != nil {
+		t.Error(err)
+	}
+	// Key-value pair shouldn't exist anymore
+	_, found, err = store.GetReader(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+}
+
+// readStream reads the whole value for the given key with GetReader and closes the reader.
+func readStream(store gokv.StreamStore, key string, t *testing.T) []byte {
+	r, found, err := store.GetReader(key)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+		return nil
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Error(err)
+	}
+	return data
+}
+
+// errReader is an io.Reader that always fails with the given error.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// TestContextStore tests if the context-aware methods of the store work properly.
+// With a valid context they must behave like their counterparts without context,
+// with a canceled context they must return an error.
+func TestContextStore(store gokv.ContextStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+	ctx := context.Background()
+
+	// Initially the key shouldn't exist
+	found, err := store.GetContext(ctx, key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+
+	// Store an object
+	val := Foo{
+		Bar: "baz",
+	}
+	err = store.SetContext(ctx, key, val)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Retrieve the object
+	expected := val
+	actualPtr := new(Foo)
+	found, err = store.GetContext(ctx, key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	actual := *actualPtr
+	if actual != expected {
+		t.Errorf("Expected: %v, but was: %v", expected, actual)
+	}
+
+	// A canceled context must lead to errors
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = store.SetContext(canceledCtx, key, val)
+	if err == nil {
+		t.Error("An error was expected")
+	}
+	_, err = store.GetContext(canceledCtx, key, new(Foo))
+	if err == nil {
+		t.Error("An error was expected")
+	}
+	err = store.DeleteContext(canceledCtx, key)
+	if err == nil {
+		t.Error("An error was expected")
+	}
+
+	// Delete
+	err = store.DeleteContext(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	// Key-value pair shouldn't exist anymore
+	found, err = store.GetContext(ctx, key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+}
+
+// TestLister tests if listing the keys of the store works properly.
+// It only checks the keys it created itself, so other key-value pairs
+// in the store don't lead to a test failure.
+func TestLister(store gokv.Lister, t *testing.T) {
+	n := strconv.FormatInt(rand.Int63(), 10)
+	prefix := n + "-k-"
+	keys := []string{prefix + "a", prefix + "b", prefix + "c/d"}
+	otherKey := "x" + prefix
+	// The prefix matching must be case-sensitive
+	upperKey := n + "-K-e"
+	for _, k := range append(keys, otherKey, upperKey) {
+		if err := store.Set(k, "foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the keys with the prefix must be listed
+	var actual []string
+	err := store.Keys(prefix, func(k string) error {
+		actual = append(actual, k)
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	sort.Strings(actual)
+	if diff := deep.Equal(actual, keys); diff != nil {
+		t.Error(diff)
+	}
+
+	// An empty prefix must list all keys, including the ones without the prefix
+	all := make(map[string]bool)
+	err = store.Keys("", func(k string) error {
+		all[k] = true
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	for _, k := range append(keys, otherKey, upperKey) {
+		if !all[k] {
+			t.Errorf("Key %v wasn't listed, but should have been", k)
+		}
+	}
+
+	// An error returned by fn must stop the iteration and be returned
+	errStop := errors.New("stop")
+	calls := 0
+	err = store.Keys(prefix, func(k string) error {
+		calls++
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("Expected error %v, but was: %v", errStop, err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call, but was: %v", calls)
+	}
+
+	// Deleting the passed keys from within fn must work
+	err = store.Keys(prefix, func(k string) error {
+		return store.Delete(k)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	err = store.Keys(prefix, func(k string) error {
+		t.Errorf("Key %v was listed, but should have been deleted", k)
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	for _, k := range []string{otherKey, upperKey} {
+		if err = store.Delete(k); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestExpiringStore tests if storing key-value pairs with a TTL works properly.
+// As many implementations only support TTLs in full seconds,
+// the test uses a TTL of 2 seconds and takes a few seconds to run.
+func TestExpiringStore(store gokv.ExpiringStore, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	expiringKey := prefix + "expiring"
+	overwrittenKey := prefix + "overwritten"
+	permanentKey := prefix + "permanent"
+	val := Foo{
+		Bar: "baz",
+	}
+
+	// A negative TTL is invalid
+	err := store.SetWithTTL(expiringKey, val, -time.Second)
+	if !errors.Is(err, gokv.ErrInvalidTTL) {
+		t.Errorf("Expected an error matching gokv.ErrInvalidTTL, but was: %v", err)
+	}
+
+	ttl := 2 * time.Second
+	if err = store.SetWithTTL(expiringKey, val, ttl); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.SetWithTTL(overwrittenKey, val, ttl); err != nil {
+		t.Fatal(err)
+	}
+	// Set must remove the TTL
+	if err = store.Set(overwrittenKey, val); err != nil {
+		t.Fatal(err)
+	}
+	// A TTL of 0 means no expiration
+	if err = store.SetWithTTL(permanentKey, val, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the TTL has passed the value must be found
+	actualPtr := new(Foo)
+	found, err := store.Get(expiringKey, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	time.Sleep(ttl + time.Second)
+
+	// After the TTL has passed the value mustn't be found anymore
+	found, err = store.Get(expiringKey, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+	for _, k := range []string{overwrittenKey, permanentKey} {
+		found, err = store.Get(k, new(Foo))
+		if err != nil {
+			t.Error(err)
+		}
+		if !found {
+			t.Errorf("No value was found for key %v, but should have been", k)
+		}
+	}
+
+	// If the store can list its keys, expired keys mustn't be listed
+	if lister, ok := store.(gokv.Lister); ok {
+		err = lister.Keys(expiringKey, func(k string) error {
+			t.Errorf("Key %v was listed, but should have expired", k)
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, k := range []string{expiringKey, overwrittenKey, permanentKey} {
+		if err = store.Delete(k); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TTLStore is a store that can store raw bytes with a TTL and return the remaining TTL of a key-value pair,
+// which is required by the dump package for exporting and importing key-value pairs with their TTLs.
+type TTLStore interface {
+	gokv.RawStore
+	SetBytesWithTTL(k string, v []byte, ttl time.Duration) error
+	TTL(k string) (ttl time.Duration, found bool, err error)
+}
+
+// TestTTLStore tests if storing raw bytes with a TTL and retrieving the remaining TTL works properly.
+func TestTTLStore(store TTLStore, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	expiringKey := prefix + "expiring"
+	permanentKey := prefix + "permanent"
+	val := []byte("some value")
+
+	// Invalid TTL and key
+	if err := store.SetBytesWithTTL(expiringKey, val, -time.Second); !errors.Is(err, gokv.ErrInvalidTTL) {
+		t.Errorf("Expected an error matching gokv.ErrInvalidTTL, but was: %v", err)
+	}
+	if _, _, err := store.TTL(""); err == nil {
+		t.Error("An error was expected")
+	}
+
+	ttl := time.Hour
+	if err := store.SetBytesWithTTL(expiringKey, val, ttl); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetBytesWithTTL(permanentKey, val, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	data, found, err := store.GetBytes(expiringKey)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || string(data) != string(val) {
+		t.Errorf("Expected %q, but was: %q (found: %v)", val, data, found)
+	}
+	// Some implementations store the expiry time with a precision of seconds
+	remaining, found, err := store.TTL(expiringKey)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || remaining <= ttl-time.Minute || remaining > ttl+time.Second {
+		t.Errorf("Expected a TTL of about %v, but was: %v (found: %v)", ttl, remaining, found)
+	}
+	remaining, found, err = store.TTL(permanentKey)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || remaining != 0 {
+		t.Errorf("Expected no TTL, but was: %v (found: %v)", remaining, found)
+	}
+
+	// SetBytes must remove the TTL
+	if err = store.SetBytes(expiringKey, val); err != nil {
+		t.Fatal(err)
+	}
+	remaining, found, err = store.TTL(expiringKey)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found || remaining != 0 {
+		t.Errorf("Expected no TTL, but was: %v (found: %v)", remaining, found)
+	}
+
+	// Missing key-value pairs aren't found
+	_, found, err = store.TTL(prefix + "missing")
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A TTL was found, but no key-value pair was expected")
+	}
+
+	for _, k := range []string{expiringKey, permanentKey} {
+		if err = store.Delete(k); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestBatchStore tests if storing, retrieving and deleting multiple key-value pairs at once works properly.
+// It also compares the results with the ones of the generic fallback functions of the gokv package.
+func TestBatchStore(store gokv.BatchStore, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	keys := []string{prefix + "a", prefix + "b", prefix + "c"}
+	missingKey := prefix + "missing"
+
+	// Different lengths are invalid
+	err := store.SetMulti(keys, []interface{}{Foo{Bar: "a"}})
+	if err == nil {
+		t.Error("An error was expected")
+	}
+	_, err = store.GetMulti(keys, []interface{}{new(Foo)})
+	if err == nil {
+		t.Error("An error was expected")
+	}
+
+	// Empty slices are valid
+	if err = store.SetMulti(nil, nil); err != nil {
+		t.Error(err)
+	}
+	if _, err = store.GetMulti(nil, nil); err != nil {
+		t.Error(err)
+	}
+	if err = store.DeleteMulti(nil); err != nil {
+		t.Error(err)
+	}
+
+	// The last value of a duplicate key wins
+	vals := []interface{}{Foo{Bar: "x"}, Foo{Bar: "b"}, Foo{Bar: "c"}, Foo{Bar: "a"}}
+	err = store.SetMulti([]string{keys[0], keys[1], keys[2], keys[0]}, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getKeys := []string{keys[0], missingKey, keys[1], keys[2], keys[0]}
+	expectedFound := []bool{true, false, true, true, true}
+	expectedVals := []Foo{{Bar: "a"}, {}, {Bar: "b"}, {Bar: "c"}, {Bar: "a"}}
+	check := func(found []bool, err error, ptrs []interface{}) {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if diff := deep.Equal(found, expectedFound); diff != nil {
+			t.Error(diff)
+		}
+		for i, ptr := range ptrs {
+			if expectedFound[i] && *ptr.(*Foo) != expectedVals[i] {
+				t.Errorf("Expected: %v, but was: %v", expectedVals[i], *ptr.(*Foo))
+			}
+		}
+	}
+	newPtrs := func() []interface{} {
+		ptrs := make([]interface{}, len(getKeys))
+		for i := range ptrs {
+			ptrs[i] = new(Foo)
+		}
+		return ptrs
+	}
+	ptrs := newPtrs()
+	found, err := store.GetMulti(getKeys, ptrs)
+	check(found, err, ptrs)
+	// The fallback must lead to the same result
+	ptrs = newPtrs()
+	found, err = gokv.GetMulti(storeOnly{store}, getKeys, ptrs)
+	check(found, err, ptrs)
+
+	// Delete
+	if err = store.DeleteMulti([]string{keys[0], keys[1], missingKey}); err != nil {
+		t.Error(err)
+	}
+	found, err = store.GetMulti(keys, []interface{}{new(Foo), new(Foo), new(Foo)})
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := deep.Equal(found, []bool{false, false, true}); diff != nil {
+		t.Error(diff)
+	}
+	if err = gokv.DeleteMulti(storeOnly{store}, keys); err != nil {
+		t.Error(err)
+	}
+	ok, err := store.Get(keys[2], new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if ok {
+		t.Error("A value was found, but no value was expected")
+	}
+}
+
+// TestAtomicStore tests if the conditional storing of values works properly,
+// including when multiple goroutines use it concurrently.
+func TestAtomicStore(store gokv.AtomicStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Invalid parameters
+	if _, err := store.SetIfAbsent("", Foo{}); err == nil {
+		t.Error("An error was expected")
+	}
+	if _, err := store.CompareAndSwap(key, Foo{}, nil); err == nil {
+		t.Error("An error was expected")
+	}
+
+	// Swapping without stored value
+	swapped, err := store.CompareAndSwap(key, Foo{Bar: "a"}, Foo{Bar: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swapped {
+		t.Error("The value was swapped, but no value was stored")
+	}
+
+	stored, err := store.SetIfAbsent(key, Foo{Bar: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored {
+		t.Error("The value wasn't stored, but no value was stored before")
+	}
+	stored, err = store.SetIfAbsent(key, Foo{Bar: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored {
+		t.Error("The value was stored, but a value was stored before")
+	}
+
+	// Swapping with wrong old value
+	swapped, err = store.CompareAndSwap(key, Foo{Bar: "x"}, Foo{Bar: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swapped {
+		t.Error("The value was swapped, but the old value was wrong")
+	}
+	swapped, err = store.CompareAndSwap(key, Foo{Bar: "a"}, Foo{Bar: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !swapped {
+		t.Error("The value wasn't swapped, but the old value was correct")
+	}
+	// Swapping with an equal value
+	swapped, err = store.CompareAndSwap(key, Foo{Bar: "b"}, Foo{Bar: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !swapped {
+		t.Error("The value wasn't swapped, but the old value was correct")
+	}
+	actual := Foo{}
+	found, err := store.Get(key, &actual)
+	handleGetError(t, err, found)
+	if actual.Bar != "b" {
+		t.Errorf("Expected: %v, but was: %v", "b", actual.Bar)
+	}
+
+	// The package-level functions must use the methods or report that they're unsupported
+	if _, err = gokv.SetIfAbsent(store, key, Foo{}); err != nil {
+		t.Error(err)
+	}
+	if _, err = gokv.SetIfAbsent(storeOnly{store}, key, Foo{}); err != gokv.ErrUnsupported {
+		t.Errorf("Expected: %v, but was: %v", gokv.ErrUnsupported, err)
+	}
+	if _, err = gokv.CompareAndSwap(storeOnly{store}, key, Foo{}, Foo{}); err != gokv.ErrUnsupported {
+		t.Errorf("Expected: %v, but was: %v", gokv.ErrUnsupported, err)
+	}
+
+	// Only one of multiple concurrent SetIfAbsent calls must succeed,
+	// and concurrent increments with CompareAndSwap must not get lost.
+	goroutineCount := 10
+	key = strconv.FormatInt(rand.Int63(), 10)
+	var storedCount int32
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(goroutineCount)
+	for i := 0; i < goroutineCount; i++ {
+		go func() {
+			defer waitGroup.Done()
+			stored, err := store.SetIfAbsent(key, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if stored {
+				atomic.AddInt32(&storedCount, 1)
+			}
+			for {
+				var counter int
+				if _, err := store.Get(key, &counter); err != nil {
+					t.Error(err)
+					return
+				}
+				swapped, err := store.CompareAndSwap(key, counter, counter+1)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if swapped {
+					return
+				}
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if storedCount != 1 {
+		t.Errorf("Expected %v successful SetIfAbsent calls, but there were %v", 1, storedCount)
+	}
+	var counter int
+	found, err = store.Get(key, &counter)
+	handleGetError(t, err, found)
+	if counter != goroutineCount {
+		t.Errorf("Expected: %v, but was: %v", goroutineCount, counter)
+	}
+}
+
+// TestCounter tests if incrementing and decrementing values works properly,
+// if the values can be retrieved with Get and if concurrent increments don't get lost.
+// Negative values aren't tested, because Memcached doesn't support them.
+func TestCounter(store gokv.Counter, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Invalid parameters
+	if _, err := store.Incr("", 1); !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected an error matching gokv.ErrEmptyKey, but was: %v", err)
+	}
+
+	// Without stored value the new value is the delta
+	value, err := store.Incr(key, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 5 {
+		t.Errorf("Expected: %v, but was: %v", 5, value)
+	}
+	value, err = store.Incr(key, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 8 {
+		t.Errorf("Expected: %v, but was: %v", 8, value)
+	}
+	value, err = store.Incr(key, -2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 6 {
+		t.Errorf("Expected: %v, but was: %v", 6, value)
+	}
+	var actual int64
+	found, err := store.Get(key, &actual)
+	handleGetError(t, err, found)
+	if actual != 6 {
+		t.Errorf("Expected: %v, but was: %v", 6, actual)
+	}
+
+	// After deleting the value, the counter must start again
+	if err = store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	value, err = store.Incr(key, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 1 {
+		t.Errorf("Expected: %v, but was: %v", 1, value)
+	}
+
+	// The package-level function must use the method or report that it's unsupported
+	if value, err = gokv.Incr(store, key, 1); err != nil {
+		t.Error(err)
+	} else if value != 2 {
+		t.Errorf("Expected: %v, but was: %v", 2, value)
+	}
+	if _, err = gokv.Incr(storeOnly{store}, key, 1); err != gokv.ErrUnsupported {
+		t.Errorf("Expected: %v, but was: %v", gokv.ErrUnsupported, err)
+	}
+
+	// Concurrent increments must not get lost
+	goroutineCount := 10
+	incrCount := 10
+	key = strconv.FormatInt(rand.Int63(), 10)
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(goroutineCount)
+	for i := 0; i < goroutineCount; i++ {
+		go func() {
+			defer waitGroup.Done()
+			for j := 0; j < incrCount; j++ {
+				if _, err := store.Incr(key, 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	waitGroup.Wait()
+	found, err = store.Get(key, &actual)
+	handleGetError(t, err, found)
+	if actual != int64(goroutineCount*incrCount) {
+		t.Errorf("Expected: %v, but was: %v", goroutineCount*incrCount, actual)
+	}
+}
+
+// TestClearer tests if deleting all key-value pairs works properly.
+// It deletes all key-value pairs of the store, not only the ones it created itself.
+func TestClearer(store gokv.Clearer, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	keys := []string{prefix + "a", prefix + "b", prefix + "c"}
+	for _, k := range keys {
+		if err := store.Set(k, Foo{Bar: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := store.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		found, err := store.Get(k, new(Foo))
+		if err != nil {
+			t.Error(err)
+		}
+		if found {
+			t.Errorf("A value was found for key %v, but no value was expected", k)
+		}
+	}
+	if lister, ok := store.(gokv.Lister); ok {
+		err = lister.Keys("", func(k string) error {
+			t.Errorf("Key %v was listed, but should have been deleted", k)
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The store must still work after clearing it, and clearing an empty store must work
+	err = store.Set(keys[0], Foo{Bar: "again"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.Get(keys[0], new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	err = store.Clear()
+	if err != nil {
+		t.Error(err)
+	}
+	err = store.Clear()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestStatsStore tests if the statistics of the store are plausible.
+// Other key-value pairs in the store don't lead to a test failure,
+// so only the statistics that the implementation knows (not -1) are checked for a lower bound.
+func TestStatsStore(store gokv.StatsStore, t *testing.T) {
+	before, err := store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	keys := []string{prefix + "a", prefix + "b", prefix + "c"}
+	for _, k := range keys {
+		if err := store.Set(k, Foo{Bar: k}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One hit and one miss
+	if _, err := store.Get(keys[0], new(Foo)); err != nil {
+		t.Error(err)
+	}
+	if _, err := store.Get(prefix+"x", new(Foo)); err != nil {
+		t.Error(err)
+	}
+
+	after, err := store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Keys != -1 && after.Keys < before.Keys+int64(len(keys)) {
+		t.Errorf("Expected at least %v keys, but was: %v", before.Keys+int64(len(keys)), after.Keys)
+	}
+	if after.Bytes < -1 {
+		t.Errorf("Expected a size of at least 0 bytes or -1, but was: %v", after.Bytes)
+	}
+	if after.Hits != -1 && after.Hits < before.Hits+1 {
+		t.Errorf("Expected at least %v hits, but was: %v", before.Hits+1, after.Hits)
+	}
+	if after.Misses != -1 && after.Misses < before.Misses+1 {
+		t.Errorf("Expected at least %v misses, but was: %v", before.Misses+1, after.Misses)
+	}
+	if after.Evictions < -1 {
+		t.Errorf("Expected at least 0 evictions or -1, but was: %v", after.Evictions)
+	}
+
+	for _, k := range keys {
+		if err := store.Delete(k); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestTransactional tests if transactions are committed and rolled back properly,
+// and that concurrent transactions don't lead to torn writes.
+func TestTransactional(store gokv.Transactional, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	keyA, keyB := prefix+"a", prefix+"b"
+	err := store.Set(keyB, Foo{Bar: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Commit
+	err = store.Update(func(tx gokv.Tx) error {
+		if err := tx.Set(keyA, Foo{Bar: "a"}); err != nil {
+			return err
+		}
+		// The transaction's own changes must be visible within the transaction
+		actual := Foo{}
+		found, err := tx.Get(keyA, &actual)
+		if err != nil {
+			return err
+		}
+		if !found || actual.Bar != "a" {
+			t.Errorf("Expected: %v, but was: %v (found: %v)", "a", actual.Bar, found)
+		}
+		if err := tx.Delete(keyB); err != nil {
+			return err
+		}
+		found, err = tx.Get(keyB, new(Foo))
+		if err != nil {
+			return err
+		}
+		if found {
+			t.Error("A value was found, but no value was expected")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := Foo{}
+	found, err := store.Get(keyA, &actual)
+	handleGetError(t, err, found)
+	if actual.Bar != "a" {
+		t.Errorf("Expected: %v, but was: %v", "a", actual.Bar)
+	}
+	found, err = store.Get(keyB, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+
+	// Rollback
+	expectedErr := errors.New("test error")
+	err = store.Update(func(tx gokv.Tx) error {
+		if err := tx.Set(keyA, Foo{Bar: "x"}); err != nil {
+			return err
+		}
+		if err := tx.Set(keyB, Foo{Bar: "x"}); err != nil {
+			return err
+		}
+		return expectedErr
+	})
+	if err != expectedErr {
+		t.Errorf("Expected: %v, but was: %v", expectedErr, err)
+	}
+	actual = Foo{}
+	found, err = store.Get(keyA, &actual)
+	handleGetError(t, err, found)
+	if actual.Bar != "a" {
+		t.Errorf("Expected: %v, but was: %v", "a", actual.Bar)
+	}
+	found, err = store.Get(keyB, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+
+	// The package-level function must report that it's unsupported
+	if err = gokv.Update(storeOnly{store}, func(tx gokv.Tx) error { return nil }); err != gokv.ErrUnsupported {
+		t.Errorf("Expected: %v, but was: %v", gokv.ErrUnsupported, err)
+	}
+
+	// Concurrent transactions must not lead to torn writes
+	goroutineCount := 5
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(goroutineCount)
+	for i := 0; i < goroutineCount; i++ {
+		go func(i int) {
+			defer waitGroup.Done()
+			err := store.Update(func(tx gokv.Tx) error {
+				if err := tx.Set(keyA, i); err != nil {
+					return err
+				}
+				return tx.Set(keyB, i)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+	var a, b int
+	found, err = store.Get(keyA, &a)
+	handleGetError(t, err, found)
+	found, err = store.Get(keyB, &b)
+	handleGetError(t, err, found)
+	if a != b {
+		t.Errorf("The values of the keys should be equal, but were %v and %v", a, b)
+	}
+}
+
+// TestWatcher tests if watching a prefix of keys leads to the expected events
+// and if the channel is closed when the context is canceled.
+func TestWatcher(store gokv.Watcher, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	key := prefix + "a"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := store.Watch(ctx, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func() gokv.Event {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("The channel was closed, but an event was expected")
+			}
+			return e
+		case <-time.After(10 * time.Second):
+			t.Fatal("No event was received within the timeout")
+		}
+		return gokv.Event{}
+	}
+
+	// A change of a key without the prefix must not lead to an event.
+	// Its event would arrive before the following ones.
+	err = store.Set("other-"+key, Foo{Bar: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set(key, Foo{Bar: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := receive()
+	if e.Key != key || e.Type != gokv.EventPut || len(e.Value) == 0 {
+		t.Errorf("Expected a %v event for key %v with a value, but was: %v event for key %v with value %q", gokv.EventPut, key, e.Type, e.Key, e.Value)
+	}
+
+	err = store.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = receive()
+	if e.Key != key || e.Type != gokv.EventDelete || e.Value != nil {
+		t.Errorf("Expected a %v event for key %v without value, but was: %v event for key %v with value %q", gokv.EventDelete, key, e.Type, e.Key, e.Value)
+	}
+
+	// An empty raw value must not be mistaken for a deletion
+	if rawStore, ok := store.(gokv.RawStore); ok {
+		err = rawStore.SetBytes(key, []byte{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e = receive()
+		if e.Key != key || e.Type != gokv.EventPut || len(e.Value) != 0 {
+			t.Errorf("Expected a %v event for key %v with an empty value, but was: %v event for key %v with value %q", gokv.EventPut, key, e.Type, e.Key, e.Value)
+		}
+		err = rawStore.Delete(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e = receive()
+		if e.Key != key || e.Type != gokv.EventDelete {
+			t.Errorf("Expected a %v event for key %v, but was: %v event for key %v", gokv.EventDelete, key, e.Type, e.Key)
+		}
+	}
+
+	// Incrementing a counter must lead to an event with the new value
+	if counter, ok := store.(gokv.Counter); ok {
+		_, err = counter.Incr(key, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e = receive()
+		if e.Key != key || e.Type != gokv.EventPut || string(e.Value) != "2" {
+			t.Errorf("Expected a %v event for key %v with value \"2\", but was: %v event for key %v with value %q", gokv.EventPut, key, e.Type, e.Key, e.Value)
+		}
+		err = counter.Delete(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e = receive()
+		if e.Key != key || e.Type != gokv.EventDelete {
+			t.Errorf("Expected a %v event for key %v, but was: %v event for key %v", gokv.EventDelete, key, e.Type, e.Key)
+		}
+	}
+
+	// Canceling the context must close the channel
+	cancel()
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("The channel wasn't closed within the timeout after canceling the context")
+		}
+	}
+}
+
+// TestNamespaces tests if two namespaces of the same underlying store are isolated from each other,
+// for example two stores created by gokv.WithPrefix with different prefixes.
+// If the stores implement gokv.Lister, it also checks that only the keys of the own namespace are listed.
+func TestNamespaces(a, b gokv.Store, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	err := a.Set(key, Foo{Bar: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key-value pair must not exist in the other namespace
+	found, err := b.Get(key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found in the other namespace, but no value was expected")
+	}
+
+	// Both namespaces must keep their own value for the same key
+	err = b.Set(key, Foo{Bar: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for expected, store := range map[string]gokv.Store{"a": a, "b": b} {
+		actual := Foo{}
+		found, err := store.Get(key, &actual)
+		if err != nil {
+			t.Error(err)
+		}
+		if !found {
+			t.Error("No value was found, but should have been")
+		}
+		if actual.Bar != expected {
+			t.Errorf("Expected %v, but was: %v", expected, actual.Bar)
+		}
+	}
+
+	// Deleting the key-value pair in one namespace must not affect the other
+	err = b.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+	found, err = a.Get(key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+
+	// Each namespace must only list its own keys
+	if lister, ok := a.(gokv.Lister); ok {
+		var actual []string
+		err = lister.Keys(key, func(k string) error {
+			actual = append(actual, k)
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := deep.Equal(actual, []string{key}); diff != nil {
+			t.Error(diff)
+		}
+	}
+	if lister, ok := b.(gokv.Lister); ok {
+		err = lister.Keys(key, func(k string) error {
+			t.Errorf("Key %v was listed, but should only exist in the other namespace", k)
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	err = a.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// storeOnly hides all methods of a store except the ones of gokv.Store,
+// for testing the generic fallbacks of the gokv package.
+type storeOnly struct {
+	gokv.Store
+}
@@ -0,0 +1,23 @@
+/*
+Package tiered contains a gokv.Store implementation that composes a fast local store
+(like freecache, bigcache or gomap) in front of a remote store (like redis, postgresql or dynamodb).
+
+Values are read from the local store and only retrieved from the remote store when they're not found (read-through).
+Writes go to the remote store, and either to the local store as well (WriteThrough) or only delete the local value (WriteAround).
+When the remote store implements gokv.Watcher, its changes invalidate the local values, so that changes made by other
+instances of a service are noticed. Otherwise the local values can be outdated, which can be limited with a LocalTTL.
+
+	local := freecache.NewStore(freecache.DefaultOptions)
+	remote, err := redis.NewClient(redis.DefaultOptions)
+	...
+	options := tiered.DefaultOptions
+	options.Local = local
+	options.Remote = remote
+	options.LocalTTL = time.Minute
+	store, err := tiered.NewStore(options)
+
+The store makes sure that its own reads and writes don't leave outdated values in the local store,
+even when they happen concurrently: A value that was retrieved from the remote store is only stored in the local store
+if the key wasn't changed in the meantime.
+*/
+package tiered
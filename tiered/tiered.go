@@ -0,0 +1,355 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
+
+// WriteMode defines how values are written to the local store.
+type WriteMode int
+
+const (
+	// WriteThrough stores values in the remote store and then in the local store,
+	// so that they can be read from the local store right away.
+	// When the remote store is watched, the events for the store's own changes invalidate the local values as well,
+	// so they're only read from the local store until the event arrives.
+	WriteThrough WriteMode = iota
+	// WriteAround stores values only in the remote store and deletes them from the local store,
+	// so that they're only stored in the local store when they're read.
+	// This keeps values that are written but rarely read out of the local store.
+	WriteAround
+)
+
+// numLocks is the number of locks that the keys are distributed over.
+const numLocks = 64
+
+// watchRetryInterval is the interval in which a failed watch of the remote store is started again.
+const watchRetryInterval = time.Second
+
+// Store is a gokv.Store that composes a fast local store in front of a remote store.
+// Values are read from the local store and, if they're not found there, from the remote store (read-through),
+// in which case they're also stored in the local store for subsequent reads.
+// Values are always written to the remote store, and depending on the WriteMode also to the local store.
+type Store struct {
+	local     gokv.Store
+	remote    gokv.Store
+	writeMode WriteMode
+	localTTL  time.Duration
+	// watch is true if the local store is invalidated by watching the remote store.
+	watch bool
+	state *state
+}
+
+// state is the state that's shared by all copies of a Store.
+type state struct {
+	locks [numLocks]keyLock
+	// watching is 1 while the watch of the remote store is running.
+	watching int32
+	cancel   context.CancelFunc
+	// done is closed when the goroutine that watches the remote store returns.
+	done chan struct{}
+}
+
+// keyLock synchronizes the changes of the local store for the keys that are distributed to it
+// with the storing of values that were read from the remote store.
+type keyLock struct {
+	sync.Mutex
+	// gen is incremented with each change of a key, so that a value that was read from the remote store
+	// isn't stored in the local store if a key was changed in the meantime, because the value might be outdated.
+	gen uint64
+}
+
+// Set stores the given value for the given key in the remote store,
+// and depending on the WriteMode in the local store.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v interface{}) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	return s.write(k, func() error {
+		return s.remote.Set(k, v)
+	}, func() error {
+		if s.writeMode == WriteThrough {
+			return s.setLocal(k, v)
+		}
+		return s.local.Delete(k)
+	})
+}
+
+// Get retrieves the stored value for the given key.
+// If the value isn't found in the local store, it's retrieved from the remote store
+// and stored in the local store, unless the key was changed in the meantime.
+// Errors of the local store lead to a retrieval from the remote store as well.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v interface{}) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	if s.localUsable() {
+		found, err := s.local.Get(k, v)
+		if err == nil && found {
+			return true, nil
+		}
+	}
+
+	l := s.lock(k)
+	l.Lock()
+	gen := l.gen
+	l.Unlock()
+
+	found, err = s.remote.Get(k, v)
+	if err != nil || !found {
+		return found, err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if l.gen == gen && s.localUsable() {
+		// The value is available anyway, so an error of the local store doesn't matter.
+		s.setLocal(k, v)
+	}
+	return true, nil
+}
+
+// Delete deletes the stored value for the given key in the remote store and in the local store.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	return s.write(k, func() error {
+		return s.remote.Delete(k)
+	}, func() error {
+		return s.local.Delete(k)
+	})
+}
+
+// write changes the given key in the remote store with writeRemote and then in the local store with writeLocal.
+// The key is deleted from the local store before the remote store is changed, so outdated values aren't read anymore.
+// writeLocal is only called if the remote store was changed successfully and no other change of the key
+// was started or finished in the meantime, because the order of the changes in the remote store is unknown then.
+// Otherwise the key is deleted from the local store.
+func (s Store) write(k string, writeRemote func() error, writeLocal func() error) error {
+	l := s.lock(k)
+	l.Lock()
+	l.gen++
+	gen := l.gen
+	err := s.local.Delete(k)
+	l.Unlock()
+	if err != nil {
+		return err
+	}
+
+	remoteErr := writeRemote()
+
+	l.Lock()
+	defer l.Unlock()
+	if remoteErr == nil && l.gen == gen {
+		err = writeLocal()
+	} else {
+		err = s.local.Delete(k)
+	}
+	l.gen++
+	if remoteErr != nil {
+		return remoteErr
+	}
+	return err
+}
+
+// setLocal stores the given value in the local store, with the local TTL if one is configured.
+func (s Store) setLocal(k string, v interface{}) error {
+	if s.localTTL > 0 {
+		return s.local.(gokv.ExpiringStore).SetWithTTL(k, v, s.localTTL)
+	}
+	return s.local.Set(k, v)
+}
+
+// localUsable returns false if the local store must not be used,
+// because the watch of the remote store isn't running, so changes of the remote store would be missed.
+func (s Store) localUsable() bool {
+	return !s.watch || atomic.LoadInt32(&s.state.watching) == 1
+}
+
+// lock returns the lock for the given key.
+func (s Store) lock(k string) *keyLock {
+	h := fnv.New32a()
+	h.Write([]byte(k))
+	return &s.state.locks[h.Sum32()%numLocks]
+}
+
+// invalidate deletes the given key from the local store,
+// and prevents values that are currently read from the remote store from being stored in the local store.
+func (s Store) invalidate(k string) {
+	l := s.lock(k)
+	l.Lock()
+	defer l.Unlock()
+	l.gen++
+	s.local.Delete(k)
+}
+
+// invalidateAll deletes all key-value pairs from the local store,
+// and prevents values that are currently read from the remote store from being stored in the local store.
+func (s Store) invalidateAll() error {
+	for i := range s.state.locks {
+		l := &s.state.locks[i]
+		l.Lock()
+		l.gen++
+		l.Unlock()
+	}
+	return gokv.Clear(s.local)
+}
+
+// startWatch watches the remote store and clears the local store,
+// because changes that happened while the remote store wasn't watched are unknown.
+// The returned function stops the watch.
+func (s Store) startWatch(ctx context.Context) (<-chan gokv.Event, context.CancelFunc, error) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	events, err := s.remote.(gokv.Watcher).Watch(watchCtx, "")
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if err := s.invalidateAll(); err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	atomic.StoreInt32(&s.state.watching, 1)
+	return events, cancel, nil
+}
+
+// handleEvents invalidates the keys of the events in the local store.
+// When the watch fails, the local store isn't used until the watch was started again.
+func (s Store) handleEvents(ctx context.Context, events <-chan gokv.Event, cancel context.CancelFunc) {
+	defer close(s.state.done)
+	for {
+		for e := range events {
+			s.invalidate(e.Key)
+		}
+		atomic.StoreInt32(&s.state.watching, 0)
+		cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchRetryInterval):
+			}
+			var err error
+			if events, cancel, err = s.startWatch(ctx); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// Close stops watching the remote store and closes both the local and the remote store.
+func (s Store) Close() error {
+	if s.state.cancel != nil {
+		s.state.cancel()
+		<-s.state.done
+	}
+	localErr := s.local.Close()
+	if err := s.remote.Close(); err != nil {
+		return err
+	}
+	return localErr
+}
+
+// Options are the options for the tiered store.
+type Options struct {
+	// Fast local store, for example a freecache, bigcache or gomap store.
+	// If the remote store supports gokv.Watcher, the local store must support gokv.Clearer or gokv.Lister,
+	// so that it can be cleared when the watch is (re-)started.
+	Local gokv.Store
+	// Remote store that's the source of truth, for example a redis, postgresql or dynamodb client.
+	Remote gokv.Store
+	// Defines how values are written to the local store.
+	// Optional (WriteThrough by default).
+	WriteMode WriteMode
+	// Time to live of the values in the local store.
+	// Limits how long a value can be outdated when the remote store is changed by others
+	// and the changes aren't noticed via a watch.
+	// Requires the local store to support gokv.ExpiringStore.
+	// Optional (0 by default, which means that the values don't expire).
+	LocalTTL time.Duration
+	// By default the local store is invalidated by watching the remote store if it supports gokv.Watcher,
+	// so that changes made by others are noticed. This disables it.
+	// Optional (false by default).
+	DisableWatch bool
+}
+
+// DefaultOptions is an Options object with default values.
+// WriteMode: WriteThrough, LocalTTL: 0, DisableWatch: false
+var DefaultOptions = Options{
+	WriteMode: WriteThrough,
+	// No need to set LocalTTL or DisableWatch because their Go zero values are fine.
+}
+
+// NewStore creates a new tiered store.
+// If the remote store supports gokv.Watcher (see gokv.Supports()) and the watch isn't disabled,
+// the local store is cleared and the remote store is watched in a background goroutine.
+// If the remote store's Watch method returns gokv.ErrUnsupported nonetheless, the remote store isn't watched.
+// While the watch isn't running, for example after the connection to the remote store was lost,
+// all values are read from the remote store.
+//
+// You must call the Close() method on the store when you're done working with it.
+// It also closes the local and the remote store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	// Precondition check
+	if options.Local == nil || options.Remote == nil {
+		return result, errors.New("The Local and Remote stores in the options must not be nil")
+	}
+	if options.WriteMode != WriteThrough && options.WriteMode != WriteAround {
+		return result, fmt.Errorf("Invalid WriteMode: %v", options.WriteMode)
+	}
+	if err := util.CheckTTL(options.LocalTTL); err != nil {
+		return result, err
+	}
+	if options.LocalTTL > 0 && !gokv.Supports(options.Local, (*gokv.ExpiringStore)(nil)) {
+		return result, fmt.Errorf("The LocalTTL requires a Local store that implements gokv.ExpiringStore: %w", gokv.ErrUnsupported)
+	}
+	watch := !options.DisableWatch && gokv.Supports(options.Remote, (*gokv.Watcher)(nil))
+	if watch && !gokv.Supports(options.Local, (*gokv.Clearer)(nil)) && !gokv.Supports(options.Local, (*gokv.Lister)(nil)) {
+		return result, fmt.Errorf("Watching the Remote store requires a Local store that implements gokv.Clearer or gokv.Lister: %w", gokv.ErrUnsupported)
+	}
+
+	result.local = options.Local
+	result.remote = options.Remote
+	result.writeMode = options.WriteMode
+	result.localTTL = options.LocalTTL
+	result.state = new(state)
+
+	if watch {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, cancelWatch, err := result.startWatch(ctx)
+		// Some stores only support watching with a certain configuration,
+		// in which case the local store is used without invalidation, like for stores that don't implement gokv.Watcher.
+		if errors.Is(err, gokv.ErrUnsupported) {
+			cancel()
+			return result, nil
+		} else if err != nil {
+			cancel()
+			return Store{}, err
+		}
+		result.watch = true
+		result.state.cancel = cancel
+		result.state.done = make(chan struct{})
+		go result.handleEvents(ctx, events, cancelWatch)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,291 @@
+package tiered_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/test"
+	"github.com/philippgille/gokv/tiered"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with write-through
+	t.Run("WriteThrough", func(t *testing.T) {
+		store, _, _ := createStore(t, tiered.WriteThrough, encoding.JSON)
+		defer store.Close()
+		test.TestStore(store, t)
+	})
+
+	// Test with write-around
+	t.Run("WriteAround", func(t *testing.T) {
+		store, _, _ := createStore(t, tiered.WriteAround, encoding.JSON)
+		defer store.Close()
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _, _ := createStore(t, tiered.WriteThrough, encoding.JSON)
+		defer store.Close()
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _, _ := createStore(t, tiered.WriteThrough, encoding.Gob)
+		defer store.Close()
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _, _ := createStore(t, tiered.WriteThrough, encoding.JSON)
+	defer store.Close()
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test missing stores
+	_, err := tiered.NewStore(tiered.DefaultOptions)
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test empty key
+	store, _, _ := createStore(t, tiered.WriteThrough, encoding.JSON)
+	defer store.Close()
+	err = store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestWriteMode tests if values are written to the local store according to the write mode,
+// and if values that are read from the remote store are stored in the local store.
+func TestWriteMode(t *testing.T) {
+	// With write-through the value must be in the local store right after storing it
+	store, local, _ := createStore(t, tiered.WriteThrough, encoding.JSON)
+	defer store.Close()
+	err := store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, local, "foo", "baz")
+
+	// With write-around the value must only be in the local store after reading it
+	store, local, _ = createStore(t, tiered.WriteAround, encoding.JSON)
+	defer store.Close()
+	err = store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, local, "foo", "")
+	checkValue(t, store, "foo", "baz")
+	checkValue(t, local, "foo", "baz")
+
+	// Deleting must delete the value from both stores
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, local, "foo", "")
+	checkValue(t, store, "foo", "")
+}
+
+// TestInvalidation tests if changes of the remote store that are made by others
+// invalidate the values in the local store.
+func TestInvalidation(t *testing.T) {
+	store, _, remote := createStore(t, tiered.WriteThrough, encoding.JSON)
+	defer store.Close()
+
+	err := store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "baz")
+
+	err = remote.Set("foo", test.Foo{Bar: "qux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The event is handled in the background
+	timeout := time.Now().Add(10 * time.Second)
+	for {
+		actual := test.Foo{}
+		if _, err := store.Get("foo", &actual); err != nil {
+			t.Fatal(err)
+		}
+		if actual.Bar == "qux" {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatal("The value in the local store wasn't invalidated within the timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = remote.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeout = time.Now().Add(10 * time.Second)
+	for {
+		found, err := store.Get("foo", new(test.Foo))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatal("The value in the local store wasn't invalidated within the timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestLocalTTL tests if values in the local store expire after the LocalTTL,
+// which limits how long outdated values are read when the remote store isn't watched.
+func TestLocalTTL(t *testing.T) {
+	local := gomap.NewStore(gomap.DefaultOptions)
+	remote := gomap.NewStore(gomap.DefaultOptions)
+	options := tiered.Options{
+		Local:        local,
+		Remote:       remote,
+		LocalTTL:     500 * time.Millisecond,
+		DisableWatch: true,
+	}
+	store, err := tiered.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err = store.Set("foo", test.Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = remote.Set("foo", test.Foo{Bar: "qux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without watching the remote store, the outdated value is read until it expires
+	checkValue(t, store, "foo", "baz")
+	time.Sleep(time.Second)
+	checkValue(t, store, "foo", "qux")
+}
+
+// TestWrapped tests if the capabilities of wrapped stores are detected,
+// which implement all optional interfaces but return gokv.ErrUnsupported for the ones the wrapped store doesn't support.
+func TestWrapped(t *testing.T) {
+	// A remote store that can't be watched is used without invalidation
+	for _, remote := range []gokv.Store{
+		gokv.WithPrefix(basicStore{gomap.NewStore(gomap.DefaultOptions)}, "prefix:"),
+		unwatchableStore{gomap.NewStore(gomap.DefaultOptions)},
+	} {
+		store, err := tiered.NewStore(tiered.Options{
+			Local:  gomap.NewStore(gomap.DefaultOptions),
+			Remote: remote,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Set("foo", test.Foo{Bar: "baz"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, store, "foo", "baz")
+		store.Close()
+	}
+
+	// A local store that doesn't support TTLs is rejected for a LocalTTL
+	_, err := tiered.NewStore(tiered.Options{
+		Local:        gokv.WithPrefix(basicStore{gomap.NewStore(gomap.DefaultOptions)}, "prefix:"),
+		Remote:       gomap.NewStore(gomap.DefaultOptions),
+		LocalTTL:     time.Minute,
+		DisableWatch: true,
+	})
+	if !errors.Is(err, gokv.ErrUnsupported) {
+		t.Errorf("Expected gokv.ErrUnsupported, but was: %v", err)
+	}
+}
+
+// basicStore is a gokv.Store that doesn't implement any optional interface.
+type basicStore struct {
+	gokv.Store
+}
+
+// unwatchableStore is a gomap.Store whose Watch method returns gokv.ErrUnsupported.
+type unwatchableStore struct {
+	gomap.Store
+}
+
+func (s unwatchableStore) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return nil, gokv.ErrUnsupported
+}
+
+// checkValue checks if the given store contains the expected value for the given key,
+// or no value if expected is "".
+func checkValue(t *testing.T, store interface {
+	Get(k string, v interface{}) (bool, error)
+}, key, expected string) {
+	t.Helper()
+	actual := test.Foo{}
+	found, err := store.Get(key, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected == "" {
+		if found {
+			t.Errorf("A value was found, but no value was expected")
+		}
+		return
+	}
+	if !found {
+		t.Errorf("No value was found, but should have been")
+	} else if actual.Bar != expected {
+		t.Errorf("Expected %v, but was: %v", expected, actual.Bar)
+	}
+}
+
+func createStore(t *testing.T, writeMode tiered.WriteMode, codec encoding.Codec) (tiered.Store, gomap.Store, gomap.Store) {
+	local := gomap.NewStore(gomap.Options{
+		Codec: codec,
+	})
+	remote := gomap.NewStore(gomap.Options{
+		Codec: codec,
+	})
+	options := tiered.Options{
+		Local:     local,
+		Remote:    remote,
+		WriteMode: writeMode,
+	}
+	store, err := tiered.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, local, remote
+}
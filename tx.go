@@ -0,0 +1,12 @@
+package gokv
+
+// Update calls fn with a new transaction of the given store
+// and commits the transaction if fn returns nil.
+// If the store doesn't implement Transactional, ErrUnsupported is returned without calling fn.
+func Update(store Store, fn func(tx Tx) error) error {
+	transactional, ok := store.(Transactional)
+	if !ok {
+		return ErrUnsupported
+	}
+	return transactional.Update(fn)
+}
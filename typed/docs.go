@@ -0,0 +1,15 @@
+/*
+Package typed contains a wrapper for gokv.Store implementations that uses generics for type-checked values.
+
+The gokv.Store interface accepts values of any type, which requires callers
+to pass a pointer to Get() and leads to errors at runtime when they don't.
+A typed.Store is bound to a single value type, so the compiler checks the values
+and Get() returns the retrieved value instead of populating a pointer:
+
+	store := typed.NewStore[Foo](gomap.NewStore(gomap.DefaultOptions))
+	err := store.Set("foo123", Foo{Bar: "baz"})
+	foo, found, err := store.Get("foo123")
+
+The package requires Go 1.18 or newer, while the other gokv modules can still be used with older Go versions.
+*/
+package typed
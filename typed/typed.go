@@ -0,0 +1,54 @@
+package typed
+
+import (
+	"github.com/philippgille/gokv"
+)
+
+// Store is a wrapper for a gokv.Store that only stores values of type T.
+type Store[T any] struct {
+	store gokv.Store
+}
+
+// Set stores the given value for the given key.
+// See gokv.Store.Set() for details.
+func (s Store[T]) Set(k string, v T) error {
+	return s.store.Set(k, v)
+}
+
+// Get retrieves the value for the given key.
+// If no value is found it returns the zero value of T and found is false.
+// See gokv.Store.Get() for details.
+func (s Store[T]) Get(k string) (v T, found bool, err error) {
+	found, err = s.store.Get(k, &v)
+	if err != nil || !found {
+		var zero T
+		return zero, found, err
+	}
+	return v, true, nil
+}
+
+// Delete deletes the stored value for the given key.
+// See gokv.Store.Delete() for details.
+func (s Store[T]) Delete(k string) error {
+	return s.store.Delete(k)
+}
+
+// Close closes the wrapped store.
+func (s Store[T]) Close() error {
+	return s.store.Close()
+}
+
+// Unwrap returns the wrapped store,
+// for example for using methods of optional interfaces like gokv.Lister.
+func (s Store[T]) Unwrap() gokv.Store {
+	return s.store
+}
+
+// NewStore creates a new typed.Store that wraps the given gokv.Store.
+// All values stored via the returned store are of type T, but the wrapped store can of course
+// contain values of other types that were stored in other ways.
+func NewStore[T any](store gokv.Store) Store[T] {
+	return Store[T]{
+		store: store,
+	}
+}
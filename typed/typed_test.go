@@ -0,0 +1,185 @@
+package typed_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/typed"
+)
+
+type foo struct {
+	Bar string
+	Baz []int
+}
+
+// TestStruct tests if storing, retrieving and deleting struct values works properly.
+func TestStruct(t *testing.T) {
+	store := createStore[foo](encoding.JSON)
+
+	expected := foo{Bar: "bar", Baz: []int{1, 2}}
+	err := store.Set("foo", expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, found, err := store.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if actual.Bar != expected.Bar || len(actual.Baz) != 2 || actual.Baz[0] != 1 || actual.Baz[1] != 2 {
+		t.Errorf("Expected %v, but was: %v", expected, actual)
+	}
+
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, found, err = store.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+	if actual.Bar != "" || actual.Baz != nil {
+		t.Errorf("Expected the zero value, but was: %v", actual)
+	}
+}
+
+// TestMissing tests if retrieving a missing key returns the zero value of T and found is false.
+func TestMissing(t *testing.T) {
+	store := createStore[int](encoding.JSON)
+
+	actual, found, err := store.Get("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+	if actual != 0 {
+		t.Errorf("Expected 0, but was: %v", actual)
+	}
+}
+
+// TestPointer tests if pointers can be used as T,
+// in which case a new value is allocated for each retrieval.
+func TestPointer(t *testing.T) {
+	store := createStore[*foo](encoding.JSON)
+
+	actual, found, err := store.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found || actual != nil {
+		t.Errorf("Expected nil and no value to be found, but was: %v (found: %v)", actual, found)
+	}
+
+	expected := &foo{Bar: "bar"}
+	err = store.Set("foo", expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, found, err = store.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if actual == nil || actual.Bar != expected.Bar {
+		t.Errorf("Expected %v, but was: %v", expected, actual)
+	}
+	if actual == expected {
+		t.Error("Expected a new pointer, but was the stored one")
+	}
+}
+
+// TestGob tests if values that can't be marshalled to JSON can be stored with encoding.Gob.
+func TestGob(t *testing.T) {
+	expected := map[complex128]string{1 + 2i: "a"}
+
+	jsonStore := createStore[map[complex128]string](encoding.JSON)
+	err := jsonStore.Set("foo", expected)
+	if err == nil {
+		t.Error("Expected an error from encoding.JSON")
+	}
+
+	store := createStore[map[complex128]string](encoding.Gob)
+	err = store.Set("foo", expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, found, err := store.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if len(actual) != 1 || actual[1+2i] != "a" {
+		t.Errorf("Expected %v, but was: %v", expected, actual)
+	}
+}
+
+// TestErrors tests if the errors of the wrapped store are returned.
+func TestErrors(t *testing.T) {
+	store := createStore[string](encoding.JSON)
+	err := store.Set("", "bar")
+	if !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected %v, but was: %v", gokv.ErrEmptyKey, err)
+	}
+	err = store.Delete("")
+	if !errors.Is(err, gokv.ErrEmptyKey) {
+		t.Errorf("Expected %v, but was: %v", gokv.ErrEmptyKey, err)
+	}
+
+	// A stored value that isn't a string can't be decoded,
+	// in which case the zero value must be returned along with the error
+	err = store.Unwrap().Set("foo", 123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, _, err := store.Get("foo")
+	if err == nil {
+		t.Error("Expected an error for a value of another type")
+	}
+	if actual != "" {
+		t.Errorf("Expected the zero value, but was: %q", actual)
+	}
+
+	failing := typed.NewStore[string](failingStore{store.Unwrap()})
+	if _, _, err = failing.Get("foo"); err != errFailing {
+		t.Errorf("Expected %v, but was: %v", errFailing, err)
+	}
+	if err = failing.Close(); err != errFailing {
+		t.Errorf("Expected %v, but was: %v", errFailing, err)
+	}
+}
+
+var errFailing = errors.New("failing")
+
+// failingStore is a gokv.Store whose Get and Close methods always fail.
+type failingStore struct {
+	gokv.Store
+}
+
+func (s failingStore) Get(k string, v interface{}) (bool, error) {
+	return false, errFailing
+}
+
+func (s failingStore) Close() error {
+	return errFailing
+}
+
+func createStore[T any](codec encoding.Codec) typed.Store[T] {
+	options := gomap.Options{
+		Codec: codec,
+	}
+	return typed.NewStore[T](gomap.NewStore(options))
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/philippgille/gokv"
+)
+
+// Broadcaster sends events to the watchers of key prefixes.
+// Stores without a native notification mechanism (like in-memory stores) use it
+// to implement gokv.Watcher, by publishing an event for each change.
+// Each watcher has its own unbounded queue, so publishing never blocks,
+// even if a watcher doesn't receive its events.
+type Broadcaster struct {
+	lock     *sync.Mutex
+	watchers map[*watcher]struct{}
+	closed   bool
+}
+
+// watcher is a single watcher of a Broadcaster.
+type watcher struct {
+	prefix string
+	lock   *sync.Mutex
+	queue  []gokv.Event
+	// wake is notified when events were added to the queue
+	wake chan struct{}
+	// stop is closed when the broadcaster is closed
+	stop chan struct{}
+}
+
+// NewBroadcaster creates a new Broadcaster without watchers.
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		lock:     new(sync.Mutex),
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// Watch returns a channel on which all events for keys with the given prefix are sent
+// that are published after Watch returned.
+// The channel is closed when the context is canceled or the broadcaster is closed.
+func (b *Broadcaster) Watch(ctx context.Context, prefix string) <-chan gokv.Event {
+	events := make(chan gokv.Event)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		close(events)
+		return events
+	}
+	w := &watcher{
+		prefix: prefix,
+		lock:   new(sync.Mutex),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	b.watchers[w] = struct{}{}
+	go b.forward(ctx, w, events)
+	return events
+}
+
+// forward sends the queued events of the watcher to the channel until the watch ends.
+func (b *Broadcaster) forward(ctx context.Context, w *watcher, events chan<- gokv.Event) {
+	defer close(events)
+	defer func() {
+		b.lock.Lock()
+		delete(b.watchers, w)
+		b.lock.Unlock()
+	}()
+	for {
+		w.lock.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.lock.Unlock()
+		for _, e := range queue {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			case <-w.stop:
+				return
+			}
+		}
+		select {
+		case <-w.wake:
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Publish sends the given event to all watchers whose prefix matches the event's key.
+// The event's value is copied, so the caller can keep using it.
+// Callers should publish events while they still hold the lock that guarded the change,
+// so that the events are sent in the order of the changes.
+func (b *Broadcaster) Publish(e gokv.Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	copied := false
+	for w := range b.watchers {
+		if !strings.HasPrefix(e.Key, w.prefix) {
+			continue
+		}
+		if !copied && e.Value != nil {
+			e.Value = append([]byte{}, e.Value...)
+			copied = true
+		}
+		w.lock.Lock()
+		w.queue = append(w.queue, e)
+		w.lock.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+			// The watcher was already woken up and will see the new event as well
+		}
+	}
+}
+
+// Close closes the channels of all watchers.
+// Watching after the broadcaster was closed returns a closed channel.
+// It's safe to call it multiple times.
+func (b *Broadcaster) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for w := range b.watchers {
+		close(w.stop)
+	}
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+)
+
+// errOverflow is returned by IncrData if the incremented value doesn't fit into an int64.
+var errOverflow = errors.New("The incremented value would overflow int64")
+
+// CheckCounterCodec returns an error matching gokv.ErrUnsupported if codec isn't encoding.JSON.
+// It's meant for gokv.Counter implementations that use the native increments of the underlying store,
+// which store the value as a decimal number, which only the JSON codec can unmarshal into an int64.
+func CheckCounterCodec(codec encoding.Codec) error {
+	if _, ok := codec.(encoding.JSONcodec); !ok {
+		return fmt.Errorf("Incr requires encoding.JSON, because the value is stored as a decimal number: %w", gokv.ErrUnsupported)
+	}
+	return nil
+}
+
+// IncrData adds delta to the int64 value that's marshalled in data with the given codec
+// and returns the new value as well as its marshalled form.
+// It's meant for gokv.Counter implementations that read, increment and write the value in a transaction.
+// If data is nil, the current value is 0.
+func IncrData(codec encoding.Codec, data []byte, delta int64) (int64, []byte, error) {
+	var value int64
+	if data != nil {
+		if err := codec.Unmarshal(data, &value); err != nil {
+			return 0, nil, err
+		}
+	}
+	if (delta > 0 && value > math.MaxInt64-delta) || (delta < 0 && value < math.MinInt64-delta) {
+		return 0, nil, errOverflow
+	}
+	value += delta
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return 0, nil, err
+	}
+	return value, data, nil
+}
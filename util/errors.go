@@ -0,0 +1,38 @@
+package util
+
+import (
+	"errors"
+)
+
+// WrapError returns an error that wraps the error of a store's client library
+// and also matches the given gokv sentinel error (like gokv.ErrClosed) with errors.Is().
+// This way callers can check for the sentinel error regardless of the store,
+// while checks for the client library's error keep working.
+// The error message is the one of err.
+// If err is nil or already matches the sentinel error, err is returned unchanged.
+func WrapError(sentinel, err error) error {
+	if err == nil || errors.Is(err, sentinel) {
+		return err
+	}
+	return wrappedError{
+		sentinel: sentinel,
+		err:      err,
+	}
+}
+
+type wrappedError struct {
+	sentinel error
+	err      error
+}
+
+func (e wrappedError) Error() string {
+	return e.err.Error()
+}
+
+func (e wrappedError) Unwrap() error {
+	return e.err
+}
+
+func (e wrappedError) Is(target error) bool {
+	return target == e.sentinel
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Sweeper periodically calls a function in a background goroutine until it's stopped.
+// Stores without native support for expiring key-value pairs use it
+// to delete expired key-value pairs that were never accessed again.
+type Sweeper struct {
+	stop     chan struct{}
+	stopOnce *sync.Once
+}
+
+// StartSweeper starts a goroutine that calls sweep every interval.
+// Errors returned by sweep are ignored, because there's no one to report them to
+// and the next sweep will try again anyway.
+func StartSweeper(interval time.Duration, sweep func() error) Sweeper {
+	s := Sweeper{
+		stop:     make(chan struct{}),
+		stopOnce: new(sync.Once),
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = sweep()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Stop stops the sweeper's goroutine.
+// It's safe to call it multiple times.
+func (s Sweeper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
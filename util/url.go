@@ -0,0 +1,209 @@
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/philippgille/gokv/encoding"
+)
+
+// URLQuery reads the query parameters of a URL that's passed to a gokv.Factory.
+// Each method only sets the destination if the parameter is present,
+// so options that aren't in the URL keep their values (typically the Go zero value, leading to the default).
+// The first invalid value is recorded and returned by Err,
+// so all parameters can be read before checking for an error.
+type URLQuery struct {
+	values url.Values
+	read   map[string]bool
+	err    error
+}
+
+// NewURLQuery creates a URLQuery for the query parameters of the given URL.
+func NewURLQuery(u *url.URL) *URLQuery {
+	return &URLQuery{
+		values: u.Query(),
+		read:   make(map[string]bool),
+	}
+}
+
+// get returns the value of the given parameter and marks it as read.
+func (q *URLQuery) get(name string) (string, bool) {
+	q.read[name] = true
+	if _, found := q.values[name]; !found {
+		return "", false
+	}
+	return q.values.Get(name), true
+}
+
+// fail records the error for an invalid value of the given parameter, unless an error was already recorded.
+func (q *URLQuery) fail(name string, err error) {
+	if q.err == nil {
+		q.err = fmt.Errorf("Invalid value for the query parameter %q: %w", name, err)
+	}
+}
+
+// String sets dst to the value of the given parameter.
+// It returns true if the parameter is present.
+func (q *URLQuery) String(name string, dst *string) bool {
+	v, found := q.get(name)
+	if found {
+		*dst = v
+	}
+	return found
+}
+
+// Strings sets dst to the comma-separated values of the given parameter.
+// It returns true if the parameter is present.
+func (q *URLQuery) Strings(name string, dst *[]string) bool {
+	v, found := q.get(name)
+	if found {
+		*dst = strings.Split(v, ",")
+	}
+	return found
+}
+
+// Int sets dst to the value of the given parameter.
+// It returns true if the parameter is present and valid.
+func (q *URLQuery) Int(name string, dst *int) bool {
+	v, found := q.get(name)
+	if !found {
+		return false
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		q.fail(name, err)
+		return false
+	}
+	*dst = i
+	return true
+}
+
+// Int64 sets dst to the value of the given parameter.
+// It returns true if the parameter is present and valid.
+func (q *URLQuery) Int64(name string, dst *int64) bool {
+	v, found := q.get(name)
+	if !found {
+		return false
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		q.fail(name, err)
+		return false
+	}
+	*dst = i
+	return true
+}
+
+// Bool sets dst to the value of the given parameter.
+// An empty value (like in "?writeSync") counts as true.
+// It returns true if the parameter is present and valid.
+func (q *URLQuery) Bool(name string, dst *bool) bool {
+	v, found := q.get(name)
+	if !found {
+		return false
+	}
+	if v == "" {
+		*dst = true
+		return true
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		q.fail(name, err)
+		return false
+	}
+	*dst = b
+	return true
+}
+
+// Duration sets dst to the value of the given parameter, which must be in the format of time.ParseDuration,
+// for example "1m30s".
+// It returns true if the parameter is present and valid.
+func (q *URLQuery) Duration(name string, dst *time.Duration) bool {
+	v, found := q.get(name)
+	if !found {
+		return false
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		q.fail(name, err)
+		return false
+	}
+	*dst = d
+	return true
+}
+
+// Codec sets dst to the codec that's named by the "codec" parameter ("json" or "gob").
+// It returns true if the parameter is present and valid.
+func (q *URLQuery) Codec(dst *encoding.Codec) bool {
+	v, found := q.get("codec")
+	if !found {
+		return false
+	}
+	switch strings.ToLower(v) {
+	case "json":
+		*dst = encoding.JSON
+	case "gob":
+		*dst = encoding.Gob
+	default:
+		q.fail("codec", fmt.Errorf("unknown codec %q", v))
+		return false
+	}
+	return true
+}
+
+// Rest returns the parameters that haven't been read yet and marks them as read.
+// It's meant for implementations that pass the remaining parameters on to the underlying client,
+// for example as part of a connection URL.
+func (q *URLQuery) Rest() url.Values {
+	result := url.Values{}
+	for name, vs := range q.values {
+		if !q.read[name] {
+			result[name] = vs
+			q.read[name] = true
+		}
+	}
+	return result
+}
+
+// Err returns the first invalid value that was encountered,
+// or an error for the parameters that haven't been read, because they're unknown to the implementation.
+func (q *URLQuery) Err() error {
+	if q.err != nil {
+		return q.err
+	}
+	var unknown []string
+	for name := range q.values {
+		if !q.read[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Unknown query parameters: %v", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// URLPath returns the path that's contained in the given URL,
+// for implementations that use a path of the local file system.
+// Both absolute paths ("bbolt:///tmp/gokv.db") and relative paths ("bbolt://gokv.db", "bbolt:gokv.db") are supported.
+func URLPath(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
+
+// URLHosts returns the comma-separated hosts of the given URL, for example
+// ["host1:2379", "host2:2379"] for "etcd://host1:2379,host2:2379".
+// It returns nil if the URL doesn't contain a host.
+func URLHosts(u *url.URL) []string {
+	if u.Host == "" {
+		return nil
+	}
+	return strings.Split(u.Host, ",")
+}
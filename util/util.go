@@ -2,6 +2,10 @@ package util
 
 import (
 	"errors"
+	"io"
+	"time"
+
+	"github.com/philippgille/gokv"
 )
 
 // CheckKeyAndValue returns an error if k == "" or if v == nil
@@ -12,18 +16,90 @@ func CheckKeyAndValue(k string, v interface{}) error {
 	return CheckVal(v)
 }
 
-// CheckKey returns an error if k == ""
+// CheckKey returns gokv.ErrEmptyKey if k == ""
 func CheckKey(k string) error {
 	if k == "" {
-		return errors.New("The passed key is an empty string, which is invalid")
+		return gokv.ErrEmptyKey
 	}
 	return nil
 }
 
-// CheckVal returns an error if v == nil
+// CheckVal returns gokv.ErrNilValue if v == nil
 func CheckVal(v interface{}) error {
 	if v == nil {
-		return errors.New("The passed value is nil, which is not allowed")
+		return gokv.ErrNilValue
+	}
+	return nil
+}
+
+// CheckKeyAndBytes returns an error if k == "" or if v == nil
+func CheckKeyAndBytes(k string, v []byte) error {
+	if err := CheckKey(k); err != nil {
+		return err
+	}
+	if v == nil {
+		return gokv.ErrNilValue
 	}
 	return nil
 }
+
+// CheckKeyAndReader returns an error if k == "" or if r == nil
+func CheckKeyAndReader(k string, r io.Reader) error {
+	if err := CheckKey(k); err != nil {
+		return err
+	}
+	if r == nil {
+		return gokv.ErrNilValue
+	}
+	return nil
+}
+
+// CheckTTL returns an error that matches gokv.ErrInvalidTTL if ttl < 0
+func CheckTTL(ttl time.Duration) error {
+	if ttl < 0 {
+		return WrapError(gokv.ErrInvalidTTL, errors.New("The passed TTL is negative, which is invalid"))
+	}
+	return nil
+}
+
+// CheckKeysAndValues returns an error if the lengths of keys and vs differ,
+// or if any key is "" or any value is nil
+func CheckKeysAndValues(keys []string, vs []interface{}) error {
+	if len(keys) != len(vs) {
+		return errors.New("The number of passed keys and values differs")
+	}
+	for i, k := range keys {
+		if err := CheckKeyAndValue(k, vs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckKeys returns an error if any key is ""
+func CheckKeys(keys []string) error {
+	for _, k := range keys {
+		if err := CheckKey(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UniqueKeyIndexes returns the indexes of the given keys without duplicates.
+// For keys that occur multiple times, the index of the last occurrence is used,
+// so that when storing multiple key-value pairs the last value wins, like with sequential Set calls.
+// The returned indexes are in ascending order.
+func UniqueKeyIndexes(keys []string) []int {
+	lastIndexes := make(map[string]int, len(keys))
+	for i, k := range keys {
+		lastIndexes[k] = i
+	}
+	result := make([]int, 0, len(lastIndexes))
+	for i, k := range keys {
+		if lastIndexes[k] == i {
+			result = append(result, i)
+		}
+	}
+	return result
+}
@@ -1,4 +1,9 @@
 /*
 Package zookeeper contains an implementation of the `gokv.Store` interface for Apache ZooKeeper.
+
+The package registers itself for the URL scheme "zookeeper", so a client can also be created with gokv.Open,
+for example with "zookeeper://host1:2181,host2:2181/gokv/?codec=gob".
+The comma-separated hosts are used as Servers, the path as PathPrefix
+and the query parameter "codec" ("json" or "gob") corresponds to the field of Options.
 */
 package zookeeper
@@ -1,12 +1,15 @@
 package zookeeper
 
 import (
+	"context"
 	"errors"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -32,15 +35,7 @@ func (c Client) Set(k string, v interface{}) error {
 		return err
 	}
 
-	k = c.pathPrefix + k
-	acl := zk.WorldACL(zk.PermAll)
-	_, err = c.c.Create(k, data, 0, acl)
-	if err != nil {
-		if err.Error() == "zk: node already exists" {
-			_, err = c.c.Set(k, data, -1)
-		}
-	}
-	return err
+	return c.set(k, data)
 }
 
 // Get retrieves the stored value for the given key.
@@ -57,10 +52,10 @@ func (c Client) Get(k string, v interface{}) (found bool, err error) {
 	k = c.pathPrefix + k
 	data, _, err := c.c.Get(k)
 	if err != nil {
-		if err.Error() == "zk: node does not exist" {
+		if err == zk.ErrNoNode {
 			return false, nil
 		}
-		return false, err
+		return false, wrapError(err)
 	}
 
 	return true, c.codec.Unmarshal(data, v)
@@ -76,9 +71,213 @@ func (c Client) Delete(k string) error {
 
 	k = c.pathPrefix + k
 	err := c.c.Delete(k, -1)
-	if err != nil && err.Error() == "zk: node does not exist" {
+	if err == zk.ErrNoNode {
 		return nil
 	}
+	return wrapError(err)
+}
+
+// SetBytes stores the given bytes for the given key without marshalling them.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetBytes(k string, v []byte) error {
+	if err := util.CheckKeyAndBytes(k, v); err != nil {
+		return err
+	}
+
+	return c.set(k, v)
+}
+
+// GetBytes retrieves the stored bytes for the given key without unmarshalling them.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetBytes(k string) (v []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	k = c.pathPrefix + k
+	data, _, err := c.c.Get(k)
+	if err != nil {
+		if err == zk.ErrNoNode {
+			return nil, false, nil
+		}
+		return nil, false, wrapError(err)
+	}
+	return data, true, nil
+}
+
+// set creates the node for the given key with the given data,
+// or updates the data if the node already exists.
+func (c Client) set(k string, data []byte) error {
+	k = c.pathPrefix + k
+	acl := zk.WorldACL(zk.PermAll)
+	_, err := c.c.Create(k, data, 0, acl)
+	if err != nil {
+		if err == zk.ErrNodeExists {
+			_, err = c.c.Set(k, data, -1)
+		}
+	}
+	return wrapError(err)
+}
+
+// SetContext stores the given value for the given key.
+// The go-zookeeper package doesn't support contexts, so the context is only checked before the request is sent.
+func (c Client) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(k, v)
+}
+
+// GetContext retrieves the stored value for the given key.
+// The context is only checked before the value is retrieved.
+func (c Client) GetContext(ctx context.Context, k string, v interface{}) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Get(k, v)
+}
+
+// DeleteContext deletes the stored value for the given key.
+// The context is only checked before the value is deleted.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Delete(k)
+}
+
+// Watch returns a channel on which an event is sent for each change
+// of a key-value pair whose key starts with the given prefix.
+// ZooKeeper watches only fire once, so a watch is set on the parent node for new keys
+// and on each key's node for changes of its value and its deletion, and they're set again after they fired.
+// Multiple changes of a value that happen before the watch is set again
+// only lead to a single event with the latest value.
+// The channel is closed when the context is canceled or the watch fails,
+// for example because the ZooKeeper session expired.
+func (c Client) Watch(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	// The keys are children of the last node of the path prefix,
+	// and the remainder of the path prefix is part of their node names.
+	i := strings.LastIndex(c.pathPrefix, "/")
+	parent := c.pathPrefix[:i]
+	if parent == "" {
+		parent = "/"
+	}
+	keyPrefix := c.pathPrefix[i+1:]
+	namePrefix := keyPrefix + prefix
+
+	children, _, childrenChan, err := c.c.ChildrenW(parent)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	stop := make(chan struct{})
+	nodeEvents := make(chan zk.Event)
+	watched := make(map[string]bool)
+	// watchNode retrieves the value of a node and sets a watch on it.
+	// found is false if the node doesn't exist (anymore).
+	watchNode := func(name string) (data []byte, found bool, err error) {
+		data, _, nodeChan, err := c.c.GetW(strings.TrimSuffix(parent, "/") + "/" + name)
+		if err != nil {
+			if err == zk.ErrNoNode {
+				return nil, false, nil
+			}
+			return nil, false, wrapError(err)
+		}
+		watched[name] = true
+		go func() {
+			select {
+			case ev := <-nodeChan:
+				select {
+				case nodeEvents <- ev:
+				case <-stop:
+				}
+			case <-stop:
+			}
+		}()
+		return data, true, nil
+	}
+	for _, name := range children {
+		if strings.HasPrefix(name, namePrefix) {
+			if _, _, err := watchNode(name); err != nil {
+				close(stop)
+				return nil, err
+			}
+		}
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer close(stop)
+		send := func(e gokv.Event) bool {
+			select {
+			case events <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-childrenChan:
+				if ev.Err != nil || ev.Type == zk.EventNotWatching {
+					return
+				}
+				children, _, childrenChan, err = c.c.ChildrenW(parent)
+				if err != nil {
+					return
+				}
+				for _, name := range children {
+					if !strings.HasPrefix(name, namePrefix) || watched[name] {
+						continue
+					}
+					data, found, err := watchNode(name)
+					if err != nil {
+						return
+					}
+					if found && !send(gokv.Event{Key: strings.TrimPrefix(name, keyPrefix), Type: gokv.EventPut, Value: data}) {
+						return
+					}
+				}
+			case ev := <-nodeEvents:
+				if ev.Err != nil || ev.Type == zk.EventNotWatching {
+					return
+				}
+				name := ev.Path[strings.LastIndex(ev.Path, "/")+1:]
+				key := strings.TrimPrefix(name, keyPrefix)
+				delete(watched, name)
+				if ev.Type == zk.EventNodeDeleted {
+					if !send(gokv.Event{Key: key, Type: gokv.EventDelete}) {
+						return
+					}
+					continue
+				}
+				data, found, err := watchNode(name)
+				if err != nil {
+					return
+				}
+				e := gokv.Event{Key: key, Type: gokv.EventPut, Value: data}
+				if !found {
+					e = gokv.Event{Key: key, Type: gokv.EventDelete}
+				}
+				if !send(e) {
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// wrapError wraps errors of the go-zookeeper package that correspond to a gokv sentinel error.
+func wrapError(err error) error {
+	switch err {
+	case zk.ErrClosing, zk.ErrConnectionClosed:
+		return util.WrapError(gokv.ErrClosed, err)
+	}
 	return err
 }
 
@@ -173,7 +372,7 @@ func NewClient(options Options) (Client, error) {
 				nodeToCreate += pathElem
 				_, _, err = c.Get(nodeToCreate)
 				if err != nil {
-					if err.Error() == "zk: node does not exist" {
+					if err == zk.ErrNoNode {
 						_, err = c.Create(nodeToCreate, nil, 0, acl)
 						if err != nil {
 							return result, err
@@ -193,3 +392,26 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("zookeeper", newClientFromURL)
+}
+
+// newClientFromURL creates a new client from a URL that's passed to gokv.Open.
+func newClientFromURL(u *url.URL) (gokv.Store, error) {
+	options := Options{
+		Servers:    util.URLHosts(u),
+		PathPrefix: u.Path,
+	}
+	q := util.NewURLQuery(u)
+	q.Codec(&options.Codec)
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
@@ -188,6 +188,48 @@ func TestClose(t *testing.T) {
 	}
 }
 
+// TestContext tests if the context-aware methods work properly.
+//
+// Note: This test is only executed if the initial connection to Apache ZooKeeper works.
+func TestContext(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Apache ZooKeeper could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestContextStore(client, t)
+}
+
+// TestWatch tests if the store sends the expected events for changes of watched keys.
+//
+// Note: This test is only executed if the initial connection to Apache ZooKeeper works.
+func TestWatch(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Apache ZooKeeper could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if storing and retrieving raw bytes works properly.
+//
+// Note: This test is only executed if the initial connection to Apache ZooKeeper works.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Apache ZooKeeper could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer client.Close()
+
+	test.TestRawStore(client, t)
+}
+
 // checkConnection returns true if a connection could be made, false otherwise.
 func checkConnection() bool {
 	c, _, err := zk.Connect([]string{"localhost:2181"}, 2*time.Second, zk.WithLogInfo(false))